@@ -0,0 +1,257 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/storage"
+	"flashcards-api.johndennehy101.tech/internal/validator"
+)
+
+const maxAttachmentSize = 10 << 20
+
+type attachmentFormat struct {
+	kind string
+	ext  string
+}
+
+// attachmentFormats maps sniffed content types to the accepted attachment
+// kinds, keyed on http.DetectContentType output.
+var attachmentFormats = map[string]attachmentFormat{
+	"image/png":       {data.AttachmentImage, ".png"},
+	"image/jpeg":      {data.AttachmentImage, ".jpg"},
+	"image/gif":       {data.AttachmentImage, ".gif"},
+	"image/webp":      {data.AttachmentImage, ".webp"},
+	"audio/mpeg":      {data.AttachmentAudio, ".mp3"},
+	"audio/wave":      {data.AttachmentAudio, ".wav"},
+	"application/ogg": {data.AttachmentAudio, ".ogg"},
+}
+
+func newStorageKey(flashcardID int64, ext string) string {
+	return fmt.Sprintf("flashcards/%d/%s%s", flashcardID, strings.ToLower(rand.Text()), ext)
+}
+
+// signAttachments fills in the time-limited download URL for each attachment.
+func (app *application) signAttachments(attachments ...*data.Attachment) error {
+	for _, attachment := range attachments {
+		url, err := app.storage.URL(attachment.StorageKey, app.config.storage.urlTTL)
+		if err != nil {
+			return err
+		}
+		attachment.URL = url
+	}
+
+	return nil
+}
+
+func (app *application) uploadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	flashcard, err := app.models.Flashcards.Get(r.Context(), id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAttachmentSize+(1<<20))
+
+	err = r.ParseMultipartForm(maxAttachmentSize)
+	if err != nil {
+		app.badRequestResponse(w, r, fmt.Errorf("body must be a multipart form no larger than %d bytes", maxAttachmentSize))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	v := validator.New()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		v.AddError("file", "must be provided")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	defer file.Close()
+
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(file, sniff)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	contentType := http.DetectContentType(sniff[:n])
+	format, ok := attachmentFormats[contentType]
+
+	v.Check(ok, "file", "must be a PNG, JPEG, GIF or WebP image, or an MP3, WAV or Ogg audio file")
+	v.Check(header.Size <= maxAttachmentSize, "file", fmt.Sprintf("must not be larger than %d bytes", maxAttachmentSize))
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	attachment := &data.Attachment{
+		FlashcardID: flashcard.ID,
+		UserID:      user.ID,
+		Kind:        format.kind,
+		StorageKey:  newStorageKey(flashcard.ID, format.ext),
+		Filename:    header.Filename,
+		ContentType: contentType,
+		Size:        header.Size,
+	}
+
+	err = app.storage.Put(attachment.StorageKey, file, header.Size, contentType)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Attachments.Insert(r.Context(), attachment)
+	if err != nil {
+		app.storage.Delete(attachment.StorageKey)
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.signAttachments(attachment)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/flashcards/%d/attachments/%d", flashcard.ID, attachment.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"attachment": attachment}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	attachments, err := app.models.Attachments.GetAllForFlashcards(r.Context(), []int64{id})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.signAttachments(attachments...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"attachments": attachments}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	attachmentID, err := app.readNamedIDParam(r, "attachment_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	attachment, err := app.models.Attachments.Get(r.Context(), attachmentID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Attachments.Delete(r.Context(), attachment.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.background(func() {
+		err := app.storage.Delete(attachment.StorageKey)
+		if err != nil {
+			app.logger.Error(err.Error(), "storage_key", attachment.StorageKey)
+		}
+	})
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "attachment successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// downloadAttachmentHandler serves blobs from the local storage backend. The
+// signed URL is the credential, so the route sits outside requirePermission.
+func (app *application) downloadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	local, ok := app.storage.(*storage.Local)
+	if !ok {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	qs := r.URL.Query()
+
+	f, err := local.Open(qs.Get("key"), qs.Get("expires"), qs.Get("signature"))
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrInvalidSignature):
+			app.notPermittedResponse(w, r)
+		default:
+			app.notFoundResponse(w, r)
+		}
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
@@ -0,0 +1,32 @@
+package main
+
+import "net/http"
+
+type capability struct {
+	Enabled  bool   `json:"enabled"`
+	Provider string `json:"provider,omitempty"`
+}
+
+// capabilitiesHandler advertises the optional subsystems configured in this
+// deployment, so clients can hide features instead of probing endpoints and
+// handling 404s. Subsystems that don't exist in this API yet are always
+// reported as disabled.
+func (app *application) capabilitiesHandler(w http.ResponseWriter, r *http.Request) {
+	capabilities := map[string]capability{
+		"llm_generation":       {Enabled: false},
+		"billing":              {Enabled: false},
+		"webhooks":             {Enabled: true},
+		"category_suggestions": {Enabled: app.config.categories.suggest},
+		"tts":                  {Enabled: app.tts != nil, Provider: app.config.tts.provider},
+		"semantic_search":      {Enabled: app.embeddings != nil, Provider: app.config.embedding.provider},
+		"external_search":      {Enabled: app.search != nil, Provider: app.config.search.engine},
+		"attachments":          {Enabled: app.storage != nil, Provider: app.config.storage.backend},
+		"lti":                  {Enabled: app.lti != nil},
+		"xapi":                 {Enabled: app.xapi != nil},
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"capabilities": capabilities}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/validator"
+)
+
+type categoryInput struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Colour      string   `json:"colour"`
+	Icon        string   `json:"icon"`
+	Keywords    []string `json:"keywords"`
+}
+
+func (input categoryInput) apply(c *data.Category) {
+	c.Name = strings.TrimSpace(input.Name)
+	c.Description = input.Description
+	c.Colour = input.Colour
+	c.Icon = input.Icon
+	c.Keywords = input.Keywords
+
+	if c.Keywords == nil {
+		c.Keywords = []string{}
+	}
+}
+
+func (app *application) createCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	var input categoryInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	category := &data.Category{}
+	input.apply(category)
+
+	v := validator.New()
+
+	if data.ValidateCategory(v, category); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Categories.Insert(r.Context(), category)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateCategoryName):
+			v.AddError("name", "a category with this name already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/categories/%d", category.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"category": category}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	category, err := app.models.Categories.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"category": category}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	v := validator.New()
+
+	// A prefix comes from a tag input suggesting existing categories, so the
+	// most used ones are listed first.
+	prefix := app.readString(qs, "prefix", "")
+
+	defaultSort := "name"
+	if prefix != "" {
+		defaultSort = "-card_count"
+	}
+
+	filters := data.Filters{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", 20, v),
+		Sort:         app.readString(qs, "sort", defaultSort),
+		SortSafelist: []string{"name", "card_count", "created_at", "-name", "-card_count", "-created_at"},
+		SortColumns:  map[string]string{"name": "c.name", "created_at": "c.created_at"},
+	}
+
+	v.Check(len(prefix) <= 100, "prefix", "must not be more than 100 bytes long")
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	categories, metadata, err := app.models.Categories.GetAll(r.Context(), prefix, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"categories": categories, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	category, err := app.models.Categories.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input categoryInput
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	input.apply(category)
+
+	v := validator.New()
+
+	if data.ValidateCategory(v, category); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	changed, err := app.models.Categories.Update(r.Context(), category)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateCategoryName):
+			v.AddError("name", "a category with this name already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.remirrorFlashcards(changed)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"category": category}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	changed, err := app.models.Categories.Delete(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.remirrorFlashcards(changed)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "category successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// mergeCategoriesHandler folds one category into another, for cleaning up
+// near-duplicate tags.
+func (app *application) mergeCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		From string `json:"from"`
+		Into string `json:"into"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(input.From != "", "from", "must be provided")
+	v.Check(input.Into != "", "into", "must be provided")
+	v.Check(data.NormalizeCategory(input.From) != data.NormalizeCategory(input.Into), "into", "must be different from from")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	for field, name := range map[string]string{"from": input.From, "into": input.Into} {
+		_, err := app.models.Categories.GetByName(r.Context(), name)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				v.AddError(field, "category does not exist")
+			default:
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+		}
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	changed, err := app.models.Categories.Merge(r.Context(), input.From, input.Into)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.remirrorFlashcards(changed)
+
+	category, err := app.models.Categories.GetByName(r.Context(), input.Into)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"category": category, "flashcards_updated": len(changed)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showCategoryStatsHandler(w http.ResponseWriter, r *http.Request) {
+	stats, err := app.models.Categories.GetStats(r.Context(), app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"categories": stats}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// retagFlashcardsHandler adds and removes categories on many cards at once.
+// Cards are picked by ids, or by a query string of the flashcard listing.
+func (app *application) retagFlashcardsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		IDs    []int64  `json:"ids"`
+		Query  *string  `json:"query"`
+		Add    []string `json:"add"`
+		Remove []string `json:"remove"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(len(input.IDs) > 0 || input.Query != nil, "ids", "ids or query must be provided")
+	v.Check(len(input.IDs) == 0 || input.Query == nil, "query", "must not be combined with ids")
+	v.Check(len(input.IDs) <= maxBatchSize, "ids", fmt.Sprintf("must not contain more than %d ids", maxBatchSize))
+	v.Check(validator.Unique(input.IDs), "ids", "must not contain duplicate values")
+	v.Check(len(input.Add)+len(input.Remove) > 0, "add", "add or remove must contain at least one category")
+
+	for field, names := range map[string][]string{"add": input.Add, "remove": input.Remove} {
+		v.Check(validator.Unique(names), field, "must not contain duplicate values")
+		data.ValidateCategoryNames(v, field, names)
+	}
+
+	removed := data.NormalizeCategories(input.Remove)
+
+	for _, name := range data.NormalizeCategories(input.Add) {
+		v.Check(!slices.Contains(removed, name), "remove", "must not contain categories that are also added")
+	}
+
+	var filter data.FlashcardFilter
+
+	if input.Query != nil {
+		qs, err := url.ParseQuery(*input.Query)
+		if err != nil {
+			v.AddError("query", "must be a valid query string")
+		} else {
+			filter, err = app.readFlashcardFilter(r.Context(), qs, v)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+		}
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	changed, err := app.models.Flashcards.Retag(r.Context(), user.ID, input.IDs, filter, input.Add, input.Remove)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.remirrorFlashcards(changed)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"flashcards_updated": len(changed), "ids": changed}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
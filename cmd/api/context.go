@@ -9,7 +9,12 @@ import (
 
 type contextKey string
 
-const userContextKey = contextKey("user")
+const (
+	userContextKey        = contextKey("user")
+	statelessContextKey   = contextKey("stateless")
+	scopesContextKey      = contextKey("scopes")
+	permissionsContextKey = contextKey("permissions")
+)
 
 func (app *application) contextSetUser(r *http.Request, user *data.User) *http.Request {
 	ctx := context.WithValue(r.Context(), userContextKey, user)
@@ -24,3 +29,41 @@ func (app *application) contextGetUser(r *http.Request) *data.User {
 
 	return user
 }
+
+// contextSetStatelessUser stores a user rebuilt from the claims of a JWT
+// rather than read from the database.
+func (app *application) contextSetStatelessUser(r *http.Request, user *data.User) *http.Request {
+	ctx := context.WithValue(r.Context(), statelessContextKey, true)
+	return app.contextSetUser(r.WithContext(ctx), user)
+}
+
+func (app *application) contextIsStateless(r *http.Request) bool {
+	stateless, _ := r.Context().Value(statelessContextKey).(bool)
+	return stateless
+}
+
+// contextSetScopes stores the scopes the request's token is limited to, nil
+// for full access.
+func (app *application) contextSetScopes(r *http.Request, scopes data.Permissions) *http.Request {
+	ctx := context.WithValue(r.Context(), scopesContextKey, scopes)
+	return r.WithContext(ctx)
+}
+
+func (app *application) contextGetScopes(r *http.Request) data.Permissions {
+	scopes, _ := r.Context().Value(scopesContextKey).(data.Permissions)
+	return scopes
+}
+
+// contextSetPermissions stores the user's permissions when the token carries
+// them, as JWTs do, so they needn't be read from the database.
+func (app *application) contextSetPermissions(r *http.Request, permissions data.Permissions) *http.Request {
+	ctx := context.WithValue(r.Context(), permissionsContextKey, permissions)
+	return r.WithContext(ctx)
+}
+
+// contextGetPermissions returns the permissions stored for the request, nil
+// when they have to be looked up.
+func (app *application) contextGetPermissions(r *http.Request) data.Permissions {
+	permissions, _ := r.Context().Value(permissionsContextKey).(data.Permissions)
+	return permissions
+}
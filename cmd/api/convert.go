@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/validator"
+)
+
+func (app *application) convertFlashcardHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	flashcard, err := app.models.Flashcards.Get(r.Context(), id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	editable, err := app.canEditFlashcard(r.Context(), user, flashcard)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !editable {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	to := data.FlashcardType(app.readString(r.URL.Query(), "to", ""))
+
+	v := validator.New()
+	v.Check(validator.PermittedValue(to, data.FlashcardTypes...), "to", "invalid flashcard type")
+
+	if v.Valid() {
+		v.Check(data.CanConvert(flashcard.Type, to), "to", fmt.Sprintf("cannot convert a %s card to %s", flashcard.Type, to))
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	var distractors []string
+
+	if to == data.FlashcardMCQ {
+		distractors, err = app.models.Flashcards.GetDistractors(r.Context(), flashcard)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	content, err := data.ConvertContent(flashcard.Content, to, distractors)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNotEnoughDistractors):
+			v.AddError("to", "the card's section has no other cards to draw MCQ options from")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	resetProgress := data.ConversionResetsProgress(flashcard.Type, to)
+
+	flashcard.Type = to
+	flashcard.Content = content
+
+	if data.ValidateFlashcard(v, flashcard); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Flashcards.Convert(r.Context(), flashcard, resetProgress)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.mirrorFlashcard(flashcard)
+	app.embedFlashcard(flashcard)
+
+	app.linkFlashcards(flashcard)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"flashcard": flashcard}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+)
+
+// dataExportTTL is how long a finished export archive can be downloaded.
+const dataExportTTL = 7 * 24 * time.Hour
+
+// createDataExportHandler starts building an archive of everything held
+// about the user in the background. Its progress and download URL are read
+// from showDataExportHandler.
+func (app *application) createDataExportHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	export, err := app.models.Users.CreateExport(r.Context(), user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrExportInProgress):
+			app.exportInProgressResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.background(func() {
+		err := app.buildDataExport(user.ID)
+		if err != nil {
+			app.logger.Error(err.Error(), "user_id", user.ID)
+
+			err = app.models.Users.FailExport(context.Background(), user.ID, "the export could not be prepared")
+			if err != nil {
+				app.logger.Error(err.Error(), "user_id", user.ID)
+			}
+		}
+	})
+
+	headers := make(http.Header)
+	headers.Set("Location", "/v1/users/me/export")
+
+	err = app.writeJSON(w, http.StatusAccepted, envelope{"export": export}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// buildDataExport writes the user's export documents into a ZIP archive and
+// stores it alongside the attachments.
+func (app *application) buildDataExport(userID int64) error {
+	ctx := context.Background()
+
+	files, err := app.models.Users.GetExportFiles(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+
+	zw := zip.NewWriter(&buf)
+
+	for _, f := range files {
+		fw, err := zw.Create(f.Name)
+		if err != nil {
+			return err
+		}
+
+		_, err = fw.Write(f.Data)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = zw.Close()
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("exports/%d/%s.zip", userID, rand.Text())
+	size := int64(buf.Len())
+
+	err = app.storage.Put(key, &buf, size, "application/zip")
+	if err != nil {
+		return err
+	}
+
+	return app.models.Users.CompleteExport(ctx, userID, key, size, dataExportTTL)
+}
+
+// showDataExportHandler reports the status of the user's latest export, with
+// a download URL once it is ready.
+func (app *application) showDataExportHandler(w http.ResponseWriter, r *http.Request) {
+	export, err := app.models.Users.GetExport(r.Context(), app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if export.StorageKey != nil {
+		export.URL, err = app.storage.URL(*export.StorageKey, app.config.storage.urlTTL)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"export": export}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/validator"
+)
+
+const deckInvitationTTL = 7 * 24 * time.Hour
+
+// getEditableDeck is getDeck for changes to the deck's cards, which editors
+// may make too.
+func (app *application) getEditableDeck(w http.ResponseWriter, r *http.Request) (*data.Deck, bool) {
+	return app.loadDeck(w, r, app.models.Decks.GetEditable)
+}
+
+// canEditFlashcard reports whether the user may edit the card: as its owner,
+// as an admin or editor, or as an editor of one of the owner's decks holding
+// it.
+func (app *application) canEditFlashcard(ctx context.Context, user *data.User, flashcard *data.Flashcard) (bool, error) {
+	deletable, err := app.canDeleteFlashcard(ctx, user, flashcard)
+	if err != nil || deletable {
+		return deletable, err
+	}
+
+	return app.models.Decks.EditableByMember(ctx, flashcard.ID, user.ID)
+}
+
+// canDeleteFlashcard reports whether the user may delete the card, which
+// only its owner, admins and editors can. Editors keep access to the
+// catalogue cards, which have no owner.
+func (app *application) canDeleteFlashcard(ctx context.Context, user *data.User, flashcard *data.Flashcard) (bool, error) {
+	if flashcard.OwnerID != nil && *flashcard.OwnerID == user.ID {
+		return true, nil
+	}
+
+	permissions, err := app.models.Permissions.GetAllForUser(ctx, user.ID)
+	if err != nil {
+		return false, err
+	}
+
+	return permissions.Include("admin:write") || permissions.Include("flashcards:write"), nil
+}
+
+// listDeckMembersHandler lists the deck's owner and members to its members.
+// Readers of a public or link-shared deck can't see who else is in it.
+func (app *application) listDeckMembersHandler(w http.ResponseWriter, r *http.Request) {
+	deck, ok := app.getVisibleDeck(w, r)
+	if !ok {
+		return
+	}
+
+	if deck.Role == "" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	members, err := app.models.Decks.GetMembers(r.Context(), deck.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"members": members}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateDeckMemberHandler(w http.ResponseWriter, r *http.Request) {
+	deck, ok := app.getDeck(w, r)
+	if !ok {
+		return
+	}
+
+	memberID, err := app.readNamedIDParam(r, "user_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Role string `json:"role"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateDeckRole(v, input.Role); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Decks.UpdateMember(r.Context(), deck.ID, memberID, input.Role)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"member": envelope{"user_id": memberID, "role": input.Role}}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// removeDeckMemberHandler lets the owner remove a member, and members leave.
+func (app *application) removeDeckMemberHandler(w http.ResponseWriter, r *http.Request) {
+	deck, ok := app.getVisibleDeck(w, r)
+	if !ok {
+		return
+	}
+
+	memberID, err := app.readNamedIDParam(r, "user_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	if !deck.Owned && memberID != app.contextGetUser(r).ID {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	err = app.models.Decks.RemoveMember(r.Context(), deck.ID, memberID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "member successfully removed"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// inviteDeckMemberHandler emails an invitation to join the deck. The token
+// is only sent to the invitee, never returned to the owner.
+func (app *application) inviteDeckMemberHandler(w http.ResponseWriter, r *http.Request) {
+	deck, ok := app.getDeck(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	inv := &data.DeckInvitation{
+		DeckID: deck.ID,
+		Email:  strings.TrimSpace(input.Email),
+		Role:   input.Role,
+	}
+
+	v := validator.New()
+
+	if data.ValidateDeckInvitation(v, inv); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Decks.Invite(r.Context(), inv, deckInvitationTTL)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	inviter := app.contextGetUser(r)
+
+	app.background(func() {
+		templateData := map[string]any{
+			"deckName":        deck.Name,
+			"inviterName":     inviter.Name,
+			"role":            inv.Role,
+			"invitationToken": inv.Plaintext,
+		}
+
+		err := app.mailer.Send(inv.Email, "deck_invitation.tmpl", templateData)
+		if err != nil {
+			app.logger.Error(err.Error())
+		}
+	})
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"invitation": inv}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listDeckInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	deck, ok := app.getDeck(w, r)
+	if !ok {
+		return
+	}
+
+	invitations, err := app.models.Decks.GetInvitations(r.Context(), deck.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"invitations": invitations}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteDeckInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	deck, ok := app.getDeck(w, r)
+	if !ok {
+		return
+	}
+
+	invitationID, err := app.readNamedIDParam(r, "invitation_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Decks.DeleteInvitation(r.Context(), invitationID, deck.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "invitation successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) acceptDeckInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		TokenPlaintext string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateTokenPlaintext(v, input.TokenPlaintext); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	deckID, err := app.models.Decks.AcceptInvitation(r.Context(), input.TokenPlaintext, user)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("token", "invalid or expired invitation token")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	deck, err := app.models.Decks.GetVisible(r.Context(), deckID, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"deck": deck}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
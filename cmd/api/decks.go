@@ -0,0 +1,926 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/validator"
+	"github.com/julienschmidt/httprouter"
+)
+
+type deckInput struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Visibility  string  `json:"visibility"`
+	Query       *string `json:"query"`
+	Scheduler   *string `json:"scheduler"`
+
+	SchedulerConfig *data.SchedulerConfig `json:"scheduler_config"`
+}
+
+func (input deckInput) apply(d *data.Deck) {
+	d.Name = strings.TrimSpace(input.Name)
+	d.Description = input.Description
+	d.Visibility = input.Visibility
+	d.Query = input.Query
+	d.Scheduler = input.Scheduler
+
+	if d.Scheduler != nil && *d.Scheduler == "" {
+		d.Scheduler = nil
+	}
+
+	d.SchedulerConfig = input.SchedulerConfig
+
+	if d.SchedulerConfig != nil && *d.SchedulerConfig == (data.SchedulerConfig{}) {
+		d.SchedulerConfig = nil
+	}
+
+	if d.Visibility == "" {
+		d.Visibility = "private"
+	}
+}
+
+// validateDeck checks the deck and, for smart decks, its query, reporting
+// query problems under query.<param>.
+func (app *application) validateDeck(ctx context.Context, v *validator.Validator, d *data.Deck) error {
+	if data.ValidateDeck(v, d); !v.Valid() || !d.Smart() {
+		return nil
+	}
+
+	return app.validateListingQuery(ctx, v, *d.Query)
+}
+
+// getDeck loads one of the requesting user's decks from the :id parameter,
+// writing the error response itself when that fails.
+func (app *application) getDeck(w http.ResponseWriter, r *http.Request) (*data.Deck, bool) {
+	return app.loadDeck(w, r, app.models.Decks.Get)
+}
+
+// getVisibleDeck is getDeck for reads, which members and public decks also
+// allow.
+func (app *application) getVisibleDeck(w http.ResponseWriter, r *http.Request) (*data.Deck, bool) {
+	return app.loadDeck(w, r, app.models.Decks.GetVisible)
+}
+
+func (app *application) loadDeck(w http.ResponseWriter, r *http.Request, get func(context.Context, int64, int64) (*data.Deck, error)) (*data.Deck, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	deck, err := get(r.Context(), id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return deck, true
+}
+
+func (app *application) createDeckHandler(w http.ResponseWriter, r *http.Request) {
+	var input deckInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	deck := &data.Deck{UserID: app.contextGetUser(r).ID}
+	input.apply(deck)
+
+	v := validator.New()
+
+	err = app.validateDeck(r.Context(), v, deck)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Decks.Insert(r.Context(), deck)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateDeckName):
+			v.AddError("name", "a deck with this name already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/decks/%d", deck.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"deck": deck}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showDeckHandler(w http.ResponseWriter, r *http.Request) {
+	deck, ok := app.getVisibleDeck(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"deck": deck}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listDecksHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	v := validator.New()
+
+	filters := data.Filters{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", 20, v),
+		Sort:         app.readString(qs, "sort", "name"),
+		SortSafelist: []string{"name", "card_count", "created_at", "-name", "-card_count", "-created_at"},
+		SortColumns:  map[string]string{"name": "d.name", "created_at": "d.created_at"},
+	}
+
+	archived := app.readBool(qs, "archived", false, v)
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	decks, metadata, err := app.models.Decks.GetAllForUser(r.Context(), app.contextGetUser(r).ID, archived, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"decks": decks, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateDeckHandler(w http.ResponseWriter, r *http.Request) {
+	deck, ok := app.getDeck(w, r)
+	if !ok {
+		return
+	}
+
+	var input deckInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	input.apply(deck)
+
+	v := validator.New()
+
+	err = app.validateDeck(r.Context(), v, deck)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Decks.Update(r.Context(), deck)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateDeckName):
+			v.AddError("name", "a deck with this name already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"deck": deck}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteDeckHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Decks.Delete(r.Context(), id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "deck successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listDeckFlashcardsHandler serves the flashcard listing limited to the deck,
+// so every listing filter, sort and include works within a deck too.
+func (app *application) listDeckFlashcardsHandler(w http.ResponseWriter, r *http.Request) {
+	deck, ok := app.getVisibleDeck(w, r)
+	if !ok {
+		return
+	}
+
+	app.listDeckFlashcards(w, r, deck)
+}
+
+// listDeckFlashcards lists a hand-picked deck's cards, or runs a smart deck's
+// query like a saved search, with the request's parameters overriding the
+// saved ones.
+func (app *application) listDeckFlashcards(w http.ResponseWriter, r *http.Request, deck *data.Deck) {
+	if !deck.Smart() {
+		app.listFlashcards(w, r, r.URL.Query(), deck.ID)
+		return
+	}
+
+	qs, err := url.ParseQuery(*deck.Query)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	for key, values := range r.URL.Query() {
+		qs[key] = values
+	}
+
+	app.listFlashcards(w, r, qs, 0)
+}
+
+// showDeckStatsHandler reports card counts, progress and accuracy for the
+// deck's cards, which for a smart deck are the cards its query matches now.
+func (app *application) showDeckStatsHandler(w http.ResponseWriter, r *http.Request) {
+	deck, ok := app.getVisibleDeck(w, r)
+	if !ok {
+		return
+	}
+
+	filter := data.FlashcardFilter{DeckID: deck.ID}
+
+	if deck.Smart() {
+		qs, err := url.ParseQuery(*deck.Query)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		v := validator.New()
+
+		filter, err = app.readFlashcardFilter(r.Context(), qs, v)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		// The query was valid when saved, but metadata fields it filters on
+		// can have been deleted since.
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+	}
+
+	stats, err := app.models.Decks.GetStats(r.Context(), app.contextGetUser(r).ID, filter)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"stats": stats}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) addDeckFlashcardsHandler(w http.ResponseWriter, r *http.Request) {
+	app.changeDeckFlashcards(w, r, true)
+}
+
+func (app *application) removeDeckFlashcardsHandler(w http.ResponseWriter, r *http.Request) {
+	app.changeDeckFlashcards(w, r, false)
+}
+
+func (app *application) changeDeckFlashcards(w http.ResponseWriter, r *http.Request, add bool) {
+	deck, ok := app.getEditableDeck(w, r)
+	if !ok {
+		return
+	}
+
+	if deck.Smart() {
+		app.smartDeckResponse(w, r)
+		return
+	}
+
+	var input struct {
+		IDs []int64 `json:"ids"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(len(input.IDs) > 0, "ids", "must contain at least one id")
+	v.Check(len(input.IDs) <= maxBatchSize, "ids", fmt.Sprintf("must not contain more than %d ids", maxBatchSize))
+	v.Check(validator.Unique(input.IDs), "ids", "must not contain duplicate values")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	var changed []int64
+	key := "added"
+
+	if add {
+		changed, err = app.models.Decks.AddFlashcards(r.Context(), deck.ID, input.IDs)
+	} else {
+		changed, err = app.models.Decks.RemoveFlashcards(r.Context(), deck.ID, input.IDs)
+		key = "removed"
+	}
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{key: changed}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deckMove places one card of a deck before or after another card, or at a
+// 1-based position.
+type deckMove struct {
+	ID       int64  `json:"id"`
+	Before   *int64 `json:"before"`
+	After    *int64 `json:"after"`
+	Position *int   `json:"position"`
+}
+
+// apply returns order with the card moved, or false when the card, or the
+// card it is placed next to, isn't in the deck. Positions past the end move
+// the card to the end.
+func (m deckMove) apply(order []int64) ([]int64, bool) {
+	from := slices.Index(order, m.ID)
+	if from < 0 {
+		return order, false
+	}
+
+	order = slices.Delete(order, from, from+1)
+
+	var to int
+
+	switch {
+	case m.Before != nil:
+		to = slices.Index(order, *m.Before)
+	case m.After != nil:
+		to = slices.Index(order, *m.After)
+		if to >= 0 {
+			to++
+		}
+	default:
+		to = min(*m.Position-1, len(order))
+	}
+
+	if to < 0 {
+		return order, false
+	}
+
+	return slices.Insert(order, to, m.ID), true
+}
+
+// reorderDeckHandler sets the manual order of a deck's cards, either from the
+// full ordered list of ids or by applying moves to the current order.
+func (app *application) reorderDeckHandler(w http.ResponseWriter, r *http.Request) {
+	deck, ok := app.getEditableDeck(w, r)
+	if !ok {
+		return
+	}
+
+	if deck.Smart() {
+		app.smartDeckResponse(w, r)
+		return
+	}
+
+	var input struct {
+		IDs   []int64    `json:"ids"`
+		Moves []deckMove `json:"moves"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check((input.IDs == nil) != (input.Moves == nil), "ids", "exactly one of ids or moves must be provided")
+	v.Check(validator.Unique(input.IDs), "ids", "must not contain duplicate values")
+
+	for i, move := range input.Moves {
+		key := fmt.Sprintf("moves[%d]", i)
+
+		given := 0
+		for _, set := range []bool{move.Before != nil, move.After != nil, move.Position != nil} {
+			if set {
+				given++
+			}
+		}
+
+		v.Check(given == 1, key, "must have exactly one of before, after or position")
+		v.Check(move.Position == nil || *move.Position >= 1, key+".position", "must be greater than zero")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	order := input.IDs
+
+	if input.Moves != nil {
+		order, err = app.models.Decks.GetOrder(r.Context(), deck.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		for i, move := range input.Moves {
+			order, ok = move.apply(order)
+			if !ok {
+				v.AddError(fmt.Sprintf("moves[%d]", i), "must only refer to cards in the deck")
+			}
+		}
+
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+	}
+
+	err = app.models.Decks.Reorder(r.Context(), deck.ID, order)
+	if err != nil {
+		switch {
+		// The moves were applied to the order as it was read, so a mismatch
+		// means the deck changed in the meantime.
+		case errors.Is(err, data.ErrIncompleteOrder) && input.Moves != nil:
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrIncompleteOrder):
+			v.AddError("ids", "must list every card in the deck exactly once")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"flashcard_ids": order}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) archiveDeckHandler(w http.ResponseWriter, r *http.Request) {
+	app.setDeckArchived(w, r, true)
+}
+
+func (app *application) unarchiveDeckHandler(w http.ResponseWriter, r *http.Request) {
+	app.setDeckArchived(w, r, false)
+}
+
+// setDeckArchived archives or unarchives one of the user's decks. Listings
+// only show archived decks with ?archived=true, and ?hide_archived=true on
+// the flashcard listing leaves out cards that are only in archived decks.
+func (app *application) setDeckArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	deck, ok := app.getDeck(w, r)
+	if !ok {
+		return
+	}
+
+	archivedAt, err := app.models.Decks.SetArchived(r.Context(), deck.ID, deck.UserID, archived)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	deck.ArchivedAt = archivedAt
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"deck": deck}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// shareDeckHandler mints a share link giving read-only access to the deck
+// without signing in. Sharing again replaces the earlier link.
+func (app *application) shareDeckHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	token, err := app.models.Decks.Share(r.Context(), id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	share := map[string]string{"token": token, "url": "/v1/shared/" + token}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"share": share}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) unshareDeckHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Decks.Unshare(r.Context(), id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "share link successfully revoked"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getSharedDeck loads the deck of the :token share link parameter.
+func (app *application) getSharedDeck(w http.ResponseWriter, r *http.Request) (*data.Deck, bool) {
+	token := httprouter.ParamsFromContext(r.Context()).ByName("token")
+
+	v := validator.New()
+
+	if data.ValidateTokenPlaintext(v, token); !v.Valid() {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	deck, err := app.models.Decks.GetByShareToken(r.Context(), token)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return deck, true
+}
+
+func (app *application) showSharedDeckHandler(w http.ResponseWriter, r *http.Request) {
+	deck, ok := app.getSharedDeck(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"deck": deck}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listSharedDeckFlashcardsHandler(w http.ResponseWriter, r *http.Request) {
+	deck, ok := app.getSharedDeck(w, r)
+	if !ok {
+		return
+	}
+
+	app.listDeckFlashcards(w, r, deck)
+}
+
+// cloneDeckHandler copies a deck the user can read into their account, so a
+// shared deck can be personalised. With "cards": "copy" its cards are
+// duplicated too; by default the clone references the same cards.
+func (app *application) cloneDeckHandler(w http.ResponseWriter, r *http.Request) {
+	src, ok := app.getVisibleDeck(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+		Cards       string  `json:"cards"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	deck := &data.Deck{
+		UserID:      user.ID,
+		Name:        src.Name,
+		Description: src.Description,
+		Visibility:  "private",
+		Query:       src.Query,
+		Scheduler:   src.Scheduler,
+
+		SchedulerConfig: src.SchedulerConfig,
+	}
+
+	if input.Name != nil {
+		deck.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Description != nil {
+		deck.Description = *input.Description
+	}
+	if input.Cards == "" {
+		input.Cards = "reference"
+	}
+
+	v := validator.New()
+
+	v.Check(validator.PermittedValue(input.Cards, "reference", "copy"), "cards", "must be reference or copy")
+	v.Check(input.Cards != "copy" || !src.Smart(), "cards", "smart decks can only be cloned by reference")
+
+	if data.ValidateDeck(v, deck); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Copies are new cards in the card bank, which needs the same permission
+	// as creating them one by one.
+	if input.Cards == "copy" {
+		permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		if !permissions.Include("flashcards:write") {
+			app.notPermittedResponse(w, r)
+			return
+		}
+	}
+
+	copied, err := app.models.Decks.Clone(r.Context(), src, deck, input.Cards == "copy")
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateDeckName):
+			v.AddError("name", "a deck with this name already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if len(copied) > 0 && (app.search != nil || app.embeddings != nil) {
+		flashcards, err := app.models.Flashcards.GetByIDs(r.Context(), copied, user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		for _, flashcard := range flashcards {
+			app.mirrorFlashcard(flashcard)
+			app.embedFlashcard(flashcard)
+		}
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/decks/%d", deck.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"deck": deck}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// exportDeckHandler downloads a deck the user can read as a DeckBundle, which
+// importDeckHandler turns back into a deck, here or on another server. The
+// attachment URLs are signed, so the files can be fetched while they last.
+func (app *application) exportDeckHandler(w http.ResponseWriter, r *http.Request) {
+	deck, ok := app.getVisibleDeck(w, r)
+	if !ok {
+		return
+	}
+
+	if deck.Smart() {
+		app.smartDeckResponse(w, r)
+		return
+	}
+
+	bundle, err := app.models.Decks.Export(r.Context(), deck)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.signAttachments(bundle.Attachments...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="deck-%d-%s.json"`, deck.ID, bundle.ExportedAt.Format("20060102-150405")))
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"bundle": bundle}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// importDeckHandler creates a private deck of new cards from an exported
+// bundle, posted as downloaded. Blobs aren't part of the bundle, so the
+// response maps the bundle's card ids to the new ones and returns the
+// attachment manifest pointing at the new cards, for the client to upload.
+func (app *application) importDeckHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name   *string         `json:"name"`
+		Bundle data.DeckBundle `json:"bundle"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	bundle := input.Bundle
+
+	deck := &data.Deck{
+		UserID:      user.ID,
+		Name:        strings.TrimSpace(bundle.Deck.Name),
+		Description: bundle.Deck.Description,
+		Visibility:  "private",
+	}
+
+	if input.Name != nil {
+		deck.Name = strings.TrimSpace(*input.Name)
+	}
+
+	v := validator.New()
+
+	v.Check(bundle.Format == data.DeckBundleFormat, "format", fmt.Sprintf("must be %d", data.DeckBundleFormat))
+
+	if data.ValidateDeck(v, deck); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	flashcards := make([]*data.Flashcard, len(bundle.Flashcards))
+	bundleIDs := make([]int64, len(bundle.Flashcards))
+
+	for i, card := range bundle.Flashcards {
+		content, err := data.DecodeContent(card.Type, card.Content)
+		if err != nil {
+			app.badRequestResponse(w, r, fmt.Errorf("flashcards[%d]: %w", i, err))
+			return
+		}
+
+		flashcard := &data.Flashcard{
+			Section:     card.Section,
+			SectionType: card.SectionType,
+			SourceFile:  card.SourceFile,
+			Text:        card.Text,
+			Question:    card.Question,
+			Type:        card.Type,
+			Content:     content,
+			Categories:  card.Categories,
+			Difficulty:  card.Difficulty,
+			Hints:       card.Hints,
+			Metadata:    card.Metadata,
+		}
+
+		// Each card is checked on its own so the errors can be reported
+		// against its position in the bundle.
+		cv := validator.New()
+
+		err = app.validateFlashcard(r, cv, flashcard, true)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		for key, message := range cv.Errors {
+			v.AddError(fmt.Sprintf("flashcards[%d].%s", i, key), message)
+		}
+
+		flashcards[i] = flashcard
+		bundleIDs[i] = card.ID
+	}
+
+	v.Check(validator.Unique(bundleIDs), "flashcards", "must not contain duplicate ids")
+
+	for i, attachment := range bundle.Attachments {
+		v.Check(slices.Contains(bundleIDs, attachment.FlashcardID), fmt.Sprintf("attachments[%d].flashcard_id", i), "must be the id of a flashcard in the bundle")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Decks.Import(r.Context(), deck, flashcards)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateDeckName):
+			v.AddError("name", "a deck with this name already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	idMap := make(map[int64]int64, len(flashcards))
+	for i, flashcard := range flashcards {
+		idMap[bundleIDs[i]] = flashcard.ID
+
+		app.mirrorFlashcard(flashcard)
+		app.embedFlashcard(flashcard)
+	}
+
+	if bundle.Attachments == nil {
+		bundle.Attachments = []*data.Attachment{}
+	}
+
+	for _, attachment := range bundle.Attachments {
+		attachment.FlashcardID = idMap[attachment.FlashcardID]
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/decks/%d", deck.ID))
+
+	env := envelope{"deck": deck, "id_map": idMap, "attachments": bundle.Attachments}
+
+	err = app.writeJSON(w, http.StatusCreated, env, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -2,7 +2,10 @@ package main
 
 import (
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 func (app *application) logError(r *http.Request, err error) {
@@ -54,16 +57,60 @@ func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Requ
 	app.errorResponse(w, r, http.StatusConflict, message)
 }
 
+func (app *application) smartDeckResponse(w http.ResponseWriter, r *http.Request) {
+	message := "this operation isn't available for smart decks, whose cards are defined by their query"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+func (app *application) insufficientScopeResponse(w http.ResponseWriter, r *http.Request) {
+	message := "your authentication token's scopes don't allow access to this resource"
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+func (app *application) exportInProgressResponse(w http.ResponseWriter, r *http.Request) {
+	message := "an export is already being prepared, please check its status later"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+func (app *application) sessionCompletedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "this study session has already been completed"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+func (app *application) examClosedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "this exam has already been submitted or its deadline has passed"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+func (app *application) duplicateQuestionResponse(w http.ResponseWriter, r *http.Request, conflictingID int64) {
+	message := map[string]any{
+		"question":       "a flashcard with this question already exists",
+		"conflicting_id": conflictingID,
+	}
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
 func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request) {
 	message := "rate limit exceeded"
 	app.errorResponse(w, r, http.StatusTooManyRequests, message)
 }
 
+func (app *application) loginLockedResponse(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	message := "too many failed login attempts, please try again later"
+	app.errorResponse(w, r, http.StatusTooManyRequests, message)
+}
+
 func (app *application) invalidCredentialsResponse(w http.ResponseWriter, r *http.Request) {
 	message := "invalid authentication credentials"
 	app.errorResponse(w, r, http.StatusUnauthorized, message)
 }
 
+func (app *application) invalidAPIKeyResponse(w http.ResponseWriter, r *http.Request) {
+	message := "invalid or expired API key"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
 func (app *application) invalidAuthenticationTokenResponse(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("WWW-Authenticate", "Bearer")
 
@@ -85,3 +132,14 @@ func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Requ
 	message := "your user account doesn't have the necessary permissions to access this resource"
 	app.errorResponse(w, r, http.StatusForbidden, message)
 }
+
+func (app *application) serverBusyResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Retry-After", "5")
+	message := "the server is busy handling similar requests, please try again shortly"
+	app.errorResponse(w, r, http.StatusServiceUnavailable, message)
+}
+
+func (app *application) featureDisabledResponse(w http.ResponseWriter, r *http.Request) {
+	message := "this feature is not enabled on this server"
+	app.errorResponse(w, r, http.StatusNotImplemented, message)
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/validator"
+)
+
+// createExamHandler builds a timed exam from randomly sampled cards. The
+// clock starts straight away: the deadline is duration_minutes from now.
+// Questions can also be timed one by one: category_time_limits_seconds sets
+// the limit for the cards of a category and question_time_limit_seconds for
+// the rest. Timed questions are only shown once they are served through
+// GET /v1/exams/:id/questions/:position, which starts their clock.
+func (app *application) createExamHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		QuestionCount      int                `json:"question_count"`
+		DurationMinutes    int                `json:"duration_minutes"`
+		QuestionTimeLimit  int                `json:"question_time_limit_seconds"`
+		CategoryTimeLimits map[string]int     `json:"category_time_limits_seconds"`
+		CategoryWeights    map[string]float64 `json:"category_weights"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	spec := data.ExamSpec{
+		QuestionCount:      input.QuestionCount,
+		Duration:           time.Duration(input.DurationMinutes) * time.Minute,
+		QuestionTimeLimit:  time.Duration(input.QuestionTimeLimit) * time.Second,
+		CategoryTimeLimits: make(map[string]time.Duration, len(input.CategoryTimeLimits)),
+		CategoryWeights:    input.CategoryWeights,
+	}
+
+	for name, seconds := range input.CategoryTimeLimits {
+		spec.CategoryTimeLimits[name] = time.Duration(seconds) * time.Second
+	}
+
+	v := validator.New()
+
+	if data.ValidateExamSpec(v, spec); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	exam := &data.Exam{UserID: user.ID}
+
+	err = app.models.Exams.Insert(r.Context(), exam, spec)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if exam.ID == 0 {
+		v.AddError("category_weights", "no flashcards match these categories")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	exam, err = app.models.Exams.Get(r.Context(), exam.ID, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	exam.Conceal()
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/exams/%d", exam.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"exam": exam}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) getExam(w http.ResponseWriter, r *http.Request) (*data.Exam, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	exam, err := app.models.Exams.Get(r.Context(), id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return exam, true
+}
+
+// showExamHandler returns the exam's questions, and its result once it has
+// been submitted or the deadline has passed.
+func (app *application) showExamHandler(w http.ResponseWriter, r *http.Request) {
+	exam, ok := app.getExam(w, r)
+	if !ok {
+		return
+	}
+
+	env := envelope{"exam": exam}
+	if exam.Closed() {
+		env["result"] = exam.Result()
+	} else {
+		exam.Conceal()
+	}
+
+	err := app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getExamQuestion loads one of the requesting user's open exams and the
+// question at the :position parameter, writing the error response itself
+// when that fails.
+func (app *application) getExamQuestion(w http.ResponseWriter, r *http.Request) (*data.Exam, *data.ExamQuestion, bool) {
+	exam, ok := app.getExam(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+
+	position, err := app.readNamedIDParam(r, "position")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, nil, false
+	}
+
+	q := exam.Question(int(position))
+	if q == nil {
+		app.notFoundResponse(w, r)
+		return nil, nil, false
+	}
+
+	if exam.Closed() {
+		app.examClosedResponse(w, r)
+		return nil, nil, false
+	}
+
+	return exam, q, true
+}
+
+// serveExamQuestionHandler returns one question of an open exam. Serving a
+// timed question for the first time starts its clock.
+func (app *application) serveExamQuestionHandler(w http.ResponseWriter, r *http.Request) {
+	exam, q, ok := app.getExamQuestion(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.models.Exams.Serve(r.Context(), exam, q)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrExamClosed):
+			app.examClosedResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	q.Correct = nil
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"question": q}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// answerExamQuestionHandler records the answer to one question of an open
+// exam as soon as it is given, so timed questions are timed up to then
+// rather than up to the submission. Whether it is correct stays hidden until
+// the exam is submitted.
+func (app *application) answerExamQuestionHandler(w http.ResponseWriter, r *http.Request) {
+	exam, q, ok := app.getExamQuestion(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Answer   json.RawMessage `json:"answer"`
+		AnswerMS *int            `json:"answer_ms"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(q.Answer == nil, "answer", "must not be given more than once")
+	v.Check(len(input.Answer) > 0, "answer", "must be provided")
+	v.Check(input.AnswerMS == nil || *input.AnswerMS >= 0, "answer_ms", "must not be negative")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	result, err := data.GradeAnswer(q.Content, input.Answer)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvalidAnswer):
+			v.AddError("answer", "invalid answer for this flashcard type")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	answerMS, overTime := q.Time(time.Now(), input.AnswerMS)
+	correct := result.Correct && !overTime
+
+	q.Answer = input.Answer
+	q.AnswerMS = answerMS
+	q.Correct = &correct
+
+	err = app.models.Exams.Answer(r.Context(), exam, q)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrExamClosed):
+			app.examClosedResponse(w, r)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	q.Correct = nil
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"question": q}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// submitExamHandler grades the answers and scores the exam. An exam takes a
+// single submission, which can leave out questions already answered on their
+// own; questions left unanswered count as incorrect, as do answers over their
+// question's time limit, timed up to the submission. The score is passed back
+// to the LMS the user last launched from.
+func (app *application) submitExamHandler(w http.ResponseWriter, r *http.Request) {
+	exam, ok := app.getExam(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Answers []struct {
+			FlashcardID int64           `json:"flashcard_id"`
+			Answer      json.RawMessage `json:"answer"`
+			AnswerMS    *int            `json:"answer_ms"`
+		} `json:"answers"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if exam.Closed() {
+		app.examClosedResponse(w, r)
+		return
+	}
+
+	questions := make(map[int64]*data.ExamQuestion, len(exam.Questions))
+	for _, q := range exam.Questions {
+		questions[q.FlashcardID] = q
+	}
+
+	v := validator.New()
+	now := time.Now()
+
+	for i, a := range input.Answers {
+		key := fmt.Sprintf("answers[%d]", i)
+
+		q, ok := questions[a.FlashcardID]
+		switch {
+		case !ok:
+			v.AddError(key+".flashcard_id", "must be a question of this exam")
+			continue
+		case q.Answer != nil:
+			v.AddError(key+".flashcard_id", "must not be answered more than once")
+			continue
+		case len(a.Answer) == 0:
+			v.AddError(key+".answer", "must be provided")
+			continue
+		case a.AnswerMS != nil && *a.AnswerMS < 0:
+			v.AddError(key+".answer_ms", "must not be negative")
+			continue
+		}
+
+		result, err := data.GradeAnswer(q.Content, a.Answer)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrInvalidAnswer):
+				v.AddError(key+".answer", "invalid answer for this flashcard type")
+				continue
+			default:
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+		}
+
+		answerMS, overTime := q.Time(now, a.AnswerMS)
+		correct := result.Correct && !overTime
+
+		q.Answer = a.Answer
+		q.AnswerMS = answerMS
+		q.Correct = &correct
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	for _, q := range exam.Questions {
+		if q.Correct == nil {
+			q.Correct = new(bool)
+		}
+	}
+
+	err = app.models.Exams.Submit(r.Context(), exam)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrExamClosed):
+			app.examClosedResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	for _, q := range exam.Questions {
+		if q.Answer == nil {
+			continue
+		}
+
+		err = app.models.Flashcards.RecordReview(r.Context(), q.FlashcardID, exam.UserID, *q.Correct, q.AnswerMS)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	result := exam.Result()
+
+	err = app.passBackScore(r.Context(), exam.UserID, float64(result.Correct), float64(result.Total), "exam")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.emitCompletion(app.contextGetUser(r), app.examActivity(exam), result.Correct, result.Total)
+
+	app.triggerHook(exam.UserID, data.EventQuizCompleted, quizCompleted{
+		Quiz:        "exam",
+		ID:          exam.ID,
+		Total:       result.Total,
+		Correct:     result.Correct,
+		Score:       result.Score,
+		CompletedAt: *exam.SubmittedAt,
+	})
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"exam": exam, "result": result}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
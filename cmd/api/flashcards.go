@@ -1,14 +1,17 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 
 	"flashcards-api.johndennehy101.tech/internal/data"
 	"flashcards-api.johndennehy101.tech/internal/validator"
+	"flashcards-api.johndennehy101.tech/internal/xapi"
 )
 
 type flashcardInput struct {
@@ -21,11 +24,17 @@ type flashcardInput struct {
 	Type        data.FlashcardType `json:"flashcard_type"`
 	Content     json.RawMessage    `json:"flashcard_content"`
 	Categories  []string           `json:"categories"`
+	Difficulty  *int               `json:"difficulty"`
+	Hints       []string           `json:"hints"`
+	Metadata    data.CardMetadata  `json:"metadata"`
 	Version     int32              `json:"version"`
 }
 
 func (app *application) createFlashcardHandler(w http.ResponseWriter, r *http.Request) {
-	var input flashcardInput
+	var input struct {
+		flashcardInput
+		DeckID *int64 `json:"deck_id"`
+	}
 
 	err := app.readJSON(w, r, &input)
 	if err != nil {
@@ -33,41 +42,9 @@ func (app *application) createFlashcardHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	v := validator.New()
-
-	var content data.FlashcardContent
-	switch input.Type {
-	case data.FlashcardQA:
-		var qa data.QAContent
-		if err := json.Unmarshal(input.Content, &qa); err != nil {
-			app.errorResponse(w, r, http.StatusBadRequest, "invalid QA content")
-			return
-		}
-		v.Check(qa.Answer != "", "flashcard_content.answer", "answer must not be empty")
-		content = qa
-
-	case data.FlashcardMCQ:
-		var mcq data.MCQContent
-		if err := json.Unmarshal(input.Content, &mcq); err != nil {
-			app.errorResponse(w, r, http.StatusBadRequest, "invalid MCQ content")
-			return
-		}
-		v.Check(len(mcq.Options) >= 2, "flashcard_content.options", "at least 2 options required")
-		v.Check(mcq.CorrectIndex >= 0 && mcq.CorrectIndex < len(mcq.Options),
-			"flashcard_content.correct_index", "correct index out of bounds")
-		v.Check(validator.Unique(mcq.Options), "flashcard_content.options", "options must be unique")
-		content = mcq
-
-	case data.FlashcardYesNo:
-		var yn data.YesNoContent
-		if err := json.Unmarshal(input.Content, &yn); err != nil {
-			app.errorResponse(w, r, http.StatusBadRequest, "invalid Yes/No content")
-			return
-		}
-		content = yn
-
-	default:
-		app.badRequestResponse(w, r, errors.New("invalid flashcard type"))
+	content, err := data.DecodeContent(input.Type, input.Content)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
 		return
 	}
 
@@ -81,27 +58,170 @@ func (app *application) createFlashcardHandler(w http.ResponseWriter, r *http.Re
 		Type:        input.Type,
 		Content:     content,
 		Categories:  input.Categories,
+		Difficulty:  input.Difficulty,
+		Hints:       input.Hints,
+		Metadata:    input.Metadata,
 		Version:     input.Version,
 		CreatedAt:   time.Now(),
 	}
 
-	if data.ValidateFlashcard(v, &flashcard); !v.Valid() {
-		app.failedValidationResponse(w, r, v.Errors)
+	app.insertFlashcard(w, r, &flashcard, input.DeckID)
+}
+
+// validateFlashcard runs the built-in checks plus the configurable ones: the
+// card policy's required fields and the defined metadata fields. New cards
+// get the policy defaults first.
+func (app *application) validateFlashcard(r *http.Request, v *validator.Validator, flashcard *data.Flashcard, isNew bool) error {
+	policy, err := app.models.Policy.Get(r.Context())
+	if err != nil {
+		return err
+	}
+
+	fields, err := app.models.Metadata.GetAll(r.Context())
+	if err != nil {
+		return err
+	}
+
+	if isNew {
+		policy.ApplyDefaults(flashcard)
+	}
+
+	if flashcard.Metadata == nil {
+		flashcard.Metadata = data.CardMetadata{}
+	}
+
+	data.ValidateFlashcard(v, flashcard)
+	policy.Check(v, flashcard)
+	data.ValidateMetadata(v, fields, flashcard.Metadata)
+
+	return nil
+}
+
+// flashcardDeck looks up the deck a new card goes into: the one requested,
+// or the user's default deck when deckID is nil. A requested deck must be
+// one the user can add cards to; a default deck that no longer qualifies,
+// because it became smart or the user lost access, is skipped instead.
+func (app *application) flashcardDeck(ctx context.Context, v *validator.Validator, user *data.User, deckID *int64) (*data.Deck, error) {
+	requested := deckID != nil
+	if !requested {
+		if user.DefaultDeckID == nil {
+			return nil, nil
+		}
+		deckID = user.DefaultDeckID
+	}
+
+	deck, err := app.models.Decks.GetEditable(ctx, *deckID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			if requested {
+				v.AddError("deck_id", "must be a deck you can add cards to")
+			}
+			return nil, nil
+		default:
+			return nil, err
+		}
+	}
+
+	if deck.Smart() {
+		if requested {
+			v.AddError("deck_id", "must not be a smart deck")
+		}
+		return nil, nil
+	}
+
+	return deck, nil
+}
+
+// insertFlashcard validates and stores a new flashcard and writes the 201
+// response. It is shared by every endpoint that creates cards so they all
+// apply the same validation, duplicate policy and side effects. The card is
+// added to the deck with deckID, or to the user's default deck when it is nil.
+func (app *application) insertFlashcard(w http.ResponseWriter, r *http.Request, flashcard *data.Flashcard, deckID *int64) {
+	v := validator.New()
+
+	err := app.validateFlashcard(r, v, flashcard, true)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	user := app.contextGetUser(r)
 
-	err = app.models.Flashcards.Insert(&flashcard, user.ID)
+	deck, err := app.flashcardDeck(r.Context(), v, user, deckID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	env := envelope{}
+
+	if app.config.duplicates != "allow" {
+		duplicateID, err := app.models.Flashcards.FindDuplicate(r.Context(), flashcard.Question, user.ID)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+		case err != nil:
+			app.serverErrorResponse(w, r, err)
+			return
+		case app.config.duplicates == "reject":
+			app.duplicateQuestionResponse(w, r, duplicateID)
+			return
+		default:
+			env["warnings"] = []map[string]any{{"code": "duplicate_question", "conflicting_id": duplicateID}}
+		}
+	}
+
+	// Suggestions are worked out before the insert so the new card isn't its
+	// own nearest neighbour. They are only advice; the client decides whether
+	// to apply them.
+	if app.config.categories.suggest {
+		suggestions, err := app.models.Flashcards.SuggestCategories(r.Context(), flashcard)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		env["suggested_categories"] = suggestions
+	}
+
+	err = app.models.Flashcards.Insert(r.Context(), flashcard, user.ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	if deck != nil {
+		_, err = app.models.Decks.AddFlashcards(r.Context(), deck.ID, []int64{flashcard.ID})
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		env["deck_id"] = deck.ID
+	}
+
+	app.mirrorFlashcard(flashcard)
+	app.embedFlashcard(flashcard)
+
+	if app.tts != nil && app.config.tts.auto {
+		app.generateSpeech(flashcard, user.ID, speechQuestion, speechAnswer)
+	}
+
 	headers := make(http.Header)
 	headers.Set("Location", fmt.Sprintf("/v1/flashcards/%d", flashcard.ID))
 
-	err = app.writeJSON(w, http.StatusCreated, envelope{"flashcard": flashcard}, headers)
+	app.linkFlashcards(flashcard)
+
+	app.triggerHook(user.ID, data.EventFlashcardCreated, flashcard)
+
+	env["flashcard"] = flashcard
+
+	err = app.writeJSON(w, http.StatusCreated, env, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -115,8 +235,18 @@ func (app *application) showFlashcardHandler(w http.ResponseWriter, r *http.Requ
 	}
 
 	user := app.contextGetUser(r)
+	v := validator.New()
+
+	includes := app.readIncludes(r.URL.Query(), v)
+	reveal := app.readReveal(r.URL.Query(), v)
+	render := app.readRender(r.URL.Query(), v)
+	shuffle := app.readBool(r.URL.Query(), "shuffle", false, v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
 
-	flashcard, err := app.models.Flashcards.Get(id, user.ID)
+	flashcard, err := app.models.Flashcards.Get(r.Context(), id, user.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -127,22 +257,90 @@ func (app *application) showFlashcardHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"flashcard": flashcard}, nil)
+	err = app.models.Flashcards.RecordView(r.Context(), id, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.expandFlashcards(r.Context(), []*data.Flashcard{flashcard}, includes, user.ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.translateFlashcards(r, flashcard)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if render {
+		err = renderFlashcards(flashcard)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if shuffle {
+		shuffleOptions(flashcard)
+	}
+
+	concealFlashcards(reveal, flashcard)
+
+	app.emitStatement(user, xapi.VerbExperienced, flashcard, nil)
+
+	app.linkFlashcards(flashcard)
+
+	headers := make(http.Header)
+	headers.Set("Content-Language", flashcard.Language)
+	headers.Set("Vary", "Accept-Language")
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"flashcard": flashcard}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// shuffleOptions randomises the order of MCQ options in the response. Answers
+// are graded by option ID, so the shuffled order never affects correctness.
+func shuffleOptions(flashcards ...*data.Flashcard) {
+	for _, flashcard := range flashcards {
+		if mcq, ok := flashcard.Content.(data.MCQContent); ok {
+			flashcard.Content = mcq.Shuffled()
+		}
 	}
 }
 
 func (app *application) showFlashcardStatsHandler(w http.ResponseWriter, r *http.Request) {
 	user := app.contextGetUser(r)
 
-	stats, err := app.models.Flashcards.GetUserStats(user.ID)
+	stats, err := app.models.Flashcards.GetUserStats(r.Context(), user.ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"stats": stats}, nil)
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"stats": stats, "formatting": app.formattingHints(r, user)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) exportFlashcardsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	export, err := app.models.Flashcards.Export(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="flashcards-%s.json"`, export.ExportedAt.Format("20060102-150405")))
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"export": export}, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -157,7 +355,7 @@ func (app *application) updateFlashcardHandler(w http.ResponseWriter, r *http.Re
 
 	user := app.contextGetUser(r)
 
-	flashcard, err := app.models.Flashcards.Get(id, user.ID)
+	flashcard, err := app.models.Flashcards.Get(r.Context(), id, user.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -168,52 +366,29 @@ func (app *application) updateFlashcardHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	var input struct {
-		Section     *string            `json:"section"`
-		SectionType *string            `json:"section_type"`
-		SourceFile  *string            `json:"source_file"`
-		Text        string             `json:"text"`
-		Question    string             `json:"question"`
-		Type        data.FlashcardType `json:"flashcard_type"`
-		Content     json.RawMessage    `json:"flashcard_content"`
-		Categories  []string           `json:"categories"`
-		Version     int32              `json:"version"`
+	// Deck editors can maintain the deck's cards without flashcards:write.
+	editable, err := app.canEditFlashcard(r.Context(), user, flashcard)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !editable {
+		app.notPermittedResponse(w, r)
+		return
 	}
 
+	var input flashcardInput
+
 	err = app.readJSON(w, r, &input)
 	if err != nil {
 		app.badRequestResponse(w, r, err)
 		return
 	}
 
-	var content data.FlashcardContent
-	switch input.Type {
-	case data.FlashcardQA:
-		var qa data.QAContent
-		if err := json.Unmarshal(input.Content, &qa); err != nil {
-			app.errorResponse(w, r, http.StatusBadRequest, "invalid QA content")
-			return
-		}
-		content = qa
-
-	case data.FlashcardMCQ:
-		var mcq data.MCQContent
-		if err := json.Unmarshal(input.Content, &mcq); err != nil {
-			app.errorResponse(w, r, http.StatusBadRequest, "invalid MCQ content")
-			return
-		}
-		content = mcq
-
-	case data.FlashcardYesNo:
-		var yn data.YesNoContent
-		if err := json.Unmarshal(input.Content, &yn); err != nil {
-			app.errorResponse(w, r, http.StatusBadRequest, "invalid Yes/No content")
-			return
-		}
-		content = yn
-
-	default:
-		app.badRequestResponse(w, r, errors.New("invalid flashcard type"))
+	content, err := data.DecodeContent(input.Type, input.Content)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
 		return
 	}
 
@@ -225,15 +400,24 @@ func (app *application) updateFlashcardHandler(w http.ResponseWriter, r *http.Re
 	flashcard.Type = input.Type
 	flashcard.Content = content
 	flashcard.Categories = input.Categories
+	flashcard.Difficulty = input.Difficulty
+	flashcard.Hints = input.Hints
+	flashcard.Metadata = input.Metadata
 
 	v := validator.New()
 
-	if data.ValidateFlashcard(v, flashcard); !v.Valid() {
+	err = app.validateFlashcard(r, v, flashcard, false)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.Flashcards.Update(flashcard)
+	err = app.models.Flashcards.Update(r.Context(), flashcard)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
@@ -244,28 +428,127 @@ func (app *application) updateFlashcardHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	app.mirrorFlashcard(flashcard)
+	app.embedFlashcard(flashcard)
+
+	app.linkFlashcards(flashcard)
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"flashcard": flashcard}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
+// readFlashcardFilter reads the card filters of the flashcard listing from a
+// query string. Saved searches store the same query string, so both go
+// through here.
+func (app *application) readFlashcardFilter(ctx context.Context, qs url.Values, v *validator.Validator) (data.FlashcardFilter, error) {
+	filter := data.FlashcardFilter{
+		Section:       app.readString(qs, "section", ""),
+		SectionType:   app.readString(qs, "section_type", ""),
+		Type:          app.readString(qs, "flashcard_type", ""),
+		SourceFile:    app.readString(qs, "source_file", app.readString(qs, "file", "")),
+		CreatedAfter:  app.readTime(qs, "created_after", v),
+		CreatedBefore: app.readTime(qs, "created_before", v),
+		Categories:    app.readCSV(qs, "categories", []string{}),
+		HideMastered:  app.readBool(qs, "hide_mastered", false, v),
+		HideArchived:  app.readBool(qs, "hide_archived", false, v),
+		MinDifficulty: app.readInt(qs, "min_difficulty", 0, v),
+		MaxDifficulty: app.readInt(qs, "max_difficulty", 0, v),
+		Query:         app.readString(qs, "q", ""),
+		Fuzzy:         app.readBool(qs, "fuzzy", false, v),
+		Similarity:    app.config.search.similarity,
+	}
+
+	v.Check(filter.MinDifficulty >= 0 && filter.MinDifficulty <= 5, "min_difficulty", "must be between 1 and 5")
+	v.Check(filter.MaxDifficulty >= 0 && filter.MaxDifficulty <= 5, "max_difficulty", "must be between 1 and 5")
+	v.Check(filter.MaxDifficulty == 0 || filter.MinDifficulty <= filter.MaxDifficulty, "max_difficulty", "must not be less than min_difficulty")
+	v.Check(filter.Type == "" || validator.PermittedValue(data.FlashcardType(filter.Type), data.FlashcardTypes...), "flashcard_type", "invalid flashcard type")
+	v.Check(filter.CreatedAfter == nil || filter.CreatedBefore == nil || filter.CreatedAfter.Before(*filter.CreatedBefore), "created_before", "must be later than created_after")
+	v.Check(!filter.Fuzzy || filter.Query != "", "fuzzy", "requires q")
+
+	if qs.Has("has_justification") {
+		hasJustification := app.readBool(qs, "has_justification", false, v)
+		filter.HasJustification = &hasJustification
+	}
+
+	categoriesMode := app.readString(qs, "categories_mode", "all")
+	v.Check(validator.PermittedValue(categoriesMode, "all", "any"), "categories_mode", "must be all or any")
+	filter.CategoriesAny = categoriesMode == "any"
+
+	var err error
+
+	filter.Metadata, err = app.readMetadataFilter(ctx, qs, v)
+	if err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
 func (app *application) listFlashcardsHandler(w http.ResponseWriter, r *http.Request) {
+	app.listFlashcards(w, r, r.URL.Query(), 0)
+}
+
+// listFlashcards writes the flashcard listing for the query string, limited to
+// the cards of a deck when deckID is set.
+func (app *application) listFlashcards(w http.ResponseWriter, r *http.Request, qs url.Values, deckID int64) {
 	user := app.contextGetUser(r)
-	qs := r.URL.Query()
 	v := validator.New()
 
-	categories := app.readCSV(qs, "categories", []string{})
-	hideMastered := app.readBool(qs, "hide_mastered", false, v)
-	file := app.readString(qs, "file", "")
-	section := app.readString(qs, "section", "")
-	qType := app.readString(qs, "flashcard_type", "")
+	filter, err := app.readFlashcardFilter(r.Context(), qs, v)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	filter.DeckID = deckID
+
+	includes := app.readIncludes(qs, v)
+	reveal := app.readReveal(qs, v)
+	render := app.readRender(qs, v)
+	shuffle := app.readBool(qs, "shuffle", false, v)
+
+	// Search matches are ranked by relevance, or by similarity for fuzzy
+	// searches, unless a sort is given. Deck listings otherwise follow the
+	// deck's manual order.
+	defaultSort := "id"
+	switch {
+	case filter.Query != "":
+		defaultSort = "-rank"
+	case deckID != 0:
+		defaultSort = "position"
+	}
 
 	paging := data.Filters{
 		Page:         app.readInt(qs, "page", 1, v),
 		PageSize:     app.readInt(qs, "page_size", 20, v),
-		Sort:         app.readString(qs, "sort", "id"),
-		SortSafelist: []string{"id", "section", "file", "-id", "-section", "-file", "random"},
+		Sort:         app.readString(qs, "sort", defaultSort),
+		SortSafelist: []string{"id", "section", "file", "question", "difficulty", "quality_score", "rank", "created_at", "-id", "-section", "-file", "-question", "-difficulty", "-quality_score", "-rank", "-created_at", "random"},
+		SortColumns:  map[string]string{"file": "f.source_file", "question": "f.question", "created_at": "f.created_at"},
+	}
+
+	if deckID != 0 {
+		paging.SortSafelist = append(paging.SortSafelist, "position", "-position")
+		paging.SortColumns["position"] = "df.position"
+	}
+
+	// ?after= and ?limit= switch to cursor pagination, which stays fast deep
+	// into large card banks where OFFSET has to skip every earlier row.
+	if qs.Has("after") || qs.Has("limit") {
+		v.Check(!qs.Has("page") && !qs.Has("page_size"), "after", "cannot be combined with page or page_size")
+
+		paging.Keyset = true
+		paging.PageSize = app.readInt(qs, "limit", 20, v)
+		paging.Sort = app.readString(qs, "sort", "id")
+		paging.SortSafelist = data.KeysetSorts
+
+		if after := qs.Get("after"); after != "" {
+			paging.After, err = data.DecodeCursor(after, []byte(app.config.cursor.secret))
+			if err != nil {
+				v.AddError("after", "invalid cursor")
+			}
+		}
 	}
 
 	if data.ValidateFilters(v, paging); !v.Valid() {
@@ -273,25 +556,86 @@ func (app *application) listFlashcardsHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	flashcards, metadata, err := app.models.Flashcards.GetAll(
-		user.ID, section, qType, file, categories, hideMastered, paging,
-	)
+	flashcards, metadata, err := app.models.Flashcards.GetAll(r.Context(), user.ID, filter, paging)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	filterOptions, err := app.models.Flashcards.GetFilterMetadata(user.ID, file, qType, hideMastered)
+	// The count of a cursor page covers the rows from the cursor onwards, so
+	// there's a next page when it exceeds the rows returned.
+	if paging.Keyset {
+		next := ""
+		if metadata.TotalRecords > len(flashcards) {
+			last := flashcards[len(flashcards)-1]
+			next = data.Cursor{Sort: paging.Sort, ID: last.ID, CreatedAt: last.CreatedAt}.Encode([]byte(app.config.cursor.secret))
+		}
+
+		metadata = data.Metadata{PageSize: paging.PageSize, NextCursor: next}
+	}
+
+	if filter.Query != "" && !filter.Fuzzy && len(flashcards) > 0 {
+		ids := make([]int64, len(flashcards))
+		for i, flashcard := range flashcards {
+			ids[i] = flashcard.ID
+		}
+
+		headlines, err := app.models.Flashcards.GetHeadlines(r.Context(), ids, filter.Query)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		for _, flashcard := range flashcards {
+			flashcard.Headline = headlines[flashcard.ID]
+		}
+	}
+
+	err = app.expandFlashcards(r.Context(), flashcards, includes, user.ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	app.writeJSON(w, http.StatusOK, envelope{
+	err = app.translateFlashcards(r, flashcards...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if render {
+		err = renderFlashcards(flashcards...)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if shuffle {
+		shuffleOptions(flashcards...)
+	}
+
+	concealFlashcards(reveal, flashcards...)
+
+	app.linkFlashcards(flashcards...)
+
+	filterOptions, err := app.models.Flashcards.GetFilterMetadata(r.Context(), user.ID, filter.SourceFile, filter.Type, filter.HideMastered)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Vary", "Accept-Language")
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{
 		"flashcards":     flashcards,
 		"metadata":       metadata,
 		"filter_options": filterOptions,
-	}, nil)
+	}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
 }
 
 func (app *application) deleteFlashcardHandler(w http.ResponseWriter, r *http.Request) {
@@ -301,7 +645,31 @@ func (app *application) deleteFlashcardHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	err = app.models.Flashcards.Delete(id)
+	user := app.contextGetUser(r)
+
+	flashcard, err := app.models.Flashcards.Get(r.Context(), id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	deletable, err := app.canDeleteFlashcard(r.Context(), user, flashcard)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !deletable {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	err = app.models.Flashcards.Delete(r.Context(), id, user.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -312,7 +680,9 @@ func (app *application) deleteFlashcardHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "flashcard successfully deleted"}, nil)
+	app.unmirrorFlashcard(id)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "flashcard moved to trash"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -327,7 +697,7 @@ func (app *application) reviewFlashcardHandler(w http.ResponseWriter, r *http.Re
 
 	user := app.contextGetUser(r)
 
-	err = app.models.Flashcards.IncrementCorrectCount(id, user.ID)
+	err = app.models.Flashcards.IncrementCorrectCount(r.Context(), id, user.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -338,6 +708,33 @@ func (app *application) reviewFlashcardHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	err = app.models.Flashcards.RecordView(r.Context(), id, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Flashcards.RecordReview(r.Context(), id, user.ID, true, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if app.xapi != nil {
+		flashcard, err := app.models.Flashcards.Get(r.Context(), id, user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		success := true
+		app.emitStatement(user, xapi.VerbAnswered, flashcard, &xapi.Result{Success: &success})
+
+		if flashcard.Status == "mastered" {
+			app.emitStatement(user, xapi.VerbPassed, flashcard, &xapi.Result{Success: &success})
+		}
+	}
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"message": "progress updated"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -353,7 +750,7 @@ func (app *application) resetFlashcardHandler(w http.ResponseWriter, r *http.Req
 
 	user := app.contextGetUser(r)
 
-	err = app.models.Flashcards.ResetCorrectCount(id, user.ID)
+	err = app.models.Flashcards.ResetCorrectCount(r.Context(), id, user.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -369,3 +766,146 @@ func (app *application) resetFlashcardHandler(w http.ResponseWriter, r *http.Req
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+const maxBatchSize = 100
+
+type batchResult struct {
+	ID        int64           `json:"id"`
+	Found     bool            `json:"found"`
+	Flashcard *data.Flashcard `json:"flashcard,omitempty"`
+}
+
+func (app *application) batchFlashcardsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	v := validator.New()
+
+	var ids []int64
+
+	reveal := app.readReveal(r.URL.Query(), v)
+
+	if r.Method == http.MethodPost {
+		var input struct {
+			IDs []int64 `json:"ids"`
+		}
+
+		err := app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		ids = input.IDs
+	} else {
+		ids = app.readInt64CSV(r.URL.Query(), "ids", v)
+	}
+
+	v.Check(len(ids) > 0, "ids", "must contain at least one id")
+	v.Check(len(ids) <= maxBatchSize, "ids", fmt.Sprintf("must not contain more than %d ids", maxBatchSize))
+	v.Check(validator.Unique(ids), "ids", "must not contain duplicate values")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	flashcards, err := app.models.Flashcards.GetByIDs(r.Context(), ids, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	concealFlashcards(reveal, flashcards...)
+
+	app.linkFlashcards(flashcards...)
+
+	found := make(map[int64]*data.Flashcard, len(flashcards))
+	for _, flashcard := range flashcards {
+		found[flashcard.ID] = flashcard
+	}
+
+	results := make([]batchResult, len(ids))
+	for i, id := range ids {
+		flashcard, ok := found[id]
+		results[i] = batchResult{ID: id, Found: ok, Flashcard: flashcard}
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"results": results}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) answerFlashcardHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Answer    json.RawMessage `json:"answer"`
+		LatencyMS *int            `json:"latency_ms"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(len(input.Answer) > 0, "answer", "must be provided")
+	v.Check(input.LatencyMS == nil || *input.LatencyMS >= 0, "latency_ms", "must not be negative")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	flashcard, err := app.models.Flashcards.Get(r.Context(), id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	result, err := data.GradeAnswer(flashcard.Content, input.Answer)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvalidAnswer):
+			v.AddError("answer", "invalid answer for this flashcard type")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Flashcards.RecordView(r.Context(), id, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Flashcards.RecordReview(r.Context(), id, user.ID, result.Correct, input.LatencyMS)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.emitStatement(user, xapi.VerbAnswered, flashcard, &xapi.Result{
+		Success:  &result.Correct,
+		Response: string(input.Answer),
+	})
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"result": result}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
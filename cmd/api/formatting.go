@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"golang.org/x/text/language"
+)
+
+// formatting tells thin clients how to render the dates and numbers in a
+// response, so they don't need to duplicate the preference logic. GeneratedAt
+// is the response time in the user's time zone; every other timestamp is
+// RFC 3339 and can be shifted with UTCOffset.
+type formatting struct {
+	Locale      string    `json:"locale"`
+	Timezone    string    `json:"timezone"`
+	UTCOffset   string    `json:"utc_offset"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// formattingHints uses the user's profile settings, falling back to the
+// client's preferred language and UTC where they aren't set.
+func (app *application) formattingHints(r *http.Request, user *data.User) formatting {
+	hints := formatting{
+		Locale:   user.Locale,
+		Timezone: user.Timezone,
+	}
+
+	if hints.Locale == "" {
+		hints.Locale = app.config.language.String()
+
+		prefs, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+		if err == nil && len(prefs) > 0 {
+			hints.Locale = prefs[0].String()
+		}
+	}
+
+	loc := userLocation(user)
+	hints.Timezone = loc.String()
+
+	hints.GeneratedAt = time.Now().In(loc).Truncate(time.Second)
+	hints.UTCOffset = hints.GeneratedAt.Format("-07:00")
+
+	return hints
+}
+
+// userLocation is the user's time zone, or UTC when they haven't set one.
+func userLocation(user *data.User) *time.Location {
+	loc, err := time.LoadLocation(user.Timezone)
+	if err != nil || user.Timezone == "" {
+		return time.UTC
+	}
+
+	return loc
+}
+
+// startOfDay returns midnight at the start of t's day in the user's time zone.
+func startOfDay(user *data.User, t time.Time) time.Time {
+	loc := userLocation(user)
+	year, month, day := t.In(loc).Date()
+
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
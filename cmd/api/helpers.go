@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"flashcards-api.johndennehy101.tech/internal/validator"
 	"github.com/julienschmidt/httprouter"
@@ -27,6 +29,17 @@ func (app *application) readIDParam(r *http.Request) (int64, error) {
 	return id, nil
 }
 
+func (app *application) readNamedIDParam(r *http.Request, name string) (int64, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.ParseInt(params.ByName(name), 10, 64)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid %s parameter", name)
+	}
+
+	return id, nil
+}
+
 func (app *application) writeJSON(w http.ResponseWriter, status int, data any, headers http.Header) error {
 	js, err := json.MarshalIndent(data, "", "\t")
 	if err != nil {
@@ -120,6 +133,23 @@ func (app *application) readCSV(qs url.Values, key string, defaultValue []string
 	return strings.Split(csv, ",")
 }
 
+func (app *application) readInt64CSV(qs url.Values, key string, v *validator.Validator) []int64 {
+	values := app.readCSV(qs, key, []string{})
+
+	ids := make([]int64, 0, len(values))
+
+	for _, value := range values {
+		id, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			v.AddError(key, "must be a comma-separated list of integer values")
+			return nil
+		}
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
 func (app *application) readInt(qs url.Values, key string, defaultValue int, v *validator.Validator) int {
 	s := qs.Get(key)
 
@@ -152,6 +182,27 @@ func (app *application) readBool(qs url.Values, key string, defaultValue bool, v
 	return b
 }
 
+// readTime reads an RFC 3339 timestamp or a plain date, which is taken as
+// midnight UTC. It returns nil when the parameter is absent.
+func (app *application) readTime(qs url.Values, key string, v *validator.Validator) *time.Time {
+	s := qs.Get(key)
+
+	if s == "" {
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t, err = time.Parse(time.DateOnly, s)
+	}
+	if err != nil {
+		v.AddError(key, "must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+		return nil
+	}
+
+	return &t
+}
+
 func (app *application) background(fn func()) {
 	app.wg.Go(func() {
 		defer func() {
@@ -161,6 +212,32 @@ func (app *application) background(fn func()) {
 			}
 		}()
 
+		// Background jobs are batch work and share the batch slots with
+		// bulk requests.
+		app.acquireBatchSlot(nil)
+		defer app.releaseBatchSlot()
+
 		fn()
 	})
 }
+
+// every runs fn each interval until ctx is cancelled. Scheduled jobs are
+// batch work and wait for a batch slot like other background jobs.
+func (app *application) every(ctx context.Context, interval time.Duration, fn func(context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !app.acquireBatchSlot(ctx.Done()) {
+				return
+			}
+
+			fn(ctx)
+			app.releaseBatchSlot()
+		}
+	}
+}
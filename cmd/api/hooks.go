@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/validator"
+)
+
+// hookPayload is the stable envelope delivered for every trigger. Data holds
+// the resource in the same shape the REST API returns it.
+type hookPayload struct {
+	ID         string    `json:"id"`
+	Event      string    `json:"event"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       any       `json:"data"`
+}
+
+// quizCompleted is the data of a quiz.completed event, the same for a
+// submitted exam and a completed study session. Quiz is "exam" or
+// "study_session" and ID is the exam's or session's id.
+type quizCompleted struct {
+	Quiz        string    `json:"quiz"`
+	ID          int64     `json:"id"`
+	Total       int       `json:"total"`
+	Correct     int       `json:"correct"`
+	Score       float64   `json:"score"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// hookClient refuses to connect to anything but public addresses, whatever
+// the target's host name resolves to at delivery time and wherever it
+// redirects to. Proxies are not used, since they would connect on its behalf.
+var hookClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: func(network, address string, c syscall.RawConn) error {
+				addrPort, err := netip.ParseAddrPort(address)
+				if err != nil {
+					return err
+				}
+
+				if !data.PublicAddr(addrPort.Addr()) {
+					return fmt.Errorf("hook target %s is not a public address", addrPort.Addr())
+				}
+
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+	},
+}
+
+// triggerHook delivers an event to every matching subscription of the user in
+// the background. Subscriptions whose target answers 410 Gone are removed, as
+// the REST Hooks pattern prescribes.
+func (app *application) triggerHook(userID int64, event string, payload any) {
+	app.background(func() {
+		subscriptions, err := app.models.Hooks.GetAllForUser(context.Background(), userID, event)
+		if err != nil {
+			app.logger.Error(err.Error(), "event", event)
+			return
+		}
+
+		if len(subscriptions) == 0 {
+			return
+		}
+
+		body, err := json.Marshal(hookPayload{
+			ID:         rand.Text(),
+			Event:      event,
+			OccurredAt: time.Now().UTC(),
+			Data:       payload,
+		})
+		if err != nil {
+			app.logger.Error(err.Error(), "event", event)
+			return
+		}
+
+		for _, s := range subscriptions {
+			status, err := deliverHook(s, body)
+			if err != nil {
+				app.logger.Error(err.Error(), "event", event, "subscription_id", s.ID)
+				continue
+			}
+
+			if status == http.StatusGone {
+				err = app.models.Hooks.Delete(context.Background(), s.ID, s.UserID)
+				if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+					app.logger.Error(err.Error(), "subscription_id", s.ID)
+				}
+			}
+		}
+	})
+}
+
+func deliverHook(s *data.HookSubscription, body []byte) (int, error) {
+	mac := hmac.New(sha256.New, []byte(s.Secret))
+	mac.Write(body)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.TargetURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Flashcards-Event", s.Event)
+	req.Header.Set("X-Flashcards-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	res, err := hookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	res.Body.Close()
+
+	if res.StatusCode >= 300 && res.StatusCode != http.StatusGone {
+		return res.StatusCode, fmt.Errorf("hook target returned %d", res.StatusCode)
+	}
+
+	return res.StatusCode, nil
+}
+
+func (app *application) subscribeHookHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Event     string `json:"event"`
+		TargetURL string `json:"target_url"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	subscription := &data.HookSubscription{
+		UserID:    user.ID,
+		Event:     input.Event,
+		TargetURL: input.TargetURL,
+		Secret:    rand.Text(),
+	}
+
+	v := validator.New()
+
+	if data.ValidateHookSubscription(v, subscription); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Hooks.Insert(r.Context(), subscription)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/hooks/%d", subscription.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"subscription": subscription}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listHooksHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	subscriptions, err := app.models.Hooks.GetAllForUser(r.Context(), user.ID, "")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	for _, s := range subscriptions {
+		s.Secret = ""
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"subscriptions": subscriptions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) unsubscribeHookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.models.Hooks.Delete(r.Context(), id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "subscription successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"slices"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/validator"
+)
+
+// includeSafelist lists the related resources that can be expanded on
+// flashcard reads through ?include=.
+var includeSafelist = []string{"stats", "source_section", "attachments", "related", "category_details"}
+
+// revealSafelist lists the hidden parts of a flashcard a study client can opt
+// into through ?reveal=.
+var revealSafelist = []string{"hints"}
+
+func (app *application) readReveal(qs url.Values, v *validator.Validator) []string {
+	reveal := app.readCSV(qs, "reveal", []string{})
+
+	for _, value := range reveal {
+		if !validator.PermittedValue(value, revealSafelist...) {
+			v.AddError("reveal", "invalid reveal value")
+			break
+		}
+	}
+
+	return reveal
+}
+
+// concealFlashcards strips the parts of the flashcards, and of any expanded
+// related cards, that were not asked for with ?reveal=.
+func concealFlashcards(reveal []string, flashcards ...*data.Flashcard) {
+	if slices.Contains(reveal, "hints") {
+		return
+	}
+
+	for _, flashcard := range flashcards {
+		flashcard.Hints = nil
+		concealFlashcards(reveal, flashcard.Related...)
+	}
+}
+
+func (app *application) readIncludes(qs url.Values, v *validator.Validator) []string {
+	includes := app.readCSV(qs, "include", []string{})
+
+	for _, include := range includes {
+		if !validator.PermittedValue(include, includeSafelist...) {
+			v.AddError("include", "invalid include value")
+			break
+		}
+	}
+
+	v.Check(validator.Unique(includes), "include", "must not contain duplicate values")
+
+	return includes
+}
+
+// expandFlashcards hydrates the requested related resources onto the given
+// flashcards, issuing one batched query per include rather than one per card.
+func (app *application) expandFlashcards(ctx context.Context, flashcards []*data.Flashcard, includes []string, userID int64) error {
+	if len(flashcards) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(flashcards))
+	for i, flashcard := range flashcards {
+		ids[i] = flashcard.ID
+	}
+
+	related, err := app.models.Flashcards.GetRelatedIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, flashcard := range flashcards {
+		flashcard.RelatedIDs = related[flashcard.ID]
+		if flashcard.RelatedIDs == nil {
+			flashcard.RelatedIDs = []int64{}
+		}
+	}
+
+	for _, include := range includes {
+		switch include {
+		case "stats":
+			stats, err := app.models.Flashcards.GetCardStats(ctx, ids, userID)
+			if err != nil {
+				return err
+			}
+
+			for _, flashcard := range flashcards {
+				flashcard.Stats = stats[flashcard.ID]
+			}
+
+		case "attachments":
+			attachments, err := app.models.Attachments.GetAllForFlashcards(ctx, ids)
+			if err != nil {
+				return err
+			}
+
+			err = app.signAttachments(attachments...)
+			if err != nil {
+				return err
+			}
+
+			byFlashcard := make(map[int64][]*data.Attachment, len(flashcards))
+			for _, attachment := range attachments {
+				byFlashcard[attachment.FlashcardID] = append(byFlashcard[attachment.FlashcardID], attachment)
+			}
+
+			for _, flashcard := range flashcards {
+				flashcard.Attachments = byFlashcard[flashcard.ID]
+			}
+
+		case "related":
+			var relatedIDs []int64
+			for _, flashcard := range flashcards {
+				relatedIDs = append(relatedIDs, flashcard.RelatedIDs...)
+			}
+
+			relatedCards, err := app.models.Flashcards.GetByIDs(ctx, relatedIDs, userID)
+			if err != nil {
+				return err
+			}
+
+			app.linkFlashcards(relatedCards...)
+
+			lookup := make(map[int64]*data.Flashcard, len(relatedCards))
+			for _, card := range relatedCards {
+				lookup[card.ID] = card
+			}
+
+			for _, flashcard := range flashcards {
+				flashcard.Related = []*data.Flashcard{}
+				for _, id := range flashcard.RelatedIDs {
+					if card, ok := lookup[id]; ok {
+						flashcard.Related = append(flashcard.Related, card)
+					}
+				}
+			}
+
+		case "category_details":
+			var names []string
+			for _, flashcard := range flashcards {
+				names = append(names, flashcard.Categories...)
+			}
+
+			categories, err := app.models.Categories.GetByNames(ctx, names)
+			if err != nil {
+				return err
+			}
+
+			lookup := make(map[string]*data.Category, len(categories))
+			for _, category := range categories {
+				lookup[category.Name] = category
+			}
+
+			for _, flashcard := range flashcards {
+				flashcard.CategoryDetails = []*data.Category{}
+				for _, name := range flashcard.Categories {
+					if category, ok := lookup[name]; ok {
+						flashcard.CategoryDetails = append(flashcard.CategoryDetails, category)
+					}
+				}
+			}
+
+		case "source_section":
+			var sections []string
+			for _, flashcard := range flashcards {
+				if flashcard.Section != nil {
+					sections = append(sections, *flashcard.Section)
+				}
+			}
+
+			sourceSections, err := app.models.Flashcards.GetSourceSections(ctx, sections)
+			if err != nil {
+				return err
+			}
+
+			type key struct{ file, section string }
+
+			lookup := make(map[key]*data.SourceSection, len(sourceSections))
+			for _, s := range sourceSections {
+				lookup[key{s.SourceFile, s.Section}] = s
+			}
+
+			for _, flashcard := range flashcards {
+				if flashcard.Section != nil && flashcard.SourceFile != nil {
+					flashcard.SourceSection = lookup[key{*flashcard.SourceFile, *flashcard.Section}]
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"expvar"
+	"net/http"
+	"time"
+)
+
+// janitorBatch bounds how many orphaned blobs a single sweep deletes.
+const janitorBatch = 500
+
+var (
+	janitorRuns    = expvar.NewInt("janitor_runs")
+	janitorRemoved = expvar.NewMap("janitor_removed")
+	janitorLastRun = expvar.NewInt("janitor_last_run")
+)
+
+type janitorResult struct {
+	ExpiredTokens       int64 `json:"expired_tokens"`
+	OrphanedAttachments int64 `json:"orphaned_attachments"`
+	PurgedTrash         int64 `json:"purged_trash"`
+	ExpiredExports      int64 `json:"expired_exports"`
+}
+
+// sweep removes expired tokens and data exports and trashed cards past their
+// retention, and deletes the blobs of attachments and exports whose rows are
+// gone. Sweeps are
+// serialized so a manual run can't overlap the scheduled one.
+func (app *application) sweep(ctx context.Context) (janitorResult, error) {
+	app.janitorMu.Lock()
+	defer app.janitorMu.Unlock()
+
+	var result janitorResult
+	var err error
+
+	result.ExpiredTokens, err = app.models.Tokens.DeleteExpired(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	result.PurgedTrash, err = app.models.Flashcards.PurgeTrash(ctx, time.Now().Add(-app.config.janitor.trashRetention))
+	if err != nil {
+		return result, err
+	}
+
+	result.ExpiredExports, err = app.models.Users.DeleteExpiredExports(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	keys, err := app.models.Attachments.GetPendingDeletions(ctx, janitorBatch)
+	if err != nil {
+		return result, err
+	}
+
+	for _, key := range keys {
+		err := app.storage.Delete(key)
+		if err != nil {
+			app.logger.Error(err.Error(), "storage_key", key)
+			continue
+		}
+
+		err = app.models.Attachments.ClearPendingDeletion(ctx, key)
+		if err != nil {
+			return result, err
+		}
+
+		result.OrphanedAttachments++
+	}
+
+	janitorRuns.Add(1)
+	janitorRemoved.Add("expired_tokens", result.ExpiredTokens)
+	janitorRemoved.Add("orphaned_attachments", result.OrphanedAttachments)
+	janitorRemoved.Add("purged_trash", result.PurgedTrash)
+	janitorRemoved.Add("expired_exports", result.ExpiredExports)
+	janitorLastRun.Set(time.Now().Unix())
+
+	return result, nil
+}
+
+func (app *application) scheduledSweep(ctx context.Context) {
+	result, err := app.sweep(ctx)
+	if err != nil {
+		app.logger.Error(err.Error())
+		return
+	}
+
+	app.logger.Info("janitor sweep completed", "expired_tokens", result.ExpiredTokens, "orphaned_attachments", result.OrphanedAttachments, "purged_trash", result.PurgedTrash, "expired_exports", result.ExpiredExports)
+}
+
+func (app *application) runJanitorHandler(w http.ResponseWriter, r *http.Request) {
+	result, err := app.sweep(r.Context())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"removed": result}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
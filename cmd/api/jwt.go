@@ -0,0 +1,184 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/jwt"
+	"github.com/tomasen/realip"
+)
+
+const (
+	jwtIssuer         = "flashcards-api"
+	authenticationTTL = 24 * time.Hour
+)
+
+// jwtKey is an HMAC secret for authentication JWTs. Keys are rotated by
+// putting the new one first, which signs new tokens, and keeping the old
+// ones after it until the tokens they signed have expired.
+type jwtKey struct {
+	kid    string
+	secret []byte
+}
+
+func parseJWTKeys(s string) ([]jwtKey, error) {
+	var keys []jwtKey
+
+	for entry := range strings.SplitSeq(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kid, secret, ok := strings.Cut(entry, ":")
+		if !ok || kid == "" {
+			return nil, errors.New("jwt keys must be given as kid:secret")
+		}
+
+		if len(secret) < 32 {
+			return nil, errors.New("jwt key " + kid + " must be at least 32 characters long")
+		}
+
+		keys = append(keys, jwtKey{kid: kid, secret: []byte(secret)})
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("jwt authentication tokens need at least one key")
+	}
+
+	return keys, nil
+}
+
+func (app *application) jwtSecret(kid string) ([]byte, error) {
+	for _, key := range app.config.auth.keys {
+		if key.kid == kid {
+			return key.secret, nil
+		}
+	}
+
+	return nil, jwt.ErrInvalidToken
+}
+
+// issueAuthenticationToken creates the token the user signs in with: a
+// database-backed token, or with -auth-tokens=jwt a signed JWT carrying a
+// snapshot of the user and their permissions, so that requests can be
+// authenticated and authorized without a lookup. JWTs can't be revoked
+// before they expire, so role changes only reach them when the user signs
+// in again, and they aren't listed as sessions.
+func (app *application) issueAuthenticationToken(r *http.Request, user *data.User, scopes []string) (*data.Token, error) {
+	if app.config.auth.tokens != "jwt" {
+		return app.models.Tokens.NewSession(r.Context(), user.ID, authenticationTTL, scopes, r.UserAgent(), realip.FromRequest(r))
+	}
+
+	permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiry := now.Add(authenticationTTL)
+
+	claims := jwt.Claims{
+		"iss":   jwtIssuer,
+		"sub":   strconv.FormatInt(user.ID, 10),
+		"iat":   now.Unix(),
+		"exp":   expiry.Unix(),
+		"jti":   rand.Text(),
+		"usr":   user,
+		"perms": permissions,
+	}
+	if scopes != nil {
+		claims["scope"] = strings.Join(scopes, " ")
+	}
+
+	key := app.config.auth.keys[0]
+
+	plaintext, err := jwt.SignHS256(claims, key.kid, key.secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.Token{Plaintext: plaintext, UserID: user.ID, Expiry: expiry, Scope: data.ScopeAuthentication, Scopes: scopes}, nil
+}
+
+// userFromJWT verifies an authentication JWT and rebuilds the user snapshot
+// it carries, with the scopes the token is limited to and the user's
+// permissions. Permissions are nil for tokens issued before they were
+// included.
+func (app *application) userFromJWT(token string) (*data.User, data.Permissions, data.Permissions, error) {
+	claims, err := jwt.VerifyHS256(token, app.jwtSecret)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if claims.String("iss") != jwtIssuer {
+		return nil, nil, nil, jwt.ErrInvalidToken
+	}
+
+	js, err := json.Marshal(claims["usr"])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var user data.User
+
+	err = json.Unmarshal(js, &user)
+	if err != nil || user.ID == 0 || strconv.FormatInt(user.ID, 10) != claims.String("sub") {
+		return nil, nil, nil, jwt.ErrInvalidToken
+	}
+
+	var scopes data.Permissions
+	if _, ok := claims["scope"]; ok {
+		scopes = strings.Fields(claims.String("scope"))
+	}
+
+	var permissions data.Permissions
+	if perms, ok := claims["perms"]; ok {
+		js, err := json.Marshal(perms)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		err = json.Unmarshal(js, &permissions)
+		if err != nil {
+			return nil, nil, nil, jwt.ErrInvalidToken
+		}
+
+		// A user without permissions still has them in the token.
+		if permissions == nil {
+			permissions = data.Permissions{}
+		}
+	}
+
+	return &user, scopes, permissions, nil
+}
+
+// loadCurrentUser returns the full record of the authenticated user. Users
+// authenticated by JWT are only a snapshot, without the password hash and
+// version that changes to the account need, so they are read again.
+func (app *application) loadCurrentUser(w http.ResponseWriter, r *http.Request) (*data.User, bool) {
+	user := app.contextGetUser(r)
+
+	if !app.contextIsStateless(r) {
+		return user, true
+	}
+
+	user, err := app.models.Users.Get(r.Context(), user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return user, true
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+)
+
+// flashcardLinks builds the _links object for a flashcard. Every handler that
+// returns flashcards goes through linkFlashcards, so the URL templates live in
+// this one place rather than in each client.
+func flashcardLinks(id int64) map[string]data.Link {
+	self := fmt.Sprintf("/v1/flashcards/%d", id)
+
+	return map[string]data.Link{
+		"self":          {Href: self, Method: http.MethodGet},
+		"update":        {Href: self, Method: http.MethodPut},
+		"delete":        {Href: self, Method: http.MethodDelete},
+		"review":        {Href: self + "/review", Method: http.MethodPost},
+		"reset":         {Href: self + "/reset", Method: http.MethodPost},
+		"answer":        {Href: self + "/answer", Method: http.MethodPost},
+		"attachments":   {Href: self + "/attachments", Method: http.MethodGet},
+		"relations":     {Href: self + "/relations", Method: http.MethodPost},
+		"prerequisites": {Href: self + "/prerequisites", Method: http.MethodPost},
+		"report":        {Href: self + "/reports", Method: http.MethodPost},
+	}
+}
+
+func (app *application) linkFlashcards(flashcards ...*data.Flashcard) {
+	for _, flashcard := range flashcards {
+		flashcard.Links = flashcardLinks(flashcard.ID)
+	}
+}
+
+// savedSearchLinks lets clients treat a saved search like a deck. Only the
+// owner gets the update and delete links.
+func savedSearchLinks(search *data.SavedSearch) map[string]data.Link {
+	self := fmt.Sprintf("/v1/saved-searches/%d", search.ID)
+
+	links := map[string]data.Link{
+		"self":        {Href: self, Method: http.MethodGet},
+		"flashcards":  {Href: self + "/flashcards", Method: http.MethodGet},
+		"subscribe":   {Href: self + "/subscription", Method: http.MethodPut},
+		"unsubscribe": {Href: self + "/subscription", Method: http.MethodDelete},
+	}
+
+	if search.Owned {
+		links["update"] = data.Link{Href: self, Method: http.MethodPut}
+		links["delete"] = data.Link{Href: self, Method: http.MethodDelete}
+	}
+
+	return links
+}
+
+func (app *application) linkSavedSearches(searches ...*data.SavedSearch) {
+	for _, search := range searches {
+		search.Links = savedSearchLinks(search)
+	}
+}
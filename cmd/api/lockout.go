@@ -0,0 +1,140 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// loginThrottle counts failed password logins per account and per client IP.
+// Once a key has had too many failures, each further one locks it for twice
+// as long as the last, up to the maximum lockout. Like the rate limiter, it
+// is kept in memory, so each instance counts separately. A nil throttle
+// never locks anything.
+type loginThrottle struct {
+	maxFailures   int
+	maxIPFailures int
+	lockout       time.Duration
+	maxLockout    time.Duration
+
+	mu       sync.Mutex
+	failures map[string]*loginFailures
+}
+
+type loginFailures struct {
+	count       int
+	lockedUntil time.Time
+	lastFailure time.Time
+}
+
+func newLoginThrottle(maxFailures, maxIPFailures int, lockout, maxLockout time.Duration) *loginThrottle {
+	t := &loginThrottle{
+		maxFailures:   maxFailures,
+		maxIPFailures: maxIPFailures,
+		lockout:       lockout,
+		maxLockout:    maxLockout,
+		failures:      make(map[string]*loginFailures),
+	}
+
+	// Failures are forgotten once they are old enough that a lockout would
+	// have ended since.
+	go func() {
+		for {
+			time.Sleep(time.Minute)
+
+			t.mu.Lock()
+
+			for key, f := range t.failures {
+				if time.Since(f.lastFailure) > t.maxLockout {
+					delete(t.failures, key)
+				}
+			}
+
+			t.mu.Unlock()
+		}
+	}()
+
+	return t
+}
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(email)
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// locked returns how much longer logins to the account or from the IP are
+// locked for, or 0 when they aren't.
+func (t *loginThrottle) locked(email, ip string) time.Duration {
+	if t == nil {
+		return 0
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var remaining time.Duration
+
+	for _, key := range []string{accountKey(email), ipKey(ip)} {
+		if f, ok := t.failures[key]; ok {
+			remaining = max(remaining, time.Until(f.lockedUntil))
+		}
+	}
+
+	return remaining
+}
+
+// failed records a failed login, locking the account or IP once it has gone
+// over its limit. A limit of 0 or less never locks.
+func (t *loginThrottle) failed(email, ip string) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.record(accountKey(email), t.maxFailures)
+	t.record(ipKey(ip), t.maxIPFailures)
+}
+
+func (t *loginThrottle) record(key string, limit int) {
+	if limit <= 0 {
+		return
+	}
+
+	f, ok := t.failures[key]
+	if !ok {
+		f = &loginFailures{}
+		t.failures[key] = f
+	}
+
+	f.count++
+	f.lastFailure = time.Now()
+
+	if f.count < limit {
+		return
+	}
+
+	lockout := t.lockout
+	for i := limit; i < f.count && lockout < t.maxLockout; i++ {
+		lockout *= 2
+	}
+
+	f.lockedUntil = f.lastFailure.Add(min(lockout, t.maxLockout))
+}
+
+// succeeded clears the account's failures. The IP's are kept, so that a
+// client guessing at many accounts isn't let off by also owning one.
+func (t *loginThrottle) succeeded(email string) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.failures, accountKey(email))
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/lti"
+)
+
+func (app *application) invalidLTILaunchResponse(w http.ResponseWriter, r *http.Request) {
+	message := "invalid or expired LTI launch"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func ltiCookie(name, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/v1/lti",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+	}
+}
+
+// ltiLoginHandler handles the platform's third-party initiated login and
+// redirects back to the platform's authorization endpoint.
+func (app *application) ltiLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if app.lti == nil {
+		app.featureDisabledResponse(w, r)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	state := rand.Text()
+	nonce := rand.Text()
+
+	redirect, err := app.lti.LoginRedirect(
+		r.Form.Get("iss"),
+		r.Form.Get("login_hint"),
+		r.Form.Get("lti_message_hint"),
+		app.config.lti.launchURL,
+		state,
+		nonce,
+	)
+	if err != nil {
+		app.invalidLTILaunchResponse(w, r)
+		return
+	}
+
+	http.SetCookie(w, ltiCookie("lti_state", state, 600))
+	http.SetCookie(w, ltiCookie("lti_nonce", nonce, 600))
+
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+// ltiLaunchHandler validates the id_token posted by the platform, maps the
+// LTI user to a local account and issues an authentication token for it. The
+// launch's context (the LMS course) is recorded but not mapped to decks or
+// deck members; learners see the cards their own account can.
+func (app *application) ltiLaunchHandler(w http.ResponseWriter, r *http.Request) {
+	if app.lti == nil {
+		app.featureDisabledResponse(w, r)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	stateCookie, err := r.Cookie("lti_state")
+	if err != nil || stateCookie.Value != r.PostForm.Get("state") {
+		app.invalidLTILaunchResponse(w, r)
+		return
+	}
+
+	nonceCookie, err := r.Cookie("lti_nonce")
+	if err != nil {
+		app.invalidLTILaunchResponse(w, r)
+		return
+	}
+
+	http.SetCookie(w, ltiCookie("lti_state", "", -1))
+	http.SetCookie(w, ltiCookie("lti_nonce", "", -1))
+
+	launch, err := app.lti.ValidateLaunch(r.PostForm.Get("id_token"), nonceCookie.Value)
+	if err != nil {
+		app.invalidLTILaunchResponse(w, r)
+		return
+	}
+
+	userID, err := app.ltiUser(r.Context(), launch)
+	if err != nil {
+		switch {
+		case errors.Is(err, lti.ErrInvalidLaunch):
+			app.invalidLTILaunchResponse(w, r)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.LTI.UpsertLaunch(r.Context(), &data.LTILaunch{
+		UserID:      userID,
+		Issuer:      app.config.lti.issuer,
+		Subject:     launch.Subject,
+		ContextID:   launch.ContextID,
+		LineItemURL: launch.LineItemURL,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	user, err := app.models.Users.Get(r.Context(), userID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	token, err := app.issueAuthenticationToken(r, user, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if app.config.lti.redirectURL != "" {
+		fragment := url.Values{"token": {token.Plaintext}}
+		http.Redirect(w, r, app.config.lti.redirectURL+"#"+fragment.Encode(), http.StatusSeeOther)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"authentication_token": token}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// ltiUser returns the local user linked to the LTI subject, linking by the
+// email claim of the launch or creating an activated account on first launch.
+// The platform is registered with the tool and the address is its account of
+// the learner, so linking claims an account that hadn't been activated, as
+// signing in with an identity provider does.
+func (app *application) ltiUser(ctx context.Context, launch *lti.Launch) (int64, error) {
+	userID, err := app.models.LTI.GetUserID(ctx, app.config.lti.issuer, launch.Subject)
+	if err == nil {
+		return userID, nil
+	}
+	if !errors.Is(err, data.ErrRecordNotFound) {
+		return 0, err
+	}
+
+	if launch.Email == "" {
+		return 0, lti.ErrInvalidLaunch
+	}
+
+	user, err := app.models.Users.GetByEmail(ctx, launch.Email)
+	switch {
+	case err == nil:
+		if !user.Activated {
+			err = app.claimUnactivatedUser(ctx, user)
+			if err != nil {
+				return 0, err
+			}
+		}
+	case errors.Is(err, data.ErrRecordNotFound):
+		user, err = app.insertExternalUser(ctx, launch.Name, launch.Email)
+		if err != nil {
+			return 0, err
+		}
+	default:
+		return 0, err
+	}
+
+	err = app.models.LTI.LinkUser(ctx, app.config.lti.issuer, launch.Subject, user.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return user.ID, nil
+}
+
+func (app *application) ltiJWKSHandler(w http.ResponseWriter, r *http.Request) {
+	if app.lti == nil {
+		app.featureDisabledResponse(w, r)
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, app.lti.JWKS(), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// passBackScore posts a grade the server worked out for the user to the line
+// item of their most recent LTI launch, in the background. It is a no-op when
+// LTI is disabled or the user has no launch that granted grade pass-back.
+func (app *application) passBackScore(ctx context.Context, userID int64, given, maximum float64, comment string) error {
+	if app.lti == nil || maximum <= 0 {
+		return nil
+	}
+
+	launch, err := app.models.LTI.GetLaunch(ctx, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			return nil
+		default:
+			return err
+		}
+	}
+
+	if launch.LineItemURL == "" {
+		return nil
+	}
+
+	score := lti.Score{
+		UserID:       launch.Subject,
+		ScoreGiven:   given,
+		ScoreMaximum: maximum,
+		Comment:      comment,
+	}
+
+	app.background(func() {
+		err := app.lti.PostScore(launch.LineItemURL, score)
+		if err != nil {
+			app.logger.Error(err.Error(), "user_id", userID)
+		}
+	})
+
+	return nil
+}
@@ -2,35 +2,75 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"expvar"
 	"flag"
 	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/embedding"
+	"flashcards-api.johndennehy101.tech/internal/lti"
 	"flashcards-api.johndennehy101.tech/internal/mailer"
-	_ "github.com/lib/pq"
+	"flashcards-api.johndennehy101.tech/internal/oauth"
+	"flashcards-api.johndennehy101.tech/internal/pwned"
+	"flashcards-api.johndennehy101.tech/internal/scheduler"
+	"flashcards-api.johndennehy101.tech/internal/search"
+	"flashcards-api.johndennehy101.tech/internal/slowquery"
+	"flashcards-api.johndennehy101.tech/internal/storage"
+	"flashcards-api.johndennehy101.tech/internal/tts"
+	"flashcards-api.johndennehy101.tech/internal/xapi"
+	"fmt"
+	"github.com/XSAM/otelsql"
+	"github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"golang.org/x/text/language"
 	"log/slog"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	_ "time/tzdata"
 )
 
 const version = "1.0.0"
 
 type config struct {
-	port int
-	env  string
-	db   struct {
+	port     int
+	env      string
+	language language.Tag
+	db       struct {
 		dsn          string
 		maxOpenConns int
 		maxIdleConns int
 		maxIdleTime  time.Duration
+		slowQuery    time.Duration
+		batchConns   int
 	}
 	limiter struct {
-		rps     float64
-		burst   int
-		enabled bool
+		rps        float64
+		burst      int
+		batchRPS   float64
+		batchBurst int
+		enabled    bool
+	}
+	argon2 struct {
+		memory      uint
+		iterations  uint
+		parallelism uint
+	}
+	breached struct {
+		source string
+		url    string
+		file   string
+	}
+	login struct {
+		maxFailures   int
+		maxIPFailures int
+		lockout       time.Duration
+		maxLockout    time.Duration
 	}
 	smtp struct {
 		host     string
@@ -39,17 +79,138 @@ type config struct {
 		password string
 		sender   string
 	}
+	duplicates   string
+	deletedCards string
+	concurrency  struct {
+		search     int
+		export     int
+		generation int
+	}
 	cors struct {
 		trustedOrigins []string
 	}
+	search struct {
+		engine     string
+		host       string
+		apiKey     string
+		index      string
+		similarity float64
+	}
+	storage struct {
+		backend string
+		dir     string
+		secret  string
+		urlTTL  time.Duration
+		s3      struct {
+			endpoint  string
+			region    string
+			bucket    string
+			accessKey string
+			secretKey string
+		}
+	}
+	tts struct {
+		provider string
+		url      string
+		apiKey   string
+		model    string
+		voice    string
+		auto     bool
+	}
+	embedding struct {
+		provider    string
+		url         string
+		apiKey      string
+		model       string
+		maxDistance float64
+	}
+	lti struct {
+		issuer       string
+		clientID     string
+		deploymentID string
+		authURL      string
+		tokenURL     string
+		jwksURL      string
+		keyFile      string
+		launchURL    string
+		redirectURL  string
+	}
+	oauth struct {
+		googleClientID     string
+		googleClientSecret string
+		githubClientID     string
+		githubClientSecret string
+	}
+	auth struct {
+		tokens       string
+		jwtKeys      string
+		keys         []jwtKey
+		magicLinkURL string
+	}
+	xapi struct {
+		endpoint     string
+		username     string
+		password     string
+		activityBase string
+		passingScore float64
+	}
+	otel struct {
+		endpoint    string
+		sampleRatio float64
+	}
+	janitor struct {
+		interval       time.Duration
+		trashRetention time.Duration
+	}
+	quality struct {
+		interval time.Duration
+	}
+	cursor struct {
+		secret string
+	}
+	savedSearches struct {
+		notifyInterval time.Duration
+	}
+	categories struct {
+		suggest bool
+	}
+	scheduler struct {
+		leitnerIntervals []int
+	}
+	study struct {
+		newPerDay     int
+		reviewsPerDay int
+	}
+	chaos struct {
+		enabled     bool
+		errorRate   float64
+		latencyRate float64
+		latency     time.Duration
+	}
 }
 
 type application struct {
-	config config
-	logger *slog.Logger
-	models data.Models
-	mailer *mailer.Mailer
-	wg     sync.WaitGroup
+	config     config
+	logger     *slog.Logger
+	models     data.Models
+	mailer     *mailer.Mailer
+	search     search.Engine
+	storage    storage.Storage
+	tts        tts.Provider
+	embeddings embedding.Provider
+	lti        *lti.Tool
+	oauth      map[string]oauth.Provider
+	pwned      pwned.Checker
+	xapi       *xapi.Client
+	wg         sync.WaitGroup
+
+	janitorMu sync.Mutex
+
+	// Locks accounts and IPs after failed logins, nil when disabled
+	logins *loginThrottle
+
+	// Bounds concurrent batch work, nil when unlimited
+	batchSlots chan struct{}
 }
 
 func main() {
@@ -57,29 +218,214 @@ func main() {
 
 	flag.IntVar(&cfg.port, "port", 4000, "API server port")
 	flag.StringVar(&cfg.env, "env", "development", "Environment (development|staging|production)")
+	flag.Func("default-language", "Language of original flashcard content (default en)", func(val string) error {
+		tag, err := language.Parse(val)
+		cfg.language = tag
+		return err
+	})
 	flag.StringVar(&cfg.db.dsn, "db-dsn", "", "PostgreSQL DSN")
 	flag.IntVar(&cfg.db.maxOpenConns, "db-max-open-conns", 25, "PostgreSQL max open connections")
 	flag.IntVar(&cfg.db.maxIdleConns, "db-max-idle-conns", 25, "PostgreSQL max idle connections")
 	flag.DurationVar(&cfg.db.maxIdleTime, "db-max-idle-time", 15*time.Minute, "PostgreSQL max connection idle time")
+	flag.IntVar(&cfg.db.batchConns, "db-batch-conns", 6, "Connections batch requests and background jobs may use at once (0 = unlimited)")
+	flag.DurationVar(&cfg.db.slowQuery, "db-slow-query-threshold", 200*time.Millisecond, "Log queries slower than this, disabled when 0")
 	flag.Float64Var(&cfg.limiter.rps, "limiter-rps", 5, "Rate limiter maximum requests per second")
 	flag.IntVar(&cfg.limiter.burst, "limiter-burst", 10, "Rate limiter maximum burst")
+	flag.Float64Var(&cfg.limiter.batchRPS, "limiter-batch-rps", 1, "Rate limiter maximum batch requests per second")
+	flag.IntVar(&cfg.limiter.batchBurst, "limiter-batch-burst", 2, "Rate limiter maximum batch burst")
 	flag.BoolVar(&cfg.limiter.enabled, "limiter-enabled", true, "Enable rate limiter")
+	flag.IntVar(&cfg.login.maxFailures, "login-max-failures", 5, "Failed logins to an account before it is locked (0 = no lockout)")
+	flag.IntVar(&cfg.login.maxIPFailures, "login-max-ip-failures", 20, "Failed logins from an IP before it is locked (0 = no lockout)")
+	flag.DurationVar(&cfg.login.lockout, "login-lockout", time.Minute, "First lockout, doubled with each further failure")
+	flag.DurationVar(&cfg.login.maxLockout, "login-max-lockout", time.Hour, "Longest lockout")
+	flag.StringVar(&cfg.breached.source, "breached-passwords", "", "Reject new passwords found in breaches (api|file), disabled when empty")
+	flag.StringVar(&cfg.breached.url, "breached-passwords-url", "https://api.pwnedpasswords.com", "Pwned Passwords range API URL")
+	flag.StringVar(&cfg.breached.file, "breached-passwords-file", "", "Sorted Pwned Passwords SHA-1 hash file")
+	flag.UintVar(&cfg.argon2.memory, "argon2-memory", 64*1024, "Argon2id password hashing memory in KiB")
+	flag.UintVar(&cfg.argon2.iterations, "argon2-iterations", 3, "Argon2id password hashing iterations")
+	flag.UintVar(&cfg.argon2.parallelism, "argon2-parallelism", 2, "Argon2id password hashing threads")
 	flag.StringVar(&cfg.smtp.host, "smtp-host", os.Getenv("SMTP_HOSTNAME"), "SMTP host")
 	flag.IntVar(&cfg.smtp.port, "smtp-port", 25, "SMTP port")
 	flag.StringVar(&cfg.smtp.username, "smtp-username", ""+
 		os.Getenv("SMTP_USERNAME"), "SMTP username")
 	flag.StringVar(&cfg.smtp.password, "smtp-password", os.Getenv("SMTP_PASSWORD"), "SMTP password")
 	flag.StringVar(&cfg.smtp.sender, "smtp-sender", os.Getenv("SMTP_SENDER"), "SMTP sender")
+	flag.StringVar(&cfg.duplicates, "duplicate-questions", "allow", "Handling of new cards whose normalized question the user already has (allow|warn|reject)")
+	flag.StringVar(&cfg.auth.tokens, "auth-tokens", "opaque", "Authentication tokens issued at login (opaque|jwt)")
+	flag.StringVar(&cfg.auth.jwtKeys, "jwt-keys", os.Getenv("JWT_KEYS"), "Comma-separated kid:secret JWT signing keys, the first signs and all are accepted")
+	flag.StringVar(&cfg.auth.magicLinkURL, "magic-link-url", "", "Client URL login links point at, with the token in the query (default is to email the token)")
+	flag.StringVar(&cfg.deletedCards, "deleted-account-cards", "anonymize", "Handling of the cards written by a deleted account (delete|anonymize)")
+	flag.Func("leitner-intervals", "Days between reviews for each Leitner box, comma separated (default 1,2,4,8,16)", func(val string) error {
+		cfg.scheduler.leitnerIntervals = nil
+		for _, field := range strings.Split(val, ",") {
+			days, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil || days < 1 {
+				return errors.New("intervals must be whole numbers of days, at least 1")
+			}
+			cfg.scheduler.leitnerIntervals = append(cfg.scheduler.leitnerIntervals, days)
+		}
+		return nil
+	})
+	flag.IntVar(&cfg.study.newPerDay, "study-new-per-day", 20, "Maximum new cards a user is given to study each day")
+	flag.IntVar(&cfg.study.reviewsPerDay, "study-reviews-per-day", 200, "Maximum review cards a user is given to study each day")
+	flag.BoolVar(&cfg.categories.suggest, "suggest-categories", false, "Suggest categories for new flashcards from category keywords and similar tagged cards")
+	flag.DurationVar(&cfg.janitor.interval, "janitor-interval", time.Hour, "Interval between cleanups of expired tokens and orphaned attachments, disabled when 0")
+	flag.DurationVar(&cfg.janitor.trashRetention, "trash-retention", 30*24*time.Hour, "How long deleted flashcards stay restorable from the trash")
+	flag.DurationVar(&cfg.quality.interval, "quality-refresh-interval", time.Hour, "Interval between quality score and measured difficulty refreshes, disabled when 0")
+	flag.DurationVar(&cfg.savedSearches.notifyInterval, "saved-search-notify-interval", time.Hour, "Interval between saved search notification emails, disabled when 0")
+	flag.IntVar(&cfg.concurrency.search, "concurrency-search", 20, "Maximum concurrent search requests (0 = unlimited)")
+	flag.IntVar(&cfg.concurrency.export, "concurrency-export", 4, "Maximum concurrent export requests (0 = unlimited)")
+	flag.IntVar(&cfg.concurrency.generation, "concurrency-generation", 4, "Maximum concurrent audio/LLM generation requests (0 = unlimited)")
 	flag.Func("cors-trusted-origins", "Trusted CORS origins (space separated)", func(val string) error {
 		cfg.cors.trustedOrigins = strings.Fields(val)
 		return nil
 	})
+	flag.StringVar(&cfg.search.engine, "search-engine", "", "External search engine (meilisearch), disabled when empty")
+	flag.StringVar(&cfg.search.host, "search-host", os.Getenv("SEARCH_HOST"), "External search engine base URL")
+	flag.StringVar(&cfg.search.apiKey, "search-api-key", os.Getenv("SEARCH_API_KEY"), "External search engine API key")
+	flag.StringVar(&cfg.search.index, "search-index", "flashcards", "External search engine index name")
+	flag.Float64Var(&cfg.search.similarity, "search-fuzzy-similarity", 0.3, "Minimum trigram word similarity (0-1) for ?fuzzy=true matches")
+	flag.StringVar(&cfg.storage.backend, "storage-backend", "local", "Attachment storage backend (local|s3)")
+	flag.StringVar(&cfg.storage.dir, "storage-dir", "./uploads", "Attachment directory for the local storage backend")
+	flag.StringVar(&cfg.storage.secret, "storage-secret", os.Getenv("STORAGE_SECRET"), "Secret used to sign local attachment download URLs")
+	flag.StringVar(&cfg.cursor.secret, "cursor-secret", os.Getenv("CURSOR_SECRET"), "Secret used to sign pagination cursors, random when empty")
+	flag.DurationVar(&cfg.storage.urlTTL, "storage-url-ttl", 15*time.Minute, "Lifetime of signed attachment download URLs")
+	flag.StringVar(&cfg.storage.s3.endpoint, "s3-endpoint", os.Getenv("S3_ENDPOINT"), "S3-compatible endpoint URL")
+	flag.StringVar(&cfg.storage.s3.region, "s3-region", "us-east-1", "S3 region")
+	flag.StringVar(&cfg.storage.s3.bucket, "s3-bucket", os.Getenv("S3_BUCKET"), "S3 bucket")
+	flag.StringVar(&cfg.storage.s3.accessKey, "s3-access-key", os.Getenv("S3_ACCESS_KEY"), "S3 access key")
+	flag.StringVar(&cfg.storage.s3.secretKey, "s3-secret-key", os.Getenv("S3_SECRET_KEY"), "S3 secret key")
+	flag.StringVar(&cfg.tts.provider, "tts-provider", "", "Text-to-speech provider (openai), disabled when empty")
+	flag.StringVar(&cfg.tts.url, "tts-url", "https://api.openai.com/v1/audio/speech", "Text-to-speech API URL")
+	flag.StringVar(&cfg.tts.apiKey, "tts-api-key", os.Getenv("TTS_API_KEY"), "Text-to-speech API key")
+	flag.StringVar(&cfg.tts.model, "tts-model", "tts-1", "Text-to-speech model")
+	flag.StringVar(&cfg.tts.voice, "tts-voice", "alloy", "Text-to-speech voice")
+	flag.BoolVar(&cfg.tts.auto, "tts-auto", false, "Generate question and answer audio for new flashcards")
+	flag.StringVar(&cfg.embedding.provider, "embedding-provider", "", "Embedding provider for semantic search (openai), disabled when empty")
+	flag.StringVar(&cfg.embedding.url, "embedding-url", "https://api.openai.com/v1/embeddings", "Embedding API URL")
+	flag.StringVar(&cfg.embedding.apiKey, "embedding-api-key", os.Getenv("EMBEDDING_API_KEY"), "Embedding API key")
+	flag.StringVar(&cfg.embedding.model, "embedding-model", "text-embedding-3-small", "Embedding model")
+	flag.Float64Var(&cfg.embedding.maxDistance, "embedding-max-distance", 0.7, "Maximum cosine distance (0-2) of semantic search matches")
+	flag.StringVar(&cfg.lti.issuer, "lti-issuer", "", "LTI 1.3 platform issuer, disabled when empty")
+	flag.StringVar(&cfg.lti.clientID, "lti-client-id", "", "LTI 1.3 client ID assigned by the platform")
+	flag.StringVar(&cfg.lti.deploymentID, "lti-deployment-id", "", "LTI 1.3 deployment ID")
+	flag.StringVar(&cfg.lti.authURL, "lti-auth-url", "", "LTI 1.3 platform OIDC authorization URL")
+	flag.StringVar(&cfg.lti.tokenURL, "lti-token-url", "", "LTI 1.3 platform OAuth2 token URL")
+	flag.StringVar(&cfg.lti.jwksURL, "lti-jwks-url", "", "LTI 1.3 platform public keyset URL")
+	flag.StringVar(&cfg.lti.keyFile, "lti-private-key", "", "PEM file holding the tool's RSA private key")
+	flag.StringVar(&cfg.lti.launchURL, "lti-launch-url", "", "Public URL of the /v1/lti/launch endpoint")
+	flag.StringVar(&cfg.xapi.endpoint, "xapi-endpoint", os.Getenv("XAPI_ENDPOINT"), "xAPI LRS endpoint, disabled when empty")
+	flag.StringVar(&cfg.xapi.username, "xapi-username", os.Getenv("XAPI_USERNAME"), "xAPI LRS basic auth username")
+	flag.StringVar(&cfg.xapi.password, "xapi-password", os.Getenv("XAPI_PASSWORD"), "xAPI LRS basic auth password")
+	flag.StringVar(&cfg.xapi.activityBase, "xapi-activity-base", "https://flashcards-api.johndennehy101.tech", "Base IRI for xAPI activity IDs")
+	flag.Float64Var(&cfg.xapi.passingScore, "xapi-passing-score", 0.8, "Scaled score (0-1) an exam or study session needs for an xAPI passed statement")
+	flag.StringVar(&cfg.lti.redirectURL, "lti-redirect-url", "", "Client URL to redirect to after launch, with the token in the fragment")
+	flag.StringVar(&cfg.oauth.googleClientID, "oauth-google-client-id", "", "Google OAuth client ID, Google sign-in disabled when empty")
+	flag.StringVar(&cfg.oauth.googleClientSecret, "oauth-google-client-secret", os.Getenv("OAUTH_GOOGLE_CLIENT_SECRET"), "Google OAuth client secret")
+	flag.StringVar(&cfg.oauth.githubClientID, "oauth-github-client-id", "", "GitHub OAuth app client ID, GitHub sign-in disabled when empty")
+	flag.StringVar(&cfg.oauth.githubClientSecret, "oauth-github-client-secret", os.Getenv("OAUTH_GITHUB_CLIENT_SECRET"), "GitHub OAuth app client secret")
+
+	flag.StringVar(&cfg.otel.endpoint, "otel-endpoint", os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"), "OTLP/HTTP traces endpoint URL, tracing disabled when empty")
+	flag.Float64Var(&cfg.otel.sampleRatio, "otel-sample-ratio", 1, "Fraction of new traces to sample")
+	flag.BoolVar(&cfg.chaos.enabled, "chaos-enabled", os.Getenv("CHAOS_ENABLED") == "true", "Inject faults into requests (never allowed in production)")
+	flag.Float64Var(&cfg.chaos.errorRate, "chaos-error-rate", 0.05, "Fraction of requests answered with a random 5xx error")
+	flag.Float64Var(&cfg.chaos.latencyRate, "chaos-latency-rate", 0.1, "Fraction of requests delayed before being handled")
+	flag.DurationVar(&cfg.chaos.latency, "chaos-latency", 2*time.Second, "Maximum injected delay")
+
+	cfg.language = language.English
+	cfg.scheduler.leitnerIntervals = scheduler.DefaultLeitnerIntervals
 
 	flag.Parse()
 
+	// Without a configured secret, cursors stop working when the server
+	// restarts and aren't shared between replicas.
+	if cfg.cursor.secret == "" {
+		cfg.cursor.secret = rand.Text()
+	}
+
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
-	db, err := openDB(cfg)
+	switch cfg.duplicates {
+	case "allow", "warn", "reject":
+	default:
+		logger.Error("unsupported duplicate question mode", "mode", cfg.duplicates)
+		os.Exit(1)
+	}
+
+	switch cfg.auth.tokens {
+	case "opaque":
+	case "jwt":
+		keys, err := parseJWTKeys(cfg.auth.jwtKeys)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+
+		cfg.auth.keys = keys
+	default:
+		logger.Error("unsupported authentication token mode", "mode", cfg.auth.tokens)
+		os.Exit(1)
+	}
+
+	if (cfg.login.maxFailures > 0 || cfg.login.maxIPFailures > 0) && (cfg.login.lockout <= 0 || cfg.login.maxLockout < cfg.login.lockout) {
+		logger.Error("login lockout needs a positive lockout no longer than the maximum lockout")
+		os.Exit(1)
+	}
+
+	if cfg.argon2.iterations < 1 || cfg.argon2.parallelism < 1 || cfg.argon2.parallelism > 255 || cfg.argon2.memory < 8*cfg.argon2.parallelism || cfg.argon2.memory > 4<<20 {
+		logger.Error("argon2 needs 1-255 threads, at least one iteration, and 8 KiB per thread up to 4 GiB of memory")
+		os.Exit(1)
+	}
+
+	switch cfg.deletedCards {
+	case "delete", "anonymize":
+	default:
+		logger.Error("unsupported deleted account cards mode", "mode", cfg.deletedCards)
+		os.Exit(1)
+	}
+
+	if cfg.embedding.maxDistance <= 0 || cfg.embedding.maxDistance > 2 {
+		logger.Error("embedding max distance must be between 0 and 2", "distance", cfg.embedding.maxDistance)
+		os.Exit(1)
+	}
+
+	if cfg.search.similarity <= 0 || cfg.search.similarity > 1 {
+		logger.Error("fuzzy search similarity must be between 0 and 1", "similarity", cfg.search.similarity)
+		os.Exit(1)
+	}
+
+	if cfg.xapi.passingScore < 0 || cfg.xapi.passingScore > 1 {
+		logger.Error("xAPI passing score must be between 0 and 1", "score", cfg.xapi.passingScore)
+		os.Exit(1)
+	}
+
+	if cfg.study.newPerDay < 0 || cfg.study.reviewsPerDay < 0 {
+		logger.Error("daily study limits must not be negative", "new", cfg.study.newPerDay, "reviews", cfg.study.reviewsPerDay)
+		os.Exit(1)
+	}
+
+	if cfg.chaos.enabled {
+		if cfg.env == "production" {
+			logger.Error("fault injection cannot be enabled in production")
+			os.Exit(1)
+		}
+
+		logger.Warn("fault injection enabled", "error_rate", cfg.chaos.errorRate, "latency_rate", cfg.chaos.latencyRate, "latency", cfg.chaos.latency)
+	}
+
+	if cfg.otel.endpoint != "" {
+		tp, err := openTracing(cfg)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+
+		defer tp.Shutdown(context.Background())
+
+		logger.Info("tracing enabled", "endpoint", cfg.otel.endpoint)
+	}
+
+	db, err := openDB(cfg, logger)
 	if err != nil {
 		logger.Error(err.Error())
 		os.Exit(1)
@@ -95,6 +441,12 @@ func main() {
 		os.Exit(1)
 	}
 
+	store, err := openStorage(cfg)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
 	expvar.NewString("version").Set(version)
 
 	expvar.Publish("goroutines", expvar.Func(func() any {
@@ -109,11 +461,102 @@ func main() {
 		return time.Now().Unix()
 	}))
 
+	schedulers := scheduler.NewSet()
+	schedulers.Leitner = scheduler.NewLeitner(cfg.scheduler.leitnerIntervals)
+
 	app := &application{
-		config: cfg,
-		logger: logger,
-		models: data.NewModels(db),
-		mailer: mailInstance,
+		config:  cfg,
+		logger:  logger,
+		models:  data.NewModels(db, schedulers),
+		mailer:  mailInstance,
+		storage: store,
+	}
+
+	if cfg.login.maxFailures > 0 || cfg.login.maxIPFailures > 0 {
+		app.logins = newLoginThrottle(cfg.login.maxFailures, cfg.login.maxIPFailures, cfg.login.lockout, cfg.login.maxLockout)
+	}
+
+	data.PasswordArgon2 = data.Argon2Params{
+		Memory:      uint32(cfg.argon2.memory),
+		Iterations:  uint32(cfg.argon2.iterations),
+		Parallelism: uint8(cfg.argon2.parallelism),
+	}
+
+	if cfg.db.batchConns > 0 {
+		app.batchSlots = make(chan struct{}, cfg.db.batchConns)
+	}
+
+	switch cfg.search.engine {
+	case "":
+	case "meilisearch":
+		app.search = search.NewMeilisearch(cfg.search.host, cfg.search.apiKey, cfg.search.index)
+		app.background(app.reindexFlashcards)
+	default:
+		logger.Error("unsupported search engine", "engine", cfg.search.engine)
+		os.Exit(1)
+	}
+
+	if cfg.xapi.endpoint != "" {
+		app.xapi = xapi.New(cfg.xapi.endpoint, cfg.xapi.username, cfg.xapi.password)
+	}
+
+	if cfg.lti.issuer != "" {
+		app.lti, err = lti.New(lti.Platform{
+			Issuer:       cfg.lti.issuer,
+			ClientID:     cfg.lti.clientID,
+			DeploymentID: cfg.lti.deploymentID,
+			AuthURL:      cfg.lti.authURL,
+			TokenURL:     cfg.lti.tokenURL,
+			JWKSURL:      cfg.lti.jwksURL,
+		}, cfg.lti.keyFile)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	switch cfg.breached.source {
+	case "":
+	case "api":
+		app.pwned = pwned.NewAPI(cfg.breached.url)
+	case "file":
+		app.pwned, err = pwned.NewFile(cfg.breached.file)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+	default:
+		logger.Error("unsupported breached password source", "source", cfg.breached.source)
+		os.Exit(1)
+	}
+
+	app.oauth = make(map[string]oauth.Provider)
+
+	if cfg.oauth.googleClientID != "" {
+		app.oauth["google"] = oauth.NewGoogle(cfg.oauth.googleClientID, cfg.oauth.googleClientSecret)
+	}
+
+	if cfg.oauth.githubClientID != "" {
+		app.oauth["github"] = oauth.NewGitHub(cfg.oauth.githubClientID, cfg.oauth.githubClientSecret)
+	}
+
+	switch cfg.tts.provider {
+	case "":
+	case "openai":
+		app.tts = tts.NewOpenAI(cfg.tts.url, cfg.tts.apiKey, cfg.tts.model, cfg.tts.voice)
+	default:
+		logger.Error("unsupported text-to-speech provider", "provider", cfg.tts.provider)
+		os.Exit(1)
+	}
+
+	switch cfg.embedding.provider {
+	case "":
+	case "openai":
+		app.embeddings = embedding.NewOpenAI(cfg.embedding.url, cfg.embedding.apiKey, cfg.embedding.model)
+		app.background(app.embedMissingFlashcards)
+	default:
+		logger.Error("unsupported embedding provider", "provider", cfg.embedding.provider)
+		os.Exit(1)
 	}
 
 	err = app.serve()
@@ -123,12 +566,29 @@ func main() {
 	}
 }
 
-func openDB(cfg config) (*sql.DB, error) {
-	db, err := sql.Open("postgres", cfg.db.dsn)
+func openDB(cfg config, logger *slog.Logger) (*sql.DB, error) {
+	pqConnector, err := pq.NewConnector(cfg.db.dsn)
 	if err != nil {
 		return nil, err
 	}
 
+	var connector driver.Connector = pqConnector
+
+	if cfg.db.slowQuery > 0 {
+		connector = slowquery.Wrap(connector, cfg.db.slowQuery, logger, expvar.NewInt("total_slow_queries"))
+	}
+
+	var db *sql.DB
+
+	if cfg.otel.endpoint != "" {
+		db = otelsql.OpenDB(connector,
+			otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL),
+			otelsql.WithSpanOptions(otelsql.SpanOptions{OmitConnResetSession: true, OmitRows: true}),
+		)
+	} else {
+		db = sql.OpenDB(connector)
+	}
+
 	db.SetMaxOpenConns(cfg.db.maxOpenConns)
 	db.SetMaxIdleConns(cfg.db.maxIdleConns)
 	db.SetConnMaxIdleTime(cfg.db.maxIdleTime)
@@ -144,3 +604,19 @@ func openDB(cfg config) (*sql.DB, error) {
 
 	return db, nil
 }
+
+func openStorage(cfg config) (storage.Storage, error) {
+	switch cfg.storage.backend {
+	case "local":
+		secret := []byte(cfg.storage.secret)
+		if len(secret) == 0 {
+			secret = []byte(rand.Text())
+		}
+		return storage.NewLocal(cfg.storage.dir, secret)
+	case "s3":
+		s3 := cfg.storage.s3
+		return storage.NewS3(s3.endpoint, s3.region, s3.bucket, s3.accessKey, s3.secretKey)
+	default:
+		return nil, fmt.Errorf("unsupported storage backend %q", cfg.storage.backend)
+	}
+}
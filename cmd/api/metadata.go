@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/validator"
+	"github.com/julienschmidt/httprouter"
+)
+
+// readMetadataFilter collects metadata.<name>=<value> query parameters into a
+// containment filter, converting each value to its field's type.
+func (app *application) readMetadataFilter(ctx context.Context, qs url.Values, v *validator.Validator) (data.CardMetadata, error) {
+	filter := data.CardMetadata{}
+
+	var fields []*data.MetadataField
+
+	for key := range qs {
+		name, ok := strings.CutPrefix(key, "metadata.")
+		if !ok {
+			continue
+		}
+
+		if fields == nil {
+			var err error
+			fields, err = app.models.Metadata.GetAll(ctx)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		i := slices.IndexFunc(fields, func(f *data.MetadataField) bool { return f.Name == name })
+		if i < 0 {
+			v.AddError(key, "is not a defined metadata field")
+			continue
+		}
+
+		value, err := data.ParseMetadataFilter(fields[i], qs.Get(key))
+		if err != nil {
+			v.AddError(key, "must be a "+fields[i].Type+" value")
+			continue
+		}
+
+		filter[name] = value
+	}
+
+	return filter, nil
+}
+
+func (app *application) listMetadataFieldsHandler(w http.ResponseWriter, r *http.Request) {
+	fields, err := app.models.Metadata.GetAll(r.Context())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"metadata_fields": fields}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createMetadataFieldHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name     string   `json:"name"`
+		Type     string   `json:"field_type"`
+		Options  []string `json:"options"`
+		Required bool     `json:"required"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	field := &data.MetadataField{
+		Name:     input.Name,
+		Type:     input.Type,
+		Options:  input.Options,
+		Required: input.Required,
+	}
+
+	v := validator.New()
+
+	if data.ValidateMetadataField(v, field); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Metadata.Insert(r.Context(), field)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateMetadataField):
+			v.AddError("name", "a metadata field with this name already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"metadata_field": field}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteMetadataFieldHandler(w http.ResponseWriter, r *http.Request) {
+	name := httprouter.ParamsFromContext(r.Context()).ByName("name")
+
+	err := app.models.Metadata.Delete(r.Context(), name)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "metadata field successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
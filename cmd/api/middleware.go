@@ -4,6 +4,7 @@ import (
 	"errors"
 	"expvar"
 	"fmt"
+	"math/rand/v2"
 	"net/http"
 	"strconv"
 	"strings"
@@ -13,6 +14,8 @@ import (
 	"flashcards-api.johndennehy101.tech/internal/data"
 	"flashcards-api.johndennehy101.tech/internal/validator"
 	"github.com/tomasen/realip"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/time/rate"
 )
 
@@ -35,9 +38,12 @@ func (app *application) rateLimit(next http.Handler) http.Handler {
 		return next
 	}
 
+	// Batch requests draw from their own, smaller bucket so an import or
+	// export run can't use up the tokens needed to keep studying.
 	type client struct {
-		limiter  *rate.Limiter
-		lastSeen time.Time
+		limiter      *rate.Limiter
+		batchLimiter *rate.Limiter
+		lastSeen     time.Time
 	}
 
 	var (
@@ -68,13 +74,19 @@ func (app *application) rateLimit(next http.Handler) http.Handler {
 
 		if _, found := clients[ip]; !found {
 			clients[ip] = &client{
-				limiter: rate.NewLimiter(rate.Limit(app.config.limiter.rps), app.config.limiter.burst),
+				limiter:      rate.NewLimiter(rate.Limit(app.config.limiter.rps), app.config.limiter.burst),
+				batchLimiter: rate.NewLimiter(rate.Limit(app.config.limiter.batchRPS), app.config.limiter.batchBurst),
 			}
 		}
 
 		clients[ip].lastSeen = time.Now()
 
-		if !clients[ip].limiter.Allow() {
+		limiter := clients[ip].limiter
+		if requestPriority(r) == priorityBatch {
+			limiter = clients[ip].batchLimiter
+		}
+
+		if !limiter.Allow() {
 			mu.Unlock()
 			app.rateLimitExceededResponse(w, r)
 			return
@@ -89,9 +101,15 @@ func (app *application) rateLimit(next http.Handler) http.Handler {
 func (app *application) authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Vary", "Authorization")
+		w.Header().Add("Vary", "X-API-Key")
 
 		authorizationHeader := r.Header.Get("Authorization")
 
+		if apiKey := r.Header.Get("X-API-Key"); apiKey != "" && authorizationHeader == "" {
+			app.authenticateAPIKey(w, r, apiKey, next)
+			return
+		}
+
 		if authorizationHeader == "" {
 			r = app.contextSetUser(r, data.AnonymousUser)
 			next.ServeHTTP(w, r)
@@ -106,6 +124,24 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 
 		token := headerParts[1]
 
+		// JWTs have three dot-separated segments; opaque tokens have none.
+		if app.config.auth.tokens == "jwt" && strings.Count(token, ".") == 2 {
+			user, scopes, permissions, err := app.userFromJWT(token)
+			if err != nil {
+				app.invalidAuthenticationTokenResponse(w, r)
+				return
+			}
+
+			trace.SpanFromContext(r.Context()).SetAttributes(attribute.Int64("enduser.id", user.ID))
+
+			r = app.contextSetStatelessUser(r, user)
+			r = app.contextSetScopes(r, scopes)
+			r = app.contextSetPermissions(r, permissions)
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		v := validator.New()
 
 		if data.ValidateTokenPlaintext(v, token); !v.Valid() {
@@ -113,7 +149,7 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		user, err := app.models.Users.GetForToken(data.ScopeAuthentication, token)
+		user, scopes, err := app.models.Users.GetForToken(r.Context(), data.ScopeAuthentication, token)
 		if err != nil {
 			switch {
 			case errors.Is(err, data.ErrRecordNotFound):
@@ -124,12 +160,44 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			return
 		}
 
+		trace.SpanFromContext(r.Context()).SetAttributes(attribute.Int64("enduser.id", user.ID))
+
 		r = app.contextSetUser(r, user)
+		r = app.contextSetScopes(r, scopes)
 
 		next.ServeHTTP(w, r)
 	})
 }
 
+// authenticateAPIKey is authenticate for requests made with an API key
+// instead of an authentication token.
+func (app *application) authenticateAPIKey(w http.ResponseWriter, r *http.Request, apiKey string, next http.Handler) {
+	v := validator.New()
+
+	if data.ValidateAPIKeyPlaintext(v, apiKey); !v.Valid() {
+		app.invalidAPIKeyResponse(w, r)
+		return
+	}
+
+	user, scopes, err := app.models.APIKeys.GetUserForKey(r.Context(), apiKey)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.invalidAPIKeyResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.Int64("enduser.id", user.ID))
+
+	r = app.contextSetUser(r, user)
+	r = app.contextSetScopes(r, scopes)
+
+	next.ServeHTTP(w, r)
+}
+
 func (app *application) requireAuthenticatedUser(next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := app.contextGetUser(r)
@@ -160,12 +228,16 @@ func (app *application) requireActivatedUser(next http.HandlerFunc) http.Handler
 
 func (app *application) requirePermission(code string, next http.HandlerFunc) http.HandlerFunc {
 	fn := func(w http.ResponseWriter, r *http.Request) {
-		user := app.contextGetUser(r)
+		permissions := app.contextGetPermissions(r)
 
-		permissions, err := app.models.Permissions.GetAllForUser(user.ID)
-		if err != nil {
-			app.serverErrorResponse(w, r, err)
-			return
+		if permissions == nil {
+			var err error
+
+			permissions, err = app.models.Permissions.GetAllForUser(r.Context(), app.contextGetUser(r).ID)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
 		}
 
 		if !permissions.Include(code) {
@@ -176,7 +248,59 @@ func (app *application) requirePermission(code string, next http.HandlerFunc) ht
 		next.ServeHTTP(w, r)
 	}
 
-	return app.requireActivatedUser(fn)
+	return app.requireActivatedUser(app.requireScope(code, fn))
+}
+
+// requireScope rejects tokens limited to scopes that don't include scope.
+// requirePermission already asks for the scope matching its permission;
+// routes that make changes with only flashcards:read ask for one of the
+// write scopes too.
+func (app *application) requireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scopes := app.contextGetScopes(r)
+
+		if scopes != nil && !scopes.Include(scope) {
+			app.insufficientScopeResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// requireFullAccess rejects tokens limited to scopes, for changes to the
+// account itself.
+func (app *application) requireFullAccess(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if app.contextGetScopes(r) != nil {
+			app.insufficientScopeResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// limitConcurrency caps how many requests to an expensive route are handled
+// at once. It sheds load straight away rather than queueing, so a burst of
+// exports or searches can't tie up database connections needed for study
+// traffic. A limit of 0 disables the cap.
+func (app *application) limitConcurrency(limit int, next http.HandlerFunc) http.HandlerFunc {
+	if limit <= 0 {
+		return next
+	}
+
+	sem := make(chan struct{}, limit)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+			next.ServeHTTP(w, r)
+		default:
+			app.serverBusyResponse(w, r)
+		}
+	}
 }
 
 func (app *application) enableCORS(next http.Handler) http.Handler {
@@ -193,7 +317,7 @@ func (app *application) enableCORS(next http.Handler) http.Handler {
 
 					if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
 						w.Header().Set("Access-Control-Allow-Methods", "OPTIONS, PUT, PATCH, DELETE")
-						w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+						w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, X-API-Key")
 
 						w.WriteHeader(http.StatusOK)
 						return
@@ -268,3 +392,50 @@ func (app *application) metrics(next http.Handler) http.Handler {
 		totalProcessingTimeMicroseconds.Add(duration)
 	})
 }
+
+// chaos injects latency and 5xx responses at the configured rates so client
+// retry and backoff behaviour can be exercised against staging. Injected
+// errors carry an X-Chaos-Injected header to tell them apart from real ones.
+func (app *application) chaos(next http.Handler) http.Handler {
+	if !app.config.chaos.enabled {
+		return next
+	}
+
+	statuses := []int{
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/debug/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if app.config.chaos.latency > 0 && rand.Float64() < app.config.chaos.latencyRate {
+			delay := rand.N(app.config.chaos.latency)
+
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		if rand.Float64() < app.config.chaos.errorRate {
+			status := statuses[rand.IntN(len(statuses))]
+
+			w.Header().Set("X-Chaos-Injected", "true")
+			if status == http.StatusServiceUnavailable {
+				w.Header().Set("Retry-After", "1")
+			}
+
+			app.errorResponse(w, r, status, http.StatusText(status))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"net/http"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/oauth"
+	"flashcards-api.johndennehy101.tech/internal/validator"
+)
+
+func (app *application) invalidOAuthCodeResponse(w http.ResponseWriter, r *http.Request) {
+	message := "invalid or expired authorization code"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+// insertExternalUser creates an activated account for someone signing in
+// through an external identity provider, which has verified their email.
+// The account gets a random password that can be replaced with a reset.
+func (app *application) insertExternalUser(ctx context.Context, name, email string) (*data.User, error) {
+	if name == "" {
+		name = email
+	}
+
+	user := &data.User{
+		Name:      name,
+		Email:     email,
+		Activated: true,
+	}
+
+	err := user.Password.Set(rand.Text() + rand.Text())
+	if err != nil {
+		return nil, err
+	}
+
+	err = app.models.Users.Insert(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	err = app.models.Permissions.AddRoleForUser(ctx, user.ID, "reader")
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// claimUnactivatedUser activates an account for someone who has just proved
+// they hold its address. Whoever registered it may not have, so the password
+// they chose is replaced with a random one and every token issued to them is
+// revoked before the account becomes usable.
+func (app *application) claimUnactivatedUser(ctx context.Context, user *data.User) error {
+	err := user.Password.Set(rand.Text() + rand.Text())
+	if err != nil {
+		return err
+	}
+
+	user.Activated = true
+
+	err = app.models.Users.Update(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	return app.models.Tokens.DeleteEveryForUser(ctx, user.ID)
+}
+
+// createOAuthTokenHandler exchanges an authorization code from one of the
+// configured identity providers for an authentication token.
+func (app *application) createOAuthTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if len(app.oauth) == 0 {
+		app.featureDisabledResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Provider    string `json:"provider"`
+		Code        string `json:"code"`
+		RedirectURI string `json:"redirect_uri"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	provider, ok := app.oauth[input.Provider]
+
+	v := validator.New()
+
+	v.Check(ok, "provider", "must be a configured identity provider")
+	v.Check(input.Code != "", "code", "must be provided")
+	v.Check(input.RedirectURI != "", "redirect_uri", "must be provided")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	identity, err := provider.Exchange(r.Context(), input.Code, input.RedirectURI)
+	if err != nil {
+		switch {
+		case errors.Is(err, oauth.ErrInvalidCode):
+			app.invalidOAuthCodeResponse(w, r)
+		case errors.Is(err, oauth.ErrUnverifiedEmail):
+			v.AddError("provider", "account has no verified email address")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.oauthUser(r.Context(), input.Provider, identity)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	token, err := app.issueAuthenticationToken(r, user, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"authentication_token": token}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// oauthUser returns the local user linked to the identity, linking by
+// verified email or creating an account the first time it signs in. Linking
+// claims an account that hadn't been activated, since the provider has
+// verified the address the activation email would have gone to.
+func (app *application) oauthUser(ctx context.Context, provider string, identity *oauth.Identity) (*data.User, error) {
+	userID, err := app.models.Identities.GetUserID(ctx, provider, identity.Subject)
+	if err == nil {
+		return app.models.Users.Get(ctx, userID)
+	}
+	if !errors.Is(err, data.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	user, err := app.models.Users.GetByEmail(ctx, identity.Email)
+	switch {
+	case err == nil:
+		if !user.Activated {
+			err = app.claimUnactivatedUser(ctx, user)
+			if err != nil {
+				return nil, err
+			}
+		}
+	case errors.Is(err, data.ErrRecordNotFound):
+		user, err = app.insertExternalUser(ctx, identity.Name, identity.Email)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	err = app.models.Identities.LinkUser(ctx, provider, identity.Subject, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
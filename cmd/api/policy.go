@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/validator"
+)
+
+func (app *application) showCardPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	policy, err := app.models.Policy.Get(r.Context())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"policy": policy}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateCardPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	policy, err := app.models.Policy.Get(r.Context())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var input struct {
+		DefaultCategories []string `json:"default_categories"`
+		RequiredFields    []string `json:"required_fields"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.DefaultCategories != nil {
+		policy.DefaultCategories = input.DefaultCategories
+	}
+
+	if input.RequiredFields != nil {
+		policy.RequiredFields = input.RequiredFields
+	}
+
+	v := validator.New()
+
+	if data.ValidateCardPolicy(v, policy); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Policy.Update(r.Context(), policy)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"policy": policy}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/validator"
+)
+
+type prerequisiteInput struct {
+	PrerequisiteID int64 `json:"prerequisite_id"`
+}
+
+func (app *application) createPrerequisiteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input prerequisiteInput
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	_, err = app.models.Flashcards.Get(r.Context(), id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.PrerequisiteID > 0, "prerequisite_id", "must be provided")
+	v.Check(input.PrerequisiteID != id, "prerequisite_id", "must not be the flashcard itself")
+
+	if v.Valid() {
+		_, err = app.models.Flashcards.Get(r.Context(), input.PrerequisiteID, user.ID)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("prerequisite_id", "flashcard does not exist")
+		case err != nil:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if v.Valid() {
+		err = app.models.Flashcards.AddPrerequisite(r.Context(), id, input.PrerequisiteID)
+		switch {
+		case errors.Is(err, data.ErrPrerequisiteCycle):
+			v.AddError("prerequisite_id", "would create a prerequisite cycle")
+		case err != nil:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"message": "prerequisite added"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deletePrerequisiteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input prerequisiteInput
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Flashcards.DeletePrerequisite(r.Context(), id, input.PrerequisiteID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "prerequisite successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) studyPathHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	category := app.readString(r.URL.Query(), "category", "")
+	reveal := app.readReveal(r.URL.Query(), v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	flashcards, err := app.models.Flashcards.GetStudyPath(r.Context(), user.ID, category)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	concealFlashcards(reveal, flashcards...)
+
+	app.linkFlashcards(flashcards...)
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"flashcards": flashcards, "formatting": app.formattingHints(r, user)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
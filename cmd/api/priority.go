@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+type priority int
+
+const (
+	priorityInteractive priority = iota
+	priorityBatch
+)
+
+// requestPriority classifies a request as interactive study traffic or as
+// batch work. Bulk reads, exports and generation are always batch; clients
+// running scripts can also opt any request into the batch class with an
+// "X-Priority: batch" header, but can never promote batch routes.
+func requestPriority(r *http.Request) priority {
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/v1/export/"),
+		strings.HasPrefix(r.URL.Path, "/v1/batch/"),
+		strings.HasPrefix(r.URL.Path, "/v1/import/"),
+		r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/export"),
+		r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/tts"):
+		return priorityBatch
+	case strings.EqualFold(r.Header.Get("X-Priority"), "batch"):
+		return priorityBatch
+	default:
+		return priorityInteractive
+	}
+}
+
+// acquireBatchSlot blocks until a batch slot is free or done is closed. The
+// slots bound how many database connections batch work can hold at once, so
+// the remainder of the pool stays available to interactive requests.
+func (app *application) acquireBatchSlot(done <-chan struct{}) bool {
+	if app.batchSlots == nil {
+		return true
+	}
+
+	select {
+	case app.batchSlots <- struct{}{}:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+func (app *application) releaseBatchSlot() {
+	if app.batchSlots != nil {
+		<-app.batchSlots
+	}
+}
+
+// throttleBatch queues batch requests behind the batch slots. Interactive
+// requests pass straight through.
+func (app *application) throttleBatch(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requestPriority(r) != priorityBatch {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !app.acquireBatchSlot(r.Context().Done()) {
+			return
+		}
+		defer app.releaseBatchSlot()
+
+		next.ServeHTTP(w, r)
+	})
+}
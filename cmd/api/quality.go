@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/validator"
+)
+
+func (app *application) refreshQualityScores(ctx context.Context) {
+	updated, err := app.models.Flashcards.RefreshQualityScores(ctx)
+	if err != nil {
+		app.logger.Error(err.Error())
+		return
+	}
+
+	app.logger.Info("quality scores refreshed", "updated", updated)
+
+	updated, err = app.models.Flashcards.RefreshMeasuredDifficulty(ctx)
+	if err != nil {
+		app.logger.Error(err.Error())
+		return
+	}
+
+	app.logger.Info("measured difficulty refreshed", "updated", updated)
+}
+
+func (app *application) reportFlashcardHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	_, err = app.models.Flashcards.Get(r.Context(), id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	report := &data.Report{
+		FlashcardID: id,
+		UserID:      user.ID,
+		Reason:      input.Reason,
+	}
+
+	v := validator.New()
+
+	if data.ValidateReport(v, report); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Flashcards.InsertReport(r.Context(), report)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"report": report}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+
+	"flashcards-api.johndennehy101.tech/internal/validator"
+)
+
+// showRecentHandler backs the "continue where you left off" screen: the cards
+// the user saw last and, for each recently studied section, where to resume.
+func (app *application) showRecentHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	v := validator.New()
+
+	limit := app.readInt(r.URL.Query(), "limit", 10, v)
+	v.Check(limit > 0 && limit <= 100, "limit", "must be between 1 and 100")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	flashcards, err := app.models.Flashcards.GetRecentFlashcards(r.Context(), user.ID, limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	sections, err := app.models.Flashcards.GetRecentSections(r.Context(), user.ID, limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"flashcards": flashcards, "sections": sections, "formatting": app.formattingHints(r, user)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/url"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/markdown"
+	"flashcards-api.johndennehy101.tech/internal/validator"
+)
+
+// readRender reports whether the client asked for ?render=html. The raw
+// Markdown is always returned; the HTML is added alongside it.
+func (app *application) readRender(qs url.Values, v *validator.Validator) bool {
+	render := app.readString(qs, "render", "")
+	v.Check(render == "" || render == "html", "render", "must be html")
+	return render == "html"
+}
+
+func renderFlashcards(flashcards ...*data.Flashcard) error {
+	for _, flashcard := range flashcards {
+		text, err := markdown.Render(flashcard.Text)
+		if err != nil {
+			return err
+		}
+
+		justification, err := markdown.Render(data.Justification(flashcard.Content))
+		if err != nil {
+			return err
+		}
+
+		flashcard.HTML = &data.RenderedHTML{Text: text, Justification: justification}
+	}
+
+	return nil
+}
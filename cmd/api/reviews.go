@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/scheduler"
+	"flashcards-api.johndennehy101.tech/internal/validator"
+	"flashcards-api.johndennehy101.tech/internal/xapi"
+)
+
+// reviewInput is a graded recall of a card: either a grade from 0 to 5 or one
+// of the again/hard/good/easy ratings, which is turned into its grade. The
+// time taken to answer can be sent as latency_ms or answer_ms.
+type reviewInput struct {
+	FlashcardID int64  `json:"flashcard_id"`
+	Grade       *int   `json:"grade"`
+	Rating      string `json:"rating"`
+	LatencyMS   *int   `json:"latency_ms"`
+	AnswerMS    *int   `json:"answer_ms"`
+}
+
+// readReview reads and validates a graded recall of a card the user can see,
+// sending the error response itself when it returns false.
+func (app *application) readReview(w http.ResponseWriter, r *http.Request) (*data.Flashcard, reviewInput, bool) {
+	var input reviewInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return nil, input, false
+	}
+
+	v := validator.New()
+
+	v.Check(input.FlashcardID > 0, "flashcard_id", "must be provided")
+	switch {
+	case input.Rating != "":
+		v.Check(input.Grade == nil, "grade", "must not be combined with rating")
+
+		grade, ok := scheduler.RatingGrade(input.Rating)
+		v.Check(ok, "rating", "must be one of "+strings.Join(scheduler.Ratings, ", "))
+		input.Grade = &grade
+	case input.Grade != nil:
+		data.ValidateGrade(v, *input.Grade)
+	default:
+		v.AddError("grade", "must be provided")
+	}
+	if input.AnswerMS != nil {
+		v.Check(input.LatencyMS == nil || *input.LatencyMS == *input.AnswerMS, "answer_ms", "must match latency_ms")
+		input.LatencyMS = input.AnswerMS
+	}
+	v.Check(input.LatencyMS == nil || *input.LatencyMS >= 0, "latency_ms", "must not be negative")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return nil, input, false
+	}
+
+	flashcard, err := app.models.Flashcards.Get(r.Context(), input.FlashcardID, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("flashcard_id", "must be an existing flashcard")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, input, false
+	}
+
+	return flashcard, input, true
+}
+
+// recordRecall counts a recall of the card outside its schedule: passing
+// grades count towards the card's mastery progress and every recall feeds its
+// quality score, as with answers.
+func (app *application) recordRecall(ctx context.Context, user *data.User, flashcard *data.Flashcard, grade int, latencyMS *int) error {
+	correct := grade >= scheduler.PassingGrade
+
+	if correct {
+		err := app.models.Flashcards.IncrementCorrectCount(ctx, flashcard.ID, user.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := app.models.Flashcards.RecordReview(ctx, flashcard.ID, user.ID, correct, latencyMS)
+	if err != nil {
+		return err
+	}
+
+	app.emitStatement(user, xapi.VerbAnswered, flashcard, &xapi.Result{Success: &correct})
+
+	return nil
+}
+
+// createReviewHandler records a graded recall of a card and reschedules it
+// with the deck's or user's algorithm. The study queues list the interval each
+// rating would give beforehand.
+func (app *application) createReviewHandler(w http.ResponseWriter, r *http.Request) {
+	flashcard, input, ok := app.readReview(w, r)
+	if !ok {
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	review := &data.Review{
+		FlashcardID: flashcard.ID,
+		UserID:      user.ID,
+		Grade:       *input.Grade,
+		LatencyMS:   input.LatencyMS,
+	}
+
+	err := app.models.Reviews.Insert(r.Context(), review)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.recordRecall(r.Context(), user, flashcard, review.Grade, review.LatencyMS)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"review": review}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listReviewsHandler returns the user's raw review log, newest first, for
+// export to analysis tools. It takes a flashcard_id and a from/to range of
+// review times.
+func (app *application) listReviewsHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	v := validator.New()
+
+	flashcardID := int64(app.readInt(qs, "flashcard_id", 0, v))
+	from := app.readTime(qs, "from", v)
+	to := app.readTime(qs, "to", v)
+
+	filters := data.Filters{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", 100, v),
+		Sort:         app.readString(qs, "sort", "-reviewed_at"),
+		SortSafelist: []string{"reviewed_at", "-reviewed_at"},
+		SortColumns:  map[string]string{"reviewed_at": "r.reviewed_at"},
+	}
+
+	v.Check(flashcardID >= 0, "flashcard_id", "must be a positive integer")
+	v.Check(from == nil || to == nil || from.Before(*to), "to", "must be after from")
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	reviews, metadata, err := app.models.Reviews.GetAll(r.Context(), app.contextGetUser(r).ID, flashcardID, from, to, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"reviews": reviews, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) suspendFlashcardHandler(w http.ResponseWriter, r *http.Request) {
+	app.holdFlashcard(w, r, func(ctx context.Context, user *data.User, id int64) (*data.CardHold, error) {
+		return app.models.Reviews.SetSuspended(ctx, user.ID, id, true)
+	})
+}
+
+func (app *application) unsuspendFlashcardHandler(w http.ResponseWriter, r *http.Request) {
+	app.holdFlashcard(w, r, func(ctx context.Context, user *data.User, id int64) (*data.CardHold, error) {
+		return app.models.Reviews.SetSuspended(ctx, user.ID, id, false)
+	})
+}
+
+// buryFlashcardHandler keeps the card out of the user's study queue until
+// midnight in their time zone.
+func (app *application) buryFlashcardHandler(w http.ResponseWriter, r *http.Request) {
+	app.holdFlashcard(w, r, func(ctx context.Context, user *data.User, id int64) (*data.CardHold, error) {
+		tomorrow := startOfDay(user, time.Now()).AddDate(0, 0, 1)
+		return app.models.Reviews.SetBuriedUntil(ctx, user.ID, id, &tomorrow)
+	})
+}
+
+func (app *application) unburyFlashcardHandler(w http.ResponseWriter, r *http.Request) {
+	app.holdFlashcard(w, r, func(ctx context.Context, user *data.User, id int64) (*data.CardHold, error) {
+		return app.models.Reviews.SetBuriedUntil(ctx, user.ID, id, nil)
+	})
+}
+
+// holdFlashcard changes the user's hold on a card they can see with set.
+// Holds are the user's own: they don't affect anyone else studying the card.
+func (app *application) holdFlashcard(w http.ResponseWriter, r *http.Request, set func(context.Context, *data.User, int64) (*data.CardHold, error)) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	flashcard, err := app.models.Flashcards.Get(r.Context(), id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	hold, err := set(r.Context(), user, flashcard.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"hold": hold}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
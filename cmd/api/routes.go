@@ -15,24 +15,168 @@ func (app *application) routes() http.Handler {
 	router.MethodNotAllowed = http.HandlerFunc(app.methodNotAllowedResponse)
 
 	router.HandlerFunc(http.MethodGet, "/v1/healthcheck", app.healthcheckHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/capabilities", app.capabilitiesHandler)
 
 	router.HandlerFunc(http.MethodGet, "/v1/flashcards", app.requirePermission("flashcards:read", app.listFlashcardsHandler))
 	router.HandlerFunc(http.MethodPost, "/v1/flashcards", app.requirePermission("flashcards:write", app.createFlashcardHandler))
 	router.HandlerFunc(http.MethodGet, "/v1/flashcards/:id", app.requirePermission("flashcards:read", app.showFlashcardHandler))
-	router.HandlerFunc(http.MethodPut, "/v1/flashcards/:id", app.requirePermission("flashcards:write", app.updateFlashcardHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/flashcards/:id", app.requirePermission("flashcards:read", app.requireScope("flashcards:write", app.updateFlashcardHandler)))
 	router.HandlerFunc(http.MethodPost, "/v1/flashcards/:id/review", app.requirePermission("flashcards:write", app.reviewFlashcardHandler))
 	router.HandlerFunc(http.MethodPost, "/v1/flashcards/:id/reset", app.requirePermission("flashcards:write", app.resetFlashcardHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/flashcards/:id/suspension", app.requirePermission("flashcards:read", app.requireScope("study:write", app.suspendFlashcardHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/flashcards/:id/suspension", app.requirePermission("flashcards:read", app.requireScope("study:write", app.unsuspendFlashcardHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/flashcards/:id/bury", app.requirePermission("flashcards:read", app.requireScope("study:write", app.buryFlashcardHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/flashcards/:id/bury", app.requirePermission("flashcards:read", app.requireScope("study:write", app.unburyFlashcardHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/flashcards/:id/answer", app.requirePermission("flashcards:read", app.requireScope("study:write", app.answerFlashcardHandler)))
+
+	router.HandlerFunc(http.MethodGet, "/v1/flashcards/:id/attachments", app.requirePermission("flashcards:read", app.listAttachmentsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/flashcards/:id/attachments", app.requirePermission("flashcards:write", app.uploadAttachmentHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/flashcards/:id/tts", app.requirePermission("flashcards:write", app.limitConcurrency(app.config.concurrency.generation, app.generateSpeechHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/flashcards/:id/attachments/:attachment_id", app.requirePermission("flashcards:write", app.deleteAttachmentHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/flashcards/:id/translations", app.requirePermission("flashcards:read", app.listTranslationsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/flashcards/:id/translations", app.requirePermission("flashcards:write", app.createTranslationHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/flashcards/:id/relations", app.requirePermission("flashcards:write", app.createRelationHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/flashcards/:id/relations", app.requirePermission("flashcards:write", app.deleteRelationHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/flashcards/:id/convert", app.requirePermission("flashcards:write", app.convertFlashcardHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/flashcards/:id/reports", app.requirePermission("flashcards:read", app.requireScope("flashcards:write", app.reportFlashcardHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/flashcards/:id/prerequisites", app.requirePermission("flashcards:write", app.createPrerequisiteHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/flashcards/:id/prerequisites", app.requirePermission("flashcards:write", app.deletePrerequisiteHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/attachments/download", app.downloadAttachmentHandler)
 
 	router.HandlerFunc(http.MethodDelete, "/v1/flashcards/:id", app.requirePermission("flashcards:write", app.deleteFlashcardHandler))
 
+	router.HandlerFunc(http.MethodGet, "/v1/batch/flashcards", app.requirePermission("flashcards:read", app.batchFlashcardsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/batch/flashcards", app.requirePermission("flashcards:read", app.batchFlashcardsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/batch/flashcards/tags", app.requirePermission("flashcards:write", app.retagFlashcardsHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/search/flashcards", app.requirePermission("flashcards:read", app.limitConcurrency(app.config.concurrency.search, app.searchFlashcardsHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/search/flashcards/semantic", app.requirePermission("flashcards:read", app.limitConcurrency(app.config.concurrency.search, app.semanticSearchFlashcardsHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/search/suggest", app.requirePermission("flashcards:read", app.suggestHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/export/flashcards", app.requirePermission("flashcards:read", app.limitConcurrency(app.config.concurrency.export, app.exportFlashcardsHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/import/decks", app.requirePermission("flashcards:write", app.importDeckHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/reviews", app.requirePermission("flashcards:read", app.listReviewsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/reviews", app.requirePermission("flashcards:read", app.requireScope("study:write", app.createReviewHandler)))
+
+	router.HandlerFunc(http.MethodGet, "/v1/study/due", app.requirePermission("flashcards:read", app.studyDueHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/study/cram", app.requirePermission("flashcards:read", app.studyCramHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/study/cram/reviews", app.requirePermission("flashcards:read", app.requireScope("study:write", app.createCramReviewHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/study/forecast", app.requirePermission("flashcards:read", app.studyForecastHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/study/custom", app.requirePermission("flashcards:read", app.studyCustomHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/study/sessions", app.requirePermission("flashcards:read", app.requireScope("study:write", app.createStudySessionHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/study/sessions/:id", app.requirePermission("flashcards:read", app.showStudySessionHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/study/sessions/:id/answers", app.requirePermission("flashcards:read", app.requireScope("study:write", app.createSessionAnswerHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/study/sessions/:id/complete", app.requirePermission("flashcards:read", app.requireScope("study:write", app.completeStudySessionHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/study/path", app.requirePermission("flashcards:read", app.studyPathHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/exams", app.requirePermission("flashcards:read", app.requireScope("study:write", app.createExamHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/exams/:id", app.requirePermission("flashcards:read", app.showExamHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/exams/:id/questions/:position", app.requirePermission("flashcards:read", app.requireScope("study:write", app.serveExamQuestionHandler)))
+	router.HandlerFunc(http.MethodPut, "/v1/exams/:id/questions/:position/answer", app.requirePermission("flashcards:read", app.requireScope("study:write", app.answerExamQuestionHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/exams/:id/submission", app.requirePermission("flashcards:read", app.requireScope("study:write", app.submitExamHandler)))
+
 	router.HandlerFunc(http.MethodGet, "/v1/stats/flashcards", app.requirePermission("flashcards:read", app.showFlashcardStatsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/stats/categories", app.requirePermission("flashcards:read", app.showCategoryStatsHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/hooks", app.requireActivatedUser(app.requireFullAccess(app.listHooksHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/hooks", app.requireActivatedUser(app.requireFullAccess(app.subscribeHookHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/hooks/:id", app.requireActivatedUser(app.requireFullAccess(app.unsubscribeHookHandler)))
+
+	router.HandlerFunc(http.MethodGet, "/v1/categories", app.requirePermission("flashcards:read", app.listCategoriesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/categories", app.requirePermission("flashcards:write", app.createCategoryHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/categories/merge", app.requirePermission("flashcards:write", app.mergeCategoriesHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/categories/:id", app.requirePermission("flashcards:read", app.showCategoryHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/categories/:id", app.requirePermission("flashcards:write", app.updateCategoryHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/categories/:id", app.requirePermission("flashcards:write", app.deleteCategoryHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/decks", app.requirePermission("flashcards:read", app.listDecksHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/decks", app.requirePermission("flashcards:read", app.requireScope("flashcards:write", app.createDeckHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/decks/:id", app.requirePermission("flashcards:read", app.showDeckHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/decks/:id", app.requirePermission("flashcards:read", app.requireScope("flashcards:write", app.updateDeckHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/decks/:id", app.requirePermission("flashcards:read", app.requireScope("flashcards:write", app.deleteDeckHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/decks/:id/stats", app.requirePermission("flashcards:read", app.showDeckStatsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/decks/:id/flashcards", app.requirePermission("flashcards:read", app.listDeckFlashcardsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/decks/:id/flashcards", app.requirePermission("flashcards:read", app.requireScope("flashcards:write", app.addDeckFlashcardsHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/decks/:id/flashcards", app.requirePermission("flashcards:read", app.requireScope("flashcards:write", app.removeDeckFlashcardsHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/decks/:id/reorder", app.requirePermission("flashcards:read", app.requireScope("flashcards:write", app.reorderDeckHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/decks/:id/clone", app.requirePermission("flashcards:read", app.requireScope("flashcards:write", app.cloneDeckHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/decks/:id/members", app.requirePermission("flashcards:read", app.listDeckMembersHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/decks/:id/members/:user_id", app.requirePermission("flashcards:read", app.requireScope("flashcards:write", app.updateDeckMemberHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/decks/:id/members/:user_id", app.requirePermission("flashcards:read", app.requireScope("flashcards:write", app.removeDeckMemberHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/decks/:id/invitations", app.requirePermission("flashcards:read", app.listDeckInvitationsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/decks/:id/invitations", app.requirePermission("flashcards:read", app.requireScope("flashcards:write", app.inviteDeckMemberHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/decks/:id/invitations/:invitation_id", app.requirePermission("flashcards:read", app.requireScope("flashcards:write", app.deleteDeckInvitationHandler)))
+	router.HandlerFunc(http.MethodPut, "/v1/deck-invitations/accepted", app.requireActivatedUser(app.requireFullAccess(app.acceptDeckInvitationHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/decks/:id/archive", app.requirePermission("flashcards:read", app.requireScope("flashcards:write", app.archiveDeckHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/decks/:id/archive", app.requirePermission("flashcards:read", app.requireScope("flashcards:write", app.unarchiveDeckHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/decks/:id/share", app.requirePermission("flashcards:read", app.requireScope("flashcards:write", app.shareDeckHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/decks/:id/share", app.requirePermission("flashcards:read", app.requireScope("flashcards:write", app.unshareDeckHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/decks/:id/export", app.requirePermission("flashcards:read", app.limitConcurrency(app.config.concurrency.export, app.exportDeckHandler)))
+
+	router.HandlerFunc(http.MethodGet, "/v1/shared/:token", app.showSharedDeckHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/shared/:token/flashcards", app.listSharedDeckFlashcardsHandler)
+
+	router.HandlerFunc(http.MethodGet, "/v1/templates", app.requirePermission("flashcards:read", app.listTemplatesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/templates", app.requirePermission("flashcards:write", app.createTemplateHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/templates/:id", app.requirePermission("flashcards:read", app.showTemplateHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/templates/:id", app.requirePermission("flashcards:write", app.updateTemplateHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/templates/:id", app.requirePermission("flashcards:write", app.deleteTemplateHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/templates/:id/flashcards", app.requirePermission("flashcards:write", app.createFlashcardFromTemplateHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/trash", app.requirePermission("flashcards:write", app.listTrashHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/trash/restore", app.requirePermission("flashcards:write", app.restoreTrashHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/saved-searches", app.requirePermission("flashcards:read", app.listSavedSearchesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/saved-searches", app.requirePermission("flashcards:read", app.requireScope("flashcards:write", app.createSavedSearchHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/saved-searches/:id", app.requirePermission("flashcards:read", app.showSavedSearchHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/saved-searches/:id", app.requirePermission("flashcards:read", app.requireScope("flashcards:write", app.updateSavedSearchHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/saved-searches/:id", app.requirePermission("flashcards:read", app.requireScope("flashcards:write", app.deleteSavedSearchHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/saved-searches/:id/flashcards", app.requirePermission("flashcards:read", app.listSavedSearchFlashcardsHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/saved-searches/:id/subscription", app.requirePermission("flashcards:read", app.requireScope("flashcards:write", app.subscribeSavedSearchHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/saved-searches/:id/subscription", app.requirePermission("flashcards:read", app.requireScope("flashcards:write", app.unsubscribeSavedSearchHandler)))
 
 	router.HandlerFunc(http.MethodPost, "/v1/users", app.registerUserHandler)
 	router.HandlerFunc(http.MethodPut, "/v1/users/activated", app.activateUserHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/users/me", app.requireActivatedUser(app.showCurrentUserHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me", app.requireAuthenticatedUser(app.requireFullAccess(app.deleteCurrentUserHandler)))
+	router.HandlerFunc(http.MethodPut, "/v1/users/me/settings", app.requireActivatedUser(app.requireFullAccess(app.updateUserSettingsHandler)))
+	router.HandlerFunc(http.MethodPut, "/v1/users/me/email", app.requireActivatedUser(app.requireFullAccess(app.updateUserEmailHandler)))
+	router.HandlerFunc(http.MethodPut, "/v1/users/email/confirmed", app.confirmUserEmailHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/streak", app.requirePermission("flashcards:read", app.showStreakHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/recent", app.requirePermission("flashcards:read", app.showRecentHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/export", app.requireActivatedUser(app.requireFullAccess(app.showDataExportHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/users/me/export", app.requireActivatedUser(app.requireFullAccess(app.createDataExportHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/api-keys", app.requireActivatedUser(app.requireFullAccess(app.listAPIKeysHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/users/me/api-keys", app.requireActivatedUser(app.requireFullAccess(app.createAPIKeyHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me/api-keys/:id", app.requireActivatedUser(app.requireFullAccess(app.deleteAPIKeyHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/sessions", app.requireAuthenticatedUser(app.requireFullAccess(app.listSessionsHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me/sessions", app.requireAuthenticatedUser(app.requireFullAccess(app.deleteOtherSessionsHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me/sessions/:id", app.requireAuthenticatedUser(app.requireFullAccess(app.deleteSessionHandler)))
 
 	router.HandlerFunc(http.MethodPost, "/v1/tokens/authentication", app.createAuthenticationTokenHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/tokens/oauth", app.createOAuthTokenHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/tokens/magic-link", app.createMagicLinkHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/tokens/magic-link/authentication", app.createMagicLinkTokenHandler)
+
+	router.HandlerFunc(http.MethodGet, "/v1/lti/login", app.ltiLoginHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/lti/login", app.ltiLoginHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/lti/launch", app.ltiLaunchHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/lti/jwks", app.ltiJWKSHandler)
+
+	router.HandlerFunc(http.MethodGet, "/v1/card-policy", app.requirePermission("flashcards:read", app.showCardPolicyHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/card-policy", app.requirePermission("admin:write", app.updateCardPolicyHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/metadata-fields", app.requirePermission("flashcards:read", app.listMetadataFieldsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/metadata-fields", app.requirePermission("admin:write", app.createMetadataFieldHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/metadata-fields/:name", app.requirePermission("admin:write", app.deleteMetadataFieldHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/admin/janitor", app.requirePermission("admin:write", app.runJanitorHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/admin/users/:id/roles", app.requirePermission("admin:write", app.listUserRolesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/admin/users/:id/roles", app.requirePermission("admin:write", app.grantUserRoleHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/admin/users/:id/roles/:role", app.requirePermission("admin:write", app.revokeUserRoleHandler))
 
 	router.Handler(http.MethodGet, "/debug/vars", expvar.Handler())
 
-	return app.metrics(app.recoverPanic(app.enableCORS(app.rateLimit(app.authenticate(router)))))
+	return app.trace(app.metrics(app.recoverPanic(app.enableCORS(app.chaos(app.rateLimit(app.throttleBatch(app.authenticate(router))))))))
 }
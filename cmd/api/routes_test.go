@@ -0,0 +1,393 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"flashcards-api.johndennehy101.tech/internal/data/mocks"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// routeTest is a request to the API whose response is compared with
+// testdata/<name>.golden. {download} in the path is replaced with a signed
+// download URL for a stored file. Bodies are JSON unless another
+// content type is given.
+type routeTest struct {
+	name        string
+	method      string
+	path        string
+	token       string
+	apiKey      string
+	body        string
+	contentType string
+}
+
+// header returns the request headers for the test's credentials and body.
+func (tt routeTest) header() http.Header {
+	header := make(http.Header)
+
+	if tt.token != "" {
+		header.Set("Authorization", "Bearer "+tt.token)
+	}
+	if tt.apiKey != "" {
+		header.Set("X-API-Key", tt.apiKey)
+	}
+
+	switch {
+	case tt.contentType != "":
+		header.Set("Content-Type", tt.contentType)
+	case tt.body != "":
+		header.Set("Content-Type", "application/json")
+	}
+
+	return header
+}
+
+// multipartPNG is an upload of a one-pixel PNG as the form's file field.
+const multipartPNG = "--boundary\r\n" +
+	"Content-Disposition: form-data; name=\"file\"; filename=\"dot.png\"\r\n" +
+	"Content-Type: image/png\r\n\r\n" +
+	"\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00\x1f\x15\xc4\x89\r\n" +
+	"--boundary--\r\n"
+
+var routeTests = []routeTest{
+	{name: "healthcheck", method: http.MethodGet, path: "/v1/healthcheck"},
+	{name: "capabilities", method: http.MethodGet, path: "/v1/capabilities"},
+	{name: "not_found", method: http.MethodGet, path: "/v1/nothing-here"},
+	{name: "method_not_allowed", method: http.MethodPatch, path: "/v1/healthcheck"},
+
+	{name: "list_flashcards", method: http.MethodGet, path: "/v1/flashcards", token: mocks.UserToken},
+	{name: "list_flashcards_unauthenticated", method: http.MethodGet, path: "/v1/flashcards"},
+	{name: "list_flashcards_invalid_token", method: http.MethodGet, path: "/v1/flashcards", token: "XXXXXXXXXXXXXXXXXXXXXXXXXX"},
+	{name: "list_flashcards_inactive", method: http.MethodGet, path: "/v1/flashcards", token: mocks.InactiveToken},
+	{name: "list_flashcards_api_key", method: http.MethodGet, path: "/v1/flashcards?flashcard_type=qa", apiKey: mocks.APIKey},
+	{name: "create_flashcard", method: http.MethodPost, path: "/v1/flashcards", token: mocks.UserToken, body: `{"question": "What is the capital of Spain?", "flashcard_type": "qa", "flashcard_content": {"answer": "Madrid"}, "categories": ["geography"], "text": "Madrid is in the centre of Spain."}`},
+	{name: "create_flashcard_invalid", method: http.MethodPost, path: "/v1/flashcards", token: mocks.UserToken, body: `{"flashcard_type": "essay"}`},
+	{name: "create_flashcard_read_only", method: http.MethodPost, path: "/v1/flashcards", token: mocks.ReadOnlyToken, body: `{}`},
+	{name: "show_flashcard", method: http.MethodGet, path: "/v1/flashcards/1", token: mocks.UserToken},
+	{name: "show_flashcard_not_found", method: http.MethodGet, path: "/v1/flashcards/99", token: mocks.UserToken},
+	{name: "update_flashcard", method: http.MethodPut, path: "/v1/flashcards/1", token: mocks.UserToken, body: `{"question": "What is the capital city of France?", "flashcard_type": "qa", "flashcard_content": {"answer": "Paris"}, "categories": ["geography"], "text": "Paris has been the capital of France since the 10th century.", "version": 1}`},
+	{name: "delete_flashcard", method: http.MethodDelete, path: "/v1/flashcards/1", token: mocks.UserToken},
+	{name: "review_flashcard", method: http.MethodPost, path: "/v1/flashcards/1/review", token: mocks.UserToken},
+	{name: "reset_flashcard", method: http.MethodPost, path: "/v1/flashcards/1/reset", token: mocks.UserToken},
+	{name: "suspend_flashcard", method: http.MethodPost, path: "/v1/flashcards/1/suspension", token: mocks.UserToken},
+	{name: "unsuspend_flashcard", method: http.MethodDelete, path: "/v1/flashcards/1/suspension", token: mocks.UserToken},
+	{name: "bury_flashcard", method: http.MethodPost, path: "/v1/flashcards/1/bury", token: mocks.UserToken},
+	{name: "unbury_flashcard", method: http.MethodDelete, path: "/v1/flashcards/1/bury", token: mocks.UserToken},
+	{name: "answer_flashcard", method: http.MethodPost, path: "/v1/flashcards/2/answer", token: mocks.UserToken, body: `{"answer": "b", "latency_ms": 1500}`},
+
+	{name: "list_attachments", method: http.MethodGet, path: "/v1/flashcards/1/attachments", token: mocks.UserToken},
+	{name: "upload_attachment", method: http.MethodPost, path: "/v1/flashcards/1/attachments", token: mocks.UserToken, body: multipartPNG, contentType: "multipart/form-data; boundary=boundary"},
+	{name: "generate_speech", method: http.MethodPost, path: "/v1/flashcards/1/tts", token: mocks.UserToken, body: `{"targets": ["question"]}`},
+	{name: "delete_attachment", method: http.MethodDelete, path: "/v1/flashcards/1/attachments/1", token: mocks.UserToken},
+	{name: "list_translations", method: http.MethodGet, path: "/v1/flashcards/1/translations", token: mocks.UserToken},
+	{name: "create_translation", method: http.MethodPost, path: "/v1/flashcards/1/translations", token: mocks.UserToken, body: `{"language": "de", "question": "Was ist die Hauptstadt von Frankreich?", "flashcard_content": {"answer": "Paris"}}`},
+	{name: "create_relation", method: http.MethodPost, path: "/v1/flashcards/1/relations", token: mocks.UserToken, body: `{"related_id": 4}`},
+	{name: "delete_relation", method: http.MethodDelete, path: "/v1/flashcards/1/relations", token: mocks.UserToken, body: `{"related_id": 2}`},
+	{name: "convert_flashcard", method: http.MethodPost, path: "/v1/flashcards/2/convert?to=qa", token: mocks.UserToken},
+	{name: "report_flashcard", method: http.MethodPost, path: "/v1/flashcards/1/reports", token: mocks.UserToken, body: `{"reason": "incorrect"}`},
+	{name: "create_prerequisite", method: http.MethodPost, path: "/v1/flashcards/4/prerequisites", token: mocks.UserToken, body: `{"prerequisite_id": 1}`},
+	{name: "create_prerequisite_cycle", method: http.MethodPost, path: "/v1/flashcards/2/prerequisites", token: mocks.UserToken, body: `{"prerequisite_id": 1}`},
+	{name: "delete_prerequisite", method: http.MethodDelete, path: "/v1/flashcards/2/prerequisites", token: mocks.UserToken, body: `{"prerequisite_id": 1}`},
+	{name: "download_attachment", method: http.MethodGet, path: "{download}"},
+	{name: "download_attachment_forged", method: http.MethodGet, path: "/v1/attachments/download?key=flashcards%2F1%2Fmap.png&expires=4102444800&signature=00"},
+
+	{name: "batch_flashcards", method: http.MethodGet, path: "/v1/batch/flashcards?ids=1,2,99", token: mocks.UserToken},
+	{name: "batch_flashcards_post", method: http.MethodPost, path: "/v1/batch/flashcards", token: mocks.UserToken, body: `{"ids": [1, 4]}`},
+	{name: "retag_flashcards", method: http.MethodPost, path: "/v1/batch/flashcards/tags", token: mocks.UserToken, body: `{"ids": [1, 2], "add": ["revision"], "remove": ["geography"]}`},
+
+	{name: "search_flashcards", method: http.MethodGet, path: "/v1/search/flashcards?q=capital", token: mocks.UserToken},
+	{name: "semantic_search_flashcards", method: http.MethodGet, path: "/v1/search/flashcards/semantic?q=red+planet", token: mocks.UserToken},
+	{name: "suggest", method: http.MethodGet, path: "/v1/search/suggest?q=what", token: mocks.UserToken},
+
+	{name: "export_flashcards", method: http.MethodGet, path: "/v1/export/flashcards", token: mocks.UserToken},
+	{name: "import_deck", method: http.MethodPost, path: "/v1/import/decks", token: mocks.UserToken, body: `{"name": "Imported", "bundle": {"format": 1, "deck": {"name": "Capitals"}, "flashcards": [{"question": "What is the capital of Italy?", "flashcard_type": "qa", "flashcard_content": {"answer": "Rome"}, "categories": ["geography"], "text": "Rome is the capital of Italy."}]}}`},
+
+	{name: "list_reviews", method: http.MethodGet, path: "/v1/reviews?flashcard_id=1", token: mocks.UserToken},
+	{name: "create_review", method: http.MethodPost, path: "/v1/reviews", token: mocks.UserToken, body: `{"flashcard_id": 1, "rating": "good", "latency_ms": 4000}`},
+	{name: "create_review_invalid", method: http.MethodPost, path: "/v1/reviews", token: mocks.UserToken, body: `{"flashcard_id": 1, "grade": 4, "rating": "easy"}`},
+
+	{name: "study_due", method: http.MethodGet, path: "/v1/study/due", token: mocks.UserToken},
+	{name: "study_cram", method: http.MethodGet, path: "/v1/study/cram?categories=geography", token: mocks.UserToken},
+	{name: "create_cram_review", method: http.MethodPost, path: "/v1/study/cram/reviews", token: mocks.UserToken, body: `{"flashcard_id": 1, "grade": 5}`},
+	{name: "study_forecast", method: http.MethodGet, path: "/v1/study/forecast?days=3", token: mocks.UserToken},
+	{name: "study_custom", method: http.MethodPost, path: "/v1/study/custom", token: mocks.UserToken, body: `{"categories": ["geography", "astronomy"], "limit": 10}`},
+	{name: "create_study_session", method: http.MethodPost, path: "/v1/study/sessions", token: mocks.UserToken, body: `{"deck_id": 1}`},
+	{name: "show_study_session", method: http.MethodGet, path: "/v1/study/sessions/2", token: mocks.UserToken},
+	{name: "create_session_answer", method: http.MethodPost, path: "/v1/study/sessions/1/answers", token: mocks.UserToken, body: `{"flashcard_id": 2, "response": "b", "latency_ms": 2000}`},
+	{name: "create_session_answer_completed", method: http.MethodPost, path: "/v1/study/sessions/2/answers", token: mocks.UserToken, body: `{"flashcard_id": 2, "correct": true}`},
+	{name: "complete_study_session", method: http.MethodPost, path: "/v1/study/sessions/1/complete", token: mocks.UserToken},
+	{name: "study_path", method: http.MethodGet, path: "/v1/study/path", token: mocks.UserToken},
+
+	{name: "create_exam", method: http.MethodPost, path: "/v1/exams", token: mocks.UserToken, body: `{"question_count": 2, "duration_minutes": 30}`},
+	{name: "show_exam", method: http.MethodGet, path: "/v1/exams/1", token: mocks.UserToken},
+	{name: "show_exam_submitted", method: http.MethodGet, path: "/v1/exams/2", token: mocks.UserToken},
+	{name: "serve_exam_question", method: http.MethodGet, path: "/v1/exams/1/questions/3", token: mocks.UserToken},
+	{name: "serve_exam_question_missing", method: http.MethodGet, path: "/v1/exams/1/questions/9", token: mocks.UserToken},
+	{name: "answer_exam_question", method: http.MethodPut, path: "/v1/exams/1/questions/2/answer", token: mocks.UserToken, body: `{"answer": "b", "answer_ms": 3000}`},
+	{name: "answer_exam_question_closed", method: http.MethodPut, path: "/v1/exams/2/questions/3/answer", token: mocks.UserToken, body: `{"answer": "yes"}`},
+	{name: "submit_exam", method: http.MethodPost, path: "/v1/exams/1/submission", token: mocks.UserToken, body: `{"answers": [{"flashcard_id": 1, "answer": "paris"}, {"flashcard_id": 2, "answer": "a", "answer_ms": 4000}]}`},
+
+	{name: "flashcard_stats", method: http.MethodGet, path: "/v1/stats/flashcards", token: mocks.UserToken},
+	{name: "category_stats", method: http.MethodGet, path: "/v1/stats/categories", token: mocks.UserToken},
+
+	{name: "list_hooks", method: http.MethodGet, path: "/v1/hooks", token: mocks.UserToken},
+	{name: "subscribe_hook", method: http.MethodPost, path: "/v1/hooks", token: mocks.UserToken, body: `{"event": "quiz.completed", "target_url": "https://hooks.example.com/new"}`},
+	{name: "subscribe_hook_metadata_endpoint", method: http.MethodPost, path: "/v1/hooks", token: mocks.UserToken, body: `{"event": "quiz.completed", "target_url": "http://169.254.169.254/latest/meta-data"}`},
+	{name: "subscribe_hook_read_only", method: http.MethodPost, path: "/v1/hooks", token: mocks.ReadOnlyToken, body: `{}`},
+	{name: "unsubscribe_hook", method: http.MethodDelete, path: "/v1/hooks/1", token: mocks.UserToken},
+
+	{name: "list_categories", method: http.MethodGet, path: "/v1/categories", token: mocks.UserToken},
+	{name: "create_category", method: http.MethodPost, path: "/v1/categories", token: mocks.UserToken, body: `{"name": "history", "description": "The past", "colour": "#795548", "icon": "scroll"}`},
+	{name: "create_category_duplicate", method: http.MethodPost, path: "/v1/categories", token: mocks.UserToken, body: `{"name": "geography"}`},
+	{name: "merge_categories", method: http.MethodPost, path: "/v1/categories/merge", token: mocks.UserToken, body: `{"from": "chemistry", "into": "biology"}`},
+	{name: "show_category", method: http.MethodGet, path: "/v1/categories/4", token: mocks.UserToken},
+	{name: "update_category", method: http.MethodPut, path: "/v1/categories/4", token: mocks.UserToken, body: `{"name": "geography", "description": "Places and peoples", "colour": "#ef6c00", "icon": "globe"}`},
+	{name: "delete_category", method: http.MethodDelete, path: "/v1/categories/3", token: mocks.UserToken},
+
+	{name: "list_decks", method: http.MethodGet, path: "/v1/decks", token: mocks.UserToken},
+	{name: "create_deck", method: http.MethodPost, path: "/v1/decks", token: mocks.UserToken, body: `{"name": "Capitals", "description": "European capitals", "visibility": "private"}`},
+	{name: "create_deck_duplicate", method: http.MethodPost, path: "/v1/decks", token: mocks.UserToken, body: `{"name": "Geography", "visibility": "private"}`},
+	{name: "show_deck", method: http.MethodGet, path: "/v1/decks/1", token: mocks.UserToken},
+	{name: "show_deck_member", method: http.MethodGet, path: "/v1/decks/3", token: mocks.UserToken},
+	{name: "update_deck", method: http.MethodPut, path: "/v1/decks/1", token: mocks.UserToken, body: `{"name": "Geography", "description": "Capitals of the world", "visibility": "public"}`},
+	{name: "delete_deck", method: http.MethodDelete, path: "/v1/decks/1", token: mocks.UserToken},
+	{name: "deck_stats", method: http.MethodGet, path: "/v1/decks/1/stats", token: mocks.UserToken},
+	{name: "list_deck_flashcards", method: http.MethodGet, path: "/v1/decks/1/flashcards", token: mocks.UserToken},
+	{name: "add_deck_flashcards", method: http.MethodPost, path: "/v1/decks/1/flashcards", token: mocks.UserToken, body: `{"ids": [2, 4]}`},
+	{name: "add_deck_flashcards_smart", method: http.MethodPost, path: "/v1/decks/2/flashcards", token: mocks.UserToken, body: `{"ids": [4]}`},
+	{name: "remove_deck_flashcards", method: http.MethodDelete, path: "/v1/decks/1/flashcards", token: mocks.UserToken, body: `{"ids": [2]}`},
+	{name: "reorder_deck", method: http.MethodPost, path: "/v1/decks/1/reorder", token: mocks.UserToken, body: `{"ids": [2, 1]}`},
+	{name: "reorder_deck_incomplete", method: http.MethodPost, path: "/v1/decks/1/reorder", token: mocks.UserToken, body: `{"ids": [2]}`},
+	{name: "clone_deck", method: http.MethodPost, path: "/v1/decks/3/clone", token: mocks.UserToken, body: `{"name": "My science", "cards": "copy"}`},
+	{name: "list_deck_members", method: http.MethodGet, path: "/v1/decks/3/members", token: mocks.UserToken},
+	{name: "update_deck_member", method: http.MethodPut, path: "/v1/decks/3/members/3", token: mocks.AdminToken, body: `{"role": "editor"}`},
+	{name: "update_deck_member_not_owner", method: http.MethodPut, path: "/v1/decks/3/members/3", token: mocks.UserToken, body: `{"role": "editor"}`},
+	{name: "remove_deck_member", method: http.MethodDelete, path: "/v1/decks/3/members/3", token: mocks.AdminToken},
+	{name: "list_deck_invitations", method: http.MethodGet, path: "/v1/decks/1/invitations", token: mocks.UserToken},
+	{name: "invite_deck_member", method: http.MethodPost, path: "/v1/decks/1/invitations", token: mocks.UserToken, body: `{"email": "carol@example.com", "role": "viewer"}`},
+	{name: "delete_deck_invitation", method: http.MethodDelete, path: "/v1/decks/1/invitations/1", token: mocks.UserToken},
+	{name: "accept_deck_invitation", method: http.MethodPut, path: "/v1/deck-invitations/accepted", token: mocks.UserToken, body: `{"token": "` + mocks.InvitationToken + `"}`},
+	{name: "archive_deck", method: http.MethodPost, path: "/v1/decks/1/archive", token: mocks.UserToken},
+	{name: "unarchive_deck", method: http.MethodDelete, path: "/v1/decks/1/archive", token: mocks.UserToken},
+	{name: "share_deck", method: http.MethodPost, path: "/v1/decks/1/share", token: mocks.UserToken},
+	{name: "unshare_deck", method: http.MethodDelete, path: "/v1/decks/1/share", token: mocks.UserToken},
+	{name: "export_deck", method: http.MethodGet, path: "/v1/decks/1/export", token: mocks.UserToken},
+
+	{name: "show_shared_deck", method: http.MethodGet, path: "/v1/shared/" + mocks.ShareToken},
+	{name: "show_shared_deck_not_found", method: http.MethodGet, path: "/v1/shared/NOSUCHSHARETOKEN0000000000"},
+	{name: "list_shared_deck_flashcards", method: http.MethodGet, path: "/v1/shared/" + mocks.ShareToken + "/flashcards"},
+
+	{name: "list_templates", method: http.MethodGet, path: "/v1/templates", token: mocks.UserToken},
+	{name: "create_template", method: http.MethodPost, path: "/v1/templates", token: mocks.UserToken, body: `{"name": "Element symbol", "description": "Asks for an element's symbol", "categories": ["chemistry"], "flashcard_type": "qa", "question": "What is the chemical symbol of gold?"}`},
+	{name: "show_template", method: http.MethodGet, path: "/v1/templates/1", token: mocks.UserToken},
+	{name: "update_template", method: http.MethodPut, path: "/v1/templates/1", token: mocks.UserToken, body: `{"name": "Capital", "description": "Asks for the capital of a country", "categories": ["geography"], "flashcard_type": "qa", "question": "What is the capital of {{country}}?", "flashcard_content": {"answer": "{{capital}}"}}`},
+	{name: "delete_template", method: http.MethodDelete, path: "/v1/templates/1", token: mocks.UserToken},
+	{name: "create_flashcard_from_template", method: http.MethodPost, path: "/v1/templates/1/flashcards?dry_run=true", token: mocks.UserToken, body: `{"values": {"country": "Portugal", "capital": "Lisbon"}}`},
+	{name: "create_flashcard_from_template_missing_value", method: http.MethodPost, path: "/v1/templates/1/flashcards", token: mocks.UserToken, body: `{"values": {"country": "Portugal"}}`},
+
+	{name: "list_trash", method: http.MethodGet, path: "/v1/trash", token: mocks.UserToken},
+	{name: "restore_trash", method: http.MethodPost, path: "/v1/trash/restore", token: mocks.UserToken, body: `{"ids": [6, 7]}`},
+
+	{name: "list_saved_searches", method: http.MethodGet, path: "/v1/saved-searches", token: mocks.UserToken},
+	{name: "create_saved_search", method: http.MethodPost, path: "/v1/saved-searches", token: mocks.UserToken, body: `{"name": "Hard cards", "query": "min_difficulty=3"}`},
+	{name: "show_saved_search", method: http.MethodGet, path: "/v1/saved-searches/1", token: mocks.UserToken},
+	{name: "update_saved_search", method: http.MethodPut, path: "/v1/saved-searches/1", token: mocks.UserToken, body: `{"shared": true}`},
+	{name: "update_saved_search_not_owner", method: http.MethodPut, path: "/v1/saved-searches/2", token: mocks.UserToken, body: `{"shared": false}`},
+	{name: "delete_saved_search", method: http.MethodDelete, path: "/v1/saved-searches/1", token: mocks.UserToken},
+	{name: "list_saved_search_flashcards", method: http.MethodGet, path: "/v1/saved-searches/1/flashcards", token: mocks.UserToken},
+	{name: "subscribe_saved_search", method: http.MethodPut, path: "/v1/saved-searches/2/subscription", token: mocks.UserToken},
+	{name: "unsubscribe_saved_search", method: http.MethodDelete, path: "/v1/saved-searches/1/subscription", token: mocks.UserToken},
+
+	{name: "register_user", method: http.MethodPost, path: "/v1/users", body: `{"name": "Carol White", "email": "carol@example.com", "password": "correct horse battery"}`},
+	{name: "register_user_duplicate", method: http.MethodPost, path: "/v1/users", body: `{"name": "Alice", "email": "alice@example.com", "password": "correct horse battery"}`},
+	{name: "register_user_invalid", method: http.MethodPost, path: "/v1/users", body: `{"name": "", "email": "not-an-email", "password": "short"}`},
+	{name: "activate_user", method: http.MethodPut, path: "/v1/users/activated", body: `{"token": "` + mocks.ActivationToken + `"}`},
+	{name: "activate_user_invalid_token", method: http.MethodPut, path: "/v1/users/activated", body: `{"token": "NOSUCHACTIVATIONTOKEN00000"}`},
+	{name: "show_current_user", method: http.MethodGet, path: "/v1/users/me", token: mocks.UserToken},
+	{name: "delete_current_user", method: http.MethodDelete, path: "/v1/users/me", token: mocks.UserToken, body: `{"password": "` + mocks.Password + `"}`},
+	{name: "delete_current_user_wrong_password", method: http.MethodDelete, path: "/v1/users/me", token: mocks.UserToken, body: `{"password": "wrong password"}`},
+	{name: "update_user_settings", method: http.MethodPut, path: "/v1/users/me/settings", token: mocks.UserToken, body: `{"timezone": "Europe/Dublin", "scheduler": "leitner", "daily_goal": 30}`},
+	{name: "update_user_email", method: http.MethodPut, path: "/v1/users/me/email", token: mocks.UserToken, body: `{"email": "alice.new@example.com", "password": "` + mocks.Password + `"}`},
+	{name: "confirm_user_email", method: http.MethodPut, path: "/v1/users/email/confirmed", body: `{"token": "` + mocks.EmailChangeToken + `"}`},
+	{name: "show_streak", method: http.MethodGet, path: "/v1/users/me/streak", token: mocks.UserToken},
+	{name: "show_recent", method: http.MethodGet, path: "/v1/users/me/recent", token: mocks.UserToken},
+	{name: "show_data_export", method: http.MethodGet, path: "/v1/users/me/export", token: mocks.UserToken},
+	{name: "create_data_export", method: http.MethodPost, path: "/v1/users/me/export", token: mocks.UserToken},
+	{name: "list_api_keys", method: http.MethodGet, path: "/v1/users/me/api-keys", token: mocks.UserToken},
+	{name: "create_api_key", method: http.MethodPost, path: "/v1/users/me/api-keys", token: mocks.UserToken, body: `{"name": "flashcard widget", "scopes": ["flashcards:read"]}`},
+	{name: "delete_api_key", method: http.MethodDelete, path: "/v1/users/me/api-keys/1", token: mocks.UserToken},
+	{name: "list_sessions", method: http.MethodGet, path: "/v1/users/me/sessions", token: mocks.UserToken},
+	{name: "delete_other_sessions", method: http.MethodDelete, path: "/v1/users/me/sessions", token: mocks.UserToken},
+	{name: "delete_session", method: http.MethodDelete, path: "/v1/users/me/sessions/2", token: mocks.UserToken},
+
+	{name: "create_authentication_token", method: http.MethodPost, path: "/v1/tokens/authentication", body: `{"email": "alice@example.com", "password": "` + mocks.Password + `"}`},
+	{name: "create_authentication_token_scoped", method: http.MethodPost, path: "/v1/tokens/authentication", body: `{"email": "alice@example.com", "password": "` + mocks.Password + `", "scopes": ["flashcards:read"]}`},
+	{name: "create_authentication_token_wrong_password", method: http.MethodPost, path: "/v1/tokens/authentication", body: `{"email": "alice@example.com", "password": "wrong password"}`},
+	{name: "create_oauth_token", method: http.MethodPost, path: "/v1/tokens/oauth", body: `{"provider": "google", "code": "valid-code", "redirect_uri": "https://app.example.com/callback"}`},
+	{name: "create_oauth_token_invalid_code", method: http.MethodPost, path: "/v1/tokens/oauth", body: `{"provider": "google", "code": "expired-code", "redirect_uri": "https://app.example.com/callback"}`},
+	{name: "create_magic_link", method: http.MethodPost, path: "/v1/tokens/magic-link", body: `{"email": "alice@example.com"}`},
+	{name: "create_magic_link_token", method: http.MethodPost, path: "/v1/tokens/magic-link/authentication", body: `{"token": "` + mocks.MagicLinkToken + `"}`},
+
+	{name: "lti_login", method: http.MethodGet, path: "/v1/lti/login?iss=https%3A%2F%2Flms.example.com&login_hint=lms-user-1"},
+	{name: "lti_login_post", method: http.MethodPost, path: "/v1/lti/login", body: "iss=https%3A%2F%2Flms.example.com&login_hint=lms-user-1", contentType: "application/x-www-form-urlencoded"},
+	{name: "lti_login_unknown_issuer", method: http.MethodGet, path: "/v1/lti/login?iss=https%3A%2F%2Fother.example.com&login_hint=lms-user-1"},
+	{name: "lti_launch", method: http.MethodPost, path: "/v1/lti/launch", body: "state=abc&id_token=invalid", contentType: "application/x-www-form-urlencoded"},
+	{name: "lti_jwks", method: http.MethodGet, path: "/v1/lti/jwks"},
+
+	{name: "show_card_policy", method: http.MethodGet, path: "/v1/card-policy", token: mocks.UserToken},
+	{name: "update_card_policy", method: http.MethodPut, path: "/v1/card-policy", token: mocks.AdminToken, body: `{"default_categories": ["geography"], "required_fields": ["source_file"]}`},
+	{name: "update_card_policy_not_admin", method: http.MethodPut, path: "/v1/card-policy", token: mocks.UserToken, body: `{}`},
+
+	{name: "list_metadata_fields", method: http.MethodGet, path: "/v1/metadata-fields", token: mocks.UserToken},
+	{name: "create_metadata_field", method: http.MethodPost, path: "/v1/metadata-fields", token: mocks.AdminToken, body: `{"name": "exam_board", "field_type": "enum", "options": ["aqa", "ocr"]}`},
+	{name: "delete_metadata_field", method: http.MethodDelete, path: "/v1/metadata-fields/source", token: mocks.AdminToken},
+
+	{name: "run_janitor", method: http.MethodPost, path: "/v1/admin/janitor", token: mocks.AdminToken},
+	{name: "list_user_roles", method: http.MethodGet, path: "/v1/admin/users/1/roles", token: mocks.AdminToken},
+	{name: "grant_user_role", method: http.MethodPost, path: "/v1/admin/users/3/roles", token: mocks.AdminToken, body: `{"role": "editor"}`},
+	{name: "revoke_user_role", method: http.MethodDelete, path: "/v1/admin/users/1/roles/editor", token: mocks.AdminToken},
+}
+
+// goldenHeaders are the response headers recorded in the golden files. The
+// others are the same for every response or depend on when the test runs.
+var goldenHeaders = []string{"Cache-Control", "Content-Disposition", "Content-Language", "Content-Type", "Location", "Retry-After", "WWW-Authenticate"}
+
+// volatile matches the parts of responses that change from run to run, such
+// as signed URLs, random keys and secrets and generation times, with their
+// replacements.
+var volatile = []struct {
+	rx   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`expires=\d+`), "expires=EXPIRES"},
+	{regexp.MustCompile(`signature=[0-9a-f]{64}`), "signature=SIGNATURE"},
+	{regexp.MustCompile(`(flashcards%2F\d+%2F)[a-z2-7]{26}`), "${1}RANDOM"},
+	{regexp.MustCompile(`(state|nonce)=[A-Z2-7]{26}`), "$1=RANDOM"},
+	{regexp.MustCompile(`"secret": "[A-Z2-7]{26}"`), `"secret": "RANDOM"`},
+	{regexp.MustCompile(`"generated_at": "[^"]+"`), `"generated_at": "GENERATED_AT"`},
+	{regexp.MustCompile(`"n": "[A-Za-z0-9_-]+"`), `"n": "MODULUS"`},
+}
+
+func TestRoutes(t *testing.T) {
+	// The file is kept apart from the fixture attachment, which one of the
+	// tests deletes in the background.
+	key := "flashcards/1/download.png"
+
+	err := testApp.storage.Delete(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = testApp.storage.Put(key, strings.NewReader("not really a PNG"), 16, "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	download, err := testApp.storage.URL(key, testApp.config.storage.urlTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range routeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := request(testApp.handler, tt.method, strings.ReplaceAll(tt.path, "{download}", download), tt.body, tt.header())
+
+			got := dump(t, res)
+			golden := filepath.Join("testdata", tt.name+".golden")
+
+			if *update {
+				err := os.WriteFile(golden, got, 0o644)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s %s: response differs from %s (run with -update to rewrite it)\ngot:\n%s", tt.method, tt.path, golden, got)
+			}
+		})
+	}
+}
+
+// dump renders the response's status, golden headers and body, with JSON
+// bodies indented and volatile values replaced.
+func dump(t *testing.T, res *http.Response) []byte {
+	t.Helper()
+
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "%s\n", res.Status)
+	for _, name := range goldenHeaders {
+		for _, value := range res.Header.Values(name) {
+			fmt.Fprintf(&buf, "%s: %s\n", name, value)
+		}
+	}
+	buf.WriteString("\n")
+
+	var indented bytes.Buffer
+	if json.Indent(&indented, body, "", "\t") == nil {
+		body = indented.Bytes()
+	}
+	buf.Write(body)
+	if len(body) > 0 && !bytes.HasSuffix(body, []byte("\n")) {
+		buf.WriteString("\n")
+	}
+
+	out := buf.Bytes()
+	for _, v := range volatile {
+		out = v.rx.ReplaceAll(out, []byte(v.repl))
+	}
+
+	return out
+}
+
+// TestRoutesCovered checks every route registered in routes.go has at least
+// one golden test.
+func TestRoutesCovered(t *testing.T) {
+	src, err := os.ReadFile("routes.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routeRX := regexp.MustCompile(`router\.HandlerFunc\(http\.Method(\w+), "([^"]+)"`)
+	paramRX := regexp.MustCompile(`:\w+`)
+
+	for _, m := range routeRX.FindAllStringSubmatch(string(src), -1) {
+		method, pattern := strings.ToUpper(m[1]), m[2]
+		rx := regexp.MustCompile("^" + paramRX.ReplaceAllString(pattern, `[^/]+`) + "$")
+
+		covered := slices.ContainsFunc(routeTests, func(tt routeTest) bool {
+			path, _, _ := strings.Cut(strings.ReplaceAll(tt.path, "{download}", "/v1/attachments/download"), "?")
+			return tt.method == method && rx.MatchString(path)
+		})
+
+		if !covered {
+			t.Errorf("%s %s has no golden test", method, pattern)
+		}
+	}
+}
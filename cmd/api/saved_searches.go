@@ -0,0 +1,400 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/validator"
+)
+
+type savedSearchInput struct {
+	Name   *string `json:"name"`
+	Query  *string `json:"query"`
+	Shared *bool   `json:"shared"`
+}
+
+func (input savedSearchInput) apply(s *data.SavedSearch) {
+	if input.Name != nil {
+		s.Name = *input.Name
+	}
+	if input.Query != nil {
+		s.Query = *input.Query
+	}
+	if input.Shared != nil {
+		s.Shared = *input.Shared
+	}
+}
+
+// validateSavedSearch checks the saved search and that its query only holds
+// filters the flashcard listing accepts, reporting problems under
+// query.<param>.
+func (app *application) validateSavedSearch(ctx context.Context, v *validator.Validator, s *data.SavedSearch) error {
+	if data.ValidateSavedSearch(v, s); !v.Valid() {
+		return nil
+	}
+
+	return app.validateListingQuery(ctx, v, s.Query)
+}
+
+// validateListingQuery checks that a stored query string only holds filters
+// the flashcard listing accepts. Smart decks store the same query strings.
+func (app *application) validateListingQuery(ctx context.Context, v *validator.Validator, query string) error {
+	qs, _ := url.ParseQuery(query)
+
+	qv := validator.New()
+
+	_, err := app.readFlashcardFilter(ctx, qs, qv)
+	if err != nil {
+		return err
+	}
+
+	for key, message := range qv.Errors {
+		v.AddError("query."+key, message)
+	}
+
+	return nil
+}
+
+func (app *application) createSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	var input savedSearchInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	search := &data.SavedSearch{UserID: app.contextGetUser(r).ID}
+	input.apply(search)
+
+	v := validator.New()
+
+	err = app.validateSavedSearch(r.Context(), v, search)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Searches.Insert(r.Context(), search)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/saved-searches/%d", search.ID))
+
+	app.linkSavedSearches(search)
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"saved_search": search}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listSavedSearchesHandler(w http.ResponseWriter, r *http.Request) {
+	searches, err := app.models.Searches.GetAllForUser(r.Context(), app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.linkSavedSearches(searches...)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"saved_searches": searches}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	search, err := app.models.Searches.Get(r.Context(), id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.linkSavedSearches(search)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"saved_search": search}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	search, err := app.models.Searches.Get(r.Context(), id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Shared searches can be used by everyone but only changed by their owner.
+	if !search.Owned {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	var input savedSearchInput
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	input.apply(search)
+
+	v := validator.New()
+
+	err = app.validateSavedSearch(r.Context(), v, search)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Searches.Update(r.Context(), search)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.linkSavedSearches(search)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"saved_search": search}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Searches.Delete(r.Context(), id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "saved search successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listSavedSearchFlashcardsHandler runs the saved search through the regular
+// flashcard listing. Parameters on the request override the saved ones, so
+// clients can page, sort or narrow the results further.
+func (app *application) listSavedSearchFlashcardsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	search, err := app.models.Searches.Get(r.Context(), id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	qs, err := url.ParseQuery(search.Query)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	for key, values := range r.URL.Query() {
+		qs[key] = values
+	}
+
+	app.listFlashcards(w, r, qs, 0)
+}
+
+func (app *application) subscribeSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	_, err = app.models.Searches.Get(r.Context(), id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Searches.Subscribe(r.Context(), id, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "subscribed to saved search"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) unsubscribeSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Searches.Unsubscribe(r.Context(), id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "unsubscribed from saved search"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// notifySavedSearches emails each subscriber the cards created since their
+// last notification that match the saved search. Subscriptions whose query no
+// longer validates, e.g. after a metadata field was removed, are skipped.
+func (app *application) notifySavedSearches(ctx context.Context) {
+	subscriptions, err := app.models.Searches.GetSubscriptions(ctx)
+	if err != nil {
+		app.logger.Error(err.Error())
+		return
+	}
+
+	notified := 0
+
+	for _, sub := range subscriptions {
+		qs, err := url.ParseQuery(sub.Search.Query)
+		if err != nil {
+			continue
+		}
+
+		v := validator.New()
+
+		filter, err := app.readFlashcardFilter(ctx, qs, v)
+		if err != nil {
+			app.logger.Error(err.Error())
+			return
+		}
+
+		if !v.Valid() {
+			app.logger.Warn("skipping invalid saved search", "saved_search_id", sub.Search.ID, "errors", v.Errors)
+			continue
+		}
+
+		// created_at has second precision, so the window ends on a whole
+		// second to avoid reporting the same card twice.
+		now := time.Now().Truncate(time.Second)
+		if filter.CreatedAfter == nil || filter.CreatedAfter.Before(sub.LastNotifiedAt) {
+			filter.CreatedAfter = &sub.LastNotifiedAt
+		}
+
+		flashcards, metadata, err := app.models.Flashcards.GetAll(ctx, sub.UserID, filter, data.Filters{
+			Page:         1,
+			PageSize:     10,
+			Sort:         "-id",
+			SortSafelist: []string{"-id"},
+		})
+		if err != nil {
+			app.logger.Error(err.Error())
+			return
+		}
+
+		if len(flashcards) > 0 {
+			templateData := map[string]any{
+				"searchID":   sub.Search.ID,
+				"searchName": sub.Search.Name,
+				"total":      metadata.TotalRecords,
+				"flashcards": flashcards,
+			}
+
+			err = app.mailer.Send(sub.Email, "saved_search_matches.tmpl", templateData)
+			if err != nil {
+				app.logger.Error(err.Error())
+				continue
+			}
+
+			notified++
+		}
+
+		err = app.models.Searches.MarkNotified(ctx, sub.Search.ID, sub.UserID, now)
+		if err != nil {
+			app.logger.Error(err.Error())
+			return
+		}
+	}
+
+	app.logger.Info("saved search notifications sent", "subscriptions", len(subscriptions), "notified", notified)
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/search"
+	"flashcards-api.johndennehy101.tech/internal/validator"
+)
+
+func searchDocument(flashcard *data.Flashcard) search.Document {
+	doc := search.Document{
+		ID:         flashcard.ID,
+		Question:   flashcard.Question,
+		Text:       flashcard.Text,
+		Type:       string(flashcard.Type),
+		Categories: flashcard.Categories,
+	}
+
+	if flashcard.Section != nil {
+		doc.Section = *flashcard.Section
+	}
+	if flashcard.SourceFile != nil {
+		doc.SourceFile = *flashcard.SourceFile
+	}
+
+	switch content := flashcard.Content.(type) {
+	case data.QAContent:
+		doc.Answer = content.Answer
+		doc.Justification = content.Justification
+	case data.MCQContent:
+		if option, ok := content.CorrectOption(); ok {
+			doc.Answer = option.Text
+		}
+		doc.Justification = content.Justification
+	case data.YesNoContent:
+		doc.Justification = content.Justification
+	}
+
+	return doc
+}
+
+// mirrorFlashcard pushes a created or updated card to the external search
+// engine in the background. It is a no-op when no engine is configured.
+func (app *application) mirrorFlashcard(flashcard *data.Flashcard) {
+	if app.search == nil {
+		return
+	}
+
+	doc := searchDocument(flashcard)
+
+	app.background(func() {
+		err := app.search.Index(doc)
+		if err != nil {
+			app.logger.Error(err.Error(), "flashcard_id", doc.ID)
+		}
+	})
+}
+
+func (app *application) unmirrorFlashcard(id int64) {
+	if app.search == nil {
+		return
+	}
+
+	app.background(func() {
+		err := app.search.Delete(id)
+		if err != nil {
+			app.logger.Error(err.Error(), "flashcard_id", id)
+		}
+	})
+}
+
+// reindexBatchSize caps how many documents go into a single indexing request,
+// so a large card bank doesn't run into the engine client's timeout.
+const reindexBatchSize = 500
+
+// reindexFlashcards mirrors the whole card bank into the search engine, so
+// cards created before the engine was configured are searchable too.
+func (app *application) reindexFlashcards() {
+	var afterID int64
+	total := 0
+
+	for {
+		flashcards, err := app.models.Flashcards.GetAllForIndex(context.Background(), afterID, reindexBatchSize)
+		if err != nil {
+			app.logger.Error(err.Error(), "after_id", afterID)
+			return
+		}
+
+		if len(flashcards) == 0 {
+			break
+		}
+
+		docs := make([]search.Document, len(flashcards))
+		for i, flashcard := range flashcards {
+			docs[i] = searchDocument(flashcard)
+		}
+
+		err = app.search.Index(docs...)
+		if err != nil {
+			app.logger.Error(err.Error(), "after_id", afterID)
+			return
+		}
+
+		total += len(docs)
+		afterID = flashcards[len(flashcards)-1].ID
+
+		if len(flashcards) < reindexBatchSize {
+			break
+		}
+	}
+
+	app.logger.Info("search index rebuilt", "documents", total)
+}
+
+// remirrorFlashcards mirrors the cards with the given ids into the search
+// engine again in the background, in batches, after a change that touched
+// many of them at once.
+func (app *application) remirrorFlashcards(ids []int64) {
+	if app.search == nil || len(ids) == 0 {
+		return
+	}
+
+	app.background(func() {
+		for batch := range slices.Chunk(ids, reindexBatchSize) {
+			flashcards, err := app.models.Flashcards.GetByIDs(context.Background(), batch, 0)
+			if err != nil {
+				app.logger.Error(err.Error(), "first_id", batch[0])
+				return
+			}
+
+			docs := make([]search.Document, len(flashcards))
+			for i, flashcard := range flashcards {
+				docs[i] = searchDocument(flashcard)
+			}
+
+			err = app.search.Index(docs...)
+			if err != nil {
+				app.logger.Error(err.Error(), "first_id", batch[0])
+				return
+			}
+		}
+	})
+}
+
+func (app *application) searchFlashcardsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	qs := r.URL.Query()
+	v := validator.New()
+
+	q := app.readString(qs, "q", "")
+	reveal := app.readReveal(qs, v)
+
+	filters := data.Filters{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", 20, v),
+		Sort:         "id",
+		SortSafelist: []string{"id"},
+	}
+
+	v.Check(q != "", "q", "must be provided")
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if app.search == nil {
+		flashcards, metadata, err := app.models.Flashcards.Search(r.Context(), user.ID, q, filters)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		concealFlashcards(reveal, flashcards...)
+
+		app.linkFlashcards(flashcards...)
+
+		err = app.writeResponse(w, r, http.StatusOK, envelope{"flashcards": flashcards, "metadata": metadata}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	ids, total, err := app.search.Search(q, filters.PageSize, (filters.Page-1)*filters.PageSize)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	flashcards, err := app.models.Flashcards.GetByIDs(r.Context(), ids, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	concealFlashcards(reveal, flashcards...)
+
+	app.linkFlashcards(flashcards...)
+
+	metadata := filters.Metadata(total)
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"flashcards": flashcards, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// suggestHandler backs type-ahead in the study UI, so it skips the search
+// concurrency limit and keeps its queries to the trigram indexes.
+func (app *application) suggestHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	v := validator.New()
+
+	q := strings.TrimSpace(app.readString(qs, "q", ""))
+	limit := app.readInt(qs, "limit", 5, v)
+
+	v.Check(q != "", "q", "must be provided")
+	v.Check(len(q) <= 100, "q", "must not be more than 100 bytes long")
+	v.Check(limit > 0 && limit <= 20, "limit", "must be between 1 and 20")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	suggestions, err := app.models.Flashcards.Suggest(r.Context(), q, limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"suggestions": suggestions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/validator"
+)
+
+// embeddingText is the text a card is embedded from: its question, answer
+// and justification, which is what a learner's query is usually about.
+func embeddingText(flashcard *data.Flashcard) string {
+	parts := []string{flashcard.Question, data.AnswerText(flashcard.Content)}
+
+	switch content := flashcard.Content.(type) {
+	case data.QAContent:
+		parts = append(parts, content.Justification)
+	case data.MCQContent:
+		parts = append(parts, content.Justification)
+	case data.YesNoContent:
+		parts = append(parts, content.Justification)
+	}
+
+	if flashcard.Text != "" {
+		parts = append(parts, flashcard.Text)
+	}
+
+	return strings.Join(parts, "\n")
+}
+
+// embedFlashcard computes and stores a created or updated card's embedding in
+// the background. It is a no-op when no embedding provider is configured.
+func (app *application) embedFlashcard(flashcard *data.Flashcard) {
+	if app.embeddings == nil {
+		return
+	}
+
+	text := embeddingText(flashcard)
+
+	app.background(func() {
+		vectors, err := app.embeddings.Embed(text)
+		if err != nil {
+			app.logger.Error(err.Error(), "flashcard_id", flashcard.ID)
+			return
+		}
+
+		err = app.models.Flashcards.SetEmbedding(context.Background(), flashcard.ID, app.embeddings.Model(), vectors[0])
+		if err != nil {
+			app.logger.Error(err.Error(), "flashcard_id", flashcard.ID)
+		}
+	})
+}
+
+// embedMissingFlashcards backfills embeddings in batches for cards created
+// before the provider, or the current model, was configured.
+func (app *application) embedMissingFlashcards() {
+	const batchSize = 100
+
+	ctx := context.Background()
+	model := app.embeddings.Model()
+	embedded := 0
+
+	for {
+		ids, err := app.models.Flashcards.GetUnembeddedIDs(ctx, model, batchSize)
+		if err != nil {
+			app.logger.Error(err.Error())
+			return
+		}
+
+		if len(ids) == 0 {
+			break
+		}
+
+		flashcards, err := app.models.Flashcards.GetByIDs(ctx, ids, 0)
+		if err != nil {
+			app.logger.Error(err.Error())
+			return
+		}
+
+		texts := make([]string, len(flashcards))
+		for i, flashcard := range flashcards {
+			texts[i] = embeddingText(flashcard)
+		}
+
+		vectors, err := app.embeddings.Embed(texts...)
+		if err != nil {
+			app.logger.Error(err.Error())
+			return
+		}
+
+		for i, flashcard := range flashcards {
+			err = app.models.Flashcards.SetEmbedding(ctx, flashcard.ID, model, vectors[i])
+			if err != nil {
+				app.logger.Error(err.Error(), "flashcard_id", flashcard.ID)
+				return
+			}
+		}
+
+		embedded += len(flashcards)
+
+		if len(ids) < batchSize {
+			break
+		}
+	}
+
+	app.logger.Info("flashcard embeddings backfilled", "model", model, "embedded", embedded)
+}
+
+// semanticSearchFlashcardsHandler returns the cards nearest in meaning to q,
+// which finds cards phrased differently from the query where the keyword
+// search wouldn't.
+func (app *application) semanticSearchFlashcardsHandler(w http.ResponseWriter, r *http.Request) {
+	if app.embeddings == nil {
+		app.featureDisabledResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	qs := r.URL.Query()
+	v := validator.New()
+
+	q := app.readString(qs, "q", "")
+	reveal := app.readReveal(qs, v)
+
+	filters := data.Filters{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", 20, v),
+		Sort:         "id",
+		SortSafelist: []string{"id"},
+	}
+
+	v.Check(q != "", "q", "must be provided")
+	v.Check(len(q) <= 1000, "q", "must not be more than 1000 bytes long")
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	vectors, err := app.embeddings.Embed(q)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	ids, metadata, err := app.models.Flashcards.NearestNeighbours(r.Context(), app.embeddings.Model(), vectors[0], app.config.embedding.maxDistance, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	flashcards, err := app.models.Flashcards.GetByIDs(r.Context(), ids, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	concealFlashcards(reveal, flashcards...)
+
+	app.linkFlashcards(flashcards...)
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"flashcards": flashcards, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
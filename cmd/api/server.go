@@ -24,6 +24,21 @@ func (app *application) serve() error {
 
 	shutdownError := make(chan error)
 
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	if app.config.janitor.interval > 0 {
+		app.wg.Go(func() { app.every(jobsCtx, app.config.janitor.interval, app.scheduledSweep) })
+	}
+
+	if app.config.quality.interval > 0 {
+		app.wg.Go(func() { app.every(jobsCtx, app.config.quality.interval, app.refreshQualityScores) })
+	}
+
+	if app.config.savedSearches.notifyInterval > 0 {
+		app.wg.Go(func() { app.every(jobsCtx, app.config.savedSearches.notifyInterval, app.notifySavedSearches) })
+	}
+
 	go func() {
 		quit := make(chan os.Signal, 1)
 		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -39,6 +54,8 @@ func (app *application) serve() error {
 			shutdownError <- err
 		}
 
+		stopJobs()
+
 		app.logger.Info("completing background tasks", "addr", srv.Addr)
 
 		app.wg.Wait()
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// showStreakHandler returns the user's study streak and today's progress
+// towards their daily goal, with days in the user's time zone.
+func (app *application) showStreakHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	streak, err := app.models.Reviews.GetStreak(r.Context(), user, time.Now(), userLocation(user))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	env := envelope{
+		"streak":     streak,
+		"formatting": app.formattingHints(r, user),
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
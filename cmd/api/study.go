@@ -0,0 +1,421 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/scheduler"
+	"flashcards-api.johndennehy101.tech/internal/validator"
+)
+
+// readStudyFilter reads the listing filters of GET /v1/flashcards and a
+// deck_id for the study endpoints, sending the error response itself when it
+// returns false. Cards only in archived decks are left out.
+func (app *application) readStudyFilter(w http.ResponseWriter, r *http.Request, v *validator.Validator) (data.FlashcardFilter, []string, bool) {
+	user := app.contextGetUser(r)
+
+	qs := r.URL.Query()
+
+	deckID := app.readInt(qs, "deck_id", 0, v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return data.FlashcardFilter{}, nil, false
+	}
+
+	var deck *data.Deck
+
+	if deckID != 0 {
+		var err error
+
+		deck, err = app.models.Decks.GetVisible(r.Context(), int64(deckID), user.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				v.AddError("deck_id", "must be an existing deck")
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return data.FlashcardFilter{}, nil, false
+		}
+
+		// A smart deck's cards are those its query matches, narrowed
+		// further by the request's own filters.
+		if deck.Smart() {
+			stored, err := url.ParseQuery(*deck.Query)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return data.FlashcardFilter{}, nil, false
+			}
+
+			for key, values := range qs {
+				stored[key] = values
+			}
+
+			qs = stored
+		}
+	}
+
+	filter, err := app.readFlashcardFilter(r.Context(), qs, v)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return data.FlashcardFilter{}, nil, false
+	}
+
+	reveal := app.readReveal(qs, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return data.FlashcardFilter{}, nil, false
+	}
+
+	if deck != nil && !deck.Smart() {
+		filter.DeckID = deck.ID
+	}
+
+	filter.HideArchived = true
+
+	return filter, reveal, true
+}
+
+// studyDueHandler returns the cards the user should study now: due reviews,
+// then new cards, each capped by what is left of the user's daily limits. The
+// day's counts start again at midnight in the user's time zone. Each card's
+// schedule lists the interval every rating would give it. It takes the
+// filters of readStudyFilter.
+func (app *application) studyDueHandler(w http.ResponseWriter, r *http.Request) {
+	filter, reveal, ok := app.readStudyFilter(w, r, validator.New())
+	if !ok {
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	now := time.Now()
+
+	studied, err := app.models.Reviews.CountSince(r.Context(), user.ID, startOfDay(user, now))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	limits := app.studyLimits(user)
+
+	remaining := data.StudyCounts{
+		New:    max(limits.New-studied.New, 0),
+		Review: max(limits.Review-studied.Review, 0),
+	}
+
+	flashcards, due, err := app.models.Flashcards.GetQueue(r.Context(), user.ID, filter, remaining.New, remaining.Review, now, true)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Reviews.Project(r.Context(), user.ID, flashcards, now)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	concealFlashcards(reveal, flashcards...)
+
+	app.linkFlashcards(flashcards...)
+
+	env := envelope{
+		"flashcards": flashcards,
+		"due":        due,
+		"studied":    studied,
+		"limits":     limits,
+		"remaining":  remaining,
+		"formatting": app.formattingHints(r, user),
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// studyCramHandler serves every card matching the filters of readStudyFilter
+// regardless of due dates or daily limits, up to ?limit (default 200): review
+// cards, weakest relative to their interval first, then new cards. Answers
+// are recorded with POST /v1/study/cram/reviews, which leaves the cards'
+// schedules alone.
+func (app *application) studyCramHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	limit := app.readInt(r.URL.Query(), "limit", 200, v)
+	v.Check(limit >= 1 && limit <= data.MaxPageSize, "limit", fmt.Sprintf("must be between 1 and %d", data.MaxPageSize))
+
+	filter, reveal, ok := app.readStudyFilter(w, r, v)
+	if !ok {
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	flashcards, available, err := app.models.Flashcards.GetQueue(r.Context(), user.ID, filter, limit, limit, time.Now(), false)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if len(flashcards) > limit {
+		flashcards = flashcards[:limit]
+	}
+
+	concealFlashcards(reveal, flashcards...)
+
+	app.linkFlashcards(flashcards...)
+
+	env := envelope{
+		"flashcards": flashcards,
+		"available":  available,
+		"formatting": app.formattingHints(r, user),
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createCramReviewHandler records a graded recall from a cram session. It
+// counts towards the card's mastery and quality score like any review, but
+// isn't logged as a scheduled review: the card's schedule, the daily limits
+// and the study streak are unaffected.
+func (app *application) createCramReviewHandler(w http.ResponseWriter, r *http.Request) {
+	flashcard, input, ok := app.readReview(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.recordRecall(r.Context(), app.contextGetUser(r), flashcard, *input.Grade, input.LatencyMS)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	review := envelope{
+		"flashcard_id": flashcard.ID,
+		"grade":        *input.Grade,
+		"correct":      *input.Grade >= scheduler.PassingGrade,
+		"latency_ms":   input.LatencyMS,
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"review": review}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// studyForecastHandler returns how many reviews fall due on each of the
+// next ?days days (default 30) in the user's time zone.
+func (app *application) studyForecastHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	v := validator.New()
+
+	days := app.readInt(qs, "days", 30, v)
+	v.Check(days >= 1 && days <= 365, "days", "must be between 1 and 365")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	forecast, err := app.models.Reviews.Forecast(r.Context(), user.ID, time.Now(), days, userLocation(user))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	env := envelope{
+		"forecast":   forecast,
+		"formatting": app.formattingHints(r, user),
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// studyLimits returns how many new and review cards the user may study each
+// day: their own settings, or the server's defaults.
+func (app *application) studyLimits(user *data.User) data.StudyCounts {
+	limits := data.StudyCounts{
+		New:    app.config.study.newPerDay,
+		Review: app.config.study.reviewsPerDay,
+	}
+
+	if user.NewPerDay != nil {
+		limits.New = *user.NewPerDay
+	}
+	if user.ReviewsPerDay != nil {
+		limits.Review = *user.ReviewsPerDay
+	}
+
+	return limits
+}
+
+// studyCustomHandler builds a one-off study queue from its own filters,
+// outside the daily limits and regardless of due dates: reviews come most
+// overdue first and may lead to studying cards ahead of schedule. new_ratio
+// is the share of new cards; when either kind runs short the other fills the
+// queue up to limit.
+func (app *application) studyCustomHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Categories     []string `json:"categories"`
+		CategoriesMode string   `json:"categories_mode"`
+		DeckIDs        []int64  `json:"deck_ids"`
+		Types          []string `json:"flashcard_types"`
+		MinDifficulty  int      `json:"min_difficulty"`
+		MaxDifficulty  int      `json:"max_difficulty"`
+		Limit          *int     `json:"limit"`
+		NewRatio       *float64 `json:"new_ratio"`
+		Reveal         []string `json:"reveal"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	limit, ratio := 50, 0.2
+	if input.Limit != nil {
+		limit = *input.Limit
+	}
+	if input.NewRatio != nil {
+		ratio = *input.NewRatio
+	}
+	if input.CategoriesMode == "" {
+		input.CategoriesMode = "all"
+	}
+
+	v := validator.New()
+
+	data.ValidateCategoryNames(v, "categories", input.Categories)
+	v.Check(validator.PermittedValue(input.CategoriesMode, "all", "any"), "categories_mode", "must be all or any")
+	v.Check(len(input.DeckIDs) <= 100, "deck_ids", "must not have more than 100 decks")
+	v.Check(validator.Unique(input.DeckIDs), "deck_ids", "must not contain duplicate values")
+	for _, t := range input.Types {
+		v.Check(validator.PermittedValue(data.FlashcardType(t), data.FlashcardTypes...), "flashcard_types", "invalid flashcard type")
+	}
+	v.Check(input.MinDifficulty >= 0 && input.MinDifficulty <= 5, "min_difficulty", "must be between 1 and 5")
+	v.Check(input.MaxDifficulty >= 0 && input.MaxDifficulty <= 5, "max_difficulty", "must be between 1 and 5")
+	v.Check(input.MaxDifficulty == 0 || input.MinDifficulty <= input.MaxDifficulty, "max_difficulty", "must not be less than min_difficulty")
+	v.Check(limit >= 1 && limit <= 500, "limit", "must be between 1 and 500")
+	v.Check(ratio >= 0 && ratio <= 1, "new_ratio", "must be between 0 and 1")
+	for _, value := range input.Reveal {
+		v.Check(validator.PermittedValue(value, revealSafelist...), "reveal", "invalid reveal value")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	for i, id := range input.DeckIDs {
+		deck, err := app.models.Decks.GetVisible(r.Context(), id, user.ID)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError(fmt.Sprintf("deck_ids[%d]", i), "must be an existing deck")
+		case err != nil:
+			app.serverErrorResponse(w, r, err)
+			return
+		case deck.Smart():
+			v.AddError(fmt.Sprintf("deck_ids[%d]", i), "must not be a smart deck")
+		}
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Cards only in archived decks stay out unless the decks are picked.
+	filter := data.FlashcardFilter{
+		Categories:    input.Categories,
+		CategoriesAny: input.CategoriesMode == "any",
+		DeckIDs:       input.DeckIDs,
+		Types:         input.Types,
+		MinDifficulty: input.MinDifficulty,
+		MaxDifficulty: input.MaxDifficulty,
+		HideArchived:  len(input.DeckIDs) == 0,
+	}
+
+	now := time.Now()
+
+	flashcards, available, err := app.models.Flashcards.GetQueue(r.Context(), user.ID, filter, limit, limit, now, false)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	flashcards = mixQueue(flashcards, limit, ratio)
+
+	err = app.models.Reviews.Project(r.Context(), user.ID, flashcards, now)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	concealFlashcards(input.Reveal, flashcards...)
+
+	app.linkFlashcards(flashcards...)
+
+	env := envelope{
+		"flashcards": flashcards,
+		"available":  available,
+		"formatting": app.formattingHints(r, user),
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// mixQueue picks up to limit cards from a queue of review cards followed by
+// new cards, aiming for ratio new cards, and spreads the new cards evenly
+// between the reviews.
+func mixQueue(queue []*data.Flashcard, limit int, ratio float64) []*data.Flashcard {
+	var reviews, fresh []*data.Flashcard
+
+	for _, flashcard := range queue {
+		if flashcard.Schedule.Queue == "new" {
+			fresh = append(fresh, flashcard)
+		} else {
+			reviews = append(reviews, flashcard)
+		}
+	}
+
+	newCount := min(int(math.Round(float64(limit)*ratio)), len(fresh))
+	reviewCount := min(limit-newCount, len(reviews))
+	newCount = min(limit-reviewCount, len(fresh))
+
+	total := newCount + reviewCount
+	mixed := make([]*data.Flashcard, 0, total)
+
+	for i, n := 0, 0; i < total; i++ {
+		if n*total < (i+1)*newCount {
+			mixed = append(mixed, fresh[n])
+			n++
+		} else {
+			mixed = append(mixed, reviews[i-n])
+		}
+	}
+
+	return mixed
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/validator"
+	"flashcards-api.johndennehy101.tech/internal/xapi"
+)
+
+func (app *application) createStudySessionHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		DeckID *int64 `json:"deck_id"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	session := &data.StudySession{UserID: user.ID, DeckID: input.DeckID}
+
+	if input.DeckID != nil {
+		_, err := app.models.Decks.GetVisible(r.Context(), *input.DeckID, user.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				v := validator.New()
+				v.AddError("deck_id", "must be an existing deck")
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+	}
+
+	err = app.models.Sessions.Insert(r.Context(), session)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/study/sessions/%d", session.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"session": session}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getStudySession loads one of the requesting user's sessions from the :id
+// parameter, writing the error response itself when that fails.
+func (app *application) getStudySession(w http.ResponseWriter, r *http.Request) (*data.StudySession, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	session, err := app.models.Sessions.Get(r.Context(), id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return session, true
+}
+
+// showStudySessionHandler returns the session with the answers so far, so a
+// client can resume it.
+func (app *application) showStudySessionHandler(w http.ResponseWriter, r *http.Request) {
+	session, ok := app.getStudySession(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"session": session, "summary": session.Summarize()}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createSessionAnswerHandler records an attempt at a card. A submitted
+// response is graded like POST /v1/flashcards/:id/answer unless the client
+// reports correct itself, as for self-assessed cards.
+func (app *application) createSessionAnswerHandler(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		FlashcardID int64           `json:"flashcard_id"`
+		Response    json.RawMessage `json:"response"`
+		Correct     *bool           `json:"correct"`
+		LatencyMS   *int            `json:"latency_ms"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(input.FlashcardID > 0, "flashcard_id", "must be provided")
+	v.Check(len(input.Response) > 0 || input.Correct != nil, "response", "must be provided unless correct is")
+	v.Check(input.LatencyMS == nil || *input.LatencyMS >= 0, "latency_ms", "must not be negative")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	flashcard, err := app.models.Flashcards.Get(r.Context(), input.FlashcardID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("flashcard_id", "must be an existing flashcard")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	answer := &data.SessionAnswer{
+		FlashcardID: flashcard.ID,
+		Response:    input.Response,
+		LatencyMS:   input.LatencyMS,
+	}
+
+	env := envelope{}
+
+	if input.Correct != nil {
+		answer.Correct = *input.Correct
+	} else {
+		result, err := data.GradeAnswer(flashcard.Content, input.Response)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrInvalidAnswer):
+				v.AddError("response", "invalid answer for this flashcard type")
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		answer.Correct = result.Correct
+		answer.Graded = true
+		env["result"] = result
+	}
+
+	err = app.models.Sessions.InsertAnswer(r.Context(), sessionID, user.ID, answer)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrSessionCompleted):
+			app.sessionCompletedResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Flashcards.RecordView(r.Context(), flashcard.ID, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Flashcards.RecordReview(r.Context(), flashcard.ID, user.ID, answer.Correct, answer.LatencyMS)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.emitStatement(user, xapi.VerbAnswered, flashcard, &xapi.Result{
+		Success:  &answer.Correct,
+		Response: string(input.Response),
+	})
+
+	env["answer"] = answer
+
+	err = app.writeJSON(w, http.StatusCreated, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// completeStudySessionHandler closes the session and passes the score of its
+// graded answers back to the LMS the user last launched from.
+func (app *application) completeStudySessionHandler(w http.ResponseWriter, r *http.Request) {
+	session, ok := app.getStudySession(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.models.Sessions.Complete(r.Context(), session)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrSessionCompleted):
+			app.sessionCompletedResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	correct, graded := session.GradedScore()
+
+	err = app.passBackScore(r.Context(), session.UserID, float64(correct), float64(graded), "study session")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	summary := session.Summarize()
+
+	var score float64
+	if summary.Accuracy != nil {
+		score = *summary.Accuracy
+	}
+
+	app.emitCompletion(app.contextGetUser(r), app.studySessionActivity(session), summary.Correct, summary.Answered)
+
+	app.triggerHook(session.UserID, data.EventQuizCompleted, quizCompleted{
+		Quiz:        "study_session",
+		ID:          session.ID,
+		Total:       summary.Answered,
+		Correct:     summary.Correct,
+		Score:       score,
+		CompletedAt: *session.CompletedAt,
+	})
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"session": session, "summary": summary}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
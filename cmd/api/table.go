@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+)
+
+const maxTableCell = 48
+
+// jsonObject is a decoded JSON object that remembers its key order, so table
+// columns follow the field order of the JSON API.
+type jsonObject struct {
+	keys   []string
+	values map[string]any
+}
+
+// writeTable renders each member of the envelope as its own section: arrays
+// of objects become a table with one row per element, objects become aligned
+// key/value pairs.
+func (app *application) writeTable(w http.ResponseWriter, status int, data any, headers http.Header) error {
+	js, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.UseNumber()
+
+	root, err := decodeOrdered(dec)
+	if err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	tw := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+
+	obj, ok := root.(*jsonObject)
+	if !ok {
+		obj = &jsonObject{keys: []string{"result"}, values: map[string]any{"result": root}}
+	}
+
+	for i, key := range obj.keys {
+		if i > 0 {
+			fmt.Fprintln(tw)
+		}
+
+		fmt.Fprintf(tw, "%s:\n", strings.ToUpper(key))
+
+		switch v := obj.values[key].(type) {
+		case []any:
+			writeTableRows(tw, v)
+		case *jsonObject:
+			for _, k := range v.keys {
+				fmt.Fprintf(tw, "%s\t%s\n", k, tableCell(v.values[k], false))
+			}
+		default:
+			fmt.Fprintln(tw, tableCell(v, false))
+		}
+	}
+
+	err = tw.Flush()
+	if err != nil {
+		return err
+	}
+
+	for key, values := range headers {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+
+	return nil
+}
+
+func writeTableRows(tw *tabwriter.Writer, rows []any) {
+	if len(rows) == 0 {
+		fmt.Fprintln(tw, "(none)")
+		return
+	}
+
+	var columns []string
+	seen := make(map[string]bool)
+
+	for _, row := range rows {
+		obj, ok := row.(*jsonObject)
+		if !ok {
+			continue
+		}
+		for _, k := range obj.keys {
+			if !seen[k] && !strings.HasPrefix(k, "_") {
+				seen[k] = true
+				columns = append(columns, k)
+			}
+		}
+	}
+
+	// A list of scalars, such as source_files.
+	if columns == nil {
+		for _, row := range rows {
+			fmt.Fprintln(tw, tableCell(row, true))
+		}
+		return
+	}
+
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(columns, "\t")))
+
+	for _, row := range rows {
+		obj, _ := row.(*jsonObject)
+		cells := make([]string, len(columns))
+
+		for i, c := range columns {
+			if obj != nil {
+				cells[i] = tableCell(obj.values[c], true)
+			}
+		}
+
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+}
+
+// tableCell formats a value for a single cell. Lists of scalars are joined
+// with commas and nested objects are written as compact JSON.
+func tableCell(v any, truncate bool) string {
+	var s string
+
+	switch v := v.(type) {
+	case nil:
+		s = "-"
+	case string:
+		s = v
+	case []any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = tableCell(item, false)
+		}
+		s = strings.Join(parts, ",")
+	case *jsonObject:
+		parts := make([]string, len(v.keys))
+		for i, k := range v.keys {
+			parts[i] = k + "=" + tableCell(v.values[k], false)
+		}
+		s = "{" + strings.Join(parts, " ") + "}"
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	s = strings.Join(strings.Fields(s), " ")
+
+	if truncate && utf8.RuneCountInString(s) > maxTableCell {
+		s = string([]rune(s)[:maxTableCell-1]) + "…"
+	}
+
+	if s == "" {
+		s = "-"
+	}
+
+	return s
+}
+
+func decodeOrdered(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+
+	switch delim {
+	case '{':
+		obj := &jsonObject{values: make(map[string]any)}
+
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+
+			obj.keys = append(obj.keys, key.(string))
+			obj.values[key.(string)] = value
+		}
+
+		_, err = dec.Token()
+		return obj, err
+
+	default:
+		list := []any{}
+
+		for dec.More() {
+			value, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+
+			list = append(list, value)
+		}
+
+		_, err = dec.Token()
+		return list, err
+	}
+}
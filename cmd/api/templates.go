@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/validator"
+)
+
+type templateInput struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Section     *string            `json:"section"`
+	SectionType *string            `json:"section_type"`
+	Categories  []string           `json:"categories"`
+	Type        data.FlashcardType `json:"flashcard_type"`
+	Question    string             `json:"question"`
+	Text        string             `json:"text"`
+	Content     json.RawMessage    `json:"flashcard_content"`
+}
+
+func (input templateInput) apply(t *data.Template) {
+	t.Name = input.Name
+	t.Description = input.Description
+	t.Section = input.Section
+	t.SectionType = input.SectionType
+	t.Categories = input.Categories
+	t.Type = input.Type
+	t.Question = input.Question
+	t.Text = input.Text
+	t.Content = input.Content
+
+	if t.Categories == nil {
+		t.Categories = []string{}
+	}
+}
+
+func (app *application) createTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	var input templateInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	template := &data.Template{}
+	input.apply(template)
+
+	v := validator.New()
+
+	if data.ValidateTemplate(v, template); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Templates.Insert(r.Context(), template)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateTemplateName):
+			v.AddError("name", "a template with this name already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/templates/%d", template.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"template": template}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	template, err := app.models.Templates.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"template": template}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	v := validator.New()
+
+	filters := data.Filters{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", 20, v),
+		Sort:         app.readString(qs, "sort", "name"),
+		SortSafelist: []string{"name", "flashcard_type", "created_at", "-name", "-flashcard_type", "-created_at"},
+	}
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	templates, metadata, err := app.models.Templates.GetAll(r.Context(), filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"templates": templates, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	template, err := app.models.Templates.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input templateInput
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	input.apply(template)
+
+	v := validator.New()
+
+	if data.ValidateTemplate(v, template); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Templates.Update(r.Context(), template)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateTemplateName):
+			v.AddError("name", "a template with this name already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"template": template}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Templates.Delete(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "template successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createFlashcardFromTemplateHandler fills the template's placeholders and
+// creates the card. With ?dry_run=true the pre-filled card is returned
+// without being saved, so clients can show it in an editor first.
+func (app *application) createFlashcardFromTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+
+	dryRun := app.readBool(r.URL.Query(), "dry_run", false, v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	template, err := app.models.Templates.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Values     map[string]string `json:"values"`
+		SourceFile *string           `json:"source_file"`
+		Text       *string           `json:"text"`
+		Content    json.RawMessage   `json:"flashcard_content"`
+		Categories []string          `json:"categories"`
+		Difficulty *int              `json:"difficulty"`
+		Hints      []string          `json:"hints"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	flashcard, err := template.Fill(v, input.Values, input.Content)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	flashcard.SourceFile = input.SourceFile
+	flashcard.Difficulty = input.Difficulty
+	flashcard.Hints = input.Hints
+
+	if input.Text != nil {
+		flashcard.Text = *input.Text
+	}
+
+	for _, category := range input.Categories {
+		if !slices.Contains(flashcard.Categories, category) {
+			flashcard.Categories = append(flashcard.Categories, category)
+		}
+	}
+
+	if dryRun {
+		err = app.writeJSON(w, http.StatusOK, envelope{"flashcard": flashcard}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.insertFlashcard(w, r, flashcard, nil)
+}
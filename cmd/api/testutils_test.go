@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/data/mocks"
+	"flashcards-api.johndennehy101.tech/internal/lti"
+	"flashcards-api.johndennehy101.tech/internal/mailer"
+	"flashcards-api.johndennehy101.tech/internal/oauth"
+	"flashcards-api.johndennehy101.tech/internal/scheduler"
+	"flashcards-api.johndennehy101.tech/internal/search"
+	"golang.org/x/text/language"
+)
+
+// testApp is the application under test and its handler, shared by every
+// test in the binary: routes registers expvar metrics, which can only happen
+// once per process.
+var testApp struct {
+	*application
+	handler http.Handler
+}
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	dir, err := os.MkdirTemp("", "flashcards-api")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	app, err := newTestApplication(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	testApp.application = app
+	testApp.handler = app.routes()
+
+	code := m.Run()
+
+	// Emails are sent in the background and fail without an SMTP server;
+	// wait for them before removing the directory.
+	app.wg.Wait()
+	os.RemoveAll(dir)
+
+	os.Exit(code)
+}
+
+// newTestApplication returns an application backed by the mock models, with
+// local attachment storage below dir, and stub
+// search, speech, embedding, OAuth and LTI integrations that never leave the
+// process.
+func newTestApplication(dir string) (*application, error) {
+	var cfg config
+
+	cfg.env = "testing"
+	cfg.language = language.English
+	cfg.duplicates = "allow"
+	cfg.deletedCards = "anonymize"
+	cfg.auth.tokens = "opaque"
+	cfg.cursor.secret = "cursor-secret"
+	cfg.concurrency.search = 20
+	cfg.concurrency.export = 4
+	cfg.concurrency.generation = 4
+	cfg.search.similarity = 0.3
+	cfg.embedding.maxDistance = 0.7
+	cfg.janitor.trashRetention = 30 * 24 * time.Hour
+	cfg.scheduler.leitnerIntervals = scheduler.DefaultLeitnerIntervals
+	cfg.study.newPerDay = 20
+	cfg.study.reviewsPerDay = 200
+	cfg.storage.backend = "local"
+	cfg.storage.dir = filepath.Join(dir, "uploads")
+	cfg.storage.secret = "storage-secret"
+	cfg.storage.urlTTL = 15 * time.Minute
+	cfg.lti.issuer = "https://lms.example.com"
+	cfg.lti.launchURL = "https://api.example.com/v1/lti/launch"
+
+	store, err := openStorage(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	mail, err := mailer.New("localhost", 25, "", "", "Flashcards <no-reply@example.com>")
+	if err != nil {
+		return nil, err
+	}
+
+	keyFile, err := writeTestKey(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	tool, err := lti.New(lti.Platform{
+		Issuer:       cfg.lti.issuer,
+		ClientID:     "flashcards",
+		DeploymentID: "1",
+		AuthURL:      "https://lms.example.com/auth",
+		TokenURL:     "https://lms.example.com/token",
+		JWKSURL:      "https://lms.example.com/jwks",
+	}, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Hashing the fixture and new passwords with the production cost would
+	// make the tests slow.
+	data.PasswordArgon2 = data.Argon2Params{Memory: 8 * 1024, Iterations: 1, Parallelism: 1}
+
+	return &application{
+		config:     cfg,
+		logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		models:     mocks.NewModels(),
+		mailer:     mail,
+		search:     stubSearch{},
+		storage:    store,
+		tts:        stubSpeech{},
+		embeddings: stubEmbeddings{},
+		lti:        tool,
+		oauth:      map[string]oauth.Provider{"google": stubOAuth{}},
+	}, nil
+}
+
+// writeTestKey writes a new RSA private key for the LTI tool below dir and
+// returns its path.
+func writeTestKey(dir string) (string, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, "lti.pem")
+	block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+
+	return path, os.WriteFile(path, pem.EncodeToMemory(block), 0o600)
+}
+
+// request sends a request with the given headers to h and returns the
+// recorded response.
+func request(h http.Handler, method, target, body string, header http.Header) *http.Response {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	maps.Copy(r.Header, header)
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, r)
+
+	return rr.Result()
+}
+
+// stubSearch matches every query with card 1.
+type stubSearch struct{}
+
+func (stubSearch) Index(docs ...search.Document) error { return nil }
+func (stubSearch) Delete(id int64) error               { return nil }
+
+func (stubSearch) Search(query string, limit, offset int) ([]int64, int, error) {
+	return []int64{1}, 1, nil
+}
+
+type stubSpeech struct{}
+
+func (stubSpeech) Synthesize(text string) ([]byte, string, error) {
+	return []byte("ID3"), "audio/mpeg", nil
+}
+
+type stubEmbeddings struct{}
+
+func (stubEmbeddings) Model() string { return "stub" }
+
+func (stubEmbeddings) Embed(texts ...string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i := range embeddings {
+		embeddings[i] = []float32{1, 0, 0}
+	}
+
+	return embeddings, nil
+}
+
+// stubOAuth signs in carol@example.com, who has no account yet, with the
+// code "valid-code".
+type stubOAuth struct{}
+
+func (stubOAuth) Exchange(ctx context.Context, code, redirectURI string) (*oauth.Identity, error) {
+	if code != "valid-code" {
+		return nil, oauth.ErrInvalidCode
+	}
+
+	return &oauth.Identity{Subject: "google-1", Name: "Carol White", Email: "carol@example.com"}, nil
+}
@@ -3,16 +3,22 @@ package main
 import (
 	"errors"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"flashcards-api.johndennehy101.tech/internal/data"
 	"flashcards-api.johndennehy101.tech/internal/validator"
+	"github.com/tomasen/realip"
 )
 
+// createAuthenticationTokenHandler signs the user in. The token can be limited
+// to scopes, for clients that shouldn't have the user's full access.
 func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Email    string   `json:"email"`
+		Password string   `json:"password"`
+		Scopes   []string `json:"scopes"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -25,16 +31,27 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 
 	data.ValidateEmail(v, input.Email)
 	data.ValidatePasswordPlaintext(v, input.Password)
+	if input.Scopes != nil {
+		data.ValidateTokenScopes(v, input.Scopes)
+	}
 
 	if !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	user, err := app.models.Users.GetByEmail(input.Email)
+	ip := realip.FromRequest(r)
+
+	if retryAfter := app.logins.locked(input.Email, ip); retryAfter > 0 {
+		app.loginLockedResponse(w, r, retryAfter)
+		return
+	}
+
+	user, err := app.models.Users.GetByEmail(r.Context(), input.Email)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
+			app.logins.failed(input.Email, ip)
 			app.invalidCredentialsResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
@@ -49,11 +66,150 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 	}
 
 	if !match {
+		app.logins.failed(input.Email, ip)
 		app.invalidCredentialsResponse(w, r)
 		return
 	}
 
-	token, err := app.models.Tokens.New(user.ID, 24*time.Hour, data.ScopeAuthentication)
+	app.logins.succeeded(input.Email)
+
+	// Passwords hashed with bcrypt, or with older Argon2id costs, are moved
+	// over now that the plaintext is known. The login goes ahead either way.
+	if user.Password.NeedsRehash() {
+		err = app.models.Users.Rehash(r.Context(), user, input.Password)
+		if err != nil {
+			app.logError(r, err)
+		}
+	}
+
+	token, err := app.issueAuthenticationToken(r, user, input.Scopes)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"authentication_token": token}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+const magicLinkTTL = 15 * time.Minute
+
+// createMagicLinkHandler emails a login link to the address. It answers the
+// same whether or not there is an account with that address, so it can't be
+// used to find out who has one.
+func (app *application) createMagicLinkHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	input.Email = strings.TrimSpace(input.Email)
+
+	v := validator.New()
+
+	if data.ValidateEmail(v, input.Email); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetByEmail(r.Context(), input.Email)
+	switch {
+	case err == nil:
+		token, err := app.models.Tokens.New(r.Context(), user.ID, magicLinkTTL, data.ScopeMagicLink)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		app.background(func() {
+			templateData := map[string]any{
+				"name":       user.Name,
+				"loginToken": token.Plaintext,
+			}
+
+			if app.config.auth.magicLinkURL != "" {
+				templateData["loginURL"] = app.config.auth.magicLinkURL + "?" + url.Values{"token": {token.Plaintext}}.Encode()
+			}
+
+			err := app.mailer.Send(user.Email, "magic_link.tmpl", templateData)
+			if err != nil {
+				app.logger.Error(err.Error())
+			}
+		})
+	case errors.Is(err, data.ErrRecordNotFound):
+	default:
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	env := envelope{"message": "if an account exists for this address, a login link has been sent to it"}
+
+	err = app.writeJSON(w, http.StatusAccepted, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createMagicLinkTokenHandler exchanges a login link's token for an
+// authentication token. Following the link proves the user holds the
+// address, so it claims accounts that hadn't been activated.
+func (app *application) createMagicLinkTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		TokenPlaintext string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateTokenPlaintext(v, input.TokenPlaintext); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	userID, err := app.models.Tokens.Use(r.Context(), data.ScopeMagicLink, input.TokenPlaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("token", "invalid or expired login token")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.models.Users.Get(r.Context(), userID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !user.Activated {
+		err = app.claimUnactivatedUser(r.Context(), user)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrEditConflict):
+				app.editConflictResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+	}
+
+	token, err := app.issueAuthenticationToken(r, user, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+)
+
+const tracerName = "flashcards-api.johndennehy101.tech"
+
+// openTracing installs a global tracer provider that batches spans to the
+// OTLP endpoint, and the W3C traceparent/baggage propagators. Outbound HTTP
+// clients built on the default transport are traced as well.
+func openTracing(cfg config) (*sdktrace.TracerProvider, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.otel.endpoint))
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(
+			semconv.ServiceName("flashcards-api"),
+			semconv.ServiceVersion(version),
+			semconv.DeploymentEnvironmentName(cfg.env),
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.otel.sampleRatio))),
+	)
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	http.DefaultTransport = otelhttp.NewTransport(http.DefaultTransport)
+
+	return tp, nil
+}
+
+// trace wraps the whole middleware chain in a server span, continuing any
+// trace started by the client's traceparent header.
+func (app *application) trace(next http.Handler) http.Handler {
+	if app.config.otel.endpoint == "" {
+		return next
+	}
+
+	return otelhttp.NewHandler(next, "http.server",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method + " " + routeTemplate(r.URL.Path)
+		}),
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return !strings.HasPrefix(r.URL.Path, "/debug/")
+		}),
+	)
+}
+
+// routeTemplate replaces numeric path segments with :id to keep span names
+// low-cardinality, e.g. /v1/flashcards/42/review becomes
+// /v1/flashcards/:id/review.
+func routeTemplate(path string) string {
+	segments := strings.Split(path, "/")
+
+	for i, segment := range segments {
+		if segment != "" && strings.Trim(segment, "0123456789") == "" {
+			segments[i] = ":id"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
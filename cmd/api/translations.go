@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/validator"
+	"golang.org/x/text/language"
+)
+
+func (app *application) createTranslationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Language string          `json:"language"`
+		Question string          `json:"question"`
+		Content  json.RawMessage `json:"flashcard_content"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	flashcard, err := app.models.Flashcards.Get(r.Context(), id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	content, err := data.DecodeContent(flashcard.Type, input.Content)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	translation := &data.Translation{
+		FlashcardID: flashcard.ID,
+		Language:    input.Language,
+		Question:    input.Question,
+		Type:        flashcard.Type,
+		Content:     content,
+	}
+
+	v := validator.New()
+
+	if data.ValidateTranslation(v, translation); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Store the canonical form of the tag so "GA-ie" and "ga-IE" are the
+	// same translation.
+	translation.Language = language.Make(translation.Language).String()
+
+	if translation.Language == app.config.language.String() {
+		v.AddError("language", "must differ from the language of the original flashcard")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Translations.Upsert(r.Context(), translation)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"translation": translation}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listTranslationsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	_, err = app.models.Flashcards.Get(r.Context(), id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	translations, err := app.models.Translations.GetAllForFlashcards(r.Context(), []int64{id})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"translations": translations}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// translateFlashcards swaps in the question and content of the translation
+// that best matches the client's Accept-Language header, keeping the
+// original wherever no translation is a better match than the original
+// language.
+func (app *application) translateFlashcards(r *http.Request, flashcards ...*data.Flashcard) error {
+	original := app.config.language.String()
+
+	for _, flashcard := range flashcards {
+		flashcard.Language = original
+	}
+
+	prefs, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	if err != nil || len(prefs) == 0 || len(flashcards) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(flashcards))
+	for i, flashcard := range flashcards {
+		ids[i] = flashcard.ID
+	}
+
+	translations, err := app.models.Translations.GetAllForFlashcards(r.Context(), ids)
+	if err != nil {
+		return err
+	}
+
+	byFlashcard := make(map[int64][]*data.Translation, len(flashcards))
+	for _, t := range translations {
+		byFlashcard[t.FlashcardID] = append(byFlashcard[t.FlashcardID], t)
+	}
+
+	for _, flashcard := range flashcards {
+		available := byFlashcard[flashcard.ID]
+		if len(available) == 0 {
+			continue
+		}
+
+		supported := []language.Tag{app.config.language}
+		for _, t := range available {
+			supported = append(supported, language.Make(t.Language))
+		}
+
+		_, index, confidence := language.NewMatcher(supported).Match(prefs...)
+		if confidence == language.No || index == 0 {
+			continue
+		}
+
+		t := available[index-1]
+		flashcard.Question = t.Question
+		flashcard.Content = t.Content
+		flashcard.Language = t.Language
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/validator"
+)
+
+func (app *application) listTrashHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	v := validator.New()
+
+	filters := data.Filters{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", 20, v),
+		Sort:         "-deleted_at",
+		SortSafelist: []string{"-deleted_at"},
+	}
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	flashcards, metadata, err := app.models.Flashcards.GetTrash(r.Context(), app.contextGetUser(r).ID, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"flashcards": flashcards, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// restoreTrashHandler brings back several trashed cards at once, e.g. after
+// an accidental mass deletion. Ids that aren't in the user's trash are
+// reported as not restored rather than failing the request.
+func (app *application) restoreTrashHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		IDs []int64 `json:"ids"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(len(input.IDs) > 0, "ids", "must contain at least one id")
+	v.Check(len(input.IDs) <= 1000, "ids", "must not contain more than 1000 ids")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	restored, err := app.models.Flashcards.Restore(r.Context(), input.IDs, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	flashcards, err := app.models.Flashcards.GetByIDs(r.Context(), restored, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	for _, flashcard := range flashcards {
+		app.mirrorFlashcard(flashcard)
+		app.embedFlashcard(flashcard)
+	}
+
+	notRestored := []int64{}
+	for _, id := range input.IDs {
+		if !slices.Contains(restored, id) {
+			notRestored = append(notRestored, id)
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"restored": restored, "not_restored": notRestored}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/validator"
+)
+
+const (
+	speechQuestion = "question"
+	speechAnswer   = "answer"
+)
+
+// spokenAnswer renders a flashcard's answer as a sentence suitable for TTS.
+func spokenAnswer(content data.FlashcardContent) string {
+	switch c := content.(type) {
+	case data.QAContent:
+		return c.Answer
+	case data.MCQContent:
+		if option, ok := c.CorrectOption(); ok {
+			return option.Text
+		}
+	case data.YesNoContent:
+		if c.Correct {
+			return "Yes"
+		}
+		return "No"
+	case data.NumericContent:
+		return strconv.FormatFloat(c.Answer, 'f', -1, 64) + " " + c.Unit
+	}
+
+	return ""
+}
+
+// generateSpeech synthesizes audio for the requested parts of the flashcard in
+// the background and stores each result as an audio attachment.
+func (app *application) generateSpeech(flashcard *data.Flashcard, userID int64, targets ...string) {
+	texts := map[string]string{
+		speechQuestion: flashcard.Question,
+		speechAnswer:   spokenAnswer(flashcard.Content),
+	}
+
+	app.background(func() {
+		for _, target := range targets {
+			text := texts[target]
+			if text == "" {
+				continue
+			}
+
+			audio, contentType, err := app.tts.Synthesize(text)
+			if err != nil {
+				app.logger.Error(err.Error(), "flashcard_id", flashcard.ID, "target", target)
+				continue
+			}
+
+			attachment := &data.Attachment{
+				FlashcardID: flashcard.ID,
+				UserID:      userID,
+				Kind:        data.AttachmentAudio,
+				StorageKey:  newStorageKey(flashcard.ID, ".mp3"),
+				Filename:    target + ".mp3",
+				ContentType: contentType,
+				Size:        int64(len(audio)),
+			}
+
+			err = app.storage.Put(attachment.StorageKey, bytes.NewReader(audio), attachment.Size, contentType)
+			if err != nil {
+				app.logger.Error(err.Error(), "flashcard_id", flashcard.ID, "target", target)
+				continue
+			}
+
+			err = app.models.Attachments.Insert(context.Background(), attachment)
+			if err != nil {
+				app.storage.Delete(attachment.StorageKey)
+				app.logger.Error(err.Error(), "flashcard_id", flashcard.ID, "target", target)
+			}
+		}
+	})
+}
+
+func (app *application) generateSpeechHandler(w http.ResponseWriter, r *http.Request) {
+	if app.tts == nil {
+		app.featureDisabledResponse(w, r)
+		return
+	}
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Targets []string `json:"targets"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if len(input.Targets) == 0 {
+		input.Targets = []string{speechQuestion, speechAnswer}
+	}
+
+	v := validator.New()
+
+	for _, target := range input.Targets {
+		v.Check(validator.PermittedValue(target, speechQuestion, speechAnswer), "targets", "must only contain question or answer")
+	}
+	v.Check(validator.Unique(input.Targets), "targets", "must not contain duplicate values")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	flashcard, err := app.models.Flashcards.Get(r.Context(), id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.generateSpeech(flashcard, user.ID, input.Targets...)
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/flashcards/%d/attachments", flashcard.ID))
+
+	err = app.writeJSON(w, http.StatusAccepted, envelope{"message": "speech generation started"}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"net/http"
+	"strings"
 	"time"
 
 	"flashcards-api.johndennehy101.tech/internal/data"
 	"flashcards-api.johndennehy101.tech/internal/validator"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Request) {
@@ -41,7 +46,12 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err = app.models.Users.Insert(user)
+	if app.checkBreachedPassword(r, v, input.Password); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Users.Insert(r.Context(), user)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrDuplicateEmail):
@@ -53,19 +63,26 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err = app.models.Permissions.AddForUser(user.ID, "flashcards:read")
+	err = app.models.Permissions.AddRoleForUser(r.Context(), user.ID, "reader")
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	token, err := app.models.Tokens.New(user.ID, 3*24*time.Hour, data.ScopeActivation)
+	token, err := app.models.Tokens.New(r.Context(), user.ID, 3*24*time.Hour, data.ScopeActivation)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	// The welcome email outlives the request, so its span joins the request's
+	// trace without inheriting the request's cancellation.
+	spanCtx := trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(r.Context()))
+
 	app.background(func() {
+		_, span := otel.Tracer(tracerName).Start(spanCtx, "mailer.Send")
+		defer span.End()
+
 		templateData := map[string]any{
 			"activationToken": token.Plaintext,
 			"userID":          user.ID,
@@ -73,6 +90,8 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 
 		err := app.mailer.Send(user.Email, "user_welcome.tmpl", templateData)
 		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "send failed")
 			app.logger.Error(err.Error())
 		}
 	})
@@ -83,6 +102,23 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 	}
 }
 
+// checkBreachedPassword adds a validation error when the password is known
+// from a data breach. A lookup that fails is logged and the password let
+// through, so the breach list being unavailable doesn't stop sign-ups.
+func (app *application) checkBreachedPassword(r *http.Request, v *validator.Validator, password string) {
+	if app.pwned == nil {
+		return
+	}
+
+	breached, err := app.pwned.Breached(r.Context(), password)
+	if err != nil {
+		app.logError(r, err)
+		return
+	}
+
+	v.Check(!breached, "password", "has appeared in a data breach, choose a different one")
+}
+
 func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
 		TokenPlaintext string `json:"token"`
@@ -101,7 +137,7 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	user, err := app.models.Users.GetForToken(data.ScopeActivation, input.TokenPlaintext)
+	user, _, err := app.models.Users.GetForToken(r.Context(), data.ScopeActivation, input.TokenPlaintext)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -115,7 +151,112 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 
 	user.Activated = true
 
-	err = app.models.Users.Update(user)
+	err = app.models.Users.Update(r.Context(), user)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Tokens.DeleteAllForUser(r.Context(), data.ScopeActivation, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showCurrentUserHandler(w http.ResponseWriter, r *http.Request) {
+	err := app.writeJSON(w, http.StatusOK, envelope{"user": app.contextGetUser(r)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateUserSettingsHandler changes the profile settings that drive the
+// formatting hints in study and stats responses, the scheduling algorithm,
+// the daily study limits and goal, and the default deck for new cards. A
+// default_deck_id of 0 clears it.
+func (app *application) updateUserSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Locale        *string `json:"locale"`
+		Timezone      *string `json:"timezone"`
+		Scheduler     *string `json:"scheduler"`
+		DefaultDeckID *int64  `json:"default_deck_id"`
+		NewPerDay     *int    `json:"new_cards_per_day"`
+		ReviewsPerDay *int    `json:"reviews_per_day"`
+		DailyGoal     *int    `json:"daily_goal"`
+		DailyGoalUnit *string `json:"daily_goal_unit"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, ok := app.loadCurrentUser(w, r)
+	if !ok {
+		return
+	}
+
+	if input.Locale != nil {
+		user.Locale = *input.Locale
+	}
+	if input.Timezone != nil {
+		user.Timezone = *input.Timezone
+	}
+	if input.Scheduler != nil {
+		user.Scheduler = *input.Scheduler
+	}
+	if input.NewPerDay != nil {
+		user.NewPerDay = input.NewPerDay
+	}
+	if input.ReviewsPerDay != nil {
+		user.ReviewsPerDay = input.ReviewsPerDay
+	}
+	if input.DailyGoal != nil {
+		user.DailyGoal = *input.DailyGoal
+	}
+	if input.DailyGoalUnit != nil {
+		user.DailyGoalUnit = *input.DailyGoalUnit
+	}
+
+	v := validator.New()
+
+	if input.DefaultDeckID != nil {
+		if *input.DefaultDeckID == 0 {
+			user.DefaultDeckID = nil
+		} else {
+			deck, err := app.flashcardDeck(r.Context(), v, user, input.DefaultDeckID)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+			if deck != nil {
+				user.DefaultDeckID = &deck.ID
+			}
+		}
+	}
+
+	data.ValidateScheduler(v, user.Scheduler)
+	data.ValidateStudyLimits(v, user)
+	data.ValidateDailyGoal(v, user)
+
+	if data.ValidateLocaleSettings(v, user); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Users.Update(r.Context(), user)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
@@ -126,14 +267,217 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err = app.models.Tokens.DeleteAllForUser(data.ScopeActivation, user.ID)
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+const emailChangeTTL = 24 * time.Hour
+
+// updateUserEmailHandler starts a change of the user's email address, which
+// needs their password. The address only changes once the token emailed to
+// the new one is confirmed, and the current address is told about the
+// request.
+func (app *application) updateUserEmailHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, ok := app.loadCurrentUser(w, r)
+	if !ok {
+		return
+	}
+
+	v := validator.New()
+
+	data.ValidateEmail(v, input.Email)
+	data.ValidatePasswordPlaintext(v, input.Password)
+	v.Check(!strings.EqualFold(input.Email, user.Email), "email", "must be different from the current email address")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	match, err := user.Password.Matches(input.Password)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !match {
+		app.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	_, err = app.models.Users.GetByEmail(r.Context(), input.Email)
+	switch {
+	case err == nil:
+		v.AddError("email", "a user with this email address already exists")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	case !errors.Is(err, data.ErrRecordNotFound):
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	change, err := app.models.Users.RequestEmailChange(r.Context(), user.ID, input.Email, emailChangeTTL)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.background(func() {
+		templateData := map[string]any{
+			"name":              user.Name,
+			"confirmationToken": change.Plaintext,
+		}
+
+		err := app.mailer.Send(change.Email, "email_change.tmpl", templateData)
+		if err != nil {
+			app.logger.Error(err.Error())
+		}
+
+		templateData = map[string]any{
+			"name":     user.Name,
+			"newEmail": change.Email,
+			"changed":  false,
+		}
+
+		err = app.mailer.Send(user.Email, "email_change_notice.tmpl", templateData)
+		if err != nil {
+			app.logger.Error(err.Error())
+		}
+	})
+
+	err = app.writeJSON(w, http.StatusAccepted, envelope{"email_change": change}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// confirmUserEmailHandler switches the account to the new address with the
+// token sent to it, and lets the previous address know.
+func (app *application) confirmUserEmailHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		TokenPlaintext string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateTokenPlaintext(v, input.TokenPlaintext); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	userID, previous, err := app.models.Users.ConfirmEmailChange(r.Context(), input.TokenPlaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("token", "invalid or expired confirmation token")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrDuplicateEmail):
+			v.AddError("email", "a user with this email address already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.models.Users.Get(r.Context(), userID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	app.background(func() {
+		templateData := map[string]any{
+			"name":     user.Name,
+			"newEmail": user.Email,
+			"changed":  true,
+		}
+
+		err := app.mailer.Send(previous, "email_change_notice.tmpl", templateData)
+		if err != nil {
+			app.logger.Error(err.Error())
+		}
+	})
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// deleteCurrentUserHandler erases the account once the user has confirmed it
+// with their password. The cards they wrote are deleted or anonymized
+// according to the -deleted-account-cards setting.
+func (app *application) deleteCurrentUserHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Password string `json:"password"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidatePasswordPlaintext(v, input.Password); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, ok := app.loadCurrentUser(w, r)
+	if !ok {
+		return
+	}
+
+	match, err := user.Password.Matches(input.Password)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !match {
+		app.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	deleted, err := app.models.Users.Delete(r.Context(), user.ID, app.config.deletedCards == "delete")
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	for _, id := range deleted {
+		app.unmirrorFlashcard(id)
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "account successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/xapi"
+)
+
+func (app *application) flashcardActivity(flashcard *data.Flashcard) xapi.Activity {
+	return xapi.Activity{
+		ObjectType: "Activity",
+		ID:         fmt.Sprintf("%s/flashcards/%d", app.config.xapi.activityBase, flashcard.ID),
+		Definition: &xapi.Definition{
+			Name: map[string]string{"en-US": flashcard.Question},
+			Type: "http://adlnet.gov/expapi/activities/cmi.interaction",
+		},
+	}
+}
+
+// emitStatement sends an xAPI statement for the user's activity on a
+// flashcard to the configured LRS in the background. It is a no-op when no
+// LRS is configured.
+func (app *application) emitStatement(user *data.User, verb xapi.Verb, flashcard *data.Flashcard, result *xapi.Result) {
+	if app.xapi == nil {
+		return
+	}
+
+	statement := xapi.Statement{
+		Actor:     xapi.NewAgent(user.Name, user.Email),
+		Verb:      verb,
+		Object:    app.flashcardActivity(flashcard),
+		Result:    result,
+		Timestamp: time.Now().UTC(),
+	}
+
+	app.background(func() {
+		err := app.xapi.Send(statement)
+		if err != nil {
+			app.logger.Error(err.Error(), "verb", verb.ID, "flashcard_id", flashcard.ID)
+		}
+	})
+}
+
+func (app *application) examActivity(exam *data.Exam) xapi.Activity {
+	return xapi.Activity{
+		ObjectType: "Activity",
+		ID:         fmt.Sprintf("%s/exams/%d", app.config.xapi.activityBase, exam.ID),
+		Definition: &xapi.Definition{
+			Name: map[string]string{"en-US": fmt.Sprintf("Exam %d", exam.ID)},
+			Type: "http://adlnet.gov/expapi/activities/assessment",
+		},
+	}
+}
+
+func (app *application) studySessionActivity(session *data.StudySession) xapi.Activity {
+	return xapi.Activity{
+		ObjectType: "Activity",
+		ID:         fmt.Sprintf("%s/study/sessions/%d", app.config.xapi.activityBase, session.ID),
+		Definition: &xapi.Definition{
+			Name: map[string]string{"en-US": fmt.Sprintf("Study session %d", session.ID)},
+			Type: "http://adlnet.gov/expapi/activities/lesson",
+		},
+	}
+}
+
+// emitCompletion sends a completed statement for an exam or study session
+// with correct answers out of total, and a passed or failed one depending on
+// whether the scaled score reached the passing score. It is a no-op when no
+// LRS is configured.
+func (app *application) emitCompletion(user *data.User, activity xapi.Activity, correct, total int) {
+	if app.xapi == nil {
+		return
+	}
+
+	score := &xapi.Score{Raw: float64(correct), Max: float64(total)}
+	if total > 0 {
+		score.Scaled = float64(correct) / float64(total)
+	}
+
+	completion := true
+	success := total > 0 && score.Scaled >= app.config.xapi.passingScore
+
+	verb := xapi.VerbFailed
+	if success {
+		verb = xapi.VerbPassed
+	}
+
+	actor := xapi.NewAgent(user.Name, user.Email)
+	now := time.Now().UTC()
+
+	statements := []xapi.Statement{
+		{Actor: actor, Verb: xapi.VerbCompleted, Object: activity, Result: &xapi.Result{Completion: &completion, Score: score}, Timestamp: now},
+		{Actor: actor, Verb: verb, Object: activity, Result: &xapi.Result{Success: &success, Score: score}, Timestamp: now},
+	}
+
+	app.background(func() {
+		err := app.xapi.Send(statements...)
+		if err != nil {
+			app.logger.Error(err.Error(), "activity", activity.ID)
+		}
+	})
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// responseFormat picks the representation for a read endpoint: ?format=table
+// wins, then the first of JSON, XML or plain text named in the Accept header.
+func responseFormat(r *http.Request) string {
+	if r.URL.Query().Get("format") == "table" {
+		return "table"
+	}
+
+	for part := range strings.SplitSeq(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		switch mediaType {
+		case "application/json":
+			return "json"
+		case "application/xml", "text/xml":
+			return "xml"
+		case "text/plain":
+			return "table"
+		}
+	}
+
+	return "json"
+}
+
+// writeResponse writes data in the format the client negotiated, falling back
+// to JSON. It is used by the read endpoints so they can also be consumed by
+// XML-only clients and from the shell.
+func (app *application) writeResponse(w http.ResponseWriter, r *http.Request, status int, data any, headers http.Header) error {
+	w.Header().Add("Vary", "Accept")
+
+	switch responseFormat(r) {
+	case "xml":
+		return app.writeXML(w, status, data, headers)
+	case "table":
+		return app.writeTable(w, status, data, headers)
+	default:
+		return app.writeJSON(w, status, data, headers)
+	}
+}
+
+// writeXML renders data by walking its JSON encoding, so the XML output uses
+// the same field names as the JSON API and polymorphic values such as
+// flashcard_content are rendered with the fields of their concrete type.
+func (app *application) writeXML(w http.ResponseWriter, status int, data any, headers http.Header) error {
+	js, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.UseNumber()
+
+	buf := new(bytes.Buffer)
+	buf.WriteString(xml.Header)
+
+	enc := xml.NewEncoder(buf)
+	enc.Indent("", "\t")
+
+	err = encodeXMLValue(enc, dec, "response")
+	if err != nil {
+		return err
+	}
+
+	err = enc.Flush()
+	if err != nil {
+		return err
+	}
+
+	buf.WriteByte('\n')
+
+	for key, values := range headers {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+
+	return nil
+}
+
+func encodeXMLValue(enc *xml.Encoder, dec *json.Decoder, name string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	start := xmlStartElement(name)
+
+	switch t := tok.(type) {
+	case json.Delim:
+		err = enc.EncodeToken(start)
+		if err != nil {
+			return err
+		}
+
+		switch t {
+		case '{':
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+
+				err = encodeXMLValue(enc, dec, key.(string))
+				if err != nil {
+					return err
+				}
+			}
+
+		case '[':
+			for dec.More() {
+				err = encodeXMLValue(enc, dec, "item")
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		// Consume the closing delimiter.
+		_, err = dec.Token()
+		if err != nil {
+			return err
+		}
+
+		return enc.EncodeToken(start.End())
+
+	case nil:
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "nil"}, Value: "true"})
+		return enc.EncodeElement("", start)
+
+	default:
+		return enc.EncodeElement(fmt.Sprint(t), start)
+	}
+}
+
+// xmlStartElement returns an element for a JSON key, falling back to an
+// <entry key="..."> element when the key is not a valid XML name.
+func xmlStartElement(name string) xml.StartElement {
+	valid := name != ""
+
+	for i, r := range name {
+		if !(unicode.IsLetter(r) || r == '_' || (i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.'))) {
+			valid = false
+			break
+		}
+	}
+
+	if valid {
+		return xml.StartElement{Name: xml.Name{Local: name}}
+	}
+
+	return xml.StartElement{
+		Name: xml.Name{Local: "entry"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: name}},
+	}
+}
@@ -0,0 +1,203 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/validator"
+	"github.com/lib/pq"
+)
+
+// apiKeyPrefix marks API keys so they can't be mistaken for authentication
+// tokens, and so leaked keys are easy to search for.
+const apiKeyPrefix = "fck_"
+
+// APIKey is a long-lived credential for scripts and CI jobs. It always has
+// scopes, so it can never change the account it belongs to.
+type APIKey struct {
+	ID         int64       `json:"id"`
+	UserID     int64       `json:"-"`
+	Name       string      `json:"name"`
+	Prefix     string      `json:"prefix"`
+	Scopes     Permissions `json:"scopes"`
+	ExpiresAt  *time.Time  `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time  `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+
+	Plaintext string `json:"key,omitempty"`
+}
+
+func ValidateAPIKey(v *validator.Validator, key *APIKey) {
+	v.Check(strings.TrimSpace(key.Name) != "", "name", "must be provided")
+	v.Check(len(key.Name) <= 100, "name", "must not be more than 100 bytes long")
+
+	ValidateTokenScopes(v, key.Scopes)
+
+	if key.ExpiresAt != nil {
+		v.Check(key.ExpiresAt.After(time.Now()), "expires_at", "must be in the future")
+	}
+}
+
+func ValidateAPIKeyPlaintext(v *validator.Validator, plaintext string) {
+	v.Check(strings.HasPrefix(plaintext, apiKeyPrefix), "key", "must be an API key")
+	v.Check(len(plaintext) == len(apiKeyPrefix)+26, "key", "must be 30 bytes long")
+}
+
+type APIKeyModel struct {
+	DB *sql.DB
+}
+
+// Insert generates the key's plaintext, which is only available on the
+// returned key, and stores its hash.
+func (m APIKeyModel) Insert(ctx context.Context, key *APIKey) error {
+	key.Plaintext = apiKeyPrefix + rand.Text()
+	key.Prefix = key.Plaintext[:len(apiKeyPrefix)+4]
+
+	hash := sha256.Sum256([]byte(key.Plaintext))
+
+	query := `
+        INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id, created_at`
+
+	args := []any{key.UserID, key.Name, key.Prefix, hash[:], pq.Array(key.Scopes), key.ExpiresAt}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&key.ID, &key.CreatedAt)
+}
+
+// GetAllForUser returns the user's keys, expired ones included, newest first.
+func (m APIKeyModel) GetAllForUser(ctx context.Context, userID int64) ([]*APIKey, error) {
+	query := `
+        SELECT id, user_id, name, prefix, scopes, expires_at, last_used_at, created_at
+        FROM api_keys
+        WHERE user_id = $1
+        ORDER BY created_at DESC, id DESC`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*APIKey{}
+
+	for rows.Next() {
+		var key APIKey
+		var scopes []string
+
+		err := rows.Scan(
+			&key.ID,
+			&key.UserID,
+			&key.Name,
+			&key.Prefix,
+			pq.Array(&scopes),
+			&key.ExpiresAt,
+			&key.LastUsedAt,
+			&key.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		key.Scopes = Permissions(scopes)
+
+		keys = append(keys, &key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Delete revokes one of the user's keys.
+func (m APIKeyModel) Delete(ctx context.Context, id, userID int64) error {
+	query := `DELETE FROM api_keys WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetUserForKey returns the owner of an unexpired key and the key's scopes,
+// and records that the key has been used.
+func (m APIKeyModel) GetUserForKey(ctx context.Context, plaintext string) (*User, Permissions, error) {
+	hash := sha256.Sum256([]byte(plaintext))
+
+	query := `
+        WITH key AS (
+            UPDATE api_keys
+            SET last_used_at = NOW()
+            WHERE key_hash = $1 AND (expires_at IS NULL OR expires_at > NOW())
+            RETURNING user_id, scopes
+        )
+        SELECT users.id, users.created_at, users.name, users.email, users.password_hash, users.activated, users.locale, users.timezone, users.scheduler, users.default_deck_id, users.new_per_day, users.reviews_per_day, users.daily_goal, users.daily_goal_unit, users.version, key.scopes
+        FROM users
+        INNER JOIN key ON users.id = key.user_id`
+
+	var user User
+
+	// lib/pq can't scan into a named slice type, so the scopes are read as
+	// a plain []string.
+	var scopes []string
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, hash[:]).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Password.hash,
+		&user.Activated,
+		&user.Locale,
+		&user.Timezone,
+		&user.Scheduler,
+		&user.DefaultDeckID,
+		&user.NewPerDay,
+		&user.ReviewsPerDay,
+		&user.DailyGoal,
+		&user.DailyGoalUnit,
+		&user.Version,
+		pq.Array(&scopes),
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil, ErrRecordNotFound
+		default:
+			return nil, nil, err
+		}
+	}
+
+	return &user, Permissions(scopes), nil
+}
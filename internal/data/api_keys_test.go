@@ -0,0 +1,48 @@
+package data
+
+import (
+	"context"
+	"database/sql/driver"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAPIKeyScopesRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	scopes := Permissions{"flashcards:read", "study:write"}
+
+	db, fake := newFakeDB(t, [][]driver.Value{{int64(1), now}})
+	keys := APIKeyModel{DB: db}
+
+	err := keys.Insert(ctx, &APIKey{UserID: 7, Name: "ci", Scopes: scopes})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Hand the stored array back exactly as PostgreSQL would return it.
+	stored := []byte(fake.args[0][4].(string))
+
+	fake.queue([]driver.Value{int64(1), int64(7), "ci", "fck_ABCD", stored, nil, nil, now})
+
+	got, err := keys.GetAllForUser(ctx, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 1 || !slices.Equal(got[0].Scopes, scopes) {
+		t.Errorf("GetAllForUser scopes = %v; want %v", got, scopes)
+	}
+
+	fake.queue([]driver.Value{int64(7), now, "Alice", "alice@example.com", []byte("hash"), true, "en", "UTC", "", nil, nil, nil, int64(20), "cards", int64(1), stored})
+
+	_, userScopes, err := keys.GetUserForKey(ctx, "fck_ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(userScopes, scopes) {
+		t.Errorf("GetUserForKey scopes = %v; want %v", userScopes, scopes)
+	}
+}
@@ -0,0 +1,197 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	AttachmentImage = "image"
+	AttachmentAudio = "audio"
+)
+
+type Attachment struct {
+	ID          int64     `json:"id"`
+	FlashcardID int64     `json:"flashcard_id"`
+	UserID      int64     `json:"-"`
+	Kind        string    `json:"kind"`
+	StorageKey  string    `json:"-"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size_bytes"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	// Signed, time-limited download URL, filled in by the API layer.
+	URL string `json:"url"`
+}
+
+type AttachmentModel struct {
+	DB *sql.DB
+}
+
+func (m AttachmentModel) Insert(ctx context.Context, attachment *Attachment) error {
+	query := `
+        INSERT INTO attachments (flashcard_id, user_id, kind, storage_key, filename, content_type, size_bytes)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING id, created_at`
+
+	args := []any{
+		attachment.FlashcardID,
+		attachment.UserID,
+		attachment.Kind,
+		attachment.StorageKey,
+		attachment.Filename,
+		attachment.ContentType,
+		attachment.Size,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&attachment.ID, &attachment.CreatedAt)
+}
+
+func (m AttachmentModel) Get(ctx context.Context, id, flashcardID int64) (*Attachment, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+        SELECT id, flashcard_id, user_id, kind, storage_key, filename, content_type, size_bytes, created_at
+        FROM attachments
+        WHERE id = $1 AND flashcard_id = $2`
+
+	var a Attachment
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id, flashcardID).Scan(
+		&a.ID, &a.FlashcardID, &a.UserID, &a.Kind, &a.StorageKey,
+		&a.Filename, &a.ContentType, &a.Size, &a.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &a, nil
+}
+
+func (m AttachmentModel) GetAllForFlashcards(ctx context.Context, flashcardIDs []int64) ([]*Attachment, error) {
+	query := `
+        SELECT id, flashcard_id, user_id, kind, storage_key, filename, content_type, size_bytes, created_at
+        FROM attachments
+        WHERE flashcard_id = ANY($1)
+        ORDER BY flashcard_id, id`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(flashcardIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []*Attachment{}
+
+	for rows.Next() {
+		var a Attachment
+
+		err := rows.Scan(
+			&a.ID, &a.FlashcardID, &a.UserID, &a.Kind, &a.StorageKey,
+			&a.Filename, &a.ContentType, &a.Size, &a.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		attachments = append(attachments, &a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+func (m AttachmentModel) Delete(ctx context.Context, id int64) error {
+	query := `DELETE FROM attachments WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetPendingDeletions returns storage keys of removed attachments whose blobs
+// have not been deleted yet, oldest first.
+func (m AttachmentModel) GetPendingDeletions(ctx context.Context, limit int) ([]string, error) {
+	query := `
+        SELECT storage_key
+        FROM attachment_deletions
+        ORDER BY deleted_at
+        LIMIT $1`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []string{}
+
+	for rows.Next() {
+		var key string
+
+		err := rows.Scan(&key)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (m AttachmentModel) ClearPendingDeletion(ctx context.Context, storageKey string) error {
+	query := `DELETE FROM attachment_deletions WHERE storage_key = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, storageKey)
+	return err
+}
@@ -0,0 +1,618 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/validator"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrDuplicateCategoryName = errors.New("duplicate category name")
+)
+
+// ColourRX matches a #RRGGBB hex colour.
+var ColourRX = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// NormalizeCategory trims and lowercases a category name and collapses runs
+// of whitespace, so " Intellectual  Property" and "intellectual property"
+// are the same category. Every category name is stored in this form. It
+// lowercases rather than case-folds, like lower() in the normalize_category
+// SQL function that backfilled the existing names, so both agree.
+func NormalizeCategory(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// NormalizeCategories normalizes the names, dropping empty ones and
+// duplicates and keeping the first occurrence of each.
+func NormalizeCategories(names []string) []string {
+	normalized := make([]string, 0, len(names))
+
+	for _, name := range names {
+		name = NormalizeCategory(name)
+		if name != "" && !slices.Contains(normalized, name) {
+			normalized = append(normalized, name)
+		}
+	}
+
+	return normalized
+}
+
+// ValidateCategoryNames checks a list of category names given under key.
+func ValidateCategoryNames(v *validator.Validator, key string, names []string) {
+	v.Check(!slices.ContainsFunc(names, func(name string) bool { return NormalizeCategory(name) == "" }), key, "must not contain empty categories")
+	v.Check(!slices.ContainsFunc(names, func(name string) bool { return len(name) > 100 }), key, "must not contain categories more than 100 bytes long")
+}
+
+// Category is a managed category. Cards still carry their category names in
+// flashcards.categories; a trigger creates the category the first time a name
+// is used and keeps the flashcard_categories join rows in step.
+type Category struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Slug        string `json:"slug"`
+	Description string `json:"description"`
+
+	// Badge styling for study UIs: a #RRGGBB colour and an icon name or
+	// emoji, both empty when unset
+	Colour string `json:"colour"`
+	Icon   string `json:"icon"`
+
+	Keywords  []string  `json:"keywords"`
+	CardCount int       `json:"card_count"`
+	Version   int32     `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func ValidateCategory(v *validator.Validator, c *Category) {
+	v.Check(NormalizeCategory(c.Name) != "", "name", "must be provided")
+	v.Check(len(c.Name) <= 100, "name", "must not be more than 100 bytes long")
+	v.Check(len(c.Description) <= 1000, "description", "must not be more than 1000 bytes long")
+	v.Check(c.Colour == "" || validator.Matches(c.Colour, ColourRX), "colour", "must be a hex colour like #1a2b3c")
+	v.Check(len(c.Icon) <= 50, "icon", "must not be more than 50 bytes long")
+	v.Check(len(c.Keywords) <= 20, "keywords", "must not contain more than 20 keywords")
+	v.Check(validator.Unique(c.Keywords), "keywords", "must not contain duplicate values")
+
+	for _, keyword := range c.Keywords {
+		v.Check(strings.TrimSpace(keyword) != "", "keywords", "must not contain empty keywords")
+		v.Check(len(keyword) <= 100, "keywords", "must not contain keywords more than 100 bytes long")
+	}
+}
+
+// CategoryStats breaks a category's cards down by type, together with the
+// requesting user's review record. Accuracy is null until the user has
+// reviewed a card in the category.
+type CategoryStats struct {
+	Name      string         `json:"name"`
+	CardCount int            `json:"card_count"`
+	ByType    map[string]int `json:"by_type"`
+	Mastered  int            `json:"mastered"`
+	Reviews   int            `json:"reviews"`
+	Correct   int            `json:"correct"`
+	Accuracy  *float64       `json:"accuracy"`
+}
+
+type CategoryModel struct {
+	DB *sql.DB
+}
+
+const categoryColumns = `
+            c.id, c.name, c.slug, c.description, c.colour, c.icon, c.keywords,
+            (SELECT count(*) FROM flashcard_categories fc WHERE fc.category_id = c.id) AS card_count,
+            c.version, c.created_at`
+
+func (c *Category) scanTargets() []any {
+	return []any{&c.ID, &c.Name, &c.Slug, &c.Description, &c.Colour, &c.Icon, pq.Array(&c.Keywords), &c.CardCount, &c.Version, &c.CreatedAt}
+}
+
+func (m CategoryModel) Insert(ctx context.Context, c *Category) error {
+	c.Name = NormalizeCategory(c.Name)
+
+	query := `
+        INSERT INTO categories (name, description, colour, icon, keywords)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, slug, version, created_at`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, c.Name, c.Description, c.Colour, c.Icon, pq.Array(c.Keywords)).Scan(&c.ID, &c.Slug, &c.Version, &c.CreatedAt)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "categories_name_key"):
+			return ErrDuplicateCategoryName
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m CategoryModel) Get(ctx context.Context, id int64) (*Category, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `SELECT ` + categoryColumns + ` FROM categories c WHERE c.id = $1`
+
+	var c Category
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(c.scanTargets()...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &c, nil
+}
+
+func (m CategoryModel) GetByName(ctx context.Context, name string) (*Category, error) {
+	query := `SELECT ` + categoryColumns + ` FROM categories c WHERE c.name = $1`
+
+	var c Category
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, NormalizeCategory(name)).Scan(c.scanTargets()...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &c, nil
+}
+
+// GetByNames returns the categories with the given names, for expanding the
+// categories of flashcards.
+func (m CategoryModel) GetByNames(ctx context.Context, names []string) ([]*Category, error) {
+	query := `SELECT ` + categoryColumns + ` FROM categories c WHERE c.name = ANY($1) ORDER BY c.name`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(NormalizeCategories(names)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []*Category{}
+
+	for rows.Next() {
+		var c Category
+
+		err := rows.Scan(c.scanTargets()...)
+		if err != nil {
+			return nil, err
+		}
+
+		categories = append(categories, &c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+// GetAll lists the categories whose name starts with prefix, ignoring case.
+// An empty prefix lists every category.
+func (m CategoryModel) GetAll(ctx context.Context, prefix string, filters Filters) ([]*Category, Metadata, error) {
+	query := fmt.Sprintf(`
+        SELECT count(*) OVER(), %s
+        FROM categories c
+        WHERE c.name ILIKE $3
+        ORDER BY %s
+        LIMIT $1 OFFSET $2`, categoryColumns, filters.orderBy("c.id"))
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, filters.limit(), filters.offset(), likeEscaper.Replace(prefix)+"%")
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	categories := []*Category{}
+
+	for rows.Next() {
+		var c Category
+
+		err := rows.Scan(append([]any{&totalRecords}, c.scanTargets()...)...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		categories = append(categories, &c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return categories, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// Update saves the name and description. A rename is applied to every card
+// tagged with the old name in the same transaction, and the ids of the cards
+// changed are returned.
+func (m CategoryModel) Update(ctx context.Context, c *Category) ([]int64, error) {
+	c.Name = NormalizeCategory(c.Name)
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+        UPDATE categories c
+        SET name = $1, description = $2, colour = $3, icon = $4, keywords = $5, version = c.version + 1
+        FROM (SELECT name FROM categories WHERE id = $6 FOR UPDATE) old
+        WHERE c.id = $6 AND c.version = $7
+        RETURNING c.version, c.slug, old.name`
+
+	var oldName string
+
+	err = tx.QueryRowContext(ctx, query, c.Name, c.Description, c.Colour, c.Icon, pq.Array(c.Keywords), c.ID, c.Version).Scan(&c.Version, &c.Slug, &oldName)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrEditConflict
+		case strings.Contains(err.Error(), "categories_name_key"):
+			return nil, ErrDuplicateCategoryName
+		default:
+			return nil, err
+		}
+	}
+
+	changed := []int64{}
+
+	if oldName != c.Name {
+		query = `
+            WITH changed AS (
+                UPDATE flashcards
+                SET categories = array_replace(categories, $1, $2), version = version + 1
+                WHERE $1 = ANY(categories)
+                RETURNING id
+            )
+            SELECT COALESCE(array_agg(id), '{}') FROM changed`
+
+		err = tx.QueryRowContext(ctx, query, oldName, c.Name).Scan(pq.Array(&changed))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return changed, tx.Commit()
+}
+
+// Delete removes the category and strips its name from every card. It
+// returns the ids of the cards changed.
+func (m CategoryModel) Delete(ctx context.Context, id int64) ([]int64, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var name string
+
+	err = tx.QueryRowContext(ctx, `SELECT name FROM categories WHERE id = $1 FOR UPDATE`, id).Scan(&name)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	query := `
+        WITH changed AS (
+            UPDATE flashcards
+            SET categories = array_remove(categories, $1), version = version + 1
+            WHERE $1 = ANY(categories)
+            RETURNING id
+        )
+        SELECT COALESCE(array_agg(id), '{}') FROM changed`
+
+	var changed []int64
+
+	err = tx.QueryRowContext(ctx, query, name).Scan(pq.Array(&changed))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM categories WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return changed, tx.Commit()
+}
+
+// Merge retags every card in category from with category into, keeping each
+// card's categories free of duplicates and in their original order, then
+// deletes from. It returns the ids of the cards changed.
+func (m CategoryModel) Merge(ctx context.Context, from, into string) ([]int64, error) {
+	from, into = NormalizeCategory(from), NormalizeCategory(into)
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var locked int
+
+	query := `
+        WITH locked AS (
+            SELECT id FROM categories WHERE name IN ($1, $2) FOR UPDATE
+        )
+        SELECT count(*) FROM locked`
+
+	err = tx.QueryRowContext(ctx, query, from, into).Scan(&locked)
+	if err != nil {
+		return nil, err
+	}
+
+	if locked != 2 {
+		return nil, ErrRecordNotFound
+	}
+
+	query = `
+        WITH changed AS (
+            UPDATE flashcards f
+            SET categories = ARRAY(
+                    SELECT c
+                    FROM unnest(array_replace(f.categories, $1, $2)) WITH ORDINALITY AS t(c, ord)
+                    GROUP BY c
+                    ORDER BY min(ord)
+                ),
+                version = version + 1
+            WHERE $1 = ANY(f.categories)
+            RETURNING f.id
+        )
+        SELECT COALESCE(array_agg(id), '{}') FROM changed`
+
+	var changed []int64
+
+	err = tx.QueryRowContext(ctx, query, from, into).Scan(pq.Array(&changed))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM categories WHERE name = $1`, from)
+	if err != nil {
+		return nil, err
+	}
+
+	return changed, tx.Commit()
+}
+
+func (m CategoryModel) GetStats(ctx context.Context, userID int64) ([]*CategoryStats, error) {
+	query := `
+        WITH types AS (
+            SELECT category_id, sum(n) AS total, jsonb_object_agg(flashcard_type, n) AS by_type
+            FROM (
+                SELECT fc.category_id, f.flashcard_type, count(*) AS n
+                FROM flashcard_categories fc
+                INNER JOIN flashcards f ON f.id = fc.flashcard_id
+                GROUP BY fc.category_id, f.flashcard_type
+            ) t
+            GROUP BY category_id
+        ), mastered AS (
+            SELECT fc.category_id, count(*) AS n
+            FROM flashcard_categories fc
+            INNER JOIN user_flashcards uf ON uf.flashcard_id = fc.flashcard_id
+            WHERE uf.user_id = $1 AND uf.status = 'mastered'
+            GROUP BY fc.category_id
+        ), reviews AS (
+            SELECT fc.category_id, count(*) AS n, count(*) FILTER (WHERE r.correct) AS correct
+            FROM flashcard_categories fc
+            INNER JOIN flashcard_reviews r ON r.flashcard_id = fc.flashcard_id
+            WHERE r.user_id = $1
+            GROUP BY fc.category_id
+        )
+        SELECT c.name, coalesce(t.total, 0), coalesce(t.by_type, '{}'),
+            coalesce(ms.n, 0), coalesce(rv.n, 0), coalesce(rv.correct, 0)
+        FROM categories c
+        LEFT JOIN types t ON t.category_id = c.id
+        LEFT JOIN mastered ms ON ms.category_id = c.id
+        LEFT JOIN reviews rv ON rv.category_id = c.id
+        ORDER BY c.name`
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []*CategoryStats{}
+
+	for rows.Next() {
+		var s CategoryStats
+		var byType []byte
+
+		err := rows.Scan(&s.Name, &s.CardCount, &byType, &s.Mastered, &s.Reviews, &s.Correct)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal(byType, &s.ByType)
+		if err != nil {
+			return nil, err
+		}
+
+		if s.Reviews > 0 {
+			accuracy := float64(s.Correct) / float64(s.Reviews)
+			s.Accuracy = &accuracy
+		}
+
+		stats = append(stats, &s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// Retag adds and removes categories on the user's matching cards in one
+// statement, keeping each card's categories unique and in order. Cards are
+// matched by ids when any are given, and by filter otherwise. It returns the
+// ids of the cards that changed.
+func (m FlashcardModel) Retag(ctx context.Context, userID int64, ids []int64, filter FlashcardFilter, add, remove []string) ([]int64, error) {
+	where, args := filter.where(userID)
+
+	args = append(args, pq.Array(ids), pq.Array(NormalizeCategories(add)), pq.Array(NormalizeCategories(remove)))
+	n := len(args) - 2
+
+	query := fmt.Sprintf(`
+        UPDATE flashcards
+        SET categories = ARRAY(
+                SELECT c
+                FROM unnest(flashcards.categories || $%[2]d::text[]) WITH ORDINALITY AS t(c, ord)
+                WHERE NOT c = ANY($%[3]d::text[])
+                GROUP BY c
+                ORDER BY min(ord)
+            ),
+            version = version + 1
+        WHERE (categories && $%[3]d::text[] OR NOT categories @> $%[2]d::text[])
+        AND id IN (
+            SELECT f.id
+            FROM flashcards f
+            LEFT JOIN user_flashcards uf ON f.id = uf.flashcard_id AND uf.user_id = $1
+            WHERE %[4]s
+            AND (cardinality($%[1]d::bigint[]) = 0 OR f.id = ANY($%[1]d::bigint[]))
+        )
+        RETURNING id`, n, n+1, n+2, where)
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changed := []int64{}
+
+	for rows.Next() {
+		var id int64
+
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+
+		changed = append(changed, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return changed, nil
+}
+
+// SuggestCategories proposes up to five categories for a card it doesn't
+// already have. Categories with a keyword found in the question or text come
+// first, then those of the most similar tagged questions by trigram
+// similarity, most frequent first.
+func (m FlashcardModel) SuggestCategories(ctx context.Context, flashcard *Flashcard) ([]string, error) {
+	query := `
+        WITH neighbours AS (
+            SELECT f.categories
+            FROM flashcards f
+            WHERE f.question % $1 AND f.categories <> '{}'
+            ORDER BY similarity(f.question, $1) DESC
+            LIMIT 5
+        ), scored AS (
+            SELECT c.name, 10 AS score
+            FROM categories c
+            WHERE EXISTS (SELECT 1 FROM unnest(c.keywords) k WHERE strpos(lower($2), lower(k)) > 0)
+            UNION ALL
+            SELECT unnest(n.categories), 1
+            FROM neighbours n
+        )
+        SELECT name
+        FROM scored
+        WHERE NOT name = ANY($3)
+        GROUP BY name
+        ORDER BY sum(score) DESC, name
+        LIMIT 5`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	text := flashcard.Question + "\n" + flashcard.Text
+
+	rows, err := m.DB.QueryContext(ctx, query, flashcard.Question, text, pq.Array(NormalizeCategories(flashcard.Categories)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []string{}
+
+	for rows.Next() {
+		var name string
+
+		err := rows.Scan(&name)
+		if err != nil {
+			return nil, err
+		}
+
+		suggestions = append(suggestions, name)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
@@ -0,0 +1,264 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrUnsupportedConversion = errors.New("unsupported conversion")
+	ErrNotEnoughDistractors  = errors.New("not enough distractors")
+)
+
+// mcqDistractors is how many wrong options a converted MCQ card gets when
+// the section has enough sibling cards.
+const mcqDistractors = 3
+
+// conversions lists the card types each type can be converted to.
+var conversions = map[FlashcardType][]FlashcardType{
+	FlashcardQA:      {FlashcardMCQ},
+	FlashcardMCQ:     {FlashcardQA},
+	FlashcardYesNo:   {FlashcardQA},
+	FlashcardNumeric: {FlashcardQA, FlashcardMCQ},
+}
+
+// distractorSources lists the sibling card types whose answers can stand in
+// as wrong options when converting a card of the given type to MCQ.
+var distractorSources = map[FlashcardType][]FlashcardType{
+	FlashcardQA:      {FlashcardQA, FlashcardMCQ},
+	FlashcardNumeric: {FlashcardNumeric},
+}
+
+// CanConvert reports whether a card of type from can be converted to type to.
+func CanConvert(from, to FlashcardType) bool {
+	return slices.Contains(conversions[from], to)
+}
+
+// ConversionResetsProgress reports whether review progress should be reset
+// when converting between the two types. Recognising an answer among options
+// says little about recalling it, so moving from MCQ or yes/no to a recall
+// type starts the card over; every other conversion keeps progress.
+func ConversionResetsProgress(from, to FlashcardType) bool {
+	recognition := func(t FlashcardType) bool {
+		return t == FlashcardMCQ || t == FlashcardYesNo
+	}
+
+	return recognition(from) && !recognition(to)
+}
+
+// AnswerText returns the correct answer of a card as display text.
+func AnswerText(content FlashcardContent) string {
+	switch c := content.(type) {
+	case QAContent:
+		return c.Answer
+	case MCQContent:
+		option, _ := c.CorrectOption()
+		return option.Text
+	case YesNoContent:
+		if c.Correct {
+			return "Yes"
+		}
+		return "No"
+	case NumericContent:
+		answer := strconv.FormatFloat(c.Answer, 'f', -1, 64)
+		if c.Unit != "" {
+			answer += " " + c.Unit
+		}
+		return answer
+	}
+	return ""
+}
+
+// ConvertContent transforms content to the given type. Converting to MCQ
+// uses the correct answer plus up to mcqDistractors of the given wrong
+// answers, in random order.
+func ConvertContent(content FlashcardContent, to FlashcardType, distractors []string) (FlashcardContent, error) {
+	answer := AnswerText(content)
+	justification := Justification(content)
+
+	switch to {
+	case FlashcardQA:
+		qa := QAContent{Answer: answer, Justification: justification}
+		if _, ok := content.(YesNoContent); ok {
+			qa.MatchMode = MatchCaseInsensitive
+		}
+		return qa, nil
+
+	case FlashcardMCQ:
+		texts := []string{answer}
+		for _, d := range distractors {
+			if len(texts) > mcqDistractors {
+				break
+			}
+			if !containsFold(texts, d) {
+				texts = append(texts, d)
+			}
+		}
+
+		if len(texts) < 2 {
+			return nil, ErrNotEnoughDistractors
+		}
+
+		// Shuffle before assigning ids so the correct option isn't always o1.
+		rand.Shuffle(len(texts), func(i, j int) {
+			texts[i], texts[j] = texts[j], texts[i]
+		})
+
+		mcq := MCQContent{Justification: justification}
+		for _, text := range texts {
+			mcq.Options = append(mcq.Options, MCQOption{Text: text})
+		}
+		mcq.AssignOptionIDs()
+
+		for _, option := range mcq.Options {
+			if option.Text == answer {
+				mcq.CorrectOptionID = option.ID
+			}
+		}
+
+		return mcq, nil
+	}
+
+	return nil, ErrUnsupportedConversion
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), strings.TrimSpace(s)) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetDistractors returns the answers of other cards in the same section and
+// source file that can serve as wrong options for the flashcard, shuffled.
+func (m FlashcardModel) GetDistractors(ctx context.Context, flashcard *Flashcard) ([]string, error) {
+	if flashcard.Section == nil {
+		return nil, nil
+	}
+
+	query := `
+        SELECT flashcard_type, flashcard_content
+        FROM flashcards
+        WHERE section = $1 AND source_file IS NOT DISTINCT FROM $2 AND id <> $3 AND flashcard_type = ANY($4)`
+
+	types := make([]string, len(distractorSources[flashcard.Type]))
+	for i, t := range distractorSources[flashcard.Type] {
+		types[i] = string(t)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, *flashcard.Section, flashcard.SourceFile, flashcard.ID, pq.Array(types))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var answers []string
+
+	for rows.Next() {
+		var flashcardType FlashcardType
+		var contentJSON []byte
+
+		err := rows.Scan(&flashcardType, &contentJSON)
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := unmarshalContent(flashcardType, contentJSON)
+		if err != nil {
+			return nil, err
+		}
+
+		if answer := AnswerText(content); answer != "" {
+			answers = append(answers, answer)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rand.Shuffle(len(answers), func(i, j int) {
+		answers[i], answers[j] = answers[j], answers[i]
+	})
+
+	return answers, nil
+}
+
+// Convert saves the flashcard's current type and content as a revision and
+// replaces them with the converted ones. The card keeps its id, so review
+// progress carries over unless resetProgress is set.
+func (m FlashcardModel) Convert(ctx context.Context, flashcard *Flashcard, resetProgress bool) error {
+	contentJSON, err := json.Marshal(flashcard.Content)
+	if err != nil {
+		return fmt.Errorf("failed to marshal flashcard content: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        INSERT INTO flashcard_revisions (flashcard_id, version, question, flashcard_type, flashcard_content)
+        SELECT id, version, question, flashcard_type, flashcard_content
+        FROM flashcards
+        WHERE id = $1 AND version = $2
+        ON CONFLICT DO NOTHING`
+
+	_, err = tx.ExecContext(ctx, query, flashcard.ID, flashcard.Version)
+	if err != nil {
+		return err
+	}
+
+	query = `
+        UPDATE flashcards
+        SET flashcard_type = $1, flashcard_content = $2, version = version + 1
+        WHERE id = $3 AND version = $4
+        RETURNING version`
+
+	err = tx.QueryRowContext(ctx, query, flashcard.Type, contentJSON, flashcard.ID, flashcard.Version).Scan(&flashcard.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	if resetProgress {
+		query = `
+            UPDATE user_flashcards
+            SET correct_count = 0, status = 'not_started'
+            WHERE flashcard_id = $1`
+
+		_, err = tx.ExecContext(ctx, query, flashcard.ID)
+		if err != nil {
+			return err
+		}
+
+		flashcard.CorrectCount = 0
+		flashcard.Status = "not_started"
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,202 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	ErrExportInProgress = errors.New("export in progress")
+)
+
+// DataExport is the archive of everything held about a user. Users have at
+// most one: asking for a new export replaces the previous archive.
+type DataExport struct {
+	Status      string     `json:"status"`
+	SizeBytes   *int64     `json:"size_bytes,omitempty"`
+	Error       *string    `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	URL         string     `json:"url,omitempty"`
+
+	StorageKey *string `json:"-"`
+}
+
+// ExportFile is one JSON document of a data export archive.
+type ExportFile struct {
+	Name string
+	Data json.RawMessage
+}
+
+// exportFiles are the archive's documents and the queries for their rows,
+// which select a single jsonb value and take the user's id as $1. Secrets and
+// hashes are left out.
+var exportFiles = []struct {
+	name  string
+	query string
+}{
+	{"profile.json", `SELECT to_jsonb(u) - 'password_hash' - 'password_scheme' - 'version' FROM users u WHERE u.id = $1`},
+	{"roles.json", `SELECT jsonb_build_object('role', r.name, 'granted_at', ur.granted_at) FROM users_roles ur INNER JOIN roles r ON r.id = ur.role_id WHERE ur.user_id = $1`},
+	{"flashcards.json", `SELECT to_jsonb(f) - 'search_vector' - 'question_normalized' FROM flashcards f WHERE f.user_id = $1 ORDER BY f.id`},
+	{"progress.json", `SELECT to_jsonb(uf) FROM user_flashcards uf WHERE uf.user_id = $1 ORDER BY uf.flashcard_id`},
+	{"card_states.json", `SELECT to_jsonb(cs) FROM user_card_state cs WHERE cs.user_id = $1 ORDER BY cs.flashcard_id`},
+	{"reviews.json", `SELECT to_jsonb(cr) FROM card_reviews cr WHERE cr.user_id = $1 ORDER BY cr.reviewed_at, cr.id`},
+	{"answers.json", `SELECT to_jsonb(fr) FROM flashcard_reviews fr WHERE fr.user_id = $1 ORDER BY fr.reviewed_at, fr.id`},
+	{"study_sessions.json", `
+        SELECT to_jsonb(s) || jsonb_build_object('answers', COALESCE((
+            SELECT jsonb_agg(to_jsonb(a) ORDER BY a.answered_at) FROM study_session_answers a WHERE a.session_id = s.id
+        ), '[]'))
+        FROM study_sessions s WHERE s.user_id = $1 ORDER BY s.id`},
+	{"exams.json", `
+        SELECT to_jsonb(e) || jsonb_build_object('questions', COALESCE((
+            SELECT jsonb_agg(to_jsonb(q) ORDER BY q.position) FROM exam_questions q WHERE q.exam_id = e.id
+        ), '[]'))
+        FROM exams e WHERE e.user_id = $1 ORDER BY e.id`},
+	{"decks.json", `SELECT to_jsonb(d) || jsonb_build_object('flashcard_ids', ARRAY(
+            SELECT df.flashcard_id FROM deck_flashcards df WHERE df.deck_id = d.id ORDER BY df.position
+        )) FROM decks d WHERE d.user_id = $1 ORDER BY d.id`},
+	{"deck_memberships.json", `SELECT to_jsonb(dm) FROM deck_members dm WHERE dm.user_id = $1 ORDER BY dm.added_at`},
+	{"saved_searches.json", `SELECT to_jsonb(ss) FROM saved_searches ss WHERE ss.user_id = $1 ORDER BY ss.id`},
+	{"views.json", `SELECT to_jsonb(fv) FROM flashcard_views fv WHERE fv.user_id = $1`},
+	{"reports.json", `SELECT to_jsonb(r) FROM flashcard_reports r WHERE r.user_id = $1 ORDER BY r.id`},
+	{"attachments.json", `SELECT to_jsonb(a) FROM attachments a WHERE a.user_id = $1 ORDER BY a.id`},
+	{"hook_subscriptions.json", `SELECT to_jsonb(h) - 'secret' FROM hook_subscriptions h WHERE h.user_id = $1 ORDER BY h.id`},
+	{"lti_accounts.json", `SELECT to_jsonb(l) FROM lti_users l WHERE l.user_id = $1`},
+}
+
+// CreateExport queues a new export for the user, replacing any previous one.
+// It returns ErrExportInProgress while an export started in the last hour is
+// still being built.
+func (m UserModel) CreateExport(ctx context.Context, userID int64) (*DataExport, error) {
+	query := `
+        INSERT INTO data_exports (user_id)
+        VALUES ($1)
+        ON CONFLICT (user_id) DO UPDATE
+        SET status = 'pending', storage_key = NULL, size_bytes = NULL, error = NULL,
+            created_at = NOW(), completed_at = NULL, expires_at = NULL
+        WHERE data_exports.status != 'pending' OR data_exports.created_at < NOW() - interval '1 hour'
+        RETURNING status, created_at`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var export DataExport
+
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(&export.Status, &export.CreatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrExportInProgress
+		default:
+			return nil, err
+		}
+	}
+
+	return &export, nil
+}
+
+// GetExport returns the user's latest export.
+func (m UserModel) GetExport(ctx context.Context, userID int64) (*DataExport, error) {
+	query := `
+        SELECT status, storage_key, size_bytes, error, created_at, completed_at, expires_at
+        FROM data_exports
+        WHERE user_id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var export DataExport
+
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(
+		&export.Status,
+		&export.StorageKey,
+		&export.SizeBytes,
+		&export.Error,
+		&export.CreatedAt,
+		&export.CompletedAt,
+		&export.ExpiresAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &export, nil
+}
+
+// GetExportFiles gathers the documents of the user's data export.
+func (m UserModel) GetExportFiles(ctx context.Context, userID int64) ([]ExportFile, error) {
+	files := make([]ExportFile, 0, len(exportFiles))
+
+	for _, f := range exportFiles {
+		query := `SELECT COALESCE(jsonb_agg(doc), '[]') FROM (` + f.query + `) AS rows(doc)`
+
+		var data json.RawMessage
+
+		err := func() error {
+			ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			defer cancel()
+
+			return m.DB.QueryRowContext(ctx, query, userID).Scan(&data)
+		}()
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, ExportFile{Name: f.name, Data: data})
+	}
+
+	return files, nil
+}
+
+// CompleteExport records the archive stored under storageKey, which is kept
+// for ttl.
+func (m UserModel) CompleteExport(ctx context.Context, userID int64, storageKey string, size int64, ttl time.Duration) error {
+	query := `
+        UPDATE data_exports
+        SET status = 'ready', storage_key = $2, size_bytes = $3, completed_at = NOW(), expires_at = $4
+        WHERE user_id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID, storageKey, size, time.Now().Add(ttl))
+	return err
+}
+
+func (m UserModel) FailExport(ctx context.Context, userID int64, reason string) error {
+	query := `
+        UPDATE data_exports
+        SET status = 'failed', error = $2, completed_at = NOW()
+        WHERE user_id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID, reason)
+	return err
+}
+
+// DeleteExpiredExports removes exports past their expiry. Their archives are
+// queued for deletion with the attachment blobs.
+func (m UserModel) DeleteExpiredExports(ctx context.Context) (int64, error) {
+	query := `DELETE FROM data_exports WHERE expires_at < NOW()`
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
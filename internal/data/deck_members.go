@@ -0,0 +1,267 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/validator"
+)
+
+// DeckRoles are the roles a deck can be shared with. Editors can change
+// which cards are in the deck and edit those cards; viewers can only read
+// it. The owner's role isn't stored: it is whoever decks.user_id points at.
+var DeckRoles = []string{"editor", "viewer"}
+
+type DeckMember struct {
+	UserID  int64     `json:"user_id"`
+	Name    string    `json:"name"`
+	Role    string    `json:"role"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+// DeckInvitation invites whoever holds Email to join a deck. The emailed
+// token can only be accepted by the user with that address.
+type DeckInvitation struct {
+	ID        int64     `json:"id"`
+	DeckID    int64     `json:"deck_id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	Expiry    time.Time `json:"expiry"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Plaintext string `json:"-"`
+}
+
+func ValidateDeckRole(v *validator.Validator, role string) {
+	v.Check(validator.PermittedValue(role, DeckRoles...), "role", "must be editor or viewer")
+}
+
+func ValidateDeckInvitation(v *validator.Validator, inv *DeckInvitation) {
+	ValidateEmail(v, inv.Email)
+	ValidateDeckRole(v, inv.Role)
+}
+
+// GetMembers returns the deck's owner followed by its members in the order
+// they joined.
+func (m DeckModel) GetMembers(ctx context.Context, deckID int64) ([]*DeckMember, error) {
+	query := `
+        SELECT id, name, role, added_at
+        FROM (
+            SELECT u.id, u.name, 'owner' AS role, d.created_at AS added_at
+            FROM decks d
+            INNER JOIN users u ON u.id = d.user_id
+            WHERE d.id = $1
+            UNION ALL
+            SELECT u.id, u.name, dm.role, dm.added_at
+            FROM deck_members dm
+            INNER JOIN users u ON u.id = dm.user_id
+            WHERE dm.deck_id = $1
+        ) members
+        ORDER BY role = 'owner' DESC, added_at, id`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, deckID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []*DeckMember{}
+
+	for rows.Next() {
+		var member DeckMember
+
+		err := rows.Scan(&member.UserID, &member.Name, &member.Role, &member.AddedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		members = append(members, &member)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// UpdateMember changes the role of one of the deck's members.
+func (m DeckModel) UpdateMember(ctx context.Context, deckID, userID int64, role string) error {
+	query := `
+        UPDATE deck_members
+        SET role = $3
+        WHERE deck_id = $1 AND user_id = $2`
+
+	return m.changeMember(ctx, query, deckID, userID, role)
+}
+
+// RemoveMember takes the user out of the deck.
+func (m DeckModel) RemoveMember(ctx context.Context, deckID, userID int64) error {
+	query := `
+        DELETE FROM deck_members
+        WHERE deck_id = $1 AND user_id = $2`
+
+	return m.changeMember(ctx, query, deckID, userID)
+}
+
+func (m DeckModel) changeMember(ctx context.Context, query string, args ...any) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Invite stores the invitation with a new token, valid for ttl. Inviting the
+// same address again replaces the earlier invitation and its token.
+func (m DeckModel) Invite(ctx context.Context, inv *DeckInvitation, ttl time.Duration) error {
+	inv.Plaintext = rand.Text()
+	inv.Expiry = time.Now().Add(ttl)
+
+	hash := sha256.Sum256([]byte(inv.Plaintext))
+
+	query := `
+        INSERT INTO deck_invitations (deck_id, email, role, token_hash, expires_at)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (deck_id, email) DO UPDATE
+        SET role = EXCLUDED.role, token_hash = EXCLUDED.token_hash, expires_at = EXCLUDED.expires_at, created_at = NOW()
+        RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, inv.DeckID, inv.Email, inv.Role, hash[:], inv.Expiry).Scan(&inv.ID, &inv.CreatedAt)
+}
+
+// GetInvitations returns the deck's pending invitations, newest first.
+func (m DeckModel) GetInvitations(ctx context.Context, deckID int64) ([]*DeckInvitation, error) {
+	query := `
+        SELECT id, deck_id, email, role, expires_at, created_at
+        FROM deck_invitations
+        WHERE deck_id = $1 AND expires_at > NOW()
+        ORDER BY created_at DESC, id DESC`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, deckID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []*DeckInvitation{}
+
+	for rows.Next() {
+		var inv DeckInvitation
+
+		err := rows.Scan(&inv.ID, &inv.DeckID, &inv.Email, &inv.Role, &inv.Expiry, &inv.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		invitations = append(invitations, &inv)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+func (m DeckModel) DeleteInvitation(ctx context.Context, id, deckID int64) error {
+	query := `DELETE FROM deck_invitations WHERE id = $1 AND deck_id = $2`
+
+	return m.changeMember(ctx, query, id, deckID)
+}
+
+// AcceptInvitation uses up the invitation token, which must have been sent to
+// user's email address, and makes the user a member of the deck with the
+// invited role. It returns the deck's id.
+func (m DeckModel) AcceptInvitation(ctx context.Context, tokenPlaintext string, user *User) (int64, error) {
+	hash := sha256.Sum256([]byte(tokenPlaintext))
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+        DELETE FROM deck_invitations
+        WHERE token_hash = $1 AND email = $2 AND expires_at > NOW()
+        RETURNING deck_id, role`
+
+	var deckID int64
+	var role string
+
+	err = tx.QueryRowContext(ctx, query, hash[:], user.Email).Scan(&deckID, &role)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	// Owners who accept an invitation to their own deck stay owners.
+	query = `
+        INSERT INTO deck_members (deck_id, user_id, role)
+        SELECT d.id, $2, $3 FROM decks d WHERE d.id = $1 AND d.user_id != $2
+        ON CONFLICT (deck_id, user_id) DO UPDATE SET role = EXCLUDED.role`
+
+	_, err = tx.ExecContext(ctx, query, deckID, user.ID, role)
+	if err != nil {
+		return 0, err
+	}
+
+	return deckID, tx.Commit()
+}
+
+// EditableByMember reports whether the user can edit the card as an editor of
+// a deck holding it. Editors act on the owner's behalf, so this only counts
+// decks whose owner also owns the card.
+func (m DeckModel) EditableByMember(ctx context.Context, flashcardID, userID int64) (bool, error) {
+	query := `
+        SELECT EXISTS (
+            SELECT 1
+            FROM deck_flashcards df
+            INNER JOIN decks d ON d.id = df.deck_id
+            INNER JOIN flashcards f ON f.id = df.flashcard_id AND f.user_id = d.user_id
+            INNER JOIN deck_members dm ON dm.deck_id = d.id AND dm.user_id = $2 AND dm.role = 'editor'
+            WHERE df.flashcard_id = $1
+        )`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var editable bool
+
+	err := m.DB.QueryRowContext(ctx, query, flashcardID, userID).Scan(&editable)
+	return editable, err
+}
@@ -0,0 +1,904 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/validator"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrDuplicateDeckName = errors.New("duplicate deck name")
+	ErrIncompleteOrder   = errors.New("order must list every card in the deck exactly once")
+)
+
+var DeckVisibilities = []string{"private", "public"}
+
+// Deck is a user's hand-picked collection of flashcards. A card can be in
+// any number of decks. Public decks can be read by every user, and a deck
+// with a share link by anyone holding it, signed in or not.
+//
+// A smart deck has a Query instead of hand-picked cards: the query string of
+// GET /v1/flashcards, evaluated whenever the deck is listed so new matching
+// cards show up by themselves.
+type Deck struct {
+	ID          int64  `json:"id"`
+	UserID      int64  `json:"-"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Visibility  string `json:"visibility"`
+	Shared      bool   `json:"shared"`
+	Owned       bool   `json:"owned"`
+
+	// The requesting user's role: owner, editor or viewer, or empty when
+	// they can only read the deck because it is public or shared by link
+	Role string `json:"role"`
+
+	// The deck this one was cloned from, nil for original decks or when the
+	// source has since been deleted
+	ForkedFrom *int64 `json:"forked_from"`
+
+	// Listing query of a smart deck, nil for hand-picked decks
+	Query *string `json:"query"`
+
+	// Spaced repetition algorithm for the deck's cards, nil to use each
+	// user's own choice
+	Scheduler *string `json:"scheduler"`
+
+	// Tuning of the algorithm's intervals for the deck's cards, nil for the
+	// algorithm's own
+	SchedulerConfig *SchedulerConfig `json:"scheduler_config"`
+
+	// When the deck was archived, nil for active decks. Archived decks are
+	// left out of deck listings and study queues but keep their cards and
+	// review history.
+	ArchivedAt *time.Time `json:"archived_at"`
+
+	// Hand-picked cards only, so always 0 for smart decks
+	CardCount int       `json:"card_count"`
+	Version   int32     `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func ValidateDeck(v *validator.Validator, d *Deck) {
+	v.Check(strings.TrimSpace(d.Name) != "", "name", "must be provided")
+	v.Check(len(d.Name) <= 100, "name", "must not be more than 100 bytes long")
+	v.Check(len(d.Description) <= 1000, "description", "must not be more than 1000 bytes long")
+	v.Check(validator.PermittedValue(d.Visibility, DeckVisibilities...), "visibility", "must be private or public")
+
+	if d.Query != nil {
+		v.Check(len(*d.Query) <= 2000, "query", "must not be more than 2000 bytes long")
+
+		_, err := url.ParseQuery(*d.Query)
+		v.Check(err == nil, "query", "must be a valid query string")
+	}
+
+	if d.Scheduler != nil {
+		ValidateScheduler(v, *d.Scheduler)
+	}
+
+	if d.SchedulerConfig != nil {
+		ValidateSchedulerConfig(v, d.SchedulerConfig)
+	}
+}
+
+// Smart reports whether the deck's cards are defined by its query.
+func (d *Deck) Smart() bool {
+	return d.Query != nil
+}
+
+// DeckBundleFormat is the version of the DeckBundle layout. It is bumped on
+// incompatible changes so imports can reject bundles they don't understand.
+const DeckBundleFormat = 1
+
+// DeckBundle is a self-contained export of a deck: its metadata, its cards and
+// a manifest of the cards' attachments. Card ids are those of the exporting
+// server and only serve to tie the attachments to their cards; imports give
+// the cards new ids.
+type DeckBundle struct {
+	Format      int                `json:"format"`
+	ExportedAt  time.Time          `json:"exported_at"`
+	Deck        BundleDeck         `json:"deck"`
+	Flashcards  []*BundleFlashcard `json:"flashcards"`
+	Attachments []*Attachment      `json:"attachments"`
+}
+
+type BundleDeck struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// BundleFlashcard is the authored content of a card, without the progress,
+// quality score and other server-side state of the API representation.
+type BundleFlashcard struct {
+	ID          int64           `json:"id"`
+	Section     *string         `json:"section"`
+	SectionType *string         `json:"section_type"`
+	SourceFile  *string         `json:"source_file"`
+	Text        string          `json:"text"`
+	Question    string          `json:"question"`
+	Type        FlashcardType   `json:"flashcard_type"`
+	Content     json.RawMessage `json:"flashcard_content"`
+	Categories  []string        `json:"categories"`
+	Difficulty  *int            `json:"difficulty"`
+	Hints       []string        `json:"hints"`
+	Metadata    CardMetadata    `json:"metadata"`
+}
+
+// DeckStats summarises a deck's cards and the requesting user's progress on
+// them. Until cards are scheduled, new, learning and mature are the cards not
+// started, in progress and mastered.
+type DeckStats struct {
+	CardCount int            `json:"card_count"`
+	ByType    map[string]int `json:"by_type"`
+	New       int            `json:"new"`
+	Learning  int            `json:"learning"`
+	Mature    int            `json:"mature"`
+	Reviews   int            `json:"reviews"`
+	Correct   int            `json:"correct"`
+	Accuracy  *float64       `json:"accuracy"`
+}
+
+type DeckModel struct {
+	DB *sql.DB
+}
+
+const deckColumns = `
+            d.id, d.user_id, d.name, d.description, d.visibility, d.share_token_hash IS NOT NULL, d.forked_from, d.query, d.scheduler, d.scheduler_config, d.archived_at,
+            (SELECT count(*) FROM deck_flashcards df WHERE df.deck_id = d.id) AS card_count,
+            d.version, d.created_at`
+
+func (d *Deck) scanTargets() []any {
+	return []any{&d.ID, &d.UserID, &d.Name, &d.Description, &d.Visibility, &d.Shared, &d.ForkedFrom, &d.Query, &d.Scheduler, &d.SchedulerConfig, &d.ArchivedAt, &d.CardCount, &d.Version, &d.CreatedAt}
+}
+
+func (m DeckModel) Insert(ctx context.Context, d *Deck) error {
+	query := `
+        INSERT INTO decks (user_id, name, description, visibility, query, scheduler, scheduler_config)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING id, version, created_at`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	d.Owned = true
+	d.Role = "owner"
+
+	err := m.DB.QueryRowContext(ctx, query, d.UserID, d.Name, d.Description, d.Visibility, d.Query, d.Scheduler, d.SchedulerConfig).Scan(&d.ID, &d.Version, &d.CreatedAt)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "decks_user_id_name_key"):
+			return ErrDuplicateDeckName
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get returns one of the user's decks.
+func (m DeckModel) Get(ctx context.Context, id, userID int64) (*Deck, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	return m.get(ctx, `d.id = $1 AND d.user_id = $2`, userID, id, userID)
+}
+
+// GetEditable returns a deck the user owns or is an editor of.
+func (m DeckModel) GetEditable(ctx context.Context, id, userID int64) (*Deck, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	where := `d.id = $1 AND (d.user_id = $2 OR EXISTS (
+            SELECT 1 FROM deck_members dm WHERE dm.deck_id = d.id AND dm.user_id = $2 AND dm.role = 'editor'))`
+
+	return m.get(ctx, where, userID, id, userID)
+}
+
+// GetVisible returns a deck the user owns, is a member of or that is public.
+func (m DeckModel) GetVisible(ctx context.Context, id, userID int64) (*Deck, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	where := `d.id = $1 AND (d.user_id = $2 OR d.visibility = 'public' OR EXISTS (
+            SELECT 1 FROM deck_members dm WHERE dm.deck_id = d.id AND dm.user_id = $2))`
+
+	return m.get(ctx, where, userID, id, userID)
+}
+
+// GetByShareToken returns the deck a share link points at.
+func (m DeckModel) GetByShareToken(ctx context.Context, tokenPlaintext string) (*Deck, error) {
+	hash := sha256.Sum256([]byte(tokenPlaintext))
+
+	return m.get(ctx, `d.share_token_hash = $1`, 0, hash[:])
+}
+
+// get returns the deck matching where, with userID's role in it.
+func (m DeckModel) get(ctx context.Context, where string, userID int64, args ...any) (*Deck, error) {
+	args = append(args, userID)
+
+	query := fmt.Sprintf(`
+        SELECT %s, %s
+        FROM decks d
+        WHERE %s`, deckColumns, deckRole(len(args)), where)
+
+	var d Deck
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(append(d.scanTargets(), &d.Role)...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	d.Owned = d.Role == "owner"
+
+	return &d, nil
+}
+
+// deckRole is the select expression for the role of the user bound to $n in
+// deck d.
+func deckRole(n int) string {
+	return fmt.Sprintf(`CASE WHEN d.user_id = $%[1]d THEN 'owner' ELSE COALESCE(
+            (SELECT dm.role FROM deck_members dm WHERE dm.deck_id = d.id AND dm.user_id = $%[1]d), '') END`, n)
+}
+
+// GetAllForUser returns the decks the user owns or is a member of, either
+// the active or the archived ones.
+func (m DeckModel) GetAllForUser(ctx context.Context, userID int64, archived bool, filters Filters) ([]*Deck, Metadata, error) {
+	query := fmt.Sprintf(`
+        SELECT count(*) OVER(), %s, %s
+        FROM decks d
+        WHERE (d.user_id = $1 OR EXISTS (SELECT 1 FROM deck_members dm WHERE dm.deck_id = d.id AND dm.user_id = $1))
+        AND (d.archived_at IS NOT NULL) = $2
+        ORDER BY %s
+        LIMIT $3 OFFSET $4`, deckColumns, deckRole(1), filters.orderBy("d.id"))
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, archived, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	decks := []*Deck{}
+
+	for rows.Next() {
+		var d Deck
+
+		err := rows.Scan(append(append([]any{&totalRecords}, d.scanTargets()...), &d.Role)...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		d.Owned = d.Role == "owner"
+
+		decks = append(decks, &d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return decks, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+func (m DeckModel) Update(ctx context.Context, d *Deck) error {
+	query := `
+        UPDATE decks
+        SET name = $1, description = $2, visibility = $3, query = $4, scheduler = $5, scheduler_config = $6, version = version + 1
+        WHERE id = $7 AND user_id = $8 AND version = $9
+        RETURNING version`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, d.Name, d.Description, d.Visibility, d.Query, d.Scheduler, d.SchedulerConfig, d.ID, d.UserID, d.Version).Scan(&d.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		case strings.Contains(err.Error(), "decks_user_id_name_key"):
+			return ErrDuplicateDeckName
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes the deck. Its cards stay in the card bank.
+func (m DeckModel) Delete(ctx context.Context, id, userID int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `DELETE FROM decks WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Share mints a new share link token for the deck, replacing any earlier
+// one. Only a hash is stored, so the plaintext is returned here once.
+func (m DeckModel) Share(ctx context.Context, id, userID int64) (string, error) {
+	plaintext := rand.Text()
+	hash := sha256.Sum256([]byte(plaintext))
+
+	query := `UPDATE decks SET share_token_hash = $1 WHERE id = $2 AND user_id = $3`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, hash[:], id, userID)
+	if err != nil {
+		return "", err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return "", err
+	}
+
+	if rowsAffected == 0 {
+		return "", ErrRecordNotFound
+	}
+
+	return plaintext, nil
+}
+
+// Unshare revokes the deck's share link.
+func (m DeckModel) Unshare(ctx context.Context, id, userID int64) error {
+	query := `UPDATE decks SET share_token_hash = NULL WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// SetArchived archives or unarchives the deck and returns its archived_at.
+// Archiving an archived deck keeps the original time.
+func (m DeckModel) SetArchived(ctx context.Context, id, userID int64, archived bool) (*time.Time, error) {
+	query := `
+        UPDATE decks
+        SET archived_at = CASE WHEN $1 THEN COALESCE(archived_at, NOW()) END
+        WHERE id = $2 AND user_id = $3
+        RETURNING archived_at`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var archivedAt *time.Time
+
+	err := m.DB.QueryRowContext(ctx, query, archived, id, userID).Scan(&archivedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return archivedAt, nil
+}
+
+// Clone creates d as a copy of the deck src in d.UserID's account. With
+// copyCards the cards are duplicated as new flashcards the user can edit
+// freely, whose ids are returned; otherwise the new deck references the same
+// cards.
+func (m DeckModel) Clone(ctx context.Context, src, d *Deck, copyCards bool) ([]int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	d.ForkedFrom = &src.ID
+	d.Owned = true
+	d.Role = "owner"
+
+	query := `
+        INSERT INTO decks (user_id, name, description, visibility, query, scheduler, scheduler_config, forked_from)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        RETURNING id, version, created_at`
+
+	err = tx.QueryRowContext(ctx, query, d.UserID, d.Name, d.Description, d.Visibility, d.Query, d.Scheduler, d.SchedulerConfig, src.ID).Scan(&d.ID, &d.Version, &d.CreatedAt)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "decks_user_id_name_key"):
+			return nil, ErrDuplicateDeckName
+		default:
+			return nil, err
+		}
+	}
+
+	copied := []int64{}
+
+	if !copyCards {
+		query = `
+            INSERT INTO deck_flashcards (deck_id, flashcard_id, position)
+            SELECT $1, flashcard_id, position FROM deck_flashcards WHERE deck_id = $2`
+
+		result, err := tx.ExecContext(ctx, query, d.ID, src.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+
+		d.CardCount = int(rowsAffected)
+
+		return copied, tx.Commit()
+	}
+
+	query = `
+        WITH copies AS (
+            INSERT INTO flashcards (
+                section, section_type, source_file, text, question,
+                flashcard_type, flashcard_content, categories, difficulty, hints, metadata, user_id
+            )
+            SELECT
+                f.section, f.section_type, f.source_file, f.text, f.question,
+                f.flashcard_type, f.flashcard_content, f.categories, f.difficulty, f.hints, f.metadata, $3
+            FROM flashcards f
+            INNER JOIN deck_flashcards df ON df.flashcard_id = f.id
+            WHERE df.deck_id = $2
+            ORDER BY df.position, f.id
+            RETURNING id
+        ), progress AS (
+            INSERT INTO user_flashcards (user_id, flashcard_id, correct_count, status, last_reviewed_at)
+            SELECT $3, id, 0, 'not_started', NOW() FROM copies
+        )
+        INSERT INTO deck_flashcards (deck_id, flashcard_id, position)
+        SELECT $1, id, row_number() OVER (ORDER BY id) FROM copies
+        RETURNING flashcard_id`
+
+	rows, err := tx.QueryContext(ctx, query, d.ID, src.ID, d.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+
+		copied = append(copied, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	d.CardCount = len(copied)
+
+	return copied, tx.Commit()
+}
+
+// AddFlashcards puts the cards at the end of the deck in the order given,
+// skipping ids that don't exist or are already in it, and returns the ids
+// that were added.
+func (m DeckModel) AddFlashcards(ctx context.Context, deckID int64, ids []int64) ([]int64, error) {
+	query := `
+        INSERT INTO deck_flashcards (deck_id, flashcard_id, position)
+        SELECT
+            $1, f.id,
+            (SELECT COALESCE(MAX(position), 0) FROM deck_flashcards WHERE deck_id = $1)
+                + row_number() OVER (ORDER BY array_position($2::bigint[], f.id))
+        FROM flashcards f
+        WHERE f.id = ANY($2)
+        AND NOT EXISTS (SELECT 1 FROM deck_flashcards df WHERE df.deck_id = $1 AND df.flashcard_id = f.id)
+        ON CONFLICT DO NOTHING
+        RETURNING flashcard_id`
+
+	return m.changeFlashcards(ctx, query, deckID, ids)
+}
+
+// RemoveFlashcards takes the cards out of the deck and returns the ids that
+// were removed.
+func (m DeckModel) RemoveFlashcards(ctx context.Context, deckID int64, ids []int64) ([]int64, error) {
+	query := `
+        DELETE FROM deck_flashcards
+        WHERE deck_id = $1 AND flashcard_id = ANY($2)
+        RETURNING flashcard_id`
+
+	return m.changeFlashcards(ctx, query, deckID, ids)
+}
+
+func (m DeckModel) changeFlashcards(ctx context.Context, query string, deckID int64, ids []int64) ([]int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, deckID, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changed := []int64{}
+
+	for rows.Next() {
+		var id int64
+
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+
+		changed = append(changed, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return changed, nil
+}
+
+// Export reads the deck's cards and attachments for a DeckBundle inside a
+// single read-only REPEATABLE READ transaction, so the manifest matches the
+// cards even while the deck is being edited.
+func (m DeckModel) Export(ctx context.Context, d *Deck) (*DeckBundle, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	bundle := DeckBundle{
+		Format:      DeckBundleFormat,
+		Deck:        BundleDeck{Name: d.Name, Description: d.Description},
+		Flashcards:  []*BundleFlashcard{},
+		Attachments: []*Attachment{},
+	}
+
+	err = tx.QueryRowContext(ctx, "SELECT NOW()").Scan(&bundle.ExportedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+        SELECT
+            f.id, f.section, f.section_type, f.source_file, f.text, f.question,
+            f.flashcard_type, f.flashcard_content, f.categories, f.difficulty, f.hints, f.metadata
+        FROM flashcards f
+        INNER JOIN deck_flashcards df ON df.flashcard_id = f.id
+        WHERE df.deck_id = $1
+        ORDER BY df.position, f.id`
+
+	rows, err := tx.QueryContext(ctx, query, d.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var card BundleFlashcard
+
+		err := rows.Scan(
+			&card.ID, &card.Section, &card.SectionType, &card.SourceFile, &card.Text, &card.Question,
+			&card.Type, &card.Content, pq.Array(&card.Categories), &card.Difficulty, pq.Array(&card.Hints), &card.Metadata,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		bundle.Flashcards = append(bundle.Flashcards, &card)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `
+        SELECT a.id, a.flashcard_id, a.user_id, a.kind, a.storage_key, a.filename, a.content_type, a.size_bytes, a.created_at
+        FROM attachments a
+        INNER JOIN deck_flashcards df ON df.flashcard_id = a.flashcard_id
+        WHERE df.deck_id = $1
+        ORDER BY a.flashcard_id, a.id`
+
+	rows, err = tx.QueryContext(ctx, query, d.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a Attachment
+
+		err := rows.Scan(
+			&a.ID, &a.FlashcardID, &a.UserID, &a.Kind, &a.StorageKey,
+			&a.Filename, &a.ContentType, &a.Size, &a.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		bundle.Attachments = append(bundle.Attachments, &a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return &bundle, nil
+}
+
+// Import creates the deck d and the flashcards in it as new cards owned by
+// d.UserID, all in one transaction. The deck and every card get their new
+// id, version and created_at filled in.
+func (m DeckModel) Import(ctx context.Context, d *Deck, flashcards []*Flashcard) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	d.Owned = true
+	d.Role = "owner"
+
+	query := `
+        INSERT INTO decks (user_id, name, description, visibility)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, version, created_at`
+
+	err = tx.QueryRowContext(ctx, query, d.UserID, d.Name, d.Description, d.Visibility).Scan(&d.ID, &d.Version, &d.CreatedAt)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "decks_user_id_name_key"):
+			return ErrDuplicateDeckName
+		default:
+			return err
+		}
+	}
+
+	query = `
+        WITH card AS (
+            INSERT INTO flashcards (
+                section, section_type, source_file, text, question,
+                flashcard_type, flashcard_content, categories, difficulty, hints, metadata, user_id
+            ) VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE($8::text[], '{}'), $9, COALESCE($10::text[], '{}'), $11, $12)
+            RETURNING id, version, created_at
+        ), progress AS (
+            INSERT INTO user_flashcards (user_id, flashcard_id, correct_count, status, last_reviewed_at)
+            SELECT $12, id, 0, 'not_started', NOW() FROM card
+        ), membership AS (
+            INSERT INTO deck_flashcards (deck_id, flashcard_id, position)
+            SELECT $13, id, $14 FROM card
+        )
+        SELECT id, version, created_at FROM card`
+
+	for i, flashcard := range flashcards {
+		flashcard.Categories = NormalizeCategories(flashcard.Categories)
+
+		contentJSON, err := json.Marshal(flashcard.Content)
+		if err != nil {
+			return fmt.Errorf("failed to marshal flashcard content: %w", err)
+		}
+
+		err = tx.QueryRowContext(ctx, query,
+			flashcard.Section, flashcard.SectionType, flashcard.SourceFile,
+			flashcard.Text, flashcard.Question, flashcard.Type,
+			contentJSON, pq.Array(flashcard.Categories), flashcard.Difficulty, pq.Array(flashcard.Hints), flashcard.Metadata,
+			d.UserID, d.ID, i+1,
+		).Scan(&flashcard.ID, &flashcard.Version, &flashcard.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		flashcard.OwnerID = &d.UserID
+	}
+
+	d.CardCount = len(flashcards)
+
+	return tx.Commit()
+}
+
+// GetOrder returns the ids of the deck's cards in deck order.
+func (m DeckModel) GetOrder(ctx context.Context, deckID int64) ([]int64, error) {
+	query := `
+        SELECT flashcard_id
+        FROM deck_flashcards
+        WHERE deck_id = $1
+        ORDER BY position, flashcard_id`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, deckID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+
+	for rows.Next() {
+		var id int64
+
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// Reorder puts the deck's cards in the order of ids, which must list every
+// card in the deck exactly once. The membership rows are locked first, so a
+// card added or removed concurrently makes the order incomplete rather than
+// leaving it with a stale position.
+func (m DeckModel) Reorder(ctx context.Context, deckID int64, ids []int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        SELECT count(*) FILTER (WHERE flashcard_id = ANY($2)), count(*)
+        FROM (SELECT flashcard_id FROM deck_flashcards WHERE deck_id = $1 FOR UPDATE) df`
+
+	var listed, total int
+
+	err = tx.QueryRowContext(ctx, query, deckID, pq.Array(ids)).Scan(&listed, &total)
+	if err != nil {
+		return err
+	}
+
+	if listed != total || total != len(ids) {
+		return ErrIncompleteOrder
+	}
+
+	query = `
+        UPDATE deck_flashcards df
+        SET position = o.position
+        FROM unnest($2::bigint[]) WITH ORDINALITY AS o(flashcard_id, position)
+        WHERE df.deck_id = $1 AND df.flashcard_id = o.flashcard_id`
+
+	_, err = tx.ExecContext(ctx, query, deckID, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetStats returns the stats of the cards matching filter, which is the
+// deck's own query for smart decks and just the DeckID otherwise.
+func (m DeckModel) GetStats(ctx context.Context, userID int64, filter FlashcardFilter) (*DeckStats, error) {
+	where, args := filter.where(userID)
+
+	query := fmt.Sprintf(`
+        WITH cards AS (
+            SELECT f.id, f.flashcard_type, COALESCE(uf.status, 'not_started') AS status
+            FROM flashcards f
+            LEFT JOIN user_flashcards uf ON f.id = uf.flashcard_id AND uf.user_id = $1
+            WHERE %s
+        ), types AS (
+            SELECT flashcard_type, count(*) AS n FROM cards GROUP BY flashcard_type
+        ), reviews AS (
+            SELECT count(*) AS n, count(*) FILTER (WHERE r.correct) AS correct
+            FROM flashcard_reviews r
+            WHERE r.user_id = $1 AND r.flashcard_id IN (SELECT id FROM cards)
+        )
+        SELECT
+            (SELECT count(*) FROM cards),
+            (SELECT COALESCE(jsonb_object_agg(flashcard_type, n), '{}') FROM types),
+            (SELECT count(*) FROM cards WHERE status = 'not_started'),
+            (SELECT count(*) FROM cards WHERE status = 'in_progress'),
+            (SELECT count(*) FROM cards WHERE status = 'mastered'),
+            reviews.n, reviews.correct
+        FROM reviews`, where)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var s DeckStats
+	var byType []byte
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&s.CardCount, &byType, &s.New, &s.Learning, &s.Mature, &s.Reviews, &s.Correct,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(byType, &s.ByType)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Reviews > 0 {
+		accuracy := float64(s.Correct) / float64(s.Reviews)
+		s.Accuracy = &accuracy
+	}
+
+	return &s, nil
+}
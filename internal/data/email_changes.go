@@ -0,0 +1,101 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// EmailChange is a pending switch of a user's email address to Email. It only
+// happens once the token sent to the new address is confirmed.
+type EmailChange struct {
+	Email     string    `json:"email"`
+	Expiry    time.Time `json:"expiry"`
+	Plaintext string    `json:"-"`
+}
+
+// RequestEmailChange stores a change of the user's address to email with a new
+// token, valid for ttl. A user has at most one pending change, so asking again
+// replaces the earlier one and its token.
+func (m UserModel) RequestEmailChange(ctx context.Context, userID int64, email string, ttl time.Duration) (*EmailChange, error) {
+	change := &EmailChange{
+		Email:     email,
+		Expiry:    time.Now().Add(ttl),
+		Plaintext: rand.Text(),
+	}
+
+	hash := sha256.Sum256([]byte(change.Plaintext))
+
+	query := `
+        INSERT INTO email_changes (user_id, email, token_hash, expires_at)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (user_id) DO UPDATE
+        SET email = EXCLUDED.email, token_hash = EXCLUDED.token_hash, expires_at = EXCLUDED.expires_at, created_at = NOW()`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID, change.Email, hash[:], change.Expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
+
+// ConfirmEmailChange uses up the token and switches the user's address to the
+// one it was sent to. It returns the user's id and their previous address.
+func (m UserModel) ConfirmEmailChange(ctx context.Context, tokenPlaintext string) (int64, string, error) {
+	hash := sha256.Sum256([]byte(tokenPlaintext))
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	defer tx.Rollback()
+
+	query := `
+        DELETE FROM email_changes
+        WHERE token_hash = $1 AND expires_at > NOW()
+        RETURNING user_id, email`
+
+	var userID int64
+	var email string
+
+	err = tx.QueryRowContext(ctx, query, hash[:]).Scan(&userID, &email)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, "", ErrRecordNotFound
+		default:
+			return 0, "", err
+		}
+	}
+
+	query = `
+        UPDATE users u
+        SET email = $2, version = u.version + 1
+        FROM users prev
+        WHERE u.id = $1 AND prev.id = u.id
+        RETURNING prev.email`
+
+	var previous string
+
+	err = tx.QueryRowContext(ctx, query, userID, email).Scan(&previous)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"`:
+			return 0, "", ErrDuplicateEmail
+		default:
+			return 0, "", err
+		}
+	}
+
+	return userID, previous, tx.Commit()
+}
@@ -0,0 +1,123 @@
+package data
+
+import (
+	"context"
+	"database/sql/driver"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Vector is an embedding in pgvector's text representation.
+type Vector []float32
+
+func (v Vector) Value() (driver.Value, error) {
+	var b strings.Builder
+
+	b.WriteByte('[')
+	for i, f := range v {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(f), 'g', -1, 32))
+	}
+	b.WriteByte(']')
+
+	return b.String(), nil
+}
+
+// SetEmbedding stores the card's embedding for model, replacing any previous
+// one.
+func (m FlashcardModel) SetEmbedding(ctx context.Context, id int64, model string, embedding Vector) error {
+	query := `
+        INSERT INTO flashcard_embeddings (flashcard_id, model, embedding)
+        VALUES ($1, $2, $3::vector)
+        ON CONFLICT (flashcard_id, model)
+        DO UPDATE SET embedding = EXCLUDED.embedding, created_at = NOW()`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, id, model, embedding)
+	return err
+}
+
+// GetUnembeddedIDs returns up to limit cards without an embedding for model.
+func (m FlashcardModel) GetUnembeddedIDs(ctx context.Context, model string, limit int) ([]int64, error) {
+	query := `
+        SELECT f.id
+        FROM flashcards f
+        WHERE NOT EXISTS (
+            SELECT 1 FROM flashcard_embeddings e WHERE e.flashcard_id = f.id AND e.model = $1
+        )
+        ORDER BY f.id
+        LIMIT $2`
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, model, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+
+	for rows.Next() {
+		var id int64
+
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// NearestNeighbours returns a page of the ids of the cards closest to
+// embedding by cosine distance, leaving out those maxDistance or further away.
+func (m FlashcardModel) NearestNeighbours(ctx context.Context, model string, embedding Vector, maxDistance float64, filters Filters) ([]int64, Metadata, error) {
+	query := `
+        SELECT count(*) OVER(), flashcard_id
+        FROM flashcard_embeddings
+        WHERE model = $1 AND embedding <=> $2::vector < $3
+        ORDER BY embedding <=> $2::vector, flashcard_id
+        LIMIT $4 OFFSET $5`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, model, embedding, maxDistance, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	ids := []int64{}
+
+	for rows.Next() {
+		var id int64
+
+		err := rows.Scan(&totalRecords, &id)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return ids, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
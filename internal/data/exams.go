@@ -0,0 +1,569 @@
+package data
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/validator"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrExamClosed = errors.New("exam already submitted or past its deadline")
+)
+
+// Exam is a timed test of cards sampled for one user. Its questions are
+// served without their answers, and the answers are only accepted once,
+// before the deadline.
+type Exam struct {
+	ID          int64           `json:"id"`
+	UserID      int64           `json:"-"`
+	Deadline    time.Time       `json:"deadline"`
+	SubmittedAt *time.Time      `json:"submitted_at"`
+	CreatedAt   time.Time       `json:"created_at"`
+	Questions   []*ExamQuestion `json:"questions"`
+}
+
+// ExamQuestion is what a learner needs to answer a card: the question and,
+// for MCQ and numeric cards, the options and unit. Category is the topic the
+// card was sampled for. A question with a TimeLimitMS is timed by the server
+// from ServedAt, when it was first served on its own. Answer, AnswerMS and
+// Correct are set once the question has been answered.
+type ExamQuestion struct {
+	Position    int             `json:"position"`
+	FlashcardID int64           `json:"flashcard_id"`
+	Category    string          `json:"category"`
+	Question    string          `json:"question,omitempty"`
+	Type        FlashcardType   `json:"flashcard_type"`
+	Options     []MCQOption     `json:"options,omitempty"`
+	Unit        string          `json:"unit,omitempty"`
+	TimeLimitMS *int            `json:"time_limit_ms,omitempty"`
+	ServedAt    *time.Time      `json:"served_at,omitempty"`
+	Answer      json.RawMessage `json:"answer,omitempty"`
+	AnswerMS    *int            `json:"answer_ms,omitempty"`
+	Correct     *bool           `json:"correct,omitempty"`
+
+	Content FlashcardContent `json:"-"`
+}
+
+// ExamSpec describes the exam to build. Questions are shared out between the
+// categories in proportion to their weights, or sampled from every card when
+// there are none. Each question gets the time limit of the category it was
+// sampled for, or QuestionTimeLimit when its category has none; zero leaves
+// it untimed.
+type ExamSpec struct {
+	QuestionCount      int
+	Duration           time.Duration
+	QuestionTimeLimit  time.Duration
+	CategoryTimeLimits map[string]time.Duration
+	CategoryWeights    map[string]float64
+}
+
+type ExamResult struct {
+	Total   int           `json:"total"`
+	Correct int           `json:"correct"`
+	Score   float64       `json:"score"`
+	Topics  []*TopicScore `json:"topics"`
+}
+
+type TopicScore struct {
+	Category string  `json:"category"`
+	Total    int     `json:"total"`
+	Correct  int     `json:"correct"`
+	Score    float64 `json:"score"`
+}
+
+type ExamModel struct {
+	DB *sql.DB
+}
+
+func ValidateExamSpec(v *validator.Validator, spec ExamSpec) {
+	v.Check(spec.QuestionCount >= 1 && spec.QuestionCount <= 200, "question_count", "must be between 1 and 200")
+	v.Check(spec.Duration >= time.Minute && spec.Duration <= 8*time.Hour, "duration_minutes", "must be between 1 and 480")
+	v.Check(spec.QuestionTimeLimit == 0 || (spec.QuestionTimeLimit >= 5*time.Second && spec.QuestionTimeLimit <= 30*time.Minute), "question_time_limit_seconds", "must be between 5 and 1800")
+	v.Check(spec.QuestionTimeLimit <= spec.Duration, "question_time_limit_seconds", "must not be longer than the exam")
+	v.Check(len(spec.CategoryWeights) <= 50, "category_weights", "must not have more than 50 categories")
+	v.Check(len(spec.CategoryTimeLimits) <= 50, "category_time_limits_seconds", "must not have more than 50 categories")
+
+	for name, limit := range spec.CategoryTimeLimits {
+		v.Check(NormalizeCategory(name) != "", "category_time_limits_seconds", "must not contain empty categories")
+		v.Check(limit >= 5*time.Second && limit <= 30*time.Minute, "category_time_limits_seconds", "must all be between 5 and 1800")
+		v.Check(limit <= spec.Duration, "category_time_limits_seconds", "must not be longer than the exam")
+	}
+
+	for name, weight := range spec.CategoryWeights {
+		v.Check(NormalizeCategory(name) != "", "category_weights", "must not contain empty categories")
+		v.Check(weight > 0, "category_weights", "must all be greater than zero")
+	}
+}
+
+// allocate shares count out between the categories in proportion to their
+// weights, giving what is left after rounding down to the largest remainders.
+func allocate(count int, weights map[string]float64) map[string]int {
+	var total float64
+	names := make([]string, 0, len(weights))
+
+	for name, weight := range weights {
+		total += weight
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	shares := make(map[string]int, len(weights))
+	remainders := make(map[string]float64, len(weights))
+	left := count
+
+	for _, name := range names {
+		exact := float64(count) * weights[name] / total
+		shares[name] = int(math.Floor(exact))
+		remainders[name] = exact - math.Floor(exact)
+		left -= shares[name]
+	}
+
+	slices.SortStableFunc(names, func(a, b string) int { return cmp.Compare(remainders[b], remainders[a]) })
+
+	for _, name := range names[:left] {
+		shares[name]++
+	}
+
+	return shares
+}
+
+// Insert samples the exam's questions at random and stores it. Categories
+// with too few cards contribute what they have, so the exam can be shorter
+// than asked; it has no questions at all when nothing matched.
+func (m ExamModel) Insert(ctx context.Context, exam *Exam, spec ExamSpec) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var ids []int64
+	var categories []string
+
+	weights := make(map[string]float64, len(spec.CategoryWeights))
+	for name, weight := range spec.CategoryWeights {
+		weights[NormalizeCategory(name)] += weight
+	}
+
+	if len(weights) == 0 {
+		query := `
+            SELECT id, COALESCE(categories[1], '')
+            FROM flashcards
+            ORDER BY random()
+            LIMIT $1`
+
+		rows, err := tx.QueryContext(ctx, query, spec.QuestionCount)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var id int64
+			var category string
+
+			err := rows.Scan(&id, &category)
+			if err != nil {
+				return err
+			}
+
+			ids = append(ids, id)
+			categories = append(categories, category)
+		}
+
+		if err = rows.Err(); err != nil {
+			return err
+		}
+	} else {
+		shares := allocate(spec.QuestionCount, weights)
+
+		query := `
+            SELECT COALESCE(array_agg(id), '{}')
+            FROM (
+                SELECT id
+                FROM flashcards
+                WHERE $1 = ANY(categories) AND NOT (id = ANY($2))
+                ORDER BY random()
+                LIMIT $3
+            ) sampled`
+
+		names := make([]string, 0, len(shares))
+		for name := range shares {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+
+		for _, name := range names {
+			if shares[name] == 0 {
+				continue
+			}
+
+			var sampled []int64
+
+			err := tx.QueryRowContext(ctx, query, name, pq.Array(ids), shares[name]).Scan(pq.Array(&sampled))
+			if err != nil {
+				return err
+			}
+
+			for _, id := range sampled {
+				ids = append(ids, id)
+				categories = append(categories, name)
+			}
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	// Mix the categories up rather than asking them one after the other.
+	rand.Shuffle(len(ids), func(i, j int) {
+		ids[i], ids[j] = ids[j], ids[i]
+		categories[i], categories[j] = categories[j], categories[i]
+	})
+
+	limits := make(map[string]time.Duration, len(spec.CategoryTimeLimits))
+	for name, limit := range spec.CategoryTimeLimits {
+		limits[NormalizeCategory(name)] = limit
+	}
+
+	// Zero stands for no limit.
+	timeLimits := make([]int64, len(ids))
+	for i, category := range categories {
+		limit, ok := limits[category]
+		if !ok {
+			limit = spec.QuestionTimeLimit
+		}
+		timeLimits[i] = limit.Milliseconds()
+	}
+
+	query := `
+        INSERT INTO exams (user_id, deadline)
+        VALUES ($1, NOW() + $2 * interval '1 second')
+        RETURNING id, deadline, created_at`
+
+	err = tx.QueryRowContext(ctx, query, exam.UserID, int64(spec.Duration.Seconds())).Scan(&exam.ID, &exam.Deadline, &exam.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	query = `
+        INSERT INTO exam_questions (exam_id, position, flashcard_id, category, time_limit_ms)
+        SELECT $1, q.position, q.flashcard_id, q.category, NULLIF(q.time_limit_ms, 0)
+        FROM unnest($2::bigint[], $3::text[], $4::integer[]) WITH ORDINALITY AS q(flashcard_id, category, time_limit_ms, position)`
+
+	_, err = tx.ExecContext(ctx, query, exam.ID, pq.Array(ids), pq.Array(categories), pq.Array(timeLimits))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Get returns one of the user's exams with its questions in order.
+func (m ExamModel) Get(ctx context.Context, id, userID int64) (*Exam, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+        SELECT id, user_id, deadline, submitted_at, created_at
+        FROM exams
+        WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var exam Exam
+
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(&exam.ID, &exam.UserID, &exam.Deadline, &exam.SubmittedAt, &exam.CreatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	query = `
+        SELECT eq.position, eq.flashcard_id, eq.category, eq.time_limit_ms, eq.served_at, eq.answer, eq.answer_ms, eq.correct, f.question, f.flashcard_type, f.flashcard_content
+        FROM exam_questions eq
+        INNER JOIN flashcards f ON f.id = eq.flashcard_id
+        WHERE eq.exam_id = $1
+        ORDER BY eq.position`
+
+	rows, err := m.DB.QueryContext(ctx, query, exam.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exam.Questions = []*ExamQuestion{}
+
+	for rows.Next() {
+		var q ExamQuestion
+		var answer, contentJSON []byte
+
+		err := rows.Scan(&q.Position, &q.FlashcardID, &q.Category, &q.TimeLimitMS, &q.ServedAt, &answer, &q.AnswerMS, &q.Correct, &q.Question, &q.Type, &contentJSON)
+		if err != nil {
+			return nil, err
+		}
+
+		q.Answer = answer
+
+		q.Content, err = unmarshalContent(q.Type, contentJSON)
+		if err != nil {
+			return nil, err
+		}
+
+		switch c := q.Content.(type) {
+		case MCQContent:
+			q.Options = c.Options
+		case NumericContent:
+			q.Unit = c.Unit
+		}
+
+		exam.Questions = append(exam.Questions, &q)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &exam, nil
+}
+
+// Serve starts the clock of an open exam's question, if it hasn't already
+// been started, and sets ServedAt.
+func (m ExamModel) Serve(ctx context.Context, exam *Exam, q *ExamQuestion) error {
+	query := `
+        UPDATE exam_questions eq
+        SET served_at = COALESCE(eq.served_at, NOW())
+        FROM exams e
+        WHERE eq.exam_id = $1 AND eq.position = $2 AND e.id = eq.exam_id
+            AND e.user_id = $3 AND e.submitted_at IS NULL AND e.deadline >= NOW()
+        RETURNING eq.served_at`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, exam.ID, q.Position, exam.UserID).Scan(&q.ServedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrExamClosed
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Answer stores the graded answer to one of an open exam's questions ahead
+// of the submission. A question only takes one answer; a second one is an
+// edit conflict.
+func (m ExamModel) Answer(ctx context.Context, exam *Exam, q *ExamQuestion) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        SELECT id
+        FROM exams
+        WHERE id = $1 AND user_id = $2 AND submitted_at IS NULL AND deadline >= NOW()
+        FOR UPDATE`
+
+	var id int64
+
+	err = tx.QueryRowContext(ctx, query, exam.ID, exam.UserID).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrExamClosed
+		default:
+			return err
+		}
+	}
+
+	query = `
+        UPDATE exam_questions
+        SET answer = $3, answer_ms = $4, correct = $5
+        WHERE exam_id = $1 AND position = $2 AND answer IS NULL`
+
+	result, err := tx.ExecContext(ctx, query, exam.ID, q.Position, []byte(q.Answer), q.AnswerMS, q.Correct)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrEditConflict
+	}
+
+	return tx.Commit()
+}
+
+// Question returns the exam's question at position, or nil when there is
+// none.
+func (e *Exam) Question(position int) *ExamQuestion {
+	for _, q := range e.Questions {
+		if q.Position == position {
+			return q
+		}
+	}
+
+	return nil
+}
+
+// Time works out how long an answer to the question given at now took. A
+// timed question is timed from when it was served: the answer_ms the client
+// reported can only lower the time recorded, not the time checked against
+// the limit. An answer is over time when it took longer than the limit, or
+// when the question was never served and so can't have been timed.
+func (q *ExamQuestion) Time(now time.Time, reportedMS *int) (answerMS *int, overTime bool) {
+	if q.TimeLimitMS == nil {
+		return reportedMS, false
+	}
+
+	if q.ServedAt == nil {
+		return reportedMS, true
+	}
+
+	elapsed := max(int(now.Sub(*q.ServedAt).Milliseconds()), 0)
+
+	answerMS = &elapsed
+	if reportedMS != nil && *reportedMS < elapsed {
+		answerMS = reportedMS
+	}
+
+	return answerMS, elapsed > *q.TimeLimitMS
+}
+
+// Conceal hides what the learner mustn't see while the exam is open: the
+// content of timed questions that haven't been served, whose clock hasn't
+// started, and whether the answers given so far are correct.
+func (e *Exam) Conceal() {
+	for _, q := range e.Questions {
+		if q.TimeLimitMS != nil && q.ServedAt == nil {
+			q.Question = ""
+			q.Options = nil
+			q.Unit = ""
+		}
+
+		q.Correct = nil
+	}
+}
+
+// Closed reports whether the exam can no longer be submitted.
+func (e *Exam) Closed() bool {
+	return e.SubmittedAt != nil || time.Now().After(e.Deadline)
+}
+
+// Submit stores the graded answers of the exam's questions. The deadline
+// is checked against the database clock, with the exam row locked, so a
+// submission can't slip in after it or race another one.
+func (m ExamModel) Submit(ctx context.Context, exam *Exam) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        UPDATE exams
+        SET submitted_at = NOW()
+        WHERE id = $1 AND user_id = $2 AND submitted_at IS NULL AND deadline >= NOW()
+        RETURNING submitted_at`
+
+	err = tx.QueryRowContext(ctx, query, exam.ID, exam.UserID).Scan(&exam.SubmittedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrExamClosed
+		default:
+			return err
+		}
+	}
+
+	query = `
+        UPDATE exam_questions
+        SET answer = $3, answer_ms = $4, correct = $5
+        WHERE exam_id = $1 AND position = $2`
+
+	for _, q := range exam.Questions {
+		var answer any
+		if q.Answer != nil {
+			answer = []byte(q.Answer)
+		}
+
+		_, err = tx.ExecContext(ctx, query, exam.ID, q.Position, answer, q.AnswerMS, q.Correct)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Result scores the exam overall and by topic. Unanswered questions count as
+// incorrect.
+func (e *Exam) Result() *ExamResult {
+	result := &ExamResult{Topics: []*TopicScore{}}
+	topics := make(map[string]*TopicScore)
+
+	for _, q := range e.Questions {
+		topic, ok := topics[q.Category]
+		if !ok {
+			topic = &TopicScore{Category: q.Category}
+			topics[q.Category] = topic
+			result.Topics = append(result.Topics, topic)
+		}
+
+		correct := q.Correct != nil && *q.Correct
+
+		result.Total++
+		topic.Total++
+		if correct {
+			result.Correct++
+			topic.Correct++
+		}
+	}
+
+	if result.Total > 0 {
+		result.Score = float64(result.Correct) / float64(result.Total)
+	}
+
+	for _, topic := range result.Topics {
+		topic.Score = float64(topic.Correct) / float64(topic.Total)
+	}
+
+	slices.SortFunc(result.Topics, func(a, b *TopicScore) int { return cmp.Compare(a.Category, b.Category) })
+
+	return result
+}
@@ -0,0 +1,53 @@
+package data
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExamQuestionTime(t *testing.T) {
+	served := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	limit := 30_000
+
+	tests := []struct {
+		name     string
+		q        ExamQuestion
+		elapsed  time.Duration
+		reported *int
+		answerMS *int
+		overTime bool
+	}{
+		{"untimed", ExamQuestion{}, time.Hour, ms(45_000), ms(45_000), false},
+		{"never served", ExamQuestion{TimeLimitMS: &limit}, 0, ms(1_000), ms(1_000), true},
+		{"within limit", ExamQuestion{TimeLimitMS: &limit, ServedAt: &served}, 20 * time.Second, nil, ms(20_000), false},
+		{"shorter report", ExamQuestion{TimeLimitMS: &limit, ServedAt: &served}, 20 * time.Second, ms(12_000), ms(12_000), false},
+		{"longer report", ExamQuestion{TimeLimitMS: &limit, ServedAt: &served}, 20 * time.Second, ms(25_000), ms(20_000), false},
+		{"over limit", ExamQuestion{TimeLimitMS: &limit, ServedAt: &served}, time.Minute, ms(1_000), ms(1_000), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			answerMS, overTime := tt.q.Time(served.Add(tt.elapsed), tt.reported)
+
+			if overTime != tt.overTime {
+				t.Errorf("over time = %t; want %t", overTime, tt.overTime)
+			}
+
+			if (answerMS == nil) != (tt.answerMS == nil) || (answerMS != nil && *answerMS != *tt.answerMS) {
+				t.Errorf("answer_ms = %v; want %v", deref(answerMS), deref(tt.answerMS))
+			}
+		})
+	}
+}
+
+func ms(n int) *int {
+	return &n
+}
+
+func deref(p *int) any {
+	if p == nil {
+		return nil
+	}
+
+	return *p
+}
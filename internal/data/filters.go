@@ -1,68 +1,223 @@
 package data
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"slices"
 	"strings"
+	"time"
 
 	"flashcards-api.johndennehy101.tech/internal/validator"
 )
 
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// KeysetSorts are the sorts that support cursor pagination: each has a
+// unique, stable key.
+var KeysetSorts = []string{"id", "-id", "created_at", "-created_at"}
+
 type Filters struct {
 	Page         int
 	PageSize     int
 	Sort         string
 	SortSafelist []string
+
+	// SortColumns maps sort keys to columns where the names differ
+	SortColumns map[string]string
+
+	// Keyset selects cursor pagination, which returns the PageSize rows
+	// following After (or the first rows when After is nil) instead of an
+	// offset page.
+	Keyset bool
+	After  *Cursor
 }
 
 type Metadata struct {
-	CurrentPage  int `json:"current_page,omitzero"`
-	PageSize     int `json:"page_size,omitzero"`
-	FirstPage    int `json:"first_page,omitzero"`
-	LastPage     int `json:"last_page,omitzero"`
-	TotalRecords int `json:"total_records,omitzero"`
+	CurrentPage  int    `json:"current_page,omitzero"`
+	PageSize     int    `json:"page_size,omitzero"`
+	FirstPage    int    `json:"first_page,omitzero"`
+	LastPage     int    `json:"last_page,omitzero"`
+	TotalRecords int    `json:"total_records,omitzero"`
+	NextCursor   string `json:"next_cursor,omitzero"`
+}
+
+// Cursor is the sort key of the last row of a keyset page.
+type Cursor struct {
+	Sort      string    `json:"s"`
+	ID        int64     `json:"i"`
+	CreatedAt time.Time `json:"t,omitzero"`
 }
 
+// Encode returns the cursor as an opaque token, signed so clients can't
+// forge positions.
+func (c Cursor) Encode(secret []byte) string {
+	js, _ := json.Marshal(c)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(js)
+
+	return base64.RawURLEncoding.EncodeToString(js) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func DecodeCursor(token string, secret []byte) (*Cursor, error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	js, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(js)
+
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+
+	err = json.Unmarshal(js, &c)
+	if err != nil || !slices.Contains(KeysetSorts, c.Sort) {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+// MaxPageSize bounds page_size, and limit for cursor pages.
+const MaxPageSize = 1000
+
 func ValidateFilters(v *validator.Validator, f Filters) {
+	sizeKey := "page_size"
+	if f.Keyset {
+		sizeKey = "limit"
+	}
+
 	v.Check(f.Page > 0, "page", "must be greater than zero")
 	v.Check(f.Page <= 10_000_000, "page", "must be a maximum of 10 million")
-	v.Check(f.PageSize > 0, "page_size", "must be greater than zero")
-	v.Check(f.PageSize <= 1000, "page_size", "must be a maximum of 100")
+	v.Check(f.PageSize > 0, sizeKey, "must be greater than zero")
+	v.Check(f.PageSize <= MaxPageSize, sizeKey, fmt.Sprintf("must be a maximum of %d", MaxPageSize))
+
+	keys := f.sortKeys()
+	seen := make(map[string]bool, len(keys))
 
-	v.Check(validator.PermittedValue(f.Sort, f.SortSafelist...), "sort", "invalid sort value")
+	for _, key := range keys {
+		v.Check(validator.PermittedValue(key, f.SortSafelist...), "sort", "invalid sort value")
+		v.Check(!seen[strings.TrimPrefix(key, "-")], "sort", "must not contain the same field twice")
+		seen[strings.TrimPrefix(key, "-")] = true
+	}
+
+	v.Check(len(keys) <= 5, "sort", "must not contain more than 5 fields")
+	v.Check(len(keys) == 1 || !slices.Contains(keys, "random"), "sort", "random can't be combined with other fields")
+
+	if f.Keyset {
+		v.Check(validator.PermittedValue(f.Sort, KeysetSorts...), "sort", "must be one of "+strings.Join(KeysetSorts, ", ")+" with cursor pagination")
+		v.Check(f.After == nil || f.After.Sort == f.Sort, "after", "cursor does not match sort")
+	}
 }
 
-func (f Filters) sortColumn() string {
-	if f.Sort == "random" {
+// sortKeys splits a multi-field sort such as "-created_at,question".
+func (f Filters) sortKeys() []string {
+	return strings.Split(f.Sort, ",")
+}
+
+func (f Filters) sortColumn(key string) string {
+	if key == "random" {
 		return "RANDOM()"
 	}
 
-	if slices.Contains(f.SortSafelist, f.Sort) {
-		return strings.TrimPrefix(f.Sort, "-")
+	if slices.Contains(f.SortSafelist, key) {
+		key = strings.TrimPrefix(key, "-")
+
+		if column, ok := f.SortColumns[key]; ok {
+			return column
+		}
+
+		return key
 	}
 
-	panic("unsafe sort parameter: " + f.Sort)
+	panic("unsafe sort parameter: " + key)
 }
 
-func (f Filters) sortDirection() string {
-	if f.Sort == "random" {
+func (f Filters) sortDirection(key string) string {
+	if key == "random" {
 		return ""
 	}
 
-	if strings.HasPrefix(f.Sort, "-") {
-		return "DESC"
+	// Unset values sort last in both directions.
+	if strings.HasPrefix(key, "-") {
+		return "DESC NULLS LAST"
 	}
 
 	return "ASC"
 }
 
+// orderBy returns the ORDER BY list for the sort, ending with tiebreak (a
+// unique column) so pages are deterministic.
+func (f Filters) orderBy(tiebreak string) string {
+	var parts []string
+
+	for _, key := range f.sortKeys() {
+		parts = append(parts, strings.TrimSpace(f.sortColumn(key)+" "+f.sortDirection(key)))
+	}
+
+	return strings.Join(append(parts, tiebreak+" ASC"), ", ")
+}
+
 func (f Filters) limit() int {
 	return f.PageSize
 }
 
 func (f Filters) offset() int {
+	if f.Keyset {
+		return 0
+	}
+
 	return (f.Page - 1) * f.PageSize
 }
 
+// keyset returns the WHERE condition selecting the rows after the cursor and
+// the matching ORDER BY, with the cursor's placeholders numbered from n. It
+// appends the cursor values to args.
+func (f Filters) keyset(n int, args *[]any) (string, string) {
+	op, direction := ">", "ASC"
+	if strings.HasPrefix(f.Sort, "-") {
+		op, direction = "<", "DESC"
+	}
+
+	if strings.TrimPrefix(f.Sort, "-") == "created_at" {
+		order := "f.created_at " + direction + ", f.id " + direction
+		if f.After == nil {
+			return "TRUE", order
+		}
+
+		*args = append(*args, f.After.CreatedAt, f.After.ID)
+		return fmt.Sprintf("(f.created_at, f.id) %s ($%d, $%d)", op, n, n+1), order
+	}
+
+	order := "f.id " + direction
+	if f.After == nil {
+		return "TRUE", order
+	}
+
+	*args = append(*args, f.After.ID)
+	return fmt.Sprintf("f.id %s $%d", op, n), order
+}
+
 func calculateMetadata(totalRecords, page, pageSize int) Metadata {
 	if totalRecords == 0 {
 		return Metadata{}
@@ -76,3 +231,9 @@ func calculateMetadata(totalRecords, page, pageSize int) Metadata {
 		TotalRecords: totalRecords,
 	}
 }
+
+// Metadata builds the pagination metadata for result sets that are counted
+// outside of PostgreSQL, such as hits from the external search engine.
+func (f Filters) Metadata(totalRecords int) Metadata {
+	return calculateMetadata(totalRecords, f.Page, f.PageSize)
+}
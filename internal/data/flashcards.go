@@ -6,6 +6,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"html"
+	"math/rand/v2"
+	"slices"
+	"strings"
 	"time"
 
 	"flashcards-api.johndennehy101.tech/internal/validator"
@@ -15,9 +19,16 @@ import (
 type FlashcardType string
 
 const (
-	FlashcardQA    FlashcardType = "qa"
-	FlashcardMCQ   FlashcardType = "mcq"
-	FlashcardYesNo FlashcardType = "yes_no"
+	FlashcardQA      FlashcardType = "qa"
+	FlashcardMCQ     FlashcardType = "mcq"
+	FlashcardYesNo   FlashcardType = "yes_no"
+	FlashcardNumeric FlashcardType = "numeric"
+)
+
+var FlashcardTypes = []FlashcardType{FlashcardQA, FlashcardMCQ, FlashcardYesNo, FlashcardNumeric}
+
+var (
+	ErrInvalidFlashcardType = errors.New("invalid flashcard type")
 )
 
 type FlashcardContent interface {
@@ -31,21 +42,186 @@ type YesNoContent struct {
 
 func (YesNoContent) isFlashcardContent() {}
 
+const (
+	MatchExact           = "exact"
+	MatchCaseInsensitive = "case_insensitive"
+	MatchLevenshtein     = "levenshtein"
+)
+
 type QAContent struct {
-	Answer        string `json:"answer"`
-	Justification string `json:"justification,omitempty"`
+	Answer          string   `json:"answer"`
+	AcceptedAnswers []string `json:"accepted_answers,omitempty"`
+	MatchMode       string   `json:"match_mode,omitempty"`
+	Justification   string   `json:"justification,omitempty"`
 }
 
 func (QAContent) isFlashcardContent() {}
 
+// MCQOption is a single choice on an MCQ card. Its ID is stable across edits
+// and shuffles, so answers are submitted by option ID rather than position.
+type MCQOption struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
 type MCQContent struct {
-	Options       []string `json:"options"`
-	CorrectIndex  int      `json:"correct_index"`
-	Justification string   `json:"justification,omitempty"`
+	Options         []MCQOption `json:"options"`
+	CorrectOptionID string      `json:"correct_option_id"`
+	Justification   string      `json:"justification,omitempty"`
 }
 
 func (MCQContent) isFlashcardContent() {}
 
+// AssignOptionIDs gives every option without an ID a positional one ("o1",
+// "o2", ...), which correct_option_id may refer to when a card is created.
+func (c *MCQContent) AssignOptionIDs() {
+	for i := range c.Options {
+		if c.Options[i].ID == "" {
+			c.Options[i].ID = fmt.Sprintf("o%d", i+1)
+		}
+	}
+}
+
+func (c MCQContent) CorrectOption() (MCQOption, bool) {
+	for _, option := range c.Options {
+		if option.ID == c.CorrectOptionID {
+			return option, true
+		}
+	}
+
+	return MCQOption{}, false
+}
+
+// Shuffled returns a copy of the content with the options in random order.
+func (c MCQContent) Shuffled() MCQContent {
+	c.Options = slices.Clone(c.Options)
+
+	rand.Shuffle(len(c.Options), func(i, j int) {
+		c.Options[i], c.Options[j] = c.Options[j], c.Options[i]
+	})
+
+	return c
+}
+
+func ValidateMCQContent(v *validator.Validator, c MCQContent) {
+	ids := make([]string, len(c.Options))
+	texts := make([]string, len(c.Options))
+
+	for i, option := range c.Options {
+		ids[i] = option.ID
+		texts[i] = option.Text
+		v.Check(option.Text != "", "flashcard_content.options", "option text must not be empty")
+	}
+
+	v.Check(len(c.Options) >= 2, "flashcard_content.options", "at least 2 options required")
+	v.Check(validator.Unique(texts), "flashcard_content.options", "options must be unique")
+	v.Check(validator.Unique(ids), "flashcard_content.options", "option ids must be unique")
+	v.Check(validator.PermittedValue(c.CorrectOptionID, ids...), "flashcard_content.correct_option_id", "must match one of the option ids")
+}
+
+// NumericContent holds a numeric answer such as a time limit or fee amount.
+// An attempt is correct when it is within Tolerance of Answer.
+type NumericContent struct {
+	Answer        float64 `json:"answer"`
+	Unit          string  `json:"unit,omitempty"`
+	Tolerance     float64 `json:"tolerance"`
+	Justification string  `json:"justification,omitempty"`
+}
+
+func (NumericContent) isFlashcardContent() {}
+
+func unmarshalContent(flashcardType FlashcardType, contentJSON []byte) (FlashcardContent, error) {
+	switch flashcardType {
+	case FlashcardQA:
+		var qa QAContent
+		if err := json.Unmarshal(contentJSON, &qa); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal QA content: %w", err)
+		}
+		return qa, nil
+
+	case FlashcardMCQ:
+		var mcq MCQContent
+		if err := json.Unmarshal(contentJSON, &mcq); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal MCQ content: %w", err)
+		}
+		return mcq, nil
+
+	case FlashcardYesNo:
+		var yn YesNoContent
+		if err := json.Unmarshal(contentJSON, &yn); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal Yes/No content: %w", err)
+		}
+		return yn, nil
+
+	case FlashcardNumeric:
+		var num NumericContent
+		if err := json.Unmarshal(contentJSON, &num); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal numeric content: %w", err)
+		}
+		return num, nil
+
+	default:
+		return nil, fmt.Errorf("unknown flashcard type: %s", flashcardType)
+	}
+}
+
+// Justification returns the explanation shown with the answer, whatever the
+// content type.
+func Justification(content FlashcardContent) string {
+	switch c := content.(type) {
+	case QAContent:
+		return c.Justification
+	case MCQContent:
+		return c.Justification
+	case YesNoContent:
+		return c.Justification
+	case NumericContent:
+		return c.Justification
+	}
+	return ""
+}
+
+// DecodeContent decodes client-supplied flashcard_content for the given type
+// and normalises it, e.g. assigning ids to MCQ options that were sent
+// without one. Every write path decodes content through here and validates
+// it with ValidateContent, via ValidateFlashcard.
+func DecodeContent(flashcardType FlashcardType, raw []byte) (FlashcardContent, error) {
+	if !validator.PermittedValue(flashcardType, FlashcardTypes...) {
+		return nil, ErrInvalidFlashcardType
+	}
+
+	content, err := unmarshalContent(flashcardType, raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s content", flashcardType)
+	}
+
+	if mcq, ok := content.(MCQContent); ok {
+		mcq.AssignOptionIDs()
+		content = mcq
+	}
+
+	return content, nil
+}
+
+func ValidateContent(v *validator.Validator, content FlashcardContent) {
+	switch c := content.(type) {
+	case QAContent:
+		v.Check(c.Answer != "", "flashcard_content.answer", "answer must not be empty")
+		v.Check(validator.Unique(c.AcceptedAnswers), "flashcard_content.accepted_answers", "accepted answers must be unique")
+		v.Check(c.MatchMode == "" || validator.PermittedValue(c.MatchMode, MatchExact, MatchCaseInsensitive, MatchLevenshtein),
+			"flashcard_content.match_mode", "invalid match mode")
+
+	case MCQContent:
+		ValidateMCQContent(v, c)
+
+	case NumericContent:
+		v.Check(c.Tolerance >= 0, "flashcard_content.tolerance", "tolerance must not be negative")
+
+	case nil:
+		v.AddError("flashcard_content", "must be provided")
+	}
+}
+
 type Flashcard struct {
 	ID int64 `json:"id"`
 
@@ -60,6 +236,10 @@ type Flashcard struct {
 
 	Text string `json:"text"`
 
+	// The user who created the card, who can edit and delete it; nil for the
+	// shared catalogue cards, which only admins maintain
+	OwnerID *int64 `json:"owner_id"`
+
 	CreatedAt time.Time `json:"-"`
 
 	Question string           `json:"question"`
@@ -67,60 +247,179 @@ type Flashcard struct {
 	Content  FlashcardContent `json:"flashcard_content"`
 
 	Categories []string `json:"categories"`
-	Version    int32    `json:"version"`
+
+	// 1 (easiest) to 5 (hardest), nil when the card has not been rated
+	Difficulty *int `json:"difficulty"`
+
+	// Language the question and content are served in, set on reads that
+	// negotiate a translation through Accept-Language
+	Language string `json:"language,omitempty"`
+
+	// Progressive hints, only returned on reads that ask for ?reveal=hints
+	Hints []string `json:"hints,omitempty"`
+
+	// Values of the custom fields defined through /v1/metadata-fields
+	Metadata CardMetadata `json:"metadata"`
+
+	// 0 (needs rewriting) to 100, refreshed periodically from review outcomes
+	// and reports; nil until the card has enough reviews
+	QualityScore *float32 `json:"quality_score"`
+
+	// Share of recent reviews by all learners that failed the card, from 0
+	// to 1, refreshed with the quality score; nil until the card has enough
+	// reviews. New cards start with an ease matching it.
+	MeasuredDifficulty *float32 `json:"measured_difficulty"`
+
+	Version int32 `json:"version"`
 
 	CorrectCount int    `json:"correct_count"`
 	Status       string `json:"status"`
+
+	RelatedIDs      []int64 `json:"related_ids,omitzero"`
+	PrerequisiteIDs []int64 `json:"prerequisite_ids,omitzero"`
+
+	// Related resources, populated only when requested through ?include=
+	Stats         *CardStats     `json:"stats,omitempty"`
+	SourceSection *SourceSection `json:"source_section,omitempty"`
+	Attachments   []*Attachment  `json:"attachments,omitempty"`
+	Related       []*Flashcard   `json:"related,omitempty"`
+
+	CategoryDetails []*Category `json:"category_details,omitempty"`
+
+	// Where the card is in the user's study queue, set by the due cards
+	// listing
+	Schedule *CardSchedule `json:"schedule,omitempty"`
+
+	// Full-text search relevance and matching excerpt, set when listing
+	// with ?q=
+	Rank     float32 `json:"-"`
+	Headline string  `json:"headline,omitempty"`
+
+	// Sanitized HTML of the Markdown fields, populated with ?render=html
+	HTML *RenderedHTML `json:"html,omitempty"`
+
+	Links map[string]Link `json:"_links,omitempty"`
+}
+
+type RenderedHTML struct {
+	Text          string `json:"text"`
+	Justification string `json:"justification,omitempty"`
+}
+
+type Link struct {
+	Href   string `json:"href"`
+	Method string `json:"method"`
+}
+
+type CardStats struct {
+	LastReviewedAt   *time.Time `json:"last_reviewed_at"`
+	Learners         int        `json:"learners"`
+	MasteredBy       int        `json:"mastered_by"`
+	AverageLatencyMS *float64   `json:"average_latency_ms"`
+}
+
+type SourceSection struct {
+	SourceFile  string `json:"source_file"`
+	Section     string `json:"section"`
+	SectionType string `json:"section_type"`
+	CardCount   int    `json:"card_count"`
 }
 type FlashcardStats struct {
-	Total      int `json:"total"`
-	Mastered   int `json:"mastered"`
-	InProgress int `json:"in_progress"`
-	NotStarted int `json:"not_started"`
+	Total            int      `json:"total"`
+	Mastered         int      `json:"mastered"`
+	InProgress       int      `json:"in_progress"`
+	NotStarted       int      `json:"not_started"`
+	AverageLatencyMS *float64 `json:"average_latency_ms"`
 }
 
-type Category struct {
+type FlashcardExport struct {
+	ExportedAt time.Time       `json:"exported_at"`
+	Stats      *FlashcardStats `json:"stats"`
+	Flashcards []*Flashcard    `json:"flashcards"`
+}
+
+type CategoryCount struct {
 	Name  string `json:"name"`
 	Count int    `json:"count"`
 }
 
 type FilterMetadata struct {
-	Categories    []Category `json:"categories"`
-	SourceFiles   []string   `json:"source_files"`
-	Sections      []string   `json:"sections"`
-	QuestionTypes []string   `json:"question_types"`
+	Categories    []CategoryCount `json:"categories"`
+	SourceFiles   []string        `json:"source_files"`
+	Sections      []string        `json:"sections"`
+	QuestionTypes []string        `json:"question_types"`
 }
 
 func ValidateFlashcard(v *validator.Validator, flashcard *Flashcard) {
 	v.Check(flashcard.Question != "", "question", "question must be provided")
 	v.Check(flashcard.Text != "", "text", "text must be provided")
 	v.Check(validator.Unique(flashcard.Categories), "categories", "categories must be unique")
-	v.Check(validator.PermittedValue(flashcard.Type, FlashcardQA, FlashcardMCQ, FlashcardYesNo),
-		"flashcard_type", "invalid flashcard type")
+	ValidateCategoryNames(v, "categories", flashcard.Categories)
+	v.Check(validator.PermittedValue(flashcard.Type, FlashcardTypes...), "flashcard_type", "invalid flashcard type")
+
+	ValidateContent(v, flashcard.Content)
+
+	if flashcard.Difficulty != nil {
+		v.Check(*flashcard.Difficulty >= 1 && *flashcard.Difficulty <= 5, "difficulty", "must be between 1 and 5")
+	}
+
+	v.Check(len(flashcard.Hints) <= 10, "hints", "must not contain more than 10 hints")
+	v.Check(validator.Unique(flashcard.Hints), "hints", "must not contain duplicate values")
+	for _, hint := range flashcard.Hints {
+		v.Check(hint != "", "hints", "must not contain empty hints")
+		v.Check(len(hint) <= 500, "hints", "each hint must not be more than 500 bytes long")
+	}
+}
+
+// flashcardColumns is the select list shared by every flashcard query. It
+// expects the flashcards table to be aliased as f and matches scanTargets.
+const flashcardColumns = `
+            f.id, f.section, f.section_type, f.source_file, f.text, f.question,
+            f.flashcard_type, f.flashcard_content, f.categories, f.difficulty,
+            f.hints, f.metadata, f.quality_score, f.measured_difficulty, f.user_id, f.version, f.created_at`
+
+// scanTargets returns the Scan destinations for flashcardColumns, followed by
+// the per-user progress columns when withProgress is set.
+func (f *Flashcard) scanTargets(contentJSON *[]byte, withProgress bool) []any {
+	targets := []any{
+		&f.ID, &f.Section, &f.SectionType, &f.SourceFile, &f.Text, &f.Question,
+		&f.Type, contentJSON, pq.Array(&f.Categories), &f.Difficulty,
+		pq.Array(&f.Hints), &f.Metadata, &f.QualityScore, &f.MeasuredDifficulty, &f.OwnerID, &f.Version, &f.CreatedAt,
+	}
+
+	if withProgress {
+		targets = append(targets, &f.CorrectCount, &f.Status)
+	}
+
+	return targets
 }
 
 type FlashcardModel struct {
 	DB *sql.DB
 }
 
-func (m FlashcardModel) Insert(flashcard *Flashcard, userID int64) error {
+// Insert creates the card, owned by the user, and starts the user's progress
+// on it.
+func (m FlashcardModel) Insert(ctx context.Context, flashcard *Flashcard, userID int64) error {
 	queryCard := `
        INSERT INTO flashcards (
           section, section_type, source_file, text, question,
-          flashcard_type, flashcard_content, categories, version, created_at
-       ) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+          flashcard_type, flashcard_content, categories, difficulty, hints, metadata, version, created_at, user_id
+       ) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,COALESCE($10::text[], '{}'),$11,$12,$13,$14)
        RETURNING id, created_at, version`
 
 	queryProgress := `
        INSERT INTO user_flashcards (user_id, flashcard_id, correct_count, status, last_reviewed_at)
        VALUES ($1, $2, 0, 'not_started', NOW())`
 
+	flashcard.Categories = NormalizeCategories(flashcard.Categories)
+
 	contentJSON, err := json.Marshal(flashcard.Content)
 	if err != nil {
 		return fmt.Errorf("failed to marshal flashcard content: %w", err)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	tx, err := m.DB.BeginTx(ctx, nil)
@@ -129,10 +428,12 @@ func (m FlashcardModel) Insert(flashcard *Flashcard, userID int64) error {
 	}
 	defer tx.Rollback()
 
+	flashcard.OwnerID = &userID
+
 	err = tx.QueryRowContext(ctx, queryCard,
 		flashcard.Section, flashcard.SectionType, flashcard.SourceFile,
 		flashcard.Text, flashcard.Question, flashcard.Type,
-		contentJSON, pq.Array(flashcard.Categories), flashcard.Version, time.Now(),
+		contentJSON, pq.Array(flashcard.Categories), flashcard.Difficulty, pq.Array(flashcard.Hints), flashcard.Metadata, flashcard.Version, time.Now(), userID,
 	).Scan(&flashcard.ID, &flashcard.CreatedAt, &flashcard.Version)
 
 	if err != nil {
@@ -147,43 +448,58 @@ func (m FlashcardModel) Insert(flashcard *Flashcard, userID int64) error {
 	return tx.Commit()
 }
 
-func (m FlashcardModel) Get(id int64, userID int64) (*Flashcard, error) {
+// FindDuplicate returns the id of one of the user's flashcards whose question
+// matches question once case, punctuation and spacing are ignored, or
+// ErrRecordNotFound if there is none.
+func (m FlashcardModel) FindDuplicate(ctx context.Context, question string, userID int64) (int64, error) {
+	query := `
+        SELECT f.id
+        FROM flashcards f
+        INNER JOIN user_flashcards uf ON uf.flashcard_id = f.id AND uf.user_id = $2
+        WHERE f.question_normalized = normalize_question($1)
+        ORDER BY f.id
+        LIMIT 1`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var id int64
+
+	err := m.DB.QueryRowContext(ctx, query, question, userID).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	return id, nil
+}
+
+func (m FlashcardModel) Get(ctx context.Context, id int64, userID int64) (*Flashcard, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
-	query := `
+	query := fmt.Sprintf(`
         SELECT 
-            f.id, f.section, f.section_type, f.source_file, f.text, f.question,
-            f.flashcard_type, f.flashcard_content, f.categories, f.version, f.created_at,
+            %s,
             COALESCE(uf.correct_count, 0),
             COALESCE(uf.status, 'not_started')
         FROM flashcards f
         LEFT JOIN user_flashcards uf ON f.id = uf.flashcard_id AND uf.user_id = $2
-        WHERE f.id = $1`
+        WHERE f.id = $1`, flashcardColumns)
 
 	var flashcard Flashcard
 	var contentJSON []byte
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(
-		&flashcard.ID,
-		&flashcard.Section,
-		&flashcard.SectionType,
-		&flashcard.SourceFile,
-		&flashcard.Text,
-		&flashcard.Question,
-		&flashcard.Type,
-		&contentJSON,
-		pq.Array(&flashcard.Categories),
-		&flashcard.Version,
-		&flashcard.CreatedAt,
-		&flashcard.CorrectCount,
-		&flashcard.Status,
-	)
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(flashcard.scanTargets(&contentJSON, true)...)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrRecordNotFound
@@ -191,36 +507,15 @@ func (m FlashcardModel) Get(id int64, userID int64) (*Flashcard, error) {
 		return nil, err
 	}
 
-	switch flashcard.Type {
-	case FlashcardQA:
-		var qa QAContent
-		if err := json.Unmarshal(contentJSON, &qa); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal QA content: %w", err)
-		}
-		flashcard.Content = qa
-
-	case FlashcardMCQ:
-		var mcq MCQContent
-		if err := json.Unmarshal(contentJSON, &mcq); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal MCQ content: %w", err)
-		}
-		flashcard.Content = mcq
-
-	case FlashcardYesNo:
-		var yn YesNoContent
-		if err := json.Unmarshal(contentJSON, &yn); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal Yes/No content: %w", err)
-		}
-		flashcard.Content = yn
-
-	default:
-		return nil, fmt.Errorf("unknown flashcard type: %s", flashcard.Type)
+	flashcard.Content, err = unmarshalContent(flashcard.Type, contentJSON)
+	if err != nil {
+		return nil, err
 	}
 
 	return &flashcard, nil
 }
 
-func (m FlashcardModel) GetFilterMetadata(userID int64, file string, qType string, hideMastered bool) (*FilterMetadata, error) {
+func (m FlashcardModel) GetFilterMetadata(ctx context.Context, userID int64, file string, qType string, hideMastered bool) (*FilterMetadata, error) {
 	query := `
         SELECT jsonb_build_object(
             'source_files', (
@@ -275,11 +570,13 @@ func (m FlashcardModel) GetFilterMetadata(userID int64, file string, qType strin
 		return nil, err
 	}
 
-	metadata.QuestionTypes = []string{"QA", "MCQ", "YesNo"}
+	metadata.QuestionTypes = []string{"QA", "MCQ", "YesNo", "Numeric"}
 	return &metadata, nil
 }
 
-func (m FlashcardModel) Update(flashcard *Flashcard) error {
+func (m FlashcardModel) Update(ctx context.Context, flashcard *Flashcard) error {
+	flashcard.Categories = NormalizeCategories(flashcard.Categories)
+
 	contentJSON, err := json.Marshal(flashcard.Content)
 	if err != nil {
 		return fmt.Errorf("failed to marshal flashcard content: %w", err)
@@ -296,8 +593,11 @@ func (m FlashcardModel) Update(flashcard *Flashcard) error {
 			flashcard_type = $6,
 			flashcard_content = $7,
 			categories = $8,
+			difficulty = $9,
+			hints = COALESCE($10::text[], '{}'),
+			metadata = $11,
 			version = version + 1
-		WHERE id = $9 AND version = $10
+		WHERE id = $12 AND version = $13
 		RETURNING version
 	`
 
@@ -310,11 +610,14 @@ func (m FlashcardModel) Update(flashcard *Flashcard) error {
 		flashcard.Type,
 		contentJSON,
 		pq.Array(flashcard.Categories),
+		flashcard.Difficulty,
+		pq.Array(flashcard.Hints),
+		flashcard.Metadata,
 		flashcard.ID,
 		flashcard.Version,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 
 	defer cancel()
 
@@ -331,12 +634,14 @@ func (m FlashcardModel) Update(flashcard *Flashcard) error {
 	return nil
 }
 
-func (m FlashcardModel) Delete(id int64) error {
+// Delete removes the card, keeping a snapshot of it in the user's trash so
+// it can be restored later.
+func (m FlashcardModel) Delete(ctx context.Context, id, userID int64) error {
 	if id < 1 {
 		return ErrRecordNotFound
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	tx, err := m.DB.BeginTx(ctx, nil)
@@ -350,6 +655,11 @@ func (m FlashcardModel) Delete(id int64) error {
 		return err
 	}
 
+	_, err = tx.ExecContext(ctx, trashQuery, id, userID)
+	if err != nil {
+		return err
+	}
+
 	query := `DELETE FROM flashcards WHERE id = $1`
 	result, err := tx.ExecContext(ctx, query, id)
 	if err != nil {
@@ -368,18 +678,19 @@ func (m FlashcardModel) Delete(id int64) error {
 	return tx.Commit()
 }
 
-func (m FlashcardModel) GetUserStats(userID int64) (*FlashcardStats, error) {
+func (m FlashcardModel) GetUserStats(ctx context.Context, userID int64) (*FlashcardStats, error) {
 	query := `
         SELECT 
             COUNT(*),
             COUNT(*) FILTER (WHERE status = 'mastered'),
             COUNT(*) FILTER (WHERE status = 'in_progress'),
-            COUNT(*) FILTER (WHERE status = 'not_started')
+            COUNT(*) FILTER (WHERE status = 'not_started'),
+            (SELECT AVG(latency_ms) FROM flashcard_reviews WHERE user_id = $1)
         FROM user_flashcards
         WHERE user_id = $1`
 
 	var stats FlashcardStats
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	err := m.DB.QueryRowContext(ctx, query, userID).Scan(
@@ -387,6 +698,7 @@ func (m FlashcardModel) GetUserStats(userID int64) (*FlashcardStats, error) {
 		&stats.Mastered,
 		&stats.InProgress,
 		&stats.NotStarted,
+		&stats.AverageLatencyMS,
 	)
 
 	if err != nil {
@@ -396,39 +708,182 @@ func (m FlashcardModel) GetUserStats(userID int64) (*FlashcardStats, error) {
 	return &stats, nil
 }
 
-func (m FlashcardModel) GetAll(userID int64, section, qType, sourceFile string, categories []string, hideMastered bool, filters Filters) ([]*Flashcard, Metadata, error) {
+// FlashcardFilter holds the filters of the flashcard listing. Zero values
+// match every card.
+type FlashcardFilter struct {
+	Section       string
+	SectionType   string
+	Type          string
+	SourceFile    string
+	Categories    []string
+	HideMastered  bool
+	MinDifficulty int
+	MaxDifficulty int
+	Metadata      CardMetadata
+
+	// Cards must have every one of Categories, or any of them when
+	// CategoriesAny is set
+	CategoriesAny bool
+
+	// Query is matched with full-text search, or by trigram word similarity
+	// against the question and text when Fuzzy is set. A fuzzy match needs a
+	// similarity of at least Similarity.
+	Query      string
+	Fuzzy      bool
+	Similarity float64
+
+	// Only cards created after, and before, these times when set
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+
+	// Only cards with, or without, a justification when set
+	HasJustification *bool
+
+	// Only cards of one of these types, and in one of these decks, when set
+	Types   []string
+	DeckIDs []int64
+
+	// Only cards in this deck when set
+	DeckID int64
+
+	// Leave out cards whose decks are all archived. Cards outside any of
+	// the user's decks are kept.
+	HideArchived bool
+}
+
+// where returns the WHERE conditions of the filter for a query over
+// flashcards f left-joined to the user's progress in user_flashcards uf,
+// together with the values they bind. userID is always $1 and Query is $10,
+// and when DeckID is set it is the last of args; callers number their own
+// parameters after len(args).
+func (filter FlashcardFilter) where(userID int64) (string, []any) {
+	args := []any{
+		userID,
+		filter.Section,
+		filter.Type,
+		filter.SourceFile,
+		pq.Array(NormalizeCategories(filter.Categories)),
+		filter.HideMastered,
+		filter.MinDifficulty,
+		filter.MaxDifficulty,
+		filter.Metadata,
+		filter.Query,
+		filter.CreatedAfter,
+		filter.CreatedBefore,
+		filter.SectionType,
+		filter.HasJustification,
+	}
+
+	match := `($10 = '' OR f.search_vector @@ websearch_to_tsquery('english', $10))`
+
+	// The remaining placeholders are only bound when used, as PostgreSQL
+	// rejects parameters it can't infer a type for.
+	if filter.Fuzzy && filter.Query != "" {
+		args = append(args, filter.Similarity)
+		match = fmt.Sprintf(`GREATEST(word_similarity($10, f.question), word_similarity($10, f.text)) >= $%d`, len(args))
+	}
+
+	types := "TRUE"
+	if len(filter.Types) > 0 {
+		args = append(args, pq.Array(filter.Types))
+		types = fmt.Sprintf(`f.flashcard_type = ANY($%d)`, len(args))
+	}
+
+	decks := "TRUE"
+	if len(filter.DeckIDs) > 0 {
+		args = append(args, pq.Array(filter.DeckIDs))
+		decks = fmt.Sprintf(`f.id IN (SELECT df.flashcard_id FROM deck_flashcards df WHERE df.deck_id = ANY($%d))`, len(args))
+	}
+
+	deck := "TRUE"
+	if filter.DeckID != 0 {
+		args = append(args, filter.DeckID)
+		deck = fmt.Sprintf(`f.id IN (SELECT df.flashcard_id FROM deck_flashcards df WHERE df.deck_id = $%d)`, len(args))
+	}
+
+	archived := "TRUE"
+	if filter.HideArchived {
+		archived = `NOT EXISTS (
+           SELECT 1 FROM deck_flashcards df INNER JOIN decks d ON d.id = df.deck_id
+           WHERE df.flashcard_id = f.id AND d.archived_at IS NOT NULL AND d.user_id = $1
+       ) OR EXISTS (
+           SELECT 1 FROM deck_flashcards df INNER JOIN decks d ON d.id = df.deck_id
+           WHERE df.flashcard_id = f.id AND d.archived_at IS NULL AND d.user_id = $1
+       )`
+	}
+
+	// Both operators are served by the GIN index on categories.
+	categories := `@>`
+	if filter.CategoriesAny {
+		categories = `&&`
+	}
+
+	where := fmt.Sprintf(`(to_tsvector('simple', f.section) @@ plainto_tsquery('simple', $2) OR $2 = '')
+       AND (f.flashcard_type = $3 OR $3 = '')
+       AND (LOWER(f.source_file) = LOWER($4) OR $4 = '')
+       AND (f.categories %s $5 OR $5 = '{}')
+       AND ($6 = false OR COALESCE(uf.status, '') != 'mastered')
+       AND ($7 = 0 OR f.difficulty >= $7)
+       AND ($8 = 0 OR f.difficulty <= $8)
+       AND f.metadata @> $9
+       AND ($11::timestamptz IS NULL OR f.created_at > $11)
+       AND ($12::timestamptz IS NULL OR f.created_at < $12)
+       AND (f.section_type = $13 OR $13 = '')
+       AND ($14::boolean IS NULL OR (COALESCE(f.flashcard_content->>'justification', '') != '') = $14)
+       AND %s
+       AND %s
+       AND %s
+       AND %s
+       AND (%s)`, categories, match, types, decks, deck, archived)
+
+	return where, args
+}
+
+func (m FlashcardModel) GetAll(ctx context.Context, userID int64, filter FlashcardFilter, filters Filters) ([]*Flashcard, Metadata, error) {
+	where, args := filter.where(userID)
+
+	rank := `CASE WHEN $10 = '' THEN 0 ELSE ts_rank(f.search_vector, websearch_to_tsquery('english', $10)) END`
+	if filter.Fuzzy && filter.Query != "" {
+		rank = `GREATEST(word_similarity($10, f.question), word_similarity($10, f.text))`
+	}
+
+	// Deck listings can be sorted by the deck's own order, df.position.
+	join := ""
+	if filter.DeckID != 0 {
+		join = fmt.Sprintf(`INNER JOIN deck_flashcards df ON df.flashcard_id = f.id AND df.deck_id = $%d`, len(args))
+	}
+
+	args = append(args, filters.limit(), filters.offset())
+	limit, offset := len(args)-1, len(args)
+
+	after := "TRUE"
+	var order string
+
+	if filters.Keyset {
+		after, order = filters.keyset(len(args)+1, &args)
+	} else {
+		order = filters.orderBy("f.id")
+	}
+
 	query := fmt.Sprintf(`
        SELECT 
           count(*) OVER(),
-          f.id, f.section, f.section_type, f.source_file, f.text, f.question,
-          f.flashcard_type, f.flashcard_content, f.categories, f.version, f.created_at,
+          %s,
           COALESCE(uf.correct_count, 0),
-          COALESCE(uf.status, 'not_started')
+          COALESCE(uf.status, 'not_started'),
+          %s AS rank
        FROM flashcards f
        LEFT JOIN user_flashcards uf ON f.id = uf.flashcard_id AND uf.user_id = $1
-       WHERE (to_tsvector('simple', f.section) @@ plainto_tsquery('simple', $2) OR $2 = '')
-       AND (f.flashcard_type = $3 OR $3 = '')
-       AND (LOWER(f.source_file) = LOWER($4) OR $4 = '')
-       AND (f.categories @> $5 OR $5 = '{}')
-       AND ($6 = false OR COALESCE(uf.status, '') != 'mastered')
-       ORDER BY %s %s, f.id ASC
-       LIMIT $7 OFFSET $8`, filters.sortColumn(), filters.sortDirection())
+       %s
+       WHERE %s
+       AND %s
+       ORDER BY %s
+       LIMIT $%d OFFSET $%d`, flashcardColumns, rank, join, where, after, order, limit, offset)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	rows, err := m.DB.QueryContext(
-		ctx,
-		query,
-		userID,
-		section,
-		qType,
-		sourceFile,
-		pq.Array(categories),
-		hideMastered,
-		filters.limit(),
-		filters.offset(),
-	)
+	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, Metadata{}, err
 	}
@@ -441,37 +896,14 @@ func (m FlashcardModel) GetAll(userID int64, section, qType, sourceFile string,
 		var flashcard Flashcard
 		var contentJSON []byte
 
-		err := rows.Scan(
-			&totalRecords, &flashcard.ID, &flashcard.Section, &flashcard.SectionType,
-			&flashcard.SourceFile, &flashcard.Text, &flashcard.Question, &flashcard.Type,
-			&contentJSON, pq.Array(&flashcard.Categories), &flashcard.Version,
-			&flashcard.CreatedAt, &flashcard.CorrectCount, &flashcard.Status,
-		)
+		err := rows.Scan(append(append([]any{&totalRecords}, flashcard.scanTargets(&contentJSON, true)...), &flashcard.Rank)...)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
 
-		switch flashcard.Type {
-		case FlashcardQA:
-			var qa QAContent
-			if err := json.Unmarshal(contentJSON, &qa); err != nil {
-				return nil, Metadata{}, err
-			}
-			flashcard.Content = qa
-		case FlashcardMCQ:
-			var mcq MCQContent
-			if err := json.Unmarshal(contentJSON, &mcq); err != nil {
-				return nil, Metadata{}, err
-			}
-			flashcard.Content = mcq
-		case FlashcardYesNo:
-			var yn YesNoContent
-			if err := json.Unmarshal(contentJSON, &yn); err != nil {
-				return nil, Metadata{}, err
-			}
-			flashcard.Content = yn
-		default:
-			return nil, Metadata{}, fmt.Errorf("unknown flashcard type: %s", flashcard.Type)
+		flashcard.Content, err = unmarshalContent(flashcard.Type, contentJSON)
+		if err != nil {
+			return nil, Metadata{}, err
 		}
 
 		flashcards = append(flashcards, &flashcard)
@@ -485,7 +917,7 @@ func (m FlashcardModel) GetAll(userID int64, section, qType, sourceFile string,
 	return flashcards, metadata, nil
 }
 
-func (m FlashcardModel) IncrementCorrectCount(id int64, userID int64) error {
+func (m FlashcardModel) IncrementCorrectCount(ctx context.Context, id int64, userID int64) error {
 	query := `
         INSERT INTO user_flashcards (user_id, flashcard_id, correct_count, last_reviewed_at, status)
         VALUES ($1, $2, 1, NOW(), 'in_progress')
@@ -499,14 +931,14 @@ func (m FlashcardModel) IncrementCorrectCount(id int64, userID int64) error {
             END
         WHERE user_flashcards.correct_count < 5`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	_, err := m.DB.ExecContext(ctx, query, userID, id)
 	return err
 }
 
-func (m FlashcardModel) ResetCorrectCount(id int64, userID int64) error {
+func (m FlashcardModel) ResetCorrectCount(ctx context.Context, id int64, userID int64) error {
 	query := `
         INSERT INTO user_flashcards (user_id, flashcard_id, correct_count, last_reviewed_at, status)
         VALUES ($1, $2, 0, NOW(), 'not_started')
@@ -516,9 +948,363 @@ func (m FlashcardModel) ResetCorrectCount(id int64, userID int64) error {
             last_reviewed_at = NOW(),
             status = 'not_started'`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	_, err := m.DB.ExecContext(ctx, query, userID, id)
 	return err
 }
+
+func (m FlashcardModel) GetByIDs(ctx context.Context, ids []int64, userID int64) ([]*Flashcard, error) {
+	query := fmt.Sprintf(`
+        SELECT 
+            %s,
+            COALESCE(uf.correct_count, 0),
+            COALESCE(uf.status, 'not_started')
+        FROM flashcards f
+        LEFT JOIN user_flashcards uf ON f.id = uf.flashcard_id AND uf.user_id = $2
+        WHERE f.id = ANY($1)
+        ORDER BY array_position($1, f.id)`, flashcardColumns)
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	flashcards := []*Flashcard{}
+
+	for rows.Next() {
+		var flashcard Flashcard
+		var contentJSON []byte
+
+		err := rows.Scan(flashcard.scanTargets(&contentJSON, true)...)
+		if err != nil {
+			return nil, err
+		}
+
+		flashcard.Content, err = unmarshalContent(flashcard.Type, contentJSON)
+		if err != nil {
+			return nil, err
+		}
+
+		flashcards = append(flashcards, &flashcard)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return flashcards, nil
+}
+
+var headlineMarks = strings.NewReplacer("[[[", "<mark>", "]]]", "</mark>")
+
+// GetHeadlines returns an HTML excerpt of each card's question and text with
+// the terms matching the full-text query q wrapped in <mark>. The card text
+// is escaped; ts_headline marks matches with placeholders that are swapped
+// for the tags afterwards.
+func (m FlashcardModel) GetHeadlines(ctx context.Context, ids []int64, q string) (map[int64]string, error) {
+	query := `
+        SELECT id, ts_headline('english', question || ' … ' || text, websearch_to_tsquery('english', $2),
+            'StartSel=[[[, StopSel=]]], MaxFragments=2, MaxWords=30, MinWords=10')
+        FROM flashcards
+        WHERE id = ANY($1)`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids), q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	headlines := make(map[int64]string, len(ids))
+
+	for rows.Next() {
+		var id int64
+		var headline string
+
+		err := rows.Scan(&id, &headline)
+		if err != nil {
+			return nil, err
+		}
+
+		headline = html.EscapeString(headline)
+		headlines[id] = headlineMarks.Replace(headline)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return headlines, nil
+}
+
+// Search is the database fallback used when no external search engine is
+// configured. It performs a case-insensitive substring match on the question
+// and text columns.
+func (m FlashcardModel) Search(ctx context.Context, userID int64, q string, filters Filters) ([]*Flashcard, Metadata, error) {
+	query := fmt.Sprintf(`
+       SELECT 
+          count(*) OVER(),
+          %s,
+          COALESCE(uf.correct_count, 0),
+          COALESCE(uf.status, 'not_started')
+       FROM flashcards f
+       LEFT JOIN user_flashcards uf ON f.id = uf.flashcard_id AND uf.user_id = $1
+       WHERE f.question ILIKE '%%' || $2 || '%%' OR f.text ILIKE '%%' || $2 || '%%'
+       ORDER BY f.id ASC
+       LIMIT $3 OFFSET $4`, flashcardColumns)
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, q, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	flashcards := []*Flashcard{}
+
+	for rows.Next() {
+		var flashcard Flashcard
+		var contentJSON []byte
+
+		err := rows.Scan(append([]any{&totalRecords}, flashcard.scanTargets(&contentJSON, true)...)...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		flashcard.Content, err = unmarshalContent(flashcard.Type, contentJSON)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		flashcards = append(flashcards, &flashcard)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return flashcards, metadata, nil
+}
+
+// GetAllForIndex returns up to limit flashcards with an id greater than
+// afterID, without any per-user progress, for mirroring the card bank into an
+// external search engine one page at a time.
+func (m FlashcardModel) GetAllForIndex(ctx context.Context, afterID int64, limit int) ([]*Flashcard, error) {
+	query := fmt.Sprintf(`
+        SELECT %s
+        FROM flashcards f
+        WHERE f.id > $1
+        ORDER BY f.id
+        LIMIT $2`, flashcardColumns)
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	flashcards := []*Flashcard{}
+
+	for rows.Next() {
+		var flashcard Flashcard
+		var contentJSON []byte
+
+		err := rows.Scan(flashcard.scanTargets(&contentJSON, false)...)
+		if err != nil {
+			return nil, err
+		}
+
+		flashcard.Content, err = unmarshalContent(flashcard.Type, contentJSON)
+		if err != nil {
+			return nil, err
+		}
+
+		flashcards = append(flashcards, &flashcard)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return flashcards, nil
+}
+
+// Export reads the user's flashcards and progress stats inside a single
+// read-only REPEATABLE READ transaction, so every query sees the same snapshot
+// and the export stays internally consistent while imports and edits run.
+func (m FlashcardModel) Export(ctx context.Context, userID int64) (*FlashcardExport, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	export := FlashcardExport{
+		Stats:      &FlashcardStats{},
+		Flashcards: []*Flashcard{},
+	}
+
+	err = tx.QueryRowContext(ctx, "SELECT NOW()").Scan(&export.ExportedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+        SELECT 
+            %s,
+            uf.correct_count, uf.status
+        FROM flashcards f
+        INNER JOIN user_flashcards uf ON f.id = uf.flashcard_id
+        WHERE uf.user_id = $1
+        ORDER BY f.id`, flashcardColumns)
+
+	rows, err := tx.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var flashcard Flashcard
+		var contentJSON []byte
+
+		err := rows.Scan(flashcard.scanTargets(&contentJSON, true)...)
+		if err != nil {
+			return nil, err
+		}
+
+		flashcard.Content, err = unmarshalContent(flashcard.Type, contentJSON)
+		if err != nil {
+			return nil, err
+		}
+
+		export.Flashcards = append(export.Flashcards, &flashcard)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	queryStats := `
+        SELECT 
+            COUNT(*),
+            COUNT(*) FILTER (WHERE status = 'mastered'),
+            COUNT(*) FILTER (WHERE status = 'in_progress'),
+            COUNT(*) FILTER (WHERE status = 'not_started')
+        FROM user_flashcards
+        WHERE user_id = $1`
+
+	err = tx.QueryRowContext(ctx, queryStats, userID).Scan(
+		&export.Stats.Total,
+		&export.Stats.Mastered,
+		&export.Stats.InProgress,
+		&export.Stats.NotStarted,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return &export, nil
+}
+
+func (m FlashcardModel) GetCardStats(ctx context.Context, ids []int64, userID int64) (map[int64]*CardStats, error) {
+	query := `
+        SELECT 
+            f.id,
+            MAX(uf.last_reviewed_at) FILTER (WHERE uf.user_id = $2),
+            COUNT(uf.user_id),
+            COUNT(uf.user_id) FILTER (WHERE uf.status = 'mastered'),
+            (SELECT AVG(fr.latency_ms) FROM flashcard_reviews fr WHERE fr.flashcard_id = f.id)
+        FROM flashcards f
+        LEFT JOIN user_flashcards uf ON f.id = uf.flashcard_id
+        WHERE f.id = ANY($1)
+        GROUP BY f.id`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make(map[int64]*CardStats, len(ids))
+
+	for rows.Next() {
+		var id int64
+		var s CardStats
+
+		err := rows.Scan(&id, &s.LastReviewedAt, &s.Learners, &s.MasteredBy, &s.AverageLatencyMS)
+		if err != nil {
+			return nil, err
+		}
+
+		stats[id] = &s
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+func (m FlashcardModel) GetSourceSections(ctx context.Context, sections []string) ([]*SourceSection, error) {
+	query := `
+        SELECT source_file, section, MIN(section_type), COUNT(*)
+        FROM flashcards
+        WHERE section = ANY($1)
+        GROUP BY source_file, section`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(sections))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sourceSections := []*SourceSection{}
+
+	for rows.Next() {
+		var s SourceSection
+
+		err := rows.Scan(&s.SourceFile, &s.Section, &s.SectionType, &s.CardCount)
+		if err != nil {
+			return nil, err
+		}
+
+		sourceSections = append(sourceSections, &s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sourceSections, nil
+}
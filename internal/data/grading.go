@@ -0,0 +1,133 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+)
+
+var (
+	ErrInvalidAnswer = errors.New("invalid answer")
+)
+
+type AnswerResult struct {
+	Correct       bool   `json:"correct"`
+	Expected      any    `json:"expected"`
+	Justification string `json:"justification,omitempty"`
+}
+
+// GradeAnswer checks a submitted answer against the flashcard content. The
+// shape of the answer depends on the flashcard type: a string for QA, the
+// option ID for MCQ, a boolean for Yes/No and a number for numeric cards.
+func GradeAnswer(content FlashcardContent, answer json.RawMessage) (*AnswerResult, error) {
+	switch c := content.(type) {
+	case QAContent:
+		var s string
+		if err := json.Unmarshal(answer, &s); err != nil {
+			return nil, ErrInvalidAnswer
+		}
+		return &AnswerResult{
+			Correct:       matchesAnswer(s, c),
+			Expected:      c.Answer,
+			Justification: c.Justification,
+		}, nil
+
+	case MCQContent:
+		var id string
+		if err := json.Unmarshal(answer, &id); err != nil {
+			return nil, ErrInvalidAnswer
+		}
+		return &AnswerResult{
+			Correct:       id == c.CorrectOptionID,
+			Expected:      c.CorrectOptionID,
+			Justification: c.Justification,
+		}, nil
+
+	case YesNoContent:
+		var b bool
+		if err := json.Unmarshal(answer, &b); err != nil {
+			return nil, ErrInvalidAnswer
+		}
+		return &AnswerResult{
+			Correct:       b == c.Correct,
+			Expected:      c.Correct,
+			Justification: c.Justification,
+		}, nil
+
+	case NumericContent:
+		var f float64
+		if err := json.Unmarshal(answer, &f); err != nil {
+			return nil, ErrInvalidAnswer
+		}
+		return &AnswerResult{
+			Correct:       math.Abs(f-c.Answer) <= c.Tolerance,
+			Expected:      map[string]any{"answer": c.Answer, "unit": c.Unit, "tolerance": c.Tolerance},
+			Justification: c.Justification,
+		}, nil
+
+	default:
+		return nil, ErrInvalidAnswer
+	}
+}
+
+// matchesAnswer compares a free-text attempt with the answer and every
+// accepted variant using the card's match mode. Case-insensitive matching is
+// the default when no mode is set.
+func matchesAnswer(attempt string, c QAContent) bool {
+	attempt = strings.TrimSpace(attempt)
+
+	candidates := append([]string{c.Answer}, c.AcceptedAnswers...)
+
+	for _, candidate := range candidates {
+		candidate = strings.TrimSpace(candidate)
+
+		switch c.MatchMode {
+		case MatchExact:
+			if attempt == candidate {
+				return true
+			}
+
+		case MatchLevenshtein:
+			a, b := strings.ToLower(attempt), strings.ToLower(candidate)
+			if levenshtein(a, b) <= max(1, len([]rune(b))/5) {
+				return true
+			}
+
+		default:
+			if strings.EqualFold(attempt, candidate) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
@@ -0,0 +1,145 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/validator"
+)
+
+const (
+	EventFlashcardCreated = "flashcard.created"
+	EventQuizCompleted    = "quiz.completed"
+)
+
+var HookEvents = []string{EventFlashcardCreated, EventQuizCompleted}
+
+type HookSubscription struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"-"`
+	Event     string    `json:"event"`
+	TargetURL string    `json:"target_url"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, which
+// netip doesn't count as private.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// PublicAddr reports whether hooks may be delivered to addr. Loopback,
+// private, shared, link-local (which takes in the 169.254.169.254 metadata
+// endpoint of cloud hosts), multicast and unspecified addresses all reach
+// the server's own network rather than the subscriber's.
+func PublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	return addr.IsValid() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!sharedAddressSpace.Contains(addr) &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsMulticast() &&
+		!addr.IsUnspecified()
+}
+
+// ValidateHookSubscription checks the target URL as written. A host name can
+// still resolve to an internal address, so deliveries check the address they
+// connect to as well.
+func ValidateHookSubscription(v *validator.Validator, s *HookSubscription) {
+	v.Check(validator.PermittedValue(s.Event, HookEvents...), "event", "invalid event")
+
+	u, err := url.Parse(s.TargetURL)
+	v.Check(err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != "", "target_url", "must be an absolute http(s) URL")
+	v.Check(len(s.TargetURL) <= 2048, "target_url", "must not be more than 2048 bytes long")
+
+	if err == nil {
+		host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+
+		public := host != "localhost" && !strings.HasSuffix(host, ".localhost")
+		if addr, err := netip.ParseAddr(host); err == nil {
+			public = PublicAddr(addr)
+		}
+
+		v.Check(public, "target_url", "must not point to a loopback, private or link-local address")
+	}
+}
+
+type HookModel struct {
+	DB *sql.DB
+}
+
+func (m HookModel) Insert(ctx context.Context, s *HookSubscription) error {
+	query := `
+        INSERT INTO hook_subscriptions (user_id, event, target_url, secret)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, s.UserID, s.Event, s.TargetURL, s.Secret).Scan(&s.ID, &s.CreatedAt)
+}
+
+func (m HookModel) GetAllForUser(ctx context.Context, userID int64, event string) ([]*HookSubscription, error) {
+	query := `
+        SELECT id, user_id, event, target_url, secret, created_at
+        FROM hook_subscriptions
+        WHERE user_id = $1 AND ($2 = '' OR event = $2)
+        ORDER BY id`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, event)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := []*HookSubscription{}
+
+	for rows.Next() {
+		var s HookSubscription
+
+		err := rows.Scan(&s.ID, &s.UserID, &s.Event, &s.TargetURL, &s.Secret, &s.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		subscriptions = append(subscriptions, &s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+func (m HookModel) Delete(ctx context.Context, id, userID int64) error {
+	query := `DELETE FROM hook_subscriptions WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
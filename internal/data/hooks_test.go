@@ -0,0 +1,40 @@
+package data
+
+import (
+	"testing"
+
+	"flashcards-api.johndennehy101.tech/internal/validator"
+)
+
+func TestValidateHookSubscriptionTarget(t *testing.T) {
+	tests := []struct {
+		target string
+		valid  bool
+	}{
+		{"https://hooks.example.com/quiz", true},
+		{"http://203.0.113.10:8080/hook", true},
+		{"https://[2001:db8::1]/hook", true},
+		{"http://localhost:4000/hook", false},
+		{"http://api.localhost./hook", false},
+		{"http://127.0.0.1/hook", false},
+		{"http://[::1]/hook", false},
+		{"http://10.0.0.5/hook", false},
+		{"http://172.16.3.4/hook", false},
+		{"http://192.168.1.1/hook", false},
+		{"http://100.64.0.1/hook", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"http://[fe80::1]/hook", false},
+		{"http://[fd00::1]/hook", false},
+		{"http://[::ffff:127.0.0.1]/hook", false},
+		{"http://0.0.0.0/hook", false},
+	}
+
+	for _, tt := range tests {
+		v := validator.New()
+		ValidateHookSubscription(v, &HookSubscription{Event: EventQuizCompleted, TargetURL: tt.target})
+
+		if v.Valid() != tt.valid {
+			t.Errorf("ValidateHookSubscription(%q) valid = %t; want %t (%v)", tt.target, v.Valid(), tt.valid, v.Errors)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// IdentityModel links users to the accounts they sign in with at external
+// identity providers.
+type IdentityModel struct {
+	DB *sql.DB
+}
+
+func (m IdentityModel) GetUserID(ctx context.Context, provider, subject string) (int64, error) {
+	query := `
+        SELECT user_id
+        FROM user_identities
+        WHERE provider = $1 AND subject = $2`
+
+	var userID int64
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, provider, subject).Scan(&userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	return userID, nil
+}
+
+func (m IdentityModel) LinkUser(ctx context.Context, provider, subject string, userID int64) error {
+	query := `
+        INSERT INTO user_identities (provider, subject, user_id)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (provider, subject) DO UPDATE SET user_id = EXCLUDED.user_id`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, provider, subject, userID)
+	return err
+}
@@ -0,0 +1,111 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type LTILaunch struct {
+	UserID      int64
+	Issuer      string
+	Subject     string
+	ContextID   string
+	LineItemURL string
+	LaunchedAt  time.Time
+}
+
+type LTIModel struct {
+	DB *sql.DB
+}
+
+func (m LTIModel) GetUserID(ctx context.Context, issuer, subject string) (int64, error) {
+	query := `
+        SELECT user_id
+        FROM lti_users
+        WHERE issuer = $1 AND subject = $2`
+
+	var userID int64
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, issuer, subject).Scan(&userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	return userID, nil
+}
+
+func (m LTIModel) LinkUser(ctx context.Context, issuer, subject string, userID int64) error {
+	query := `
+        INSERT INTO lti_users (issuer, subject, user_id)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (issuer, subject) DO UPDATE SET user_id = EXCLUDED.user_id`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, issuer, subject, userID)
+	return err
+}
+
+// UpsertLaunch records the user's most recent launch, which holds the line
+// item that grades are passed back to.
+func (m LTIModel) UpsertLaunch(ctx context.Context, launch *LTILaunch) error {
+	query := `
+        INSERT INTO lti_launches (user_id, issuer, subject, context_id, lineitem_url, launched_at)
+        VALUES ($1, $2, $3, $4, $5, NOW())
+        ON CONFLICT (user_id) DO UPDATE SET
+            issuer = EXCLUDED.issuer,
+            subject = EXCLUDED.subject,
+            context_id = EXCLUDED.context_id,
+            lineitem_url = EXCLUDED.lineitem_url,
+            launched_at = NOW()
+        RETURNING launched_at`
+
+	args := []any{launch.UserID, launch.Issuer, launch.Subject, launch.ContextID, launch.LineItemURL}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&launch.LaunchedAt)
+}
+
+func (m LTIModel) GetLaunch(ctx context.Context, userID int64) (*LTILaunch, error) {
+	query := `
+        SELECT user_id, issuer, subject, context_id, lineitem_url, launched_at
+        FROM lti_launches
+        WHERE user_id = $1`
+
+	var launch LTILaunch
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(
+		&launch.UserID,
+		&launch.Issuer,
+		&launch.Subject,
+		&launch.ContextID,
+		&launch.LineItemURL,
+		&launch.LaunchedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &launch, nil
+}
@@ -0,0 +1,217 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/validator"
+	"github.com/lib/pq"
+)
+
+const (
+	MetadataString  = "string"
+	MetadataNumber  = "number"
+	MetadataBoolean = "boolean"
+	MetadataEnum    = "enum"
+)
+
+var MetadataTypes = []string{MetadataString, MetadataNumber, MetadataBoolean, MetadataEnum}
+
+var (
+	ErrDuplicateMetadataField = errors.New("duplicate metadata field")
+)
+
+var metadataNameRX = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// MetadataField defines a custom key that flashcards may carry in their
+// metadata, such as "jurisdiction" or "exam_year".
+type MetadataField struct {
+	Name      string    `json:"name"`
+	Type      string    `json:"field_type"`
+	Options   []string  `json:"options,omitempty"`
+	Required  bool      `json:"required"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func ValidateMetadataField(v *validator.Validator, field *MetadataField) {
+	v.Check(metadataNameRX.MatchString(field.Name), "name", "must be lowercase letters, digits and underscores, starting with a letter")
+	v.Check(len(field.Name) <= 50, "name", "must not be more than 50 bytes long")
+	v.Check(validator.PermittedValue(field.Type, MetadataTypes...), "field_type", "must be one of "+strings.Join(MetadataTypes, ", "))
+
+	if field.Type == MetadataEnum {
+		v.Check(len(field.Options) > 0, "options", "must be provided for enum fields")
+		v.Check(validator.Unique(field.Options), "options", "must not contain duplicate values")
+	} else {
+		v.Check(len(field.Options) == 0, "options", "only allowed for enum fields")
+	}
+}
+
+// CardMetadata holds a flashcard's custom metadata values, keyed by field name.
+type CardMetadata map[string]any
+
+func (m CardMetadata) Value() (driver.Value, error) {
+	if m == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(m)
+}
+
+func (m *CardMetadata) Scan(src any) error {
+	b, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("unsupported metadata type %T", src)
+	}
+	return json.Unmarshal(b, m)
+}
+
+// ValidateMetadata checks a card's metadata against the defined fields.
+// Unknown keys are rejected so typos don't silently become new fields.
+func ValidateMetadata(v *validator.Validator, fields []*MetadataField, metadata CardMetadata) {
+	for key, value := range metadata {
+		i := slices.IndexFunc(fields, func(f *MetadataField) bool { return f.Name == key })
+		if i < 0 {
+			v.AddError("metadata."+key, "is not a defined metadata field")
+			continue
+		}
+
+		field := fields[i]
+
+		switch field.Type {
+		case MetadataString:
+			_, ok := value.(string)
+			v.Check(ok, "metadata."+key, "must be a string")
+		case MetadataNumber:
+			_, ok := value.(float64)
+			v.Check(ok, "metadata."+key, "must be a number")
+		case MetadataBoolean:
+			_, ok := value.(bool)
+			v.Check(ok, "metadata."+key, "must be a boolean")
+		case MetadataEnum:
+			s, ok := value.(string)
+			v.Check(ok && slices.Contains(field.Options, s), "metadata."+key, "must be one of "+strings.Join(field.Options, ", "))
+		}
+	}
+
+	for _, field := range fields {
+		if field.Required {
+			_, ok := metadata[field.Name]
+			v.Check(ok, "metadata."+field.Name, "must be provided")
+		}
+	}
+}
+
+// ParseMetadataFilter converts a query string value to the field's type, for
+// filtering listings by metadata.
+func ParseMetadataFilter(field *MetadataField, raw string) (any, error) {
+	switch field.Type {
+	case MetadataNumber:
+		return strconv.ParseFloat(raw, 64)
+	case MetadataBoolean:
+		return strconv.ParseBool(raw)
+	default:
+		return raw, nil
+	}
+}
+
+type MetadataModel struct {
+	DB *sql.DB
+}
+
+func (m MetadataModel) Insert(ctx context.Context, field *MetadataField) error {
+	query := `
+        INSERT INTO metadata_fields (name, field_type, options, required)
+        VALUES ($1, $2, $3, $4)
+        RETURNING created_at`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, field.Name, field.Type, pq.Array(field.Options), field.Required).Scan(&field.CreatedAt)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "metadata_fields_pkey"):
+			return ErrDuplicateMetadataField
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m MetadataModel) GetAll(ctx context.Context) ([]*MetadataField, error) {
+	query := `
+        SELECT name, field_type, options, required, created_at
+        FROM metadata_fields
+        ORDER BY name`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fields := []*MetadataField{}
+
+	for rows.Next() {
+		var field MetadataField
+
+		err := rows.Scan(&field.Name, &field.Type, pq.Array(&field.Options), &field.Required, &field.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		fields = append(fields, &field)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// Delete removes a field definition along with its values on every card.
+func (m MetadataModel) Delete(ctx context.Context, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM metadata_fields WHERE name = $1`, name)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE flashcards SET metadata = metadata - $1::text WHERE metadata ? $1::text`, name)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,51 @@
+package mocks
+
+import (
+	"context"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+)
+
+type APIKeyModel struct{}
+
+func (m APIKeyModel) Insert(ctx context.Context, key *data.APIKey) error {
+	key.ID = 2
+	key.Plaintext = APIKey
+	key.Prefix = APIKey[:8]
+	key.CreatedAt = Created
+
+	return nil
+}
+
+func (m APIKeyModel) GetAllForUser(ctx context.Context, userID int64) ([]*data.APIKey, error) {
+	return []*data.APIKey{
+		{
+			ID:         1,
+			UserID:     userID,
+			Name:       "study widget",
+			Prefix:     APIKey[:8],
+			Scopes:     data.Permissions{"flashcards:read"},
+			LastUsedAt: ptr(Created),
+			CreatedAt:  Created,
+		},
+	}, nil
+}
+
+func (m APIKeyModel) Delete(ctx context.Context, id, userID int64) error {
+	if id != 1 {
+		return data.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetUserForKey authenticates APIKey as the user with id 1, with read-only
+// access.
+func (m APIKeyModel) GetUserForKey(ctx context.Context, plaintext string) (*data.User, data.Permissions, error) {
+	if plaintext != APIKey {
+		return nil, nil, data.ErrRecordNotFound
+	}
+
+	u, err := user(1)
+	return u, data.Permissions{"flashcards:read"}, err
+}
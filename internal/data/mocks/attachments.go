@@ -0,0 +1,67 @@
+package mocks
+
+import (
+	"context"
+	"slices"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+)
+
+// attachments returns the fixture attachments: a diagram on card 1.
+func attachments() []*data.Attachment {
+	return []*data.Attachment{
+		{
+			ID:          1,
+			FlashcardID: 1,
+			UserID:      1,
+			Kind:        data.AttachmentImage,
+			StorageKey:  "flashcards/1/map.png",
+			Filename:    "map.png",
+			ContentType: "image/png",
+			Size:        1024,
+			CreatedAt:   Created,
+		},
+	}
+}
+
+type AttachmentModel struct{}
+
+func (m AttachmentModel) Insert(ctx context.Context, attachment *data.Attachment) error {
+	attachment.ID = 2
+	attachment.CreatedAt = Created
+
+	return nil
+}
+
+func (m AttachmentModel) Get(ctx context.Context, id, flashcardID int64) (*data.Attachment, error) {
+	for _, attachment := range attachments() {
+		if attachment.ID == id && attachment.FlashcardID == flashcardID {
+			return attachment, nil
+		}
+	}
+
+	return nil, data.ErrRecordNotFound
+}
+
+func (m AttachmentModel) GetAllForFlashcards(ctx context.Context, flashcardIDs []int64) ([]*data.Attachment, error) {
+	found := []*data.Attachment{}
+	for _, attachment := range attachments() {
+		if slices.Contains(flashcardIDs, attachment.FlashcardID) {
+			found = append(found, attachment)
+		}
+	}
+
+	return found, nil
+}
+
+func (m AttachmentModel) Delete(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (m AttachmentModel) GetPendingDeletions(ctx context.Context, limit int) ([]string, error) {
+	return []string{}, nil
+}
+
+func (m AttachmentModel) ClearPendingDeletion(ctx context.Context, storageKey string) error {
+	return nil
+}
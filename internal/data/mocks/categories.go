@@ -0,0 +1,109 @@
+package mocks
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+)
+
+// categories returns the fixture categories, one for each fixture card's
+// category.
+func categories() []*data.Category {
+	categories := []*data.Category{
+		{ID: 1, Name: "astronomy", Slug: "astronomy", Description: "Stars and planets", Colour: "#1f3a93", Icon: "planet", Keywords: []string{"planet", "star"}, CardCount: 1},
+		{ID: 2, Name: "biology", Slug: "biology", Description: "Living things", Colour: "#2e7d32", Icon: "leaf", Keywords: []string{"cell", "bone"}, CardCount: 1},
+		{ID: 3, Name: "chemistry", Slug: "chemistry", Description: "Elements and compounds", Colour: "#8e24aa", Icon: "flask", Keywords: []string{"compound"}, CardCount: 1},
+		{ID: 4, Name: "geography", Slug: "geography", Description: "Places of the world", Colour: "#ef6c00", Icon: "globe", Keywords: []string{"capital", "country"}, CardCount: 1},
+	}
+
+	for _, c := range categories {
+		c.Version = 1
+		c.CreatedAt = Created
+	}
+
+	return categories
+}
+
+type CategoryModel struct{}
+
+// Insert refuses the names of the fixture categories as taken.
+func (m CategoryModel) Insert(ctx context.Context, c *data.Category) error {
+	if _, err := m.GetByName(ctx, c.Name); err == nil {
+		return data.ErrDuplicateCategoryName
+	}
+
+	c.ID = 5
+	c.Version = 1
+	c.CreatedAt = Created
+
+	return nil
+}
+
+func (m CategoryModel) Get(ctx context.Context, id int64) (*data.Category, error) {
+	for _, c := range categories() {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+
+	return nil, data.ErrRecordNotFound
+}
+
+func (m CategoryModel) GetByName(ctx context.Context, name string) (*data.Category, error) {
+	for _, c := range categories() {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+
+	return nil, data.ErrRecordNotFound
+}
+
+func (m CategoryModel) GetByNames(ctx context.Context, names []string) ([]*data.Category, error) {
+	found := []*data.Category{}
+	for _, c := range categories() {
+		if slices.Contains(names, c.Name) {
+			found = append(found, c)
+		}
+	}
+
+	return found, nil
+}
+
+func (m CategoryModel) GetAll(ctx context.Context, prefix string, filters data.Filters) ([]*data.Category, data.Metadata, error) {
+	found := []*data.Category{}
+	for _, c := range categories() {
+		if strings.HasPrefix(c.Name, prefix) {
+			found = append(found, c)
+		}
+	}
+
+	return found, page(filters, len(found)), nil
+}
+
+func (m CategoryModel) Update(ctx context.Context, c *data.Category) ([]int64, error) {
+	c.Version++
+	return []int64{}, nil
+}
+
+func (m CategoryModel) Delete(ctx context.Context, id int64) ([]int64, error) {
+	_, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return []int64{}, nil
+}
+
+func (m CategoryModel) Merge(ctx context.Context, from, into string) ([]int64, error) {
+	return []int64{1}, nil
+}
+
+func (m CategoryModel) GetStats(ctx context.Context, userID int64) ([]*data.CategoryStats, error) {
+	return []*data.CategoryStats{
+		{Name: "astronomy", CardCount: 1, ByType: map[string]int{"mcq": 1}, Reviews: 2, Correct: 1, Accuracy: ptr(0.5)},
+		{Name: "geography", CardCount: 1, ByType: map[string]int{"qa": 1}, Reviews: 3, Correct: 3, Accuracy: ptr(1.0)},
+	}, nil
+}
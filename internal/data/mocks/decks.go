@@ -0,0 +1,390 @@
+package mocks
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+)
+
+// decks returns the fixture decks. The user with id 1 owns a hand-picked
+// deck of cards 1 and 2, which has a share link, and a smart deck of the
+// QA cards. The admin owns a public deck the user with id 1 can edit.
+func decks() []*data.Deck {
+	return []*data.Deck{
+		{
+			ID:          1,
+			UserID:      1,
+			Name:        "Geography",
+			Description: "Capitals and planets",
+			Visibility:  "private",
+			Shared:      true,
+			CardCount:   2,
+			Version:     1,
+			CreatedAt:   Created,
+		},
+		{
+			ID:          2,
+			UserID:      1,
+			Name:        "Short answers",
+			Description: "Every QA card",
+			Visibility:  "private",
+			Query:       ptr("type=qa"),
+			CardCount:   1,
+			Version:     1,
+			CreatedAt:   Created,
+		},
+		{
+			ID:          3,
+			UserID:      2,
+			Name:        "Science",
+			Description: "Shared with the class",
+			Visibility:  "public",
+			CardCount:   2,
+			Version:     1,
+			CreatedAt:   Created,
+		},
+	}
+}
+
+// deckMembers are the roles of the users decks are shared with, by deck id.
+var deckMembers = map[int64]map[int64]string{3: {1: "editor", 3: "viewer"}}
+
+// deckCards are the cards in the hand-picked decks, in order.
+var deckCards = map[int64][]int64{1: {1, 2}, 3: {3, 4}}
+
+// deck returns the deck with userID's role in it.
+func deck(id, userID int64) (*data.Deck, error) {
+	for _, d := range decks() {
+		if d.ID != id {
+			continue
+		}
+
+		switch {
+		case d.UserID == userID:
+			d.Role = "owner"
+		default:
+			d.Role = deckMembers[id][userID]
+		}
+
+		d.Owned = d.Role == "owner"
+
+		return d, nil
+	}
+
+	return nil, data.ErrRecordNotFound
+}
+
+type DeckModel struct{}
+
+func (m DeckModel) GetMembers(ctx context.Context, deckID int64) ([]*data.DeckMember, error) {
+	members := []*data.DeckMember{{UserID: 2, Name: "Admin User", Role: "owner", AddedAt: Created}}
+
+	if deckID == 3 {
+		members = append(members,
+			&data.DeckMember{UserID: 1, Name: "Alice Smith", Role: "editor", AddedAt: Created},
+			&data.DeckMember{UserID: 3, Name: "Bob Jones", Role: "viewer", AddedAt: Created},
+		)
+	}
+
+	return members, nil
+}
+
+func (m DeckModel) UpdateMember(ctx context.Context, deckID, userID int64, role string) error {
+	if _, ok := deckMembers[deckID][userID]; !ok {
+		return data.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m DeckModel) RemoveMember(ctx context.Context, deckID, userID int64) error {
+	return m.UpdateMember(ctx, deckID, userID, "")
+}
+
+func (m DeckModel) Invite(ctx context.Context, inv *data.DeckInvitation, ttl time.Duration) error {
+	inv.ID = 2
+	inv.Expiry = Created.Add(ttl)
+	inv.CreatedAt = Created
+	inv.Plaintext = InvitationToken
+
+	return nil
+}
+
+func (m DeckModel) GetInvitations(ctx context.Context, deckID int64) ([]*data.DeckInvitation, error) {
+	return []*data.DeckInvitation{
+		{ID: 1, DeckID: deckID, Email: "carol@example.com", Role: "viewer", Expiry: Created.Add(7 * 24 * time.Hour), CreatedAt: Created},
+	}, nil
+}
+
+func (m DeckModel) DeleteInvitation(ctx context.Context, id, deckID int64) error {
+	if id != 1 {
+		return data.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// AcceptInvitation joins the user to deck 3.
+func (m DeckModel) AcceptInvitation(ctx context.Context, tokenPlaintext string, u *data.User) (int64, error) {
+	if tokenPlaintext != InvitationToken {
+		return 0, data.ErrRecordNotFound
+	}
+
+	return 3, nil
+}
+
+// EditableByMember reports whether the card is in a deck the user is an
+// editor of.
+func (m DeckModel) EditableByMember(ctx context.Context, flashcardID, userID int64) (bool, error) {
+	for deckID, cards := range deckCards {
+		if deckMembers[deckID][userID] == "editor" && slices.Contains(cards, flashcardID) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Insert refuses the names of the fixture decks as taken.
+func (m DeckModel) Insert(ctx context.Context, d *data.Deck) error {
+	for _, existing := range decks() {
+		if existing.UserID == d.UserID && existing.Name == d.Name {
+			return data.ErrDuplicateDeckName
+		}
+	}
+
+	d.ID = 4
+	d.Role = "owner"
+	d.Owned = true
+	d.Version = 1
+	d.CreatedAt = Created
+
+	return nil
+}
+
+func (m DeckModel) Get(ctx context.Context, id, userID int64) (*data.Deck, error) {
+	d, err := deck(id, userID)
+	if err != nil || !d.Owned {
+		return nil, data.ErrRecordNotFound
+	}
+
+	return d, nil
+}
+
+func (m DeckModel) GetEditable(ctx context.Context, id, userID int64) (*data.Deck, error) {
+	d, err := deck(id, userID)
+	if err != nil || (d.Role != "owner" && d.Role != "editor") {
+		return nil, data.ErrRecordNotFound
+	}
+
+	return d, nil
+}
+
+func (m DeckModel) GetVisible(ctx context.Context, id, userID int64) (*data.Deck, error) {
+	d, err := deck(id, userID)
+	if err != nil || (d.Role == "" && d.Visibility != "public") {
+		return nil, data.ErrRecordNotFound
+	}
+
+	return d, nil
+}
+
+// GetByShareToken returns deck 1, the one with a share link.
+func (m DeckModel) GetByShareToken(ctx context.Context, tokenPlaintext string) (*data.Deck, error) {
+	if tokenPlaintext != ShareToken {
+		return nil, data.ErrRecordNotFound
+	}
+
+	return deck(1, 0)
+}
+
+func (m DeckModel) GetAllForUser(ctx context.Context, userID int64, archived bool, filters data.Filters) ([]*data.Deck, data.Metadata, error) {
+	all := []*data.Deck{}
+
+	if !archived {
+		for _, d := range decks() {
+			d, _ := deck(d.ID, userID)
+			if d.Role != "" {
+				all = append(all, d)
+			}
+		}
+	}
+
+	return all, page(filters, len(all)), nil
+}
+
+func (m DeckModel) Update(ctx context.Context, d *data.Deck) error {
+	d.Version++
+	return nil
+}
+
+func (m DeckModel) Delete(ctx context.Context, id, userID int64) error {
+	_, err := m.Get(ctx, id, userID)
+	return err
+}
+
+func (m DeckModel) Share(ctx context.Context, id, userID int64) (string, error) {
+	_, err := m.Get(ctx, id, userID)
+	if err != nil {
+		return "", err
+	}
+
+	return ShareToken, nil
+}
+
+func (m DeckModel) Unshare(ctx context.Context, id, userID int64) error {
+	_, err := m.Get(ctx, id, userID)
+	return err
+}
+
+func (m DeckModel) SetArchived(ctx context.Context, id, userID int64, archived bool) (*time.Time, error) {
+	_, err := m.Get(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !archived {
+		return nil, nil
+	}
+
+	return ptr(Created), nil
+}
+
+// Clone copies the deck as deck 4, with cards 5 and 6 as the copies of the
+// source's cards.
+func (m DeckModel) Clone(ctx context.Context, src, d *data.Deck, copyCards bool) ([]int64, error) {
+	err := m.Insert(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	d.ForkedFrom = ptr(src.ID)
+	d.CardCount = src.CardCount
+
+	if !copyCards {
+		return []int64{}, nil
+	}
+
+	return []int64{5, 6}, nil
+}
+
+// AddFlashcards returns the cards that weren't in the deck yet.
+func (m DeckModel) AddFlashcards(ctx context.Context, deckID int64, ids []int64) ([]int64, error) {
+	added := []int64{}
+	for _, id := range existingIDs(ids) {
+		if !slices.Contains(deckCards[deckID], id) {
+			added = append(added, id)
+		}
+	}
+
+	return added, nil
+}
+
+func (m DeckModel) RemoveFlashcards(ctx context.Context, deckID int64, ids []int64) ([]int64, error) {
+	removed := []int64{}
+	for _, id := range ids {
+		if slices.Contains(deckCards[deckID], id) {
+			removed = append(removed, id)
+		}
+	}
+
+	return removed, nil
+}
+
+func (m DeckModel) Export(ctx context.Context, d *data.Deck) (*data.DeckBundle, error) {
+	bundle := &data.DeckBundle{
+		Format:      data.DeckBundleFormat,
+		ExportedAt:  Created,
+		Deck:        data.BundleDeck{Name: d.Name, Description: d.Description},
+		Flashcards:  []*data.BundleFlashcard{},
+		Attachments: []*data.Attachment{},
+	}
+
+	for _, id := range deckCards[d.ID] {
+		flashcard, err := flashcard(id)
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := json.Marshal(flashcard.Content)
+		if err != nil {
+			return nil, err
+		}
+
+		bundle.Flashcards = append(bundle.Flashcards, &data.BundleFlashcard{
+			ID:          flashcard.ID,
+			Section:     flashcard.Section,
+			SectionType: flashcard.SectionType,
+			SourceFile:  flashcard.SourceFile,
+			Text:        flashcard.Text,
+			Question:    flashcard.Question,
+			Type:        flashcard.Type,
+			Content:     content,
+			Categories:  flashcard.Categories,
+			Difficulty:  flashcard.Difficulty,
+			Hints:       flashcard.Hints,
+			Metadata:    flashcard.Metadata,
+		})
+	}
+
+	attachments, err := AttachmentModel{}.GetAllForFlashcards(ctx, deckCards[d.ID])
+	if err != nil {
+		return nil, err
+	}
+
+	bundle.Attachments = append(bundle.Attachments, attachments...)
+
+	return bundle, nil
+}
+
+func (m DeckModel) Import(ctx context.Context, d *data.Deck, flashcards []*data.Flashcard) error {
+	err := m.Insert(ctx, d)
+	if err != nil {
+		return err
+	}
+
+	for i, flashcard := range flashcards {
+		flashcard.ID = int64(5 + i)
+		flashcard.OwnerID = ptr(d.UserID)
+		flashcard.CreatedAt = Created
+		flashcard.Version = 1
+	}
+
+	d.CardCount = len(flashcards)
+
+	return nil
+}
+
+func (m DeckModel) GetOrder(ctx context.Context, deckID int64) ([]int64, error) {
+	return slices.Clone(deckCards[deckID]), nil
+}
+
+// Reorder accepts any permutation of the deck's cards.
+func (m DeckModel) Reorder(ctx context.Context, deckID int64, ids []int64) error {
+	current := slices.Clone(deckCards[deckID])
+	sorted := slices.Clone(ids)
+
+	slices.Sort(current)
+	slices.Sort(sorted)
+
+	if !slices.Equal(current, sorted) {
+		return data.ErrIncompleteOrder
+	}
+
+	return nil
+}
+
+func (m DeckModel) GetStats(ctx context.Context, userID int64, filter data.FlashcardFilter) (*data.DeckStats, error) {
+	return &data.DeckStats{
+		CardCount: 2,
+		ByType:    map[string]int{"mcq": 1, "qa": 1},
+		New:       1,
+		Learning:  1,
+		Reviews:   4,
+		Correct:   3,
+		Accuracy:  ptr(0.75),
+	}, nil
+}
@@ -0,0 +1,88 @@
+package mocks
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+)
+
+// Deadline is the deadline of the open exam, far enough ahead that it is
+// still open whenever the tests run.
+var Deadline = time.Date(2100, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// exam returns the fixture exams of the user with id 1: exam 1 is open with
+// questions on cards 1, 2 and 3, the last two timed and only card 2's served,
+// and exam 2 was submitted with card 1 answered correctly.
+func exam(id, userID int64) (*data.Exam, error) {
+	if userID != 1 || (id != 1 && id != 2) {
+		return nil, data.ErrRecordNotFound
+	}
+
+	first, err := flashcard(1)
+	if err != nil {
+		return nil, err
+	}
+
+	second, err := flashcard(2)
+	if err != nil {
+		return nil, err
+	}
+
+	third, err := flashcard(3)
+	if err != nil {
+		return nil, err
+	}
+
+	exam := &data.Exam{
+		ID:        id,
+		UserID:    userID,
+		Deadline:  Deadline,
+		CreatedAt: Created,
+		Questions: []*data.ExamQuestion{
+			{Position: 1, FlashcardID: 1, Category: "geography", Question: first.Question, Type: first.Type, Content: first.Content},
+			{Position: 2, FlashcardID: 2, Category: "astronomy", Question: second.Question, Type: second.Type, Content: second.Content, Options: second.Content.(data.MCQContent).Options, TimeLimitMS: ptr(30000), ServedAt: ptr(Created)},
+			{Position: 3, FlashcardID: 3, Category: "chemistry", Question: third.Question, Type: third.Type, Content: third.Content, TimeLimitMS: ptr(30000)},
+		},
+	}
+
+	if id == 2 {
+		exam.Deadline = Created.Add(time.Hour)
+		exam.SubmittedAt = ptr(Created.Add(30 * time.Minute))
+		exam.Questions[0].Answer = json.RawMessage(`"Paris"`)
+		exam.Questions[0].Correct = ptr(true)
+		exam.Questions[1].Correct = ptr(false)
+		exam.Questions[2].Correct = ptr(false)
+	}
+
+	return exam, nil
+}
+
+type ExamModel struct{}
+
+func (m ExamModel) Insert(ctx context.Context, e *data.Exam, spec data.ExamSpec) error {
+	e.ID = 1
+	return nil
+}
+
+func (m ExamModel) Get(ctx context.Context, id, userID int64) (*data.Exam, error) {
+	return exam(id, userID)
+}
+
+func (m ExamModel) Serve(ctx context.Context, e *data.Exam, q *data.ExamQuestion) error {
+	if q.ServedAt == nil {
+		q.ServedAt = ptr(Created.Add(time.Minute))
+	}
+
+	return nil
+}
+
+func (m ExamModel) Answer(ctx context.Context, e *data.Exam, q *data.ExamQuestion) error {
+	return nil
+}
+
+func (m ExamModel) Submit(ctx context.Context, e *data.Exam) error {
+	e.SubmittedAt = ptr(Created.Add(30 * time.Minute))
+	return nil
+}
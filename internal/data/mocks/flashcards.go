@@ -0,0 +1,475 @@
+package mocks
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+)
+
+// flashcards returns the fixture cards, one of each type. The user with id 1
+// owns all of them but the yes/no card, which is in the shared catalogue.
+func flashcards() []*data.Flashcard {
+	return []*data.Flashcard{
+		{
+			ID:           1,
+			Section:      ptr("1.1 Capitals"),
+			SectionType:  ptr("chapter"),
+			SourceFile:   ptr("geography.md"),
+			Text:         "Paris has been the capital of France since the 10th century.",
+			OwnerID:      ptr(int64(1)),
+			CreatedAt:    Created,
+			Question:     "What is the capital of France?",
+			Type:         data.FlashcardQA,
+			Content:      data.QAContent{Answer: "Paris", Justification: "Paris is the seat of the French government."},
+			Categories:   []string{"geography"},
+			Difficulty:   ptr(2),
+			Hints:        []string{"It is on the Seine."},
+			Metadata:     data.CardMetadata{},
+			Version:      1,
+			CorrectCount: 1,
+			Status:       "in_progress",
+		},
+		{
+			ID:          2,
+			Section:     ptr("2.1 Planets"),
+			SectionType: ptr("chapter"),
+			SourceFile:  ptr("astronomy.md"),
+			Text:        "Iron oxide on its surface gives Mars its colour.",
+			OwnerID:     ptr(int64(1)),
+			CreatedAt:   Created,
+			Question:    "Which planet is known as the Red Planet?",
+			Type:        data.FlashcardMCQ,
+			Content: data.MCQContent{
+				Options: []data.MCQOption{
+					{ID: "a", Text: "Venus"},
+					{ID: "b", Text: "Mars"},
+					{ID: "c", Text: "Jupiter"},
+				},
+				CorrectOptionID: "b",
+			},
+			Categories: []string{"astronomy"},
+			Difficulty: ptr(1),
+			Metadata:   data.CardMetadata{},
+			Version:    1,
+			Status:     "not_started",
+		},
+		{
+			ID:         3,
+			Text:       "Water is made of hydrogen and oxygen.",
+			CreatedAt:  Created,
+			Question:   "Is water a compound?",
+			Type:       data.FlashcardYesNo,
+			Content:    data.YesNoContent{Correct: true},
+			Categories: []string{"chemistry"},
+			Metadata:   data.CardMetadata{},
+			Version:    1,
+			Status:     "not_started",
+		},
+		{
+			ID:         4,
+			Text:       "An adult human skeleton has 206 bones.",
+			OwnerID:    ptr(int64(1)),
+			CreatedAt:  Created,
+			Question:   "How many bones are in the adult human body?",
+			Type:       data.FlashcardNumeric,
+			Content:    data.NumericContent{Answer: 206},
+			Categories: []string{"biology"},
+			Difficulty: ptr(3),
+			Metadata:   data.CardMetadata{},
+			Version:    1,
+			Status:     "mastered",
+		},
+	}
+}
+
+func flashcard(id int64) (*data.Flashcard, error) {
+	for _, flashcard := range flashcards() {
+		if flashcard.ID == id {
+			return flashcard, nil
+		}
+	}
+
+	return nil, data.ErrRecordNotFound
+}
+
+func flashcardIDs(flashcards []*data.Flashcard) []int64 {
+	ids := make([]int64, len(flashcards))
+	for i, flashcard := range flashcards {
+		ids[i] = flashcard.ID
+	}
+
+	return ids
+}
+
+// existingIDs keeps the ids of fixture cards, in order.
+func existingIDs(ids []int64) []int64 {
+	existing := []int64{}
+	for _, id := range ids {
+		if _, err := flashcard(id); err == nil {
+			existing = append(existing, id)
+		}
+	}
+
+	return existing
+}
+
+type FlashcardModel struct{}
+
+func (m FlashcardModel) Retag(ctx context.Context, userID int64, ids []int64, filter data.FlashcardFilter, add, remove []string) ([]int64, error) {
+	if ids == nil {
+		return flashcardIDs(flashcards()), nil
+	}
+
+	return existingIDs(ids), nil
+}
+
+func (m FlashcardModel) SuggestCategories(ctx context.Context, f *data.Flashcard) ([]string, error) {
+	return []string{"geography"}, nil
+}
+
+func (m FlashcardModel) GetDistractors(ctx context.Context, f *data.Flashcard) ([]string, error) {
+	return []string{"Lyon", "Marseille", "Toulouse"}, nil
+}
+
+func (m FlashcardModel) Convert(ctx context.Context, f *data.Flashcard, resetProgress bool) error {
+	f.Version++
+	return nil
+}
+
+func (m FlashcardModel) SetEmbedding(ctx context.Context, id int64, model string, embedding data.Vector) error {
+	return nil
+}
+
+func (m FlashcardModel) GetUnembeddedIDs(ctx context.Context, model string, limit int) ([]int64, error) {
+	return []int64{}, nil
+}
+
+func (m FlashcardModel) NearestNeighbours(ctx context.Context, model string, embedding data.Vector, maxDistance float64, filters data.Filters) ([]int64, data.Metadata, error) {
+	return []int64{2, 1}, page(filters, 2), nil
+}
+
+func (m FlashcardModel) Insert(ctx context.Context, f *data.Flashcard, userID int64) error {
+	f.ID = 5
+	f.OwnerID = ptr(userID)
+	f.CreatedAt = Created
+	f.Version = 1
+	f.Status = "not_started"
+
+	return nil
+}
+
+// FindDuplicate reports the fixture card with the same question.
+func (m FlashcardModel) FindDuplicate(ctx context.Context, question string, userID int64) (int64, error) {
+	for _, flashcard := range flashcards() {
+		if strings.EqualFold(flashcard.Question, strings.TrimSpace(question)) {
+			return flashcard.ID, nil
+		}
+	}
+
+	return 0, nil
+}
+
+func (m FlashcardModel) Get(ctx context.Context, id int64, userID int64) (*data.Flashcard, error) {
+	return flashcard(id)
+}
+
+func (m FlashcardModel) GetFilterMetadata(ctx context.Context, userID int64, file string, qType string, hideMastered bool) (*data.FilterMetadata, error) {
+	metadata := &data.FilterMetadata{
+		Categories: []data.CategoryCount{
+			{Name: "astronomy", Count: 1},
+			{Name: "biology", Count: 1},
+			{Name: "chemistry", Count: 1},
+			{Name: "geography", Count: 1},
+		},
+		SourceFiles:   []string{"astronomy.md", "geography.md"},
+		Sections:      []string{},
+		QuestionTypes: []string{"mcq", "numeric", "qa", "yes_no"},
+	}
+
+	if file == "geography.md" {
+		metadata.Sections = []string{"1.1 Capitals"}
+	}
+
+	return metadata, nil
+}
+
+func (m FlashcardModel) Update(ctx context.Context, f *data.Flashcard) error {
+	f.Version++
+	return nil
+}
+
+func (m FlashcardModel) Delete(ctx context.Context, id, userID int64) error {
+	_, err := flashcard(id)
+	return err
+}
+
+func (m FlashcardModel) GetUserStats(ctx context.Context, userID int64) (*data.FlashcardStats, error) {
+	return &data.FlashcardStats{Total: 4, Mastered: 1, InProgress: 1, NotStarted: 2, AverageLatencyMS: ptr(2500.0)}, nil
+}
+
+// GetAll lists the fixture cards of the types asked for, ignoring the other
+// filters.
+func (m FlashcardModel) GetAll(ctx context.Context, userID int64, filter data.FlashcardFilter, filters data.Filters) ([]*data.Flashcard, data.Metadata, error) {
+	all := []*data.Flashcard{}
+
+	for _, flashcard := range flashcards() {
+		switch {
+		case filter.Type != "" && string(flashcard.Type) != filter.Type:
+		case len(filter.Types) > 0 && !slices.Contains(filter.Types, string(flashcard.Type)):
+		default:
+			all = append(all, flashcard)
+		}
+	}
+
+	return all, page(filters, len(all)), nil
+}
+
+func (m FlashcardModel) IncrementCorrectCount(ctx context.Context, id int64, userID int64) error {
+	_, err := flashcard(id)
+	return err
+}
+
+func (m FlashcardModel) ResetCorrectCount(ctx context.Context, id int64, userID int64) error {
+	_, err := flashcard(id)
+	return err
+}
+
+func (m FlashcardModel) GetByIDs(ctx context.Context, ids []int64, userID int64) ([]*data.Flashcard, error) {
+	found := []*data.Flashcard{}
+	for _, id := range ids {
+		if flashcard, err := flashcard(id); err == nil {
+			found = append(found, flashcard)
+		}
+	}
+
+	return found, nil
+}
+
+func (m FlashcardModel) GetHeadlines(ctx context.Context, ids []int64, q string) (map[int64]string, error) {
+	headlines := make(map[int64]string, len(ids))
+	for _, id := range ids {
+		headlines[id] = "<b>" + q + "</b>"
+	}
+
+	return headlines, nil
+}
+
+func (m FlashcardModel) Search(ctx context.Context, userID int64, q string, filters data.Filters) ([]*data.Flashcard, data.Metadata, error) {
+	found := []*data.Flashcard{}
+	for _, flashcard := range flashcards() {
+		if strings.Contains(strings.ToLower(flashcard.Question), strings.ToLower(q)) {
+			found = append(found, flashcard)
+		}
+	}
+
+	return found, page(filters, len(found)), nil
+}
+
+func (m FlashcardModel) GetAllForIndex(ctx context.Context, afterID int64, limit int) ([]*data.Flashcard, error) {
+	batch := []*data.Flashcard{}
+	for _, flashcard := range flashcards() {
+		if flashcard.ID > afterID && len(batch) < limit {
+			batch = append(batch, flashcard)
+		}
+	}
+
+	return batch, nil
+}
+
+func (m FlashcardModel) Export(ctx context.Context, userID int64) (*data.FlashcardExport, error) {
+	stats, err := m.GetUserStats(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.FlashcardExport{ExportedAt: Created, Stats: stats, Flashcards: flashcards()}, nil
+}
+
+func (m FlashcardModel) GetCardStats(ctx context.Context, ids []int64, userID int64) (map[int64]*data.CardStats, error) {
+	stats := make(map[int64]*data.CardStats, len(ids))
+	for _, id := range ids {
+		stats[id] = &data.CardStats{LastReviewedAt: ptr(Created), Learners: 3, MasteredBy: 1, AverageLatencyMS: ptr(3200.0)}
+	}
+
+	return stats, nil
+}
+
+func (m FlashcardModel) GetSourceSections(ctx context.Context, sections []string) ([]*data.SourceSection, error) {
+	all := []*data.SourceSection{
+		{SourceFile: "geography.md", Section: "1.1 Capitals", SectionType: "chapter", CardCount: 1},
+		{SourceFile: "astronomy.md", Section: "2.1 Planets", SectionType: "chapter", CardCount: 1},
+	}
+
+	found := []*data.SourceSection{}
+	for _, s := range all {
+		if slices.Contains(sections, s.Section) {
+			found = append(found, s)
+		}
+	}
+
+	return found, nil
+}
+
+func (m FlashcardModel) AddPrerequisite(ctx context.Context, id, prerequisiteID int64) error {
+	if prerequisiteID == 1 && id == 2 {
+		return data.ErrPrerequisiteCycle
+	}
+
+	return nil
+}
+
+func (m FlashcardModel) DeletePrerequisite(ctx context.Context, id, prerequisiteID int64) error {
+	return nil
+}
+
+// GetStudyPath orders the category's cards with card 1 as a prerequisite of
+// card 2.
+func (m FlashcardModel) GetStudyPath(ctx context.Context, userID int64, category string) ([]*data.Flashcard, error) {
+	path := []*data.Flashcard{}
+	for _, flashcard := range flashcards() {
+		if category == "" || slices.Contains(flashcard.Categories, category) {
+			path = append(path, flashcard)
+		}
+	}
+
+	for _, flashcard := range path {
+		flashcard.PrerequisiteIDs = []int64{}
+		if flashcard.ID == 2 {
+			flashcard.PrerequisiteIDs = []int64{1}
+		}
+	}
+
+	return path, nil
+}
+
+func (m FlashcardModel) RecordReview(ctx context.Context, id, userID int64, correct bool, latencyMS *int) error {
+	return nil
+}
+
+func (m FlashcardModel) InsertReport(ctx context.Context, report *data.Report) error {
+	report.ID = 1
+	report.CreatedAt = Created
+
+	return nil
+}
+
+func (m FlashcardModel) RefreshQualityScores(ctx context.Context) (int64, error) {
+	return 4, nil
+}
+
+func (m FlashcardModel) RefreshMeasuredDifficulty(ctx context.Context) (int64, error) {
+	return 4, nil
+}
+
+func (m FlashcardModel) RecordView(ctx context.Context, id, userID int64) error {
+	return nil
+}
+
+func (m FlashcardModel) GetRecentFlashcards(ctx context.Context, userID int64, limit int) ([]*data.RecentFlashcard, error) {
+	return []*data.RecentFlashcard{
+		{ID: 2, Question: "Which planet is known as the Red Planet?", Section: ptr("2.1 Planets"), SeenAt: Created.Add(time.Hour)},
+		{ID: 1, Question: "What is the capital of France?", Section: ptr("1.1 Capitals"), SeenAt: Created},
+	}, nil
+}
+
+func (m FlashcardModel) GetRecentSections(ctx context.Context, userID int64, limit int) ([]*data.RecentSection, error) {
+	return []*data.RecentSection{
+		{Section: "2.1 Planets", LastSeenAt: Created.Add(time.Hour), LastFlashcardID: 2, Remaining: 0, Total: 1},
+		{Section: "1.1 Capitals", LastSeenAt: Created, LastFlashcardID: 1, ResumeID: ptr(int64(1)), Remaining: 1, Total: 1},
+	}, nil
+}
+
+func (m FlashcardModel) AddRelation(ctx context.Context, id, relatedID int64) error {
+	return nil
+}
+
+func (m FlashcardModel) DeleteRelation(ctx context.Context, id, relatedID int64) error {
+	return nil
+}
+
+// GetRelatedIDs relates cards 1 and 2 to each other.
+func (m FlashcardModel) GetRelatedIDs(ctx context.Context, ids []int64) (map[int64][]int64, error) {
+	related := make(map[int64][]int64)
+	for _, id := range ids {
+		switch id {
+		case 1:
+			related[id] = []int64{2}
+		case 2:
+			related[id] = []int64{1}
+		}
+	}
+
+	return related, nil
+}
+
+// GetQueue serves the cards the user hasn't mastered, card 1 as a review
+// and the rest as new cards.
+func (m FlashcardModel) GetQueue(ctx context.Context, userID int64, filter data.FlashcardFilter, newLimit, reviewLimit int, now time.Time, dueOnly bool) ([]*data.Flashcard, data.StudyCounts, error) {
+	queue := []*data.Flashcard{}
+	var counts data.StudyCounts
+
+	for _, flashcard := range flashcards() {
+		switch {
+		case flashcard.Status == "mastered":
+		case flashcard.Status == "in_progress" && counts.Review < reviewLimit:
+			counts.Review++
+			flashcard.Schedule = &data.CardSchedule{Queue: "review", DueAt: ptr(Created), Interval: 1, CardState: data.CardState{State: "learning"}}
+			queue = append(queue, flashcard)
+		case flashcard.Status == "not_started" && !dueOnly && counts.New < newLimit:
+			counts.New++
+			flashcard.Schedule = &data.CardSchedule{Queue: "new", CardState: data.CardState{State: "new"}}
+			queue = append(queue, flashcard)
+		}
+	}
+
+	return queue, counts, nil
+}
+
+func (m FlashcardModel) Suggest(ctx context.Context, q string, limit int) (*data.Suggestions, error) {
+	suggestions := &data.Suggestions{
+		Questions:  []data.QuestionSuggestion{},
+		Categories: []string{},
+		Sections:   []string{},
+	}
+
+	q = strings.ToLower(q)
+
+	for _, flashcard := range flashcards() {
+		if strings.Contains(strings.ToLower(flashcard.Question), q) {
+			suggestions.Questions = append(suggestions.Questions, data.QuestionSuggestion{ID: flashcard.ID, Question: flashcard.Question})
+		}
+
+		for _, category := range flashcard.Categories {
+			if strings.HasPrefix(category, q) {
+				suggestions.Categories = append(suggestions.Categories, category)
+			}
+		}
+	}
+
+	return suggestions, nil
+}
+
+func (m FlashcardModel) GetTrash(ctx context.Context, userID int64, filters data.Filters) ([]*data.TrashedFlashcard, data.Metadata, error) {
+	trash := []*data.TrashedFlashcard{
+		{ID: 6, Question: "What is the largest ocean?", Section: ptr("1.2 Oceans"), Type: "qa", DeletedAt: Created.Add(time.Hour)},
+	}
+
+	return trash, page(filters, len(trash)), nil
+}
+
+// Restore brings back card 6, the one in the trash.
+func (m FlashcardModel) Restore(ctx context.Context, ids []int64, userID int64) ([]int64, error) {
+	restored := []int64{}
+	if slices.Contains(ids, 6) {
+		restored = append(restored, 6)
+	}
+
+	return restored, nil
+}
+
+func (m FlashcardModel) PurgeTrash(ctx context.Context, cutoff time.Time) (int64, error) {
+	return 0, nil
+}
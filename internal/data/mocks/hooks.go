@@ -0,0 +1,36 @@
+package mocks
+
+import (
+	"context"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+)
+
+type HookModel struct{}
+
+func (m HookModel) Insert(ctx context.Context, s *data.HookSubscription) error {
+	s.ID = 2
+	s.CreatedAt = Created
+
+	return nil
+}
+
+// GetAllForUser lists the fixture subscription, but only to the listing:
+// events triggered during tests aren't delivered anywhere.
+func (m HookModel) GetAllForUser(ctx context.Context, userID int64, event string) ([]*data.HookSubscription, error) {
+	if event != "" {
+		return []*data.HookSubscription{}, nil
+	}
+
+	return []*data.HookSubscription{
+		{ID: 1, UserID: userID, Event: data.EventQuizCompleted, TargetURL: "https://hooks.example.com/quiz", Secret: "secret", CreatedAt: Created},
+	}, nil
+}
+
+func (m HookModel) Delete(ctx context.Context, id, userID int64) error {
+	if id != 1 {
+		return data.ErrRecordNotFound
+	}
+
+	return nil
+}
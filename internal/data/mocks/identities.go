@@ -0,0 +1,51 @@
+package mocks
+
+import (
+	"context"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+)
+
+type IdentityModel struct{}
+
+// GetUserID knows no external identities, so every sign-in links or creates
+// an account.
+func (m IdentityModel) GetUserID(ctx context.Context, provider, subject string) (int64, error) {
+	return 0, data.ErrRecordNotFound
+}
+
+func (m IdentityModel) LinkUser(ctx context.Context, provider, subject string, userID int64) error {
+	return nil
+}
+
+type LTIModel struct{}
+
+// GetUserID knows no LTI subjects, so every launch links or creates an
+// account.
+func (m LTIModel) GetUserID(ctx context.Context, issuer, subject string) (int64, error) {
+	return 0, data.ErrRecordNotFound
+}
+
+func (m LTIModel) LinkUser(ctx context.Context, issuer, subject string, userID int64) error {
+	return nil
+}
+
+func (m LTIModel) UpsertLaunch(ctx context.Context, launch *data.LTILaunch) error {
+	launch.LaunchedAt = Created
+	return nil
+}
+
+// GetLaunch returns a launch without grade pass-back for the user with id 1.
+func (m LTIModel) GetLaunch(ctx context.Context, userID int64) (*data.LTILaunch, error) {
+	if userID != 1 {
+		return nil, data.ErrRecordNotFound
+	}
+
+	return &data.LTILaunch{
+		UserID:     userID,
+		Issuer:     "https://lms.example.com",
+		Subject:    "lms-user-1",
+		ContextID:  "course-1",
+		LaunchedAt: Created,
+	}, nil
+}
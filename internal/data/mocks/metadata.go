@@ -0,0 +1,47 @@
+package mocks
+
+import (
+	"context"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+)
+
+type MetadataModel struct{}
+
+func (m MetadataModel) Insert(ctx context.Context, field *data.MetadataField) error {
+	field.CreatedAt = Created
+	return nil
+}
+
+func (m MetadataModel) GetAll(ctx context.Context) ([]*data.MetadataField, error) {
+	return []*data.MetadataField{
+		{Name: "source", Type: "string", CreatedAt: Created},
+		{Name: "level", Type: "enum", Options: []string{"gcse", "a-level"}, CreatedAt: Created},
+	}, nil
+}
+
+func (m MetadataModel) Delete(ctx context.Context, name string) error {
+	if name != "source" && name != "level" {
+		return data.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+type PolicyModel struct{}
+
+func (m PolicyModel) Get(ctx context.Context) (*data.CardPolicy, error) {
+	return &data.CardPolicy{
+		DefaultCategories: []string{},
+		RequiredFields:    []string{},
+		Version:           1,
+		UpdatedAt:         Created,
+	}, nil
+}
+
+func (m PolicyModel) Update(ctx context.Context, p *data.CardPolicy) error {
+	p.Version++
+	p.UpdatedAt = Created
+
+	return nil
+}
@@ -0,0 +1,83 @@
+// Package mocks holds stand-ins for the data models, so the handlers can be
+// tested without a database. They serve fixed fixtures: records with id 1
+// exist and belong to the user with id 1, other ids are not found, and
+// writes succeed without storing anything.
+package mocks
+
+import (
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+)
+
+// Created is when every fixture was made. Anything a mock would stamp with
+// the current time gets it too, so responses don't change between runs.
+var Created = time.Date(2024, time.January, 2, 15, 4, 5, 0, time.UTC)
+
+// The tokens and keys the fixtures are reachable with.
+const (
+	// UserToken authenticates the user with id 1, with full access
+	UserToken = "USERAUTHTOKEN0000000000001"
+	// AdminToken authenticates the admin with id 2
+	AdminToken = "ADMINAUTHTOKEN000000000002"
+	// InactiveToken authenticates the user with id 3, who isn't activated
+	InactiveToken = "INACTIVEAUTHTOKEN000000003"
+	// ReadOnlyToken authenticates the user with id 1, limited to
+	// flashcards:read
+	ReadOnlyToken = "READONLYAUTHTOKEN000000004"
+
+	APIKey = "fck_USERAPIKEY0000000000000005"
+
+	ActivationToken  = "ACTIVATIONTOKEN00000000006"
+	MagicLinkToken   = "MAGICLINKTOKEN000000000007"
+	ShareToken       = "DECKSHARETOKEN000000000008"
+	InvitationToken  = "INVITATIONTOKEN00000000009"
+	EmailChangeToken = "EMAILCHANGETOKEN0000000010"
+
+	// Password is every user's password
+	Password = "pa55word"
+)
+
+// NewModels returns the mocks in place of the database-backed models.
+func NewModels() data.Models {
+	return data.Models{
+		APIKeys:      APIKeyModel{},
+		Attachments:  AttachmentModel{},
+		Categories:   CategoryModel{},
+		Decks:        DeckModel{},
+		Exams:        ExamModel{},
+		Flashcards:   FlashcardModel{},
+		Hooks:        HookModel{},
+		Identities:   IdentityModel{},
+		LTI:          LTIModel{},
+		Metadata:     MetadataModel{},
+		Permissions:  PermissionModel{},
+		Policy:       PolicyModel{},
+		Reviews:      ReviewModel{},
+		Searches:     SavedSearchModel{},
+		Sessions:     StudySessionModel{},
+		Templates:    TemplateModel{},
+		Tokens:       TokenModel{},
+		Translations: TranslationModel{},
+		Users:        UserModel{},
+	}
+}
+
+// page is the metadata of a listing that fits on its first page.
+func page(filters data.Filters, total int) data.Metadata {
+	if total == 0 {
+		return data.Metadata{}
+	}
+
+	return data.Metadata{
+		CurrentPage:  1,
+		PageSize:     filters.PageSize,
+		FirstPage:    1,
+		LastPage:     1,
+		TotalRecords: total,
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
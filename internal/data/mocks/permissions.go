@@ -0,0 +1,50 @@
+package mocks
+
+import (
+	"context"
+	"slices"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+)
+
+// roles are the fixture users' roles: the user with id 1 is an editor, the
+// one with id 2 an admin, and the unactivated one with id 3 a reader.
+var roles = map[int64]string{1: "editor", 2: "admin", 3: "reader"}
+
+var rolePermissions = map[string]data.Permissions{
+	"reader": {"flashcards:read"},
+	"editor": {"flashcards:read", "flashcards:write"},
+	"admin":  {"flashcards:read", "flashcards:write", "admin:write"},
+}
+
+type PermissionModel struct{}
+
+func (m PermissionModel) GetAllForUser(ctx context.Context, userID int64) (data.Permissions, error) {
+	return slices.Clone(rolePermissions[roles[userID]]), nil
+}
+
+func (m PermissionModel) AddForUser(ctx context.Context, userID int64, codes ...string) error {
+	return nil
+}
+
+func (m PermissionModel) GetRolesForUser(ctx context.Context, userID int64) ([]*data.UserRole, error) {
+	userRoles := []*data.UserRole{}
+
+	if role, ok := roles[userID]; ok {
+		userRoles = append(userRoles, &data.UserRole{Role: role, GrantedAt: Created})
+	}
+
+	return userRoles, nil
+}
+
+func (m PermissionModel) AddRoleForUser(ctx context.Context, userID int64, role string) error {
+	return nil
+}
+
+func (m PermissionModel) RemoveRoleForUser(ctx context.Context, userID int64, role string) error {
+	if roles[userID] != role {
+		return data.ErrRecordNotFound
+	}
+
+	return nil
+}
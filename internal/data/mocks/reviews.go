@@ -0,0 +1,92 @@
+package mocks
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+	"flashcards-api.johndennehy101.tech/internal/scheduler"
+)
+
+type ReviewModel struct{}
+
+// Insert schedules every review a day out with SM-2, the default algorithm.
+func (m ReviewModel) Insert(ctx context.Context, review *data.Review) error {
+	review.ID = 2
+	review.Algorithm = scheduler.Default
+	review.State = json.RawMessage(`{"ease_factor":2.5,"repetitions":1}`)
+	review.Interval = 1
+	review.ReviewedAt = Created
+	review.DueAt = Created.AddDate(0, 0, 1)
+
+	return nil
+}
+
+func (m ReviewModel) GetAll(ctx context.Context, userID, flashcardID int64, from, to *time.Time, filters data.Filters) ([]*data.Review, data.Metadata, error) {
+	reviews := []*data.Review{}
+
+	if flashcardID == 0 || flashcardID == 1 {
+		reviews = append(reviews, &data.Review{
+			ID:          1,
+			FlashcardID: 1,
+			UserID:      userID,
+			Grade:       4,
+			Algorithm:   scheduler.Default,
+			State:       json.RawMessage(`{"ease_factor":2.5,"repetitions":1}`),
+			Interval:    1,
+			DueAt:       Created.AddDate(0, 0, 1),
+			LatencyMS:   ptr(3200),
+			ReviewedAt:  Created,
+		})
+	}
+
+	return reviews, page(filters, len(reviews)), nil
+}
+
+func (m ReviewModel) Project(ctx context.Context, userID int64, flashcards []*data.Flashcard, now time.Time) error {
+	for _, flashcard := range flashcards {
+		if flashcard.Schedule != nil {
+			flashcard.Schedule.Projected = map[string]int{"again": 1, "hard": 1, "good": 1, "easy": 4}
+		}
+	}
+
+	return nil
+}
+
+func (m ReviewModel) GetStreak(ctx context.Context, user *data.User, now time.Time, loc *time.Location) (*data.Streak, error) {
+	return &data.Streak{
+		Current:     2,
+		Longest:     5,
+		LastGoalMet: ptr("2024-01-02"),
+		Today:       data.StudyToday{Cards: 4, Goal: user.DailyGoal, Unit: user.DailyGoalUnit},
+	}, nil
+}
+
+func (m ReviewModel) Forecast(ctx context.Context, userID int64, now time.Time, days int, loc *time.Location) ([]data.ForecastDay, error) {
+	forecast := make([]data.ForecastDay, days)
+	for i := range forecast {
+		forecast[i] = data.ForecastDay{Date: Created.AddDate(0, 0, i).Format(time.DateOnly), Due: 1, Total: i + 1}
+	}
+
+	return forecast, nil
+}
+
+func (m ReviewModel) SetSuspended(ctx context.Context, userID, flashcardID int64, suspended bool) (*data.CardHold, error) {
+	return &data.CardHold{FlashcardID: flashcardID, Suspended: suspended}, nil
+}
+
+// SetBuriedUntil reports cards as buried until the day after Created, as the
+// real deadline depends on when the tests run.
+func (m ReviewModel) SetBuriedUntil(ctx context.Context, userID, flashcardID int64, until *time.Time) (*data.CardHold, error) {
+	hold := &data.CardHold{FlashcardID: flashcardID}
+	if until != nil {
+		hold.BuriedUntil = ptr(Created.AddDate(0, 0, 1))
+	}
+
+	return hold, nil
+}
+
+func (m ReviewModel) CountSince(ctx context.Context, userID int64, since time.Time) (data.StudyCounts, error) {
+	return data.StudyCounts{New: 1, Review: 2}, nil
+}
@@ -0,0 +1,87 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+)
+
+// savedSearches returns the fixture searches: one of the user with id 1's,
+// which they're subscribed to, and one the admin shared.
+func savedSearches() []*data.SavedSearch {
+	return []*data.SavedSearch{
+		{ID: 1, UserID: 1, Name: "Geography", Query: "categories=geography", Subscribed: true, Version: 1, CreatedAt: Created},
+		{ID: 2, UserID: 2, Name: "Chemistry", Query: "categories=chemistry", Shared: true, Version: 1, CreatedAt: Created},
+	}
+}
+
+type SavedSearchModel struct{}
+
+func (m SavedSearchModel) Insert(ctx context.Context, s *data.SavedSearch) error {
+	s.ID = 3
+	s.Owned = true
+	s.Version = 1
+	s.CreatedAt = Created
+
+	return nil
+}
+
+func (m SavedSearchModel) Get(ctx context.Context, id, userID int64) (*data.SavedSearch, error) {
+	for _, s := range savedSearches() {
+		if s.ID == id && (s.UserID == userID || s.Shared) {
+			s.Owned = s.UserID == userID
+			s.Subscribed = s.Subscribed && s.Owned
+			return s, nil
+		}
+	}
+
+	return nil, data.ErrRecordNotFound
+}
+
+func (m SavedSearchModel) GetAllForUser(ctx context.Context, userID int64) ([]*data.SavedSearch, error) {
+	searches := []*data.SavedSearch{}
+	for _, s := range savedSearches() {
+		s, err := m.Get(ctx, s.ID, userID)
+		if err == nil {
+			searches = append(searches, s)
+		}
+	}
+
+	return searches, nil
+}
+
+func (m SavedSearchModel) Update(ctx context.Context, s *data.SavedSearch) error {
+	s.Version++
+	return nil
+}
+
+func (m SavedSearchModel) Delete(ctx context.Context, id, userID int64) error {
+	s, err := m.Get(ctx, id, userID)
+	if err != nil || !s.Owned {
+		return data.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m SavedSearchModel) Subscribe(ctx context.Context, id, userID int64) error {
+	return nil
+}
+
+func (m SavedSearchModel) Unsubscribe(ctx context.Context, id, userID int64) error {
+	s, err := m.Get(ctx, id, userID)
+	if err != nil || !s.Subscribed {
+		return data.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m SavedSearchModel) GetSubscriptions(ctx context.Context) ([]*data.SearchSubscription, error) {
+	return []*data.SearchSubscription{}, nil
+}
+
+func (m SavedSearchModel) MarkNotified(ctx context.Context, id, userID int64, notifiedAt time.Time) error {
+	return nil
+}
@@ -0,0 +1,68 @@
+package mocks
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+)
+
+type StudySessionModel struct{}
+
+func (m StudySessionModel) Insert(ctx context.Context, s *data.StudySession) error {
+	s.ID = 3
+	s.StartedAt = Created
+
+	return nil
+}
+
+// Get returns the user with id 1's sessions: session 1 is in progress with
+// one answer and session 2 is completed.
+func (m StudySessionModel) Get(ctx context.Context, id, userID int64) (*data.StudySession, error) {
+	if userID != 1 || (id != 1 && id != 2) {
+		return nil, data.ErrRecordNotFound
+	}
+
+	s := &data.StudySession{
+		ID:        id,
+		UserID:    userID,
+		DeckID:    ptr(int64(1)),
+		StartedAt: Created,
+		Answers: []*data.SessionAnswer{
+			{ID: 1, FlashcardID: 1, Response: json.RawMessage(`"Paris"`), Correct: true, Graded: true, LatencyMS: ptr(2500), AnsweredAt: Created.Add(time.Minute)},
+		},
+	}
+
+	if id == 2 {
+		s.CompletedAt = ptr(Created.Add(10 * time.Minute))
+	}
+
+	return s, nil
+}
+
+func (m StudySessionModel) InsertAnswer(ctx context.Context, sessionID, userID int64, a *data.SessionAnswer) error {
+	s, err := m.Get(ctx, sessionID, userID)
+	if err != nil {
+		return err
+	}
+
+	if s.CompletedAt != nil {
+		return data.ErrSessionCompleted
+	}
+
+	a.ID = 2
+	a.AnsweredAt = Created.Add(2 * time.Minute)
+
+	return nil
+}
+
+func (m StudySessionModel) Complete(ctx context.Context, s *data.StudySession) error {
+	if s.CompletedAt != nil {
+		return data.ErrSessionCompleted
+	}
+
+	s.CompletedAt = ptr(Created.Add(10 * time.Minute))
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package mocks
+
+import (
+	"context"
+	"encoding/json"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+)
+
+// template returns the fixture template, a QA card asking for a country's
+// capital.
+func template() *data.Template {
+	return &data.Template{
+		ID:           1,
+		Name:         "Capital city",
+		Description:  "Asks for the capital of a country",
+		Categories:   []string{"geography"},
+		Type:         data.FlashcardQA,
+		Question:     "What is the capital of {{country}}?",
+		Content:      json.RawMessage(`{"answer": "{{capital}}"}`),
+		Version:      1,
+		CreatedAt:    Created,
+		Placeholders: []string{"country", "capital"},
+	}
+}
+
+type TemplateModel struct{}
+
+// Insert doesn't find the placeholders in the template, so they're only
+// listed for the fixture.
+func (m TemplateModel) Insert(ctx context.Context, t *data.Template) error {
+	t.ID = 2
+	t.Version = 1
+	t.CreatedAt = Created
+	t.Placeholders = []string{}
+
+	return nil
+}
+
+func (m TemplateModel) Get(ctx context.Context, id int64) (*data.Template, error) {
+	if id != 1 {
+		return nil, data.ErrRecordNotFound
+	}
+
+	return template(), nil
+}
+
+func (m TemplateModel) GetAll(ctx context.Context, filters data.Filters) ([]*data.Template, data.Metadata, error) {
+	return []*data.Template{template()}, page(filters, 1), nil
+}
+
+func (m TemplateModel) Update(ctx context.Context, t *data.Template) error {
+	t.Version++
+	return nil
+}
+
+func (m TemplateModel) Delete(ctx context.Context, id int64) error {
+	_, err := m.Get(ctx, id)
+	return err
+}
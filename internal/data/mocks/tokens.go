@@ -0,0 +1,100 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+)
+
+type TokenModel struct{}
+
+// New issues the fixture token for scope, so tests can use it next.
+func (m TokenModel) New(ctx context.Context, userID int64, ttl time.Duration, scope string) (*data.Token, error) {
+	plaintext := UserToken
+
+	switch scope {
+	case data.ScopeActivation:
+		plaintext = ActivationToken
+	case data.ScopeMagicLink:
+		plaintext = MagicLinkToken
+	}
+
+	return &data.Token{Plaintext: plaintext, UserID: userID, Expiry: Created.Add(ttl), Scope: scope}, nil
+}
+
+func (m TokenModel) NewSession(ctx context.Context, userID int64, ttl time.Duration, scopes []string, userAgent, ip string) (*data.Token, error) {
+	token := &data.Token{
+		Plaintext: UserToken,
+		UserID:    userID,
+		Expiry:    Created.Add(ttl),
+		Scope:     data.ScopeAuthentication,
+		Scopes:    scopes,
+		UserAgent: userAgent,
+		IP:        ip,
+	}
+
+	if userID == 2 {
+		token.Plaintext = AdminToken
+	}
+
+	return token, nil
+}
+
+func (m TokenModel) Insert(ctx context.Context, token *data.Token) error {
+	return nil
+}
+
+func (m TokenModel) Use(ctx context.Context, scope, tokenPlaintext string) (int64, error) {
+	if scope == data.ScopeMagicLink && tokenPlaintext == MagicLinkToken {
+		return 3, nil
+	}
+
+	return 0, data.ErrRecordNotFound
+}
+
+func (m TokenModel) DeleteAllForUser(ctx context.Context, scope string, userID int64) error {
+	return nil
+}
+
+func (m TokenModel) DeleteEveryForUser(ctx context.Context, userID int64) error {
+	return nil
+}
+
+func (m TokenModel) GetSessionsForUser(ctx context.Context, userID int64, current string) ([]*data.AuthSession, error) {
+	return []*data.AuthSession{
+		{
+			ID:         1,
+			CreatedAt:  Created,
+			LastUsedAt: ptr(Created.Add(time.Hour)),
+			Expiry:     Created.Add(24 * time.Hour),
+			UserAgent:  "Mozilla/5.0",
+			IP:         "192.0.2.1",
+			Current:    current == UserToken,
+		},
+		{
+			ID:        2,
+			CreatedAt: Created,
+			Expiry:    Created.Add(24 * time.Hour),
+			UserAgent: "flashcards-cli/1.0",
+			IP:        "192.0.2.2",
+			Scopes:    data.Permissions{"flashcards:read"},
+		},
+	}, nil
+}
+
+func (m TokenModel) DeleteSession(ctx context.Context, id, userID int64) error {
+	if id != 1 && id != 2 {
+		return data.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m TokenModel) DeleteOtherSessions(ctx context.Context, userID int64, current string) (int64, error) {
+	return 1, nil
+}
+
+func (m TokenModel) DeleteExpired(ctx context.Context) (int64, error) {
+	return 0, nil
+}
@@ -0,0 +1,36 @@
+package mocks
+
+import (
+	"context"
+	"slices"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+)
+
+type TranslationModel struct{}
+
+func (m TranslationModel) Upsert(ctx context.Context, t *data.Translation) error {
+	t.CreatedAt = Created
+	t.UpdatedAt = Created
+
+	return nil
+}
+
+// GetAllForFlashcards returns the French translation of card 1.
+func (m TranslationModel) GetAllForFlashcards(ctx context.Context, flashcardIDs []int64) ([]*data.Translation, error) {
+	if !slices.Contains(flashcardIDs, 1) {
+		return []*data.Translation{}, nil
+	}
+
+	return []*data.Translation{
+		{
+			FlashcardID: 1,
+			Language:    "fr",
+			Question:    "Quelle est la capitale de la France ?",
+			Type:        data.FlashcardQA,
+			Content:     data.QAContent{Answer: "Paris", Justification: "Paris est le siège du gouvernement français."},
+			CreatedAt:   Created,
+			UpdatedAt:   Created,
+		},
+	}, nil
+}
@@ -0,0 +1,169 @@
+package mocks
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/data"
+)
+
+// users are the fixture accounts, built once because hashing their password
+// is slow. Callers get copies they are free to change.
+var users = sync.OnceValue(func() []data.User {
+	users := []data.User{
+		{ID: 1, Name: "Alice Smith", Email: "alice@example.com", Activated: true},
+		{ID: 2, Name: "Admin User", Email: "admin@example.com", Activated: true},
+		{ID: 3, Name: "Bob Jones", Email: "bob@example.com"},
+	}
+
+	for i := range users {
+		users[i].CreatedAt = Created
+		users[i].Locale = "en-GB"
+		users[i].Timezone = "UTC"
+		users[i].DailyGoal = 20
+		users[i].DailyGoalUnit = "cards"
+		users[i].Version = 1
+
+		err := users[i].Password.Set(Password)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	return users
+})
+
+func user(id int64) (*data.User, error) {
+	for _, u := range users() {
+		if u.ID == id {
+			return &u, nil
+		}
+	}
+
+	return nil, data.ErrRecordNotFound
+}
+
+type UserModel struct{}
+
+func (m UserModel) CreateExport(ctx context.Context, userID int64) (*data.DataExport, error) {
+	return &data.DataExport{Status: "pending", CreatedAt: Created}, nil
+}
+
+// GetExport reports a finished export for the user with id 1; nobody else
+// has asked for one.
+func (m UserModel) GetExport(ctx context.Context, userID int64) (*data.DataExport, error) {
+	if userID != 1 {
+		return nil, data.ErrRecordNotFound
+	}
+
+	return &data.DataExport{
+		Status:      "ready",
+		SizeBytes:   ptr(int64(2048)),
+		CreatedAt:   Created,
+		CompletedAt: ptr(Created.Add(time.Minute)),
+		ExpiresAt:   ptr(Created.Add(7 * 24 * time.Hour)),
+		StorageKey:  ptr("exports/1/export.zip"),
+	}, nil
+}
+
+func (m UserModel) GetExportFiles(ctx context.Context, userID int64) ([]data.ExportFile, error) {
+	return []data.ExportFile{{Name: "user.json", Data: json.RawMessage(`{}`)}}, nil
+}
+
+func (m UserModel) CompleteExport(ctx context.Context, userID int64, storageKey string, size int64, ttl time.Duration) error {
+	return nil
+}
+
+func (m UserModel) FailExport(ctx context.Context, userID int64, reason string) error {
+	return nil
+}
+
+func (m UserModel) DeleteExpiredExports(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (m UserModel) RequestEmailChange(ctx context.Context, userID int64, email string, ttl time.Duration) (*data.EmailChange, error) {
+	return &data.EmailChange{Email: email, Expiry: Created.Add(ttl), Plaintext: EmailChangeToken}, nil
+}
+
+func (m UserModel) ConfirmEmailChange(ctx context.Context, tokenPlaintext string) (int64, string, error) {
+	if tokenPlaintext != EmailChangeToken {
+		return 0, "", data.ErrRecordNotFound
+	}
+
+	return 1, "alice.old@example.com", nil
+}
+
+// Insert refuses the fixture users' addresses as taken.
+func (m UserModel) Insert(ctx context.Context, u *data.User) error {
+	_, err := m.GetByEmail(ctx, u.Email)
+	if err == nil {
+		return data.ErrDuplicateEmail
+	}
+
+	u.ID = 4
+	u.CreatedAt = Created
+	u.Version = 1
+
+	return nil
+}
+
+func (m UserModel) Get(ctx context.Context, id int64) (*data.User, error) {
+	return user(id)
+}
+
+func (m UserModel) GetByEmail(ctx context.Context, email string) (*data.User, error) {
+	for _, u := range users() {
+		if strings.EqualFold(u.Email, email) {
+			return &u, nil
+		}
+	}
+
+	return nil, data.ErrRecordNotFound
+}
+
+func (m UserModel) Update(ctx context.Context, u *data.User) error {
+	u.Version++
+	return nil
+}
+
+func (m UserModel) Rehash(ctx context.Context, u *data.User, plaintextPassword string) error {
+	return nil
+}
+
+// GetForToken finds the user the fixture tokens were issued to, with the
+// scopes the token is limited to.
+func (m UserModel) GetForToken(ctx context.Context, tokenScope, tokenPlaintext string) (*data.User, data.Permissions, error) {
+	var id int64
+	var scopes data.Permissions
+
+	switch {
+	case tokenScope == data.ScopeAuthentication && tokenPlaintext == UserToken:
+		id = 1
+	case tokenScope == data.ScopeAuthentication && tokenPlaintext == AdminToken:
+		id = 2
+	case tokenScope == data.ScopeAuthentication && tokenPlaintext == InactiveToken:
+		id = 3
+	case tokenScope == data.ScopeAuthentication && tokenPlaintext == ReadOnlyToken:
+		id = 1
+		scopes = data.Permissions{"flashcards:read"}
+	case tokenScope == data.ScopeActivation && tokenPlaintext == ActivationToken:
+		id = 3
+	default:
+		return nil, nil, data.ErrRecordNotFound
+	}
+
+	u, err := user(id)
+	return u, scopes, err
+}
+
+func (m UserModel) Delete(ctx context.Context, userID int64, deleteCards bool) ([]int64, error) {
+	if deleteCards {
+		return []int64{1, 2, 3}, nil
+	}
+
+	return []int64{}, nil
+}
@@ -1,8 +1,12 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/scheduler"
 )
 
 var (
@@ -10,18 +14,272 @@ var (
 	ErrEditConflict   = errors.New("edit conflict")
 )
 
+// Models holds the models behind interfaces, so the handlers can be tested
+// against mocks instead of a database.
 type Models struct {
-	Flashcards  FlashcardModel
-	Users       UserModel
-	Tokens      TokenModel
-	Permissions PermissionModel
+	APIKeys      APIKeyModelInterface
+	Attachments  AttachmentModelInterface
+	Categories   CategoryModelInterface
+	Decks        DeckModelInterface
+	Exams        ExamModelInterface
+	Flashcards   FlashcardModelInterface
+	Hooks        HookModelInterface
+	Identities   IdentityModelInterface
+	LTI          LTIModelInterface
+	Metadata     MetadataModelInterface
+	Users        UserModelInterface
+	Tokens       TokenModelInterface
+	Translations TranslationModelInterface
+	Permissions  PermissionModelInterface
+	Policy       PolicyModelInterface
+	Reviews      ReviewModelInterface
+	Searches     SavedSearchModelInterface
+	Sessions     StudySessionModelInterface
+	Templates    TemplateModelInterface
+}
+
+type APIKeyModelInterface interface {
+	Insert(ctx context.Context, key *APIKey) error
+	GetAllForUser(ctx context.Context, userID int64) ([]*APIKey, error)
+	Delete(ctx context.Context, id, userID int64) error
+	GetUserForKey(ctx context.Context, plaintext string) (*User, Permissions, error)
+}
+
+type AttachmentModelInterface interface {
+	Insert(ctx context.Context, attachment *Attachment) error
+	Get(ctx context.Context, id, flashcardID int64) (*Attachment, error)
+	GetAllForFlashcards(ctx context.Context, flashcardIDs []int64) ([]*Attachment, error)
+	Delete(ctx context.Context, id int64) error
+	GetPendingDeletions(ctx context.Context, limit int) ([]string, error)
+	ClearPendingDeletion(ctx context.Context, storageKey string) error
+}
+
+type CategoryModelInterface interface {
+	Insert(ctx context.Context, c *Category) error
+	Get(ctx context.Context, id int64) (*Category, error)
+	GetByName(ctx context.Context, name string) (*Category, error)
+	GetByNames(ctx context.Context, names []string) ([]*Category, error)
+	GetAll(ctx context.Context, prefix string, filters Filters) ([]*Category, Metadata, error)
+	Update(ctx context.Context, c *Category) ([]int64, error)
+	Delete(ctx context.Context, id int64) ([]int64, error)
+	Merge(ctx context.Context, from, into string) ([]int64, error)
+	GetStats(ctx context.Context, userID int64) ([]*CategoryStats, error)
+}
+
+type DeckModelInterface interface {
+	GetMembers(ctx context.Context, deckID int64) ([]*DeckMember, error)
+	UpdateMember(ctx context.Context, deckID, userID int64, role string) error
+	RemoveMember(ctx context.Context, deckID, userID int64) error
+	Invite(ctx context.Context, inv *DeckInvitation, ttl time.Duration) error
+	GetInvitations(ctx context.Context, deckID int64) ([]*DeckInvitation, error)
+	DeleteInvitation(ctx context.Context, id, deckID int64) error
+	AcceptInvitation(ctx context.Context, tokenPlaintext string, user *User) (int64, error)
+	EditableByMember(ctx context.Context, flashcardID, userID int64) (bool, error)
+	Insert(ctx context.Context, d *Deck) error
+	Get(ctx context.Context, id, userID int64) (*Deck, error)
+	GetEditable(ctx context.Context, id, userID int64) (*Deck, error)
+	GetVisible(ctx context.Context, id, userID int64) (*Deck, error)
+	GetByShareToken(ctx context.Context, tokenPlaintext string) (*Deck, error)
+	GetAllForUser(ctx context.Context, userID int64, archived bool, filters Filters) ([]*Deck, Metadata, error)
+	Update(ctx context.Context, d *Deck) error
+	Delete(ctx context.Context, id, userID int64) error
+	Share(ctx context.Context, id, userID int64) (string, error)
+	Unshare(ctx context.Context, id, userID int64) error
+	SetArchived(ctx context.Context, id, userID int64, archived bool) (*time.Time, error)
+	Clone(ctx context.Context, src, d *Deck, copyCards bool) ([]int64, error)
+	AddFlashcards(ctx context.Context, deckID int64, ids []int64) ([]int64, error)
+	RemoveFlashcards(ctx context.Context, deckID int64, ids []int64) ([]int64, error)
+	Export(ctx context.Context, d *Deck) (*DeckBundle, error)
+	Import(ctx context.Context, d *Deck, flashcards []*Flashcard) error
+	GetOrder(ctx context.Context, deckID int64) ([]int64, error)
+	Reorder(ctx context.Context, deckID int64, ids []int64) error
+	GetStats(ctx context.Context, userID int64, filter FlashcardFilter) (*DeckStats, error)
+}
+
+type ExamModelInterface interface {
+	Insert(ctx context.Context, exam *Exam, spec ExamSpec) error
+	Get(ctx context.Context, id, userID int64) (*Exam, error)
+	Serve(ctx context.Context, exam *Exam, q *ExamQuestion) error
+	Answer(ctx context.Context, exam *Exam, q *ExamQuestion) error
+	Submit(ctx context.Context, exam *Exam) error
+}
+
+type FlashcardModelInterface interface {
+	Retag(ctx context.Context, userID int64, ids []int64, filter FlashcardFilter, add, remove []string) ([]int64, error)
+	SuggestCategories(ctx context.Context, flashcard *Flashcard) ([]string, error)
+	GetDistractors(ctx context.Context, flashcard *Flashcard) ([]string, error)
+	Convert(ctx context.Context, flashcard *Flashcard, resetProgress bool) error
+	SetEmbedding(ctx context.Context, id int64, model string, embedding Vector) error
+	GetUnembeddedIDs(ctx context.Context, model string, limit int) ([]int64, error)
+	NearestNeighbours(ctx context.Context, model string, embedding Vector, maxDistance float64, filters Filters) ([]int64, Metadata, error)
+	Insert(ctx context.Context, flashcard *Flashcard, userID int64) error
+	FindDuplicate(ctx context.Context, question string, userID int64) (int64, error)
+	Get(ctx context.Context, id int64, userID int64) (*Flashcard, error)
+	GetFilterMetadata(ctx context.Context, userID int64, file string, qType string, hideMastered bool) (*FilterMetadata, error)
+	Update(ctx context.Context, flashcard *Flashcard) error
+	Delete(ctx context.Context, id, userID int64) error
+	GetUserStats(ctx context.Context, userID int64) (*FlashcardStats, error)
+	GetAll(ctx context.Context, userID int64, filter FlashcardFilter, filters Filters) ([]*Flashcard, Metadata, error)
+	IncrementCorrectCount(ctx context.Context, id int64, userID int64) error
+	ResetCorrectCount(ctx context.Context, id int64, userID int64) error
+	GetByIDs(ctx context.Context, ids []int64, userID int64) ([]*Flashcard, error)
+	GetHeadlines(ctx context.Context, ids []int64, q string) (map[int64]string, error)
+	Search(ctx context.Context, userID int64, q string, filters Filters) ([]*Flashcard, Metadata, error)
+	GetAllForIndex(ctx context.Context, afterID int64, limit int) ([]*Flashcard, error)
+	Export(ctx context.Context, userID int64) (*FlashcardExport, error)
+	GetCardStats(ctx context.Context, ids []int64, userID int64) (map[int64]*CardStats, error)
+	GetSourceSections(ctx context.Context, sections []string) ([]*SourceSection, error)
+	AddPrerequisite(ctx context.Context, id, prerequisiteID int64) error
+	DeletePrerequisite(ctx context.Context, id, prerequisiteID int64) error
+	GetStudyPath(ctx context.Context, userID int64, category string) ([]*Flashcard, error)
+	RecordReview(ctx context.Context, id, userID int64, correct bool, latencyMS *int) error
+	InsertReport(ctx context.Context, report *Report) error
+	RefreshQualityScores(ctx context.Context) (int64, error)
+	RefreshMeasuredDifficulty(ctx context.Context) (int64, error)
+	RecordView(ctx context.Context, id, userID int64) error
+	GetRecentFlashcards(ctx context.Context, userID int64, limit int) ([]*RecentFlashcard, error)
+	GetRecentSections(ctx context.Context, userID int64, limit int) ([]*RecentSection, error)
+	AddRelation(ctx context.Context, id, relatedID int64) error
+	DeleteRelation(ctx context.Context, id, relatedID int64) error
+	GetRelatedIDs(ctx context.Context, ids []int64) (map[int64][]int64, error)
+	GetQueue(ctx context.Context, userID int64, filter FlashcardFilter, newLimit, reviewLimit int, now time.Time, dueOnly bool) ([]*Flashcard, StudyCounts, error)
+	Suggest(ctx context.Context, q string, limit int) (*Suggestions, error)
+	GetTrash(ctx context.Context, userID int64, filters Filters) ([]*TrashedFlashcard, Metadata, error)
+	Restore(ctx context.Context, ids []int64, userID int64) ([]int64, error)
+	PurgeTrash(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+type HookModelInterface interface {
+	Insert(ctx context.Context, s *HookSubscription) error
+	GetAllForUser(ctx context.Context, userID int64, event string) ([]*HookSubscription, error)
+	Delete(ctx context.Context, id, userID int64) error
+}
+
+type IdentityModelInterface interface {
+	GetUserID(ctx context.Context, provider, subject string) (int64, error)
+	LinkUser(ctx context.Context, provider, subject string, userID int64) error
+}
+
+type LTIModelInterface interface {
+	GetUserID(ctx context.Context, issuer, subject string) (int64, error)
+	LinkUser(ctx context.Context, issuer, subject string, userID int64) error
+	UpsertLaunch(ctx context.Context, launch *LTILaunch) error
+	GetLaunch(ctx context.Context, userID int64) (*LTILaunch, error)
+}
+
+type MetadataModelInterface interface {
+	Insert(ctx context.Context, field *MetadataField) error
+	GetAll(ctx context.Context) ([]*MetadataField, error)
+	Delete(ctx context.Context, name string) error
+}
+
+type PermissionModelInterface interface {
+	GetAllForUser(ctx context.Context, userID int64) (Permissions, error)
+	AddForUser(ctx context.Context, userID int64, codes ...string) error
+	GetRolesForUser(ctx context.Context, userID int64) ([]*UserRole, error)
+	AddRoleForUser(ctx context.Context, userID int64, role string) error
+	RemoveRoleForUser(ctx context.Context, userID int64, role string) error
+}
+
+type PolicyModelInterface interface {
+	Get(ctx context.Context) (*CardPolicy, error)
+	Update(ctx context.Context, p *CardPolicy) error
+}
+
+type ReviewModelInterface interface {
+	Insert(ctx context.Context, review *Review) error
+	GetAll(ctx context.Context, userID, flashcardID int64, from, to *time.Time, filters Filters) ([]*Review, Metadata, error)
+	Project(ctx context.Context, userID int64, flashcards []*Flashcard, now time.Time) error
+	GetStreak(ctx context.Context, user *User, now time.Time, loc *time.Location) (*Streak, error)
+	Forecast(ctx context.Context, userID int64, now time.Time, days int, loc *time.Location) ([]ForecastDay, error)
+	SetSuspended(ctx context.Context, userID, flashcardID int64, suspended bool) (*CardHold, error)
+	SetBuriedUntil(ctx context.Context, userID, flashcardID int64, until *time.Time) (*CardHold, error)
+	CountSince(ctx context.Context, userID int64, since time.Time) (StudyCounts, error)
+}
+
+type SavedSearchModelInterface interface {
+	Insert(ctx context.Context, s *SavedSearch) error
+	Get(ctx context.Context, id, userID int64) (*SavedSearch, error)
+	GetAllForUser(ctx context.Context, userID int64) ([]*SavedSearch, error)
+	Update(ctx context.Context, s *SavedSearch) error
+	Delete(ctx context.Context, id, userID int64) error
+	Subscribe(ctx context.Context, id, userID int64) error
+	Unsubscribe(ctx context.Context, id, userID int64) error
+	GetSubscriptions(ctx context.Context) ([]*SearchSubscription, error)
+	MarkNotified(ctx context.Context, id, userID int64, notifiedAt time.Time) error
+}
+
+type StudySessionModelInterface interface {
+	Insert(ctx context.Context, s *StudySession) error
+	Get(ctx context.Context, id, userID int64) (*StudySession, error)
+	InsertAnswer(ctx context.Context, sessionID, userID int64, a *SessionAnswer) error
+	Complete(ctx context.Context, s *StudySession) error
+}
+
+type TemplateModelInterface interface {
+	Insert(ctx context.Context, t *Template) error
+	Get(ctx context.Context, id int64) (*Template, error)
+	GetAll(ctx context.Context, filters Filters) ([]*Template, Metadata, error)
+	Update(ctx context.Context, t *Template) error
+	Delete(ctx context.Context, id int64) error
+}
+
+type TokenModelInterface interface {
+	New(ctx context.Context, userID int64, ttl time.Duration, scope string) (*Token, error)
+	NewSession(ctx context.Context, userID int64, ttl time.Duration, scopes []string, userAgent, ip string) (*Token, error)
+	Insert(ctx context.Context, token *Token) error
+	Use(ctx context.Context, scope, tokenPlaintext string) (int64, error)
+	DeleteAllForUser(ctx context.Context, scope string, userID int64) error
+	DeleteEveryForUser(ctx context.Context, userID int64) error
+	GetSessionsForUser(ctx context.Context, userID int64, current string) ([]*AuthSession, error)
+	DeleteSession(ctx context.Context, id, userID int64) error
+	DeleteOtherSessions(ctx context.Context, userID int64, current string) (int64, error)
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type TranslationModelInterface interface {
+	Upsert(ctx context.Context, t *Translation) error
+	GetAllForFlashcards(ctx context.Context, flashcardIDs []int64) ([]*Translation, error)
+}
+
+type UserModelInterface interface {
+	CreateExport(ctx context.Context, userID int64) (*DataExport, error)
+	GetExport(ctx context.Context, userID int64) (*DataExport, error)
+	GetExportFiles(ctx context.Context, userID int64) ([]ExportFile, error)
+	CompleteExport(ctx context.Context, userID int64, storageKey string, size int64, ttl time.Duration) error
+	FailExport(ctx context.Context, userID int64, reason string) error
+	DeleteExpiredExports(ctx context.Context) (int64, error)
+	RequestEmailChange(ctx context.Context, userID int64, email string, ttl time.Duration) (*EmailChange, error)
+	ConfirmEmailChange(ctx context.Context, tokenPlaintext string) (int64, string, error)
+	Insert(ctx context.Context, user *User) error
+	Get(ctx context.Context, id int64) (*User, error)
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	Update(ctx context.Context, user *User) error
+	Rehash(ctx context.Context, user *User, plaintextPassword string) error
+	GetForToken(ctx context.Context, tokenScope, tokenPlaintext string) (*User, Permissions, error)
+	Delete(ctx context.Context, userID int64, deleteCards bool) ([]int64, error)
 }
 
-func NewModels(db *sql.DB) Models {
+func NewModels(db *sql.DB, schedulers scheduler.Set) Models {
 	return Models{
-		Flashcards:  FlashcardModel{DB: db},
-		Permissions: PermissionModel{DB: db},
-		Tokens:      TokenModel{DB: db},
-		Users:       UserModel{DB: db},
+		APIKeys:      APIKeyModel{DB: db},
+		Attachments:  AttachmentModel{DB: db},
+		Categories:   CategoryModel{DB: db},
+		Decks:        DeckModel{DB: db},
+		Exams:        ExamModel{DB: db},
+		Flashcards:   FlashcardModel{DB: db},
+		Hooks:        HookModel{DB: db},
+		Identities:   IdentityModel{DB: db},
+		LTI:          LTIModel{DB: db},
+		Metadata:     MetadataModel{DB: db},
+		Permissions:  PermissionModel{DB: db},
+		Policy:       PolicyModel{DB: db},
+		Reviews:      ReviewModel{DB: db, Schedulers: schedulers},
+		Searches:     SavedSearchModel{DB: db},
+		Sessions:     StudySessionModel{DB: db},
+		Templates:    TemplateModel{DB: db},
+		Tokens:       TokenModel{DB: db},
+		Translations: TranslationModel{DB: db},
+		Users:        UserModel{DB: db},
 	}
 }
@@ -0,0 +1,203 @@
+package data
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"flashcards-api.johndennehy101.tech/internal/validator"
+	"golang.org/x/crypto/argon2"
+)
+
+// MinPasswordEntropy is the estimated strength, in bits, a new password must
+// have. Eleven lowercase letters, or eight characters mixing capitals,
+// digits and symbols, clear it.
+const MinPasswordEntropy = 50
+
+// passwordEntropy estimates the bits of entropy in a password from the size
+// of the character classes it draws on and its length. Runs of one character
+// and steps through the alphabet or the digits ("aaaa", "abcd", "1234") only
+// count once, since they are the first things a guesser tries.
+func passwordEntropy(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+	var length int
+	var prev rune = -1
+
+	for _, c := range password {
+		switch {
+		case c < unicode.MaxASCII && unicode.IsLower(c):
+			lower = true
+		case c < unicode.MaxASCII && unicode.IsUpper(c):
+			upper = true
+		case c < unicode.MaxASCII && unicode.IsDigit(c):
+			digit = true
+		case c < unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+
+		if c != prev && c != prev+1 && c != prev-1 {
+			length++
+		}
+		prev = c
+	}
+
+	var pool int
+	if lower {
+		pool += 26
+	}
+	if upper {
+		pool += 26
+	}
+	if digit {
+		pool += 10
+	}
+	if symbol {
+		pool += 33
+	}
+	if other {
+		pool += 100
+	}
+
+	if pool == 0 {
+		return 0
+	}
+
+	return float64(length) * math.Log2(float64(pool))
+}
+
+// ValidatePasswordStrength checks a new password is hard enough to guess and
+// isn't made of the user's own name or email address. It goes further than
+// ValidatePasswordPlaintext, which logins also use and which existing
+// passwords must keep passing.
+func ValidatePasswordStrength(v *validator.Validator, password string, user *User) {
+	lower := strings.ToLower(password)
+
+	localPart, _, _ := strings.Cut(strings.ToLower(user.Email), "@")
+	if len(localPart) >= 3 {
+		v.Check(!strings.Contains(lower, localPart), "password", "must not contain your email address")
+	}
+
+	for word := range strings.FieldsSeq(strings.ToLower(user.Name)) {
+		if len(word) >= 3 {
+			v.Check(!strings.Contains(lower, word), "password", "must not contain your name")
+		}
+	}
+
+	v.Check(passwordEntropy(password) >= MinPasswordEntropy, "password", "is too easy to guess, use a longer password or mix in capitals, digits and symbols")
+}
+
+const (
+	PasswordSchemeBcrypt   = "bcrypt"
+	PasswordSchemeArgon2id = "argon2id"
+)
+
+// Argon2Params are the Argon2id costs new password hashes are made with.
+// Memory is in KiB.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// PasswordArgon2 holds the costs for new hashes. It is set once at startup;
+// hashes made with other costs still verify, and are redone with these at
+// the user's next login.
+var PasswordArgon2 = Argon2Params{Memory: 64 * 1024, Iterations: 3, Parallelism: 2}
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+var errInvalidArgon2Hash = errors.New("invalid argon2id password hash")
+
+// hashArgon2id hashes the password in the PHC string format,
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>, so each hash carries the
+// costs it was made with.
+func hashArgon2id(plaintext string, params Argon2Params) []byte {
+	salt := make([]byte, argon2SaltLength)
+	rand.Read(salt)
+
+	key := argon2.IDKey([]byte(plaintext), salt, params.Iterations, params.Memory, params.Parallelism, argon2KeyLength)
+
+	return fmt.Appendf(nil, "$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, params.Memory, params.Iterations, params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+func decodeArgon2id(hash []byte) (Argon2Params, []byte, []byte, error) {
+	var params Argon2Params
+	var version int
+
+	parts := strings.Split(string(hash), "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, errInvalidArgon2Hash
+	}
+
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return params, nil, nil, errInvalidArgon2Hash
+	}
+
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
+	if err != nil {
+		return params, nil, nil, errInvalidArgon2Hash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, errInvalidArgon2Hash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, errInvalidArgon2Hash
+	}
+
+	// argon2.IDKey panics without an iteration or a thread, and an empty key
+	// would match every password.
+	if params.Iterations < 1 || params.Parallelism < 1 || len(salt) == 0 || len(key) == 0 {
+		return params, nil, nil, errInvalidArgon2Hash
+	}
+
+	return params, salt, key, nil
+}
+
+func matchesArgon2id(plaintext string, hash []byte) (bool, error) {
+	params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return false, err
+	}
+
+	other := argon2.IDKey([]byte(plaintext), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+// Scheme returns the algorithm the password is hashed with.
+func (p *password) Scheme() string {
+	if bytes.HasPrefix(p.hash, []byte("$argon2id$")) {
+		return PasswordSchemeArgon2id
+	}
+	return PasswordSchemeBcrypt
+}
+
+// NeedsRehash reports whether the password isn't hashed with Argon2id at the
+// current costs, so should be hashed again the next time its plaintext is
+// known.
+func (p *password) NeedsRehash() bool {
+	if p.Scheme() != PasswordSchemeArgon2id {
+		return true
+	}
+
+	params, _, _, err := decodeArgon2id(p.hash)
+	return err != nil || params != PasswordArgon2
+}
@@ -0,0 +1,44 @@
+package data
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMatchesArgon2id(t *testing.T) {
+	params := Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1}
+	hash := hashArgon2id("correct horse battery staple", params)
+
+	match, err := matchesArgon2id("correct horse battery staple", hash)
+	if err != nil || !match {
+		t.Errorf("matchesArgon2id(correct) = %t, %v; want true, nil", match, err)
+	}
+
+	match, err = matchesArgon2id("wrong password", hash)
+	if err != nil || match {
+		t.Errorf("matchesArgon2id(wrong) = %t, %v; want false, nil", match, err)
+	}
+}
+
+func TestMatchesArgon2idRejectsInvalidHashes(t *testing.T) {
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{"no iterations", "$argon2id$v=19$m=64,t=0,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5"},
+		{"no threads", "$argon2id$v=19$m=64,t=1,p=0$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5"},
+		{"empty salt", "$argon2id$v=19$m=64,t=1,p=1$$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5"},
+		{"empty key", "$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$"},
+		{"wrong version", "$argon2id$v=16$m=64,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5"},
+		{"too few fields", "$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, err := matchesArgon2id("anything", []byte(tt.hash))
+			if match || !errors.Is(err, errInvalidArgon2Hash) {
+				t.Errorf("matchesArgon2id = %t, %v; want false, %v", match, err, errInvalidArgon2Hash)
+			}
+		})
+	}
+}
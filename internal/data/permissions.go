@@ -6,9 +6,14 @@ import (
 	"slices"
 	"time"
 
+	"flashcards-api.johndennehy101.tech/internal/validator"
 	"github.com/lib/pq"
 )
 
+// Roles bundle permissions: readers can study, editors can also write cards,
+// and admins can also change site-wide settings and other users' roles.
+var Roles = []string{"admin", "editor", "reader"}
+
 type Permissions []string
 
 func (p Permissions) Include(code string) bool {
@@ -19,15 +24,32 @@ type PermissionModel struct {
 	DB *sql.DB
 }
 
-func (m PermissionModel) GetAllForUser(userID int64) (Permissions, error) {
+// UserRole is a role held by a user.
+type UserRole struct {
+	Role      string    `json:"role"`
+	GrantedAt time.Time `json:"granted_at"`
+}
+
+func ValidateRole(v *validator.Validator, role string) {
+	v.Check(validator.PermittedValue(role, Roles...), "role", "must be admin, editor or reader")
+}
+
+// GetAllForUser returns the user's permissions, whether granted directly or
+// through one of their roles.
+func (m PermissionModel) GetAllForUser(ctx context.Context, userID int64) (Permissions, error) {
 	query := `
         SELECT permissions.code
         FROM permissions
         INNER JOIN users_permissions ON users_permissions.permission_id = permissions.id
-        INNER JOIN users ON users_permissions.user_id = users.id
-        WHERE users.id = $1`
+        WHERE users_permissions.user_id = $1
+        UNION
+        SELECT permissions.code
+        FROM permissions
+        INNER JOIN roles_permissions ON roles_permissions.permission_id = permissions.id
+        INNER JOIN users_roles ON users_roles.role_id = roles_permissions.role_id
+        WHERE users_roles.user_id = $1`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	rows, err := m.DB.QueryContext(ctx, query, userID)
@@ -55,14 +77,94 @@ func (m PermissionModel) GetAllForUser(userID int64) (Permissions, error) {
 	return permissions, nil
 }
 
-func (m PermissionModel) AddForUser(userID int64, codes ...string) error {
+func (m PermissionModel) AddForUser(ctx context.Context, userID int64, codes ...string) error {
 	query := `
         INSERT INTO users_permissions
         SELECT $1, permissions.id FROM permissions WHERE permissions.code = ANY($2)`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	_, err := m.DB.ExecContext(ctx, query, userID, pq.Array(codes))
 	return err
 }
+
+// GetRolesForUser returns the user's roles in the order they were granted.
+func (m PermissionModel) GetRolesForUser(ctx context.Context, userID int64) ([]*UserRole, error) {
+	query := `
+        SELECT roles.name, users_roles.granted_at
+        FROM users_roles
+        INNER JOIN roles ON roles.id = users_roles.role_id
+        WHERE users_roles.user_id = $1
+        ORDER BY users_roles.granted_at, roles.id`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []*UserRole{}
+
+	for rows.Next() {
+		var role UserRole
+
+		err := rows.Scan(&role.Role, &role.GrantedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		roles = append(roles, &role)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+// AddRoleForUser grants the role to the user. Granting a role the user
+// already holds does nothing.
+func (m PermissionModel) AddRoleForUser(ctx context.Context, userID int64, role string) error {
+	query := `
+        INSERT INTO users_roles (user_id, role_id)
+        SELECT $1, roles.id FROM roles WHERE roles.name = $2
+        ON CONFLICT (user_id, role_id) DO NOTHING`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID, role)
+	return err
+}
+
+// RemoveRoleForUser takes the role away from the user. Permissions granted
+// to the user directly are kept.
+func (m PermissionModel) RemoveRoleForUser(ctx context.Context, userID int64, role string) error {
+	query := `
+        DELETE FROM users_roles
+        USING roles
+        WHERE users_roles.role_id = roles.id AND users_roles.user_id = $1 AND roles.name = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, role)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
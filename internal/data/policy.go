@@ -0,0 +1,114 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/validator"
+	"github.com/lib/pq"
+)
+
+// PolicyFields are the optional flashcard fields a policy can make required.
+var PolicyFields = []string{"section", "section_type", "source_file", "categories", "difficulty", "hints"}
+
+// CardPolicy holds organisation-wide card standards: defaults filled into new
+// cards and fields every card must have.
+type CardPolicy struct {
+	DefaultCategories []string  `json:"default_categories"`
+	RequiredFields    []string  `json:"required_fields"`
+	Version           int32     `json:"version"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+func ValidateCardPolicy(v *validator.Validator, p *CardPolicy) {
+	v.Check(validator.Unique(p.DefaultCategories), "default_categories", "must not contain duplicate values")
+	v.Check(validator.Unique(p.RequiredFields), "required_fields", "must not contain duplicate values")
+
+	for _, field := range p.RequiredFields {
+		v.Check(validator.PermittedValue(field, PolicyFields...), "required_fields", "invalid field "+field)
+	}
+}
+
+// ApplyDefaults fills in defaults for fields left empty on a new card.
+func (p *CardPolicy) ApplyDefaults(f *Flashcard) {
+	if len(f.Categories) == 0 && len(p.DefaultCategories) > 0 {
+		f.Categories = slices.Clone(p.DefaultCategories)
+	}
+}
+
+// Check records a validation error for every required field the card lacks.
+func (p *CardPolicy) Check(v *validator.Validator, f *Flashcard) {
+	empty := func(s *string) bool {
+		return s == nil || *s == ""
+	}
+
+	for _, field := range p.RequiredFields {
+		switch field {
+		case "section":
+			v.Check(!empty(f.Section), field, "required by the card policy")
+		case "section_type":
+			v.Check(!empty(f.SectionType), field, "required by the card policy")
+		case "source_file":
+			v.Check(!empty(f.SourceFile), field, "required by the card policy")
+		case "categories":
+			v.Check(len(f.Categories) > 0, field, "required by the card policy")
+		case "difficulty":
+			v.Check(f.Difficulty != nil, field, "required by the card policy")
+		case "hints":
+			v.Check(len(f.Hints) > 0, field, "required by the card policy")
+		}
+	}
+}
+
+type PolicyModel struct {
+	DB *sql.DB
+}
+
+func (m PolicyModel) Get(ctx context.Context) (*CardPolicy, error) {
+	query := `
+        SELECT default_categories, required_fields, version, updated_at
+        FROM card_policy`
+
+	var p CardPolicy
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query).Scan(pq.Array(&p.DefaultCategories), pq.Array(&p.RequiredFields), &p.Version, &p.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return &CardPolicy{DefaultCategories: []string{}, RequiredFields: []string{}}, nil
+		default:
+			return nil, err
+		}
+	}
+
+	return &p, nil
+}
+
+func (m PolicyModel) Update(ctx context.Context, p *CardPolicy) error {
+	query := `
+        UPDATE card_policy
+        SET default_categories = $1, required_fields = $2, version = version + 1, updated_at = NOW()
+        WHERE version = $3
+        RETURNING version, updated_at`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, pq.Array(p.DefaultCategories), pq.Array(p.RequiredFields), p.Version).Scan(&p.Version, &p.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
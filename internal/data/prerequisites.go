@@ -0,0 +1,190 @@
+package data
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+)
+
+var (
+	ErrPrerequisiteCycle = errors.New("prerequisite would create a cycle")
+)
+
+// AddPrerequisite records that prerequisiteID must be studied before id. It
+// returns ErrPrerequisiteCycle if id is already, directly or transitively, a
+// prerequisite of prerequisiteID.
+func (m FlashcardModel) AddPrerequisite(ctx context.Context, id, prerequisiteID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        WITH RECURSIVE ancestors AS (
+            SELECT prerequisite_id FROM flashcard_prerequisites WHERE flashcard_id = $1
+            UNION
+            SELECT p.prerequisite_id
+            FROM flashcard_prerequisites p
+            INNER JOIN ancestors a ON p.flashcard_id = a.prerequisite_id
+        )
+        SELECT EXISTS (SELECT 1 FROM ancestors WHERE prerequisite_id = $2)`
+
+	var cycle bool
+
+	err = tx.QueryRowContext(ctx, query, prerequisiteID, id).Scan(&cycle)
+	if err != nil {
+		return err
+	}
+
+	if cycle {
+		return ErrPrerequisiteCycle
+	}
+
+	query = `
+        INSERT INTO flashcard_prerequisites (flashcard_id, prerequisite_id)
+        VALUES ($1, $2)
+        ON CONFLICT DO NOTHING`
+
+	_, err = tx.ExecContext(ctx, query, id, prerequisiteID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m FlashcardModel) DeletePrerequisite(ctx context.Context, id, prerequisiteID int64) error {
+	query := `DELETE FROM flashcard_prerequisites WHERE flashcard_id = $1 AND prerequisite_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, prerequisiteID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetStudyPath returns the flashcards in the category, together with every
+// prerequisite they transitively depend on, ordered so that each card comes
+// after all of its prerequisites. Ties are broken by id for a stable order.
+func (m FlashcardModel) GetStudyPath(ctx context.Context, userID int64, category string) ([]*Flashcard, error) {
+	query := `
+        WITH RECURSIVE path AS (
+            SELECT id FROM flashcards WHERE ($1 = '' OR $1 = ANY(categories))
+            UNION
+            SELECT p.prerequisite_id
+            FROM flashcard_prerequisites p
+            INNER JOIN path ON p.flashcard_id = path.id
+        )
+        SELECT p.flashcard_id, p.prerequisite_id
+        FROM flashcard_prerequisites p
+        WHERE p.flashcard_id IN (SELECT id FROM path)
+        UNION ALL
+        SELECT id, NULL FROM path`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, category)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	prerequisites := make(map[int64][]int64)
+
+	for rows.Next() {
+		var id int64
+		var prerequisiteID sql.NullInt64
+
+		err := rows.Scan(&id, &prerequisiteID)
+		if err != nil {
+			return nil, err
+		}
+
+		if prerequisiteID.Valid {
+			prerequisites[id] = append(prerequisites[id], prerequisiteID.Int64)
+		} else {
+			ids = append(ids, id)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	flashcards, err := m.GetByIDs(ctx, topologicalOrder(ids, prerequisites), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, flashcard := range flashcards {
+		flashcard.PrerequisiteIDs = prerequisites[flashcard.ID]
+		if flashcard.PrerequisiteIDs == nil {
+			flashcard.PrerequisiteIDs = []int64{}
+		}
+		slices.Sort(flashcard.PrerequisiteIDs)
+	}
+
+	return flashcards, nil
+}
+
+// topologicalOrder sorts ids with Kahn's algorithm, always taking the lowest
+// available id next.
+func topologicalOrder(ids []int64, prerequisites map[int64][]int64) []int64 {
+	remaining := make(map[int64]int, len(ids))
+	dependents := make(map[int64][]int64)
+
+	for _, id := range ids {
+		remaining[id] = len(prerequisites[id])
+		for _, p := range prerequisites[id] {
+			dependents[p] = append(dependents[p], id)
+		}
+	}
+
+	var ready []int64
+	for _, id := range ids {
+		if remaining[id] == 0 {
+			ready = append(ready, id)
+		}
+	}
+
+	order := make([]int64, 0, len(ids))
+
+	for len(ready) > 0 {
+		slices.SortFunc(ready, func(a, b int64) int { return cmp.Compare(b, a) })
+
+		id := ready[len(ready)-1]
+		ready = ready[:len(ready)-1]
+		order = append(order, id)
+
+		for _, d := range dependents[id] {
+			remaining[d]--
+			if remaining[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+
+	return order
+}
@@ -0,0 +1,135 @@
+package data
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/validator"
+)
+
+var ReportReasons = []string{"incorrect", "unclear", "outdated", "duplicate", "other"}
+
+type Report struct {
+	ID          int64     `json:"id"`
+	FlashcardID int64     `json:"flashcard_id"`
+	UserID      int64     `json:"-"`
+	Reason      string    `json:"reason"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func ValidateReport(v *validator.Validator, report *Report) {
+	v.Check(validator.PermittedValue(report.Reason, ReportReasons...), "reason", "must be one of "+strings.Join(ReportReasons, ", "))
+}
+
+// RecordReview logs the outcome of a single review, which feeds the card's
+// quality score. latencyMS is nil when the client didn't time the answer.
+func (m FlashcardModel) RecordReview(ctx context.Context, id, userID int64, correct bool, latencyMS *int) error {
+	query := `
+        INSERT INTO flashcard_reviews (flashcard_id, user_id, correct, latency_ms)
+        VALUES ($1, $2, $3, $4)`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, id, userID, correct, latencyMS)
+	return err
+}
+
+// InsertReport flags a card as needing attention. A user has at most one open
+// report per card; reporting again updates the reason.
+func (m FlashcardModel) InsertReport(ctx context.Context, report *Report) error {
+	query := `
+        INSERT INTO flashcard_reports (flashcard_id, user_id, reason)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (flashcard_id, user_id) WHERE resolved_at IS NULL
+        DO UPDATE SET reason = EXCLUDED.reason
+        RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, report.FlashcardID, report.UserID, report.Reason).Scan(&report.ID, &report.CreatedAt)
+}
+
+// RefreshQualityScores recomputes quality_score for every card from the last
+// 90 days of reviews and its open reports. The score runs from 0 to 100,
+// higher being better: the lapse rate weighs 60%, slow answers (averaging 30
+// seconds or more) 20% and reports (five or more) 20%. Cards with fewer than
+// five reviews and no reports are left unscored.
+func (m FlashcardModel) RefreshQualityScores(ctx context.Context) (int64, error) {
+	query := `
+        WITH reviews AS (
+            SELECT flashcard_id,
+                   COUNT(*) AS total,
+                   AVG(CASE WHEN correct THEN 0 ELSE 1 END) AS lapse_rate,
+                   AVG(latency_ms) AS latency_ms
+            FROM flashcard_reviews
+            WHERE reviewed_at > NOW() - INTERVAL '90 days'
+            GROUP BY flashcard_id
+        ), reports AS (
+            SELECT flashcard_id, COUNT(*) AS open
+            FROM flashcard_reports
+            WHERE resolved_at IS NULL
+            GROUP BY flashcard_id
+        ), scores AS (
+            SELECT f.id,
+                   CASE WHEN COALESCE(r.total, 0) < 5 AND COALESCE(p.open, 0) = 0 THEN NULL
+                   ELSE round((100 * (1
+                       - 0.6 * CASE WHEN COALESCE(r.total, 0) >= 5 THEN r.lapse_rate ELSE 0 END
+                       - 0.2 * LEAST(COALESCE(r.latency_ms, 0) / 30000.0, 1)
+                       - 0.2 * LEAST(COALESCE(p.open, 0) / 5.0, 1)))::numeric, 1)::real
+                   END AS score
+            FROM flashcards f
+            LEFT JOIN reviews r ON r.flashcard_id = f.id
+            LEFT JOIN reports p ON p.flashcard_id = f.id
+        )
+        UPDATE flashcards f
+        SET quality_score = s.score
+        FROM scores s
+        WHERE f.id = s.id AND f.quality_score IS DISTINCT FROM s.score`
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// RefreshMeasuredDifficulty recomputes measured_difficulty for every card as
+// the share of its reviews in the last 90 days that failed, across all users.
+// Cards with fewer than ten reviews are left unmeasured.
+func (m FlashcardModel) RefreshMeasuredDifficulty(ctx context.Context) (int64, error) {
+	query := `
+        WITH reviews AS (
+            SELECT flashcard_id, COUNT(*) AS total, AVG(CASE WHEN correct THEN 0 ELSE 1 END) AS failure_rate
+            FROM flashcard_reviews
+            WHERE reviewed_at > NOW() - INTERVAL '90 days'
+            GROUP BY flashcard_id
+        ), difficulties AS (
+            SELECT f.id,
+                   CASE WHEN COALESCE(r.total, 0) < 10 THEN NULL
+                   ELSE round(r.failure_rate::numeric, 3)::real
+                   END AS difficulty
+            FROM flashcards f
+            LEFT JOIN reviews r ON r.flashcard_id = f.id
+        )
+        UPDATE flashcards f
+        SET measured_difficulty = d.difficulty
+        FROM difficulties d
+        WHERE f.id = d.id AND f.measured_difficulty IS DISTINCT FROM d.difficulty`
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,137 @@
+package data
+
+import (
+	"context"
+	"time"
+)
+
+// RecentFlashcard is a card the user has recently viewed or studied.
+type RecentFlashcard struct {
+	ID       int64     `json:"id"`
+	Question string    `json:"question"`
+	Section  *string   `json:"section"`
+	SeenAt   time.Time `json:"seen_at"`
+}
+
+// RecentSection summarises the user's progress through a section they have
+// recently studied. ResumeID is the first card after the last one seen, in
+// id order, that the user hasn't mastered; it is nil once there is nothing
+// left to study in the section.
+type RecentSection struct {
+	Section         string    `json:"section"`
+	LastSeenAt      time.Time `json:"last_seen_at"`
+	LastFlashcardID int64     `json:"last_flashcard_id"`
+	ResumeID        *int64    `json:"resume_flashcard_id"`
+	Remaining       int       `json:"remaining"`
+	Total           int       `json:"total"`
+}
+
+// RecordView marks the card as seen by the user now.
+func (m FlashcardModel) RecordView(ctx context.Context, id, userID int64) error {
+	query := `
+        INSERT INTO flashcard_views (user_id, flashcard_id)
+        VALUES ($1, $2)
+        ON CONFLICT (user_id, flashcard_id) DO UPDATE SET seen_at = NOW()`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID, id)
+	return err
+}
+
+// GetRecentFlashcards returns the cards the user has seen most recently.
+func (m FlashcardModel) GetRecentFlashcards(ctx context.Context, userID int64, limit int) ([]*RecentFlashcard, error) {
+	query := `
+        SELECT f.id, f.question, f.section, fv.seen_at
+        FROM flashcard_views fv
+        INNER JOIN flashcards f ON f.id = fv.flashcard_id
+        WHERE fv.user_id = $1
+        ORDER BY fv.seen_at DESC, f.id DESC
+        LIMIT $2`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	flashcards := []*RecentFlashcard{}
+
+	for rows.Next() {
+		var flashcard RecentFlashcard
+
+		err := rows.Scan(&flashcard.ID, &flashcard.Question, &flashcard.Section, &flashcard.SeenAt)
+		if err != nil {
+			return nil, err
+		}
+
+		flashcards = append(flashcards, &flashcard)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return flashcards, nil
+}
+
+// GetRecentSections returns the sections the user has studied most recently
+// with the position to resume each one from.
+func (m FlashcardModel) GetRecentSections(ctx context.Context, userID int64, limit int) ([]*RecentSection, error) {
+	query := `
+        WITH last_seen AS (
+            SELECT DISTINCT ON (f.section) f.section, f.id, fv.seen_at
+            FROM flashcard_views fv
+            INNER JOIN flashcards f ON f.id = fv.flashcard_id
+            WHERE fv.user_id = $1 AND f.section IS NOT NULL
+            ORDER BY f.section, fv.seen_at DESC, f.id DESC
+        ),
+        recent AS (
+            SELECT * FROM last_seen ORDER BY seen_at DESC, id DESC LIMIT $2
+        )
+        SELECT r.section, r.seen_at, r.id,
+            (SELECT MIN(f.id)
+             FROM flashcards f
+             LEFT JOIN user_flashcards uf ON uf.flashcard_id = f.id AND uf.user_id = $1
+             WHERE f.section = r.section AND f.id > r.id
+             AND COALESCE(uf.status, '') != 'mastered'),
+            (SELECT COUNT(*)
+             FROM flashcards f
+             LEFT JOIN user_flashcards uf ON uf.flashcard_id = f.id AND uf.user_id = $1
+             WHERE f.section = r.section AND COALESCE(uf.status, '') != 'mastered'),
+            (SELECT COUNT(*) FROM flashcards f WHERE f.section = r.section)
+        FROM recent r
+        ORDER BY r.seen_at DESC, r.id DESC`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sections := []*RecentSection{}
+
+	for rows.Next() {
+		var section RecentSection
+
+		err := rows.Scan(&section.Section, &section.LastSeenAt, &section.LastFlashcardID, &section.ResumeID, &section.Remaining, &section.Total)
+		if err != nil {
+			return nil, err
+		}
+
+		sections = append(sections, &section)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sections, nil
+}
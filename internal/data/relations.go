@@ -0,0 +1,85 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// AddRelation links two flashcards. Relations are symmetric, so the pair is
+// stored once with the lower id first; adding an existing relation is a no-op.
+func (m FlashcardModel) AddRelation(ctx context.Context, id, relatedID int64) error {
+	query := `
+        INSERT INTO flashcard_relations (flashcard_id, related_id)
+        VALUES (LEAST($1::bigint, $2::bigint), GREATEST($1::bigint, $2::bigint))
+        ON CONFLICT DO NOTHING`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, id, relatedID)
+	return err
+}
+
+func (m FlashcardModel) DeleteRelation(ctx context.Context, id, relatedID int64) error {
+	query := `
+        DELETE FROM flashcard_relations
+        WHERE flashcard_id = LEAST($1::bigint, $2::bigint) AND related_id = GREATEST($1::bigint, $2::bigint)`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, relatedID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetRelatedIDs returns the ids related to each of the given flashcards.
+func (m FlashcardModel) GetRelatedIDs(ctx context.Context, ids []int64) (map[int64][]int64, error) {
+	query := `
+        SELECT flashcard_id, related_id FROM flashcard_relations WHERE flashcard_id = ANY($1)
+        UNION ALL
+        SELECT related_id, flashcard_id FROM flashcard_relations WHERE related_id = ANY($1)
+        ORDER BY 1, 2`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	related := make(map[int64][]int64)
+
+	for rows.Next() {
+		var id, relatedID int64
+
+		err := rows.Scan(&id, &relatedID)
+		if err != nil {
+			return nil, err
+		}
+
+		related[id] = append(related[id], relatedID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return related, nil
+}
@@ -0,0 +1,377 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/scheduler"
+	"flashcards-api.johndennehy101.tech/internal/validator"
+	"github.com/lib/pq"
+)
+
+// Review is one graded recall of a card and the schedule it produced. State
+// is the scheduling algorithm's own record of the card after the review, and
+// PreviousInterval the card's interval going into it, nil on its first
+// review.
+type Review struct {
+	ID               int64           `json:"id"`
+	FlashcardID      int64           `json:"flashcard_id"`
+	UserID           int64           `json:"-"`
+	Grade            int             `json:"grade"`
+	Algorithm        string          `json:"algorithm"`
+	State            json.RawMessage `json:"state"`
+	PreviousInterval *int            `json:"previous_interval_days"`
+	Interval         int             `json:"interval_days"`
+	DueAt            time.Time       `json:"due_at"`
+	LatencyMS        *int            `json:"latency_ms"`
+	ReviewedAt       time.Time       `json:"reviewed_at"`
+}
+
+// CardStates are the learning phases of a card for one user: new until its
+// first review, learning until it is recalled at an interval of more than a
+// day, then review. A card forgotten in review lapses into relearning until
+// it graduates again.
+var CardStates = []string{"new", "learning", "review", "relearning"}
+
+// CardState is a user's progress with a card, as kept in user_card_state.
+type CardState struct {
+	State  string `json:"state"`
+	Lapses int    `json:"lapses"`
+}
+
+// advance moves the card to its phase after a review with the given outcome
+// and new interval in days.
+func (c *CardState) advance(passed bool, interval int) {
+	switch {
+	case !passed && (c.State == "review" || c.State == "relearning"):
+		if c.State == "review" {
+			c.Lapses++
+		}
+		c.State = "relearning"
+	case !passed:
+		c.State = "learning"
+	case interval > 1:
+		c.State = "review"
+	case c.State == "new":
+		c.State = "learning"
+	}
+}
+
+type ReviewModel struct {
+	DB         *sql.DB
+	Schedulers scheduler.Set
+}
+
+func ValidateGrade(v *validator.Validator, grade int) {
+	v.Check(grade >= scheduler.MinGrade && grade <= scheduler.MaxGrade, "grade", "must be between 0 and 5")
+}
+
+// ValidateScheduler checks a deck or user choice of scheduling algorithm,
+// where empty means the default.
+func ValidateScheduler(v *validator.Validator, name string) {
+	v.Check(name == "" || validator.PermittedValue(name, scheduler.Algorithms...), "scheduler", "must be one of "+strings.Join(scheduler.Algorithms, ", "))
+}
+
+// schedulerSource joins each card in ids to the deck that picks its algorithm
+// or tunes it for user $1: one the user owns, else one they are a member
+// of. schedulerColumns then selects the algorithm for the card, falling back
+// to the user's own choice and then to $3, the deck's config and the card's
+// measured difficulty.
+const (
+	schedulerSource = `
+        LEFT JOIN LATERAL (
+            SELECT d.scheduler, d.scheduler_config
+            FROM deck_flashcards df
+            INNER JOIN decks d ON d.id = df.deck_id
+            WHERE df.flashcard_id = ids.id AND (d.scheduler IS NOT NULL OR d.scheduler_config IS NOT NULL)
+            AND (d.user_id = $1 OR EXISTS (SELECT 1 FROM deck_members dm WHERE dm.deck_id = d.id AND dm.user_id = $1))
+            ORDER BY d.user_id = $1 DESC, d.id
+            LIMIT 1
+        ) ds ON TRUE`
+
+	schedulerColumns = `COALESCE(ds.scheduler, (SELECT NULLIF(scheduler, '') FROM users WHERE id = $1), $3), ds.scheduler_config,
+        (SELECT measured_difficulty FROM flashcards WHERE id = ids.id)`
+)
+
+// scheduler returns the named algorithm tuned with a deck's config.
+func (m ReviewModel) scheduler(name string, config *SchedulerConfig) (scheduler.Scheduler, error) {
+	var cfg scheduler.Config
+	if config != nil {
+		cfg = config.Config
+	}
+
+	algorithm, ok := m.Schedulers.Get(name, cfg)
+	if !ok {
+		return nil, fmt.Errorf("unknown scheduler %q", name)
+	}
+
+	return algorithm, nil
+}
+
+// SchedulerConfig is a deck's tuning of the algorithm for its cards, stored
+// as jsonb.
+type SchedulerConfig struct {
+	scheduler.Config
+}
+
+func (c SchedulerConfig) Value() (driver.Value, error) {
+	return json.Marshal(c.Config)
+}
+
+func (c *SchedulerConfig) Scan(src any) error {
+	b, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("unsupported scheduler config type %T", src)
+	}
+	return json.Unmarshal(b, &c.Config)
+}
+
+func ValidateSchedulerConfig(v *validator.Validator, c *SchedulerConfig) {
+	v.Check(c.GraduatingInterval >= 0 && c.GraduatingInterval <= 365, "scheduler_config.graduating_interval", "must be between 1 and 365")
+	v.Check(c.EasyBonus == 0 || (c.EasyBonus >= 1 && c.EasyBonus <= 5), "scheduler_config.easy_bonus", "must be between 1 and 5")
+	v.Check(c.MaxInterval >= 0 && c.MaxInterval <= 36500, "scheduler_config.maximum_interval", "must be between 1 and 36500")
+	v.Check(c.LapseInterval >= 0 && c.LapseInterval <= 1, "scheduler_config.lapse_interval", "must be between 0 and 1")
+	v.Check(c.MaxInterval == 0 || c.GraduatingInterval <= c.MaxInterval, "scheduler_config.graduating_interval", "must not be more than maximum_interval")
+}
+
+// Insert records the review and reschedules the card for its user. The card's
+// schedule row is locked while the next interval is worked out so concurrent
+// reviews of the same card are applied one after the other.
+//
+// The algorithm is the one picked by a deck holding the card that the user
+// owns or is a member of, then the user's own choice, then the default, with
+// that deck's config. A card whose algorithm has changed since its last
+// review starts afresh under the new one, like a new card: from its measured
+// difficulty when the algorithm can use it.
+func (m ReviewModel) Insert(ctx context.Context, review *Review) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + schedulerColumns + ` FROM (SELECT $2::bigint AS id) ids` + schedulerSource
+
+	var config *SchedulerConfig
+	var difficulty *float32
+
+	err = tx.QueryRowContext(ctx, query, review.UserID, review.FlashcardID, scheduler.Default).Scan(&review.Algorithm, &config, &difficulty)
+	if err != nil {
+		return err
+	}
+
+	algorithm, err := m.scheduler(review.Algorithm, config)
+	if err != nil {
+		return err
+	}
+
+	query = `
+        SELECT algorithm, scheduler_state, interval_days, state, lapses
+        FROM user_card_state
+        WHERE user_id = $1 AND flashcard_id = $2
+        FOR UPDATE`
+
+	var current string
+	var state []byte
+	card := CardState{State: "new"}
+
+	err = tx.QueryRowContext(ctx, query, review.UserID, review.FlashcardID).Scan(&current, &state, &review.PreviousInterval, &card.State, &card.Lapses)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	if current != review.Algorithm || state == nil {
+		state, err = scheduler.Initial(algorithm, difficulty)
+		if err != nil {
+			return err
+		}
+	}
+
+	review.ReviewedAt = time.Now()
+
+	result, err := algorithm.Review(state, review.Grade, review.ReviewedAt)
+	if err != nil {
+		return err
+	}
+
+	review.State = result.State
+	review.Interval = result.Interval
+	review.DueAt = result.Due
+
+	card.advance(review.Grade >= scheduler.PassingGrade, review.Interval)
+
+	// The ease is copied out of algorithms that keep one, for queries that
+	// shouldn't have to look inside scheduler_state.
+	query = `
+        INSERT INTO user_card_state (user_id, flashcard_id, algorithm, scheduler_state, interval_days, due_at, last_reviewed_at, state, lapses, ease)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, ($4::jsonb->>'ease_factor')::real)
+        ON CONFLICT (user_id, flashcard_id) DO UPDATE
+        SET algorithm = EXCLUDED.algorithm, scheduler_state = EXCLUDED.scheduler_state, interval_days = EXCLUDED.interval_days,
+            due_at = EXCLUDED.due_at, last_reviewed_at = EXCLUDED.last_reviewed_at, state = EXCLUDED.state,
+            lapses = EXCLUDED.lapses, ease = EXCLUDED.ease`
+
+	args := []any{review.UserID, review.FlashcardID, review.Algorithm, []byte(review.State), review.Interval, review.DueAt, review.ReviewedAt, card.State, card.Lapses}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	query = `
+        INSERT INTO card_reviews (user_id, flashcard_id, grade, algorithm, state, previous_interval_days, interval_days, due_at, latency_ms, reviewed_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+        RETURNING id`
+
+	args = []any{review.UserID, review.FlashcardID, review.Grade, review.Algorithm, []byte(review.State), review.PreviousInterval, review.Interval, review.DueAt, review.LatencyMS, review.ReviewedAt}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&review.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetAll returns a page of the user's review log, optionally only for one
+// card (when flashcardID isn't 0) and within [from, to).
+func (m ReviewModel) GetAll(ctx context.Context, userID, flashcardID int64, from, to *time.Time, filters Filters) ([]*Review, Metadata, error) {
+	query := fmt.Sprintf(`
+        SELECT count(*) OVER(), r.id, r.flashcard_id, r.grade, r.algorithm, r.state, r.previous_interval_days,
+            r.interval_days, r.due_at, r.latency_ms, r.reviewed_at
+        FROM card_reviews r
+        WHERE r.user_id = $1
+        AND ($2 = 0 OR r.flashcard_id = $2)
+        AND ($3::timestamptz IS NULL OR r.reviewed_at >= $3)
+        AND ($4::timestamptz IS NULL OR r.reviewed_at < $4)
+        ORDER BY %s
+        LIMIT $5 OFFSET $6`, filters.orderBy("r.id"))
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, flashcardID, from, to, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	reviews := []*Review{}
+
+	for rows.Next() {
+		var review Review
+		var state []byte
+
+		err := rows.Scan(
+			&totalRecords,
+			&review.ID,
+			&review.FlashcardID,
+			&review.Grade,
+			&review.Algorithm,
+			&state,
+			&review.PreviousInterval,
+			&review.Interval,
+			&review.DueAt,
+			&review.LatencyMS,
+			&review.ReviewedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		review.UserID = userID
+		review.State = state
+
+		reviews = append(reviews, &review)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return reviews, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// Project sets the intervals each rating would give the user's cards if they
+// were reviewed at now, under the algorithm that would schedule them, on the
+// cards' Schedule.
+func (m ReviewModel) Project(ctx context.Context, userID int64, flashcards []*Flashcard, now time.Time) error {
+	ids := make([]int64, 0, len(flashcards))
+	for _, flashcard := range flashcards {
+		if flashcard.Schedule != nil {
+			ids = append(ids, flashcard.ID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := `
+        SELECT ids.id, ` + schedulerColumns + `, s.algorithm, s.scheduler_state
+        FROM unnest($2::bigint[]) AS ids(id)` + schedulerSource + `
+        LEFT JOIN user_card_state s ON s.user_id = $1 AND s.flashcard_id = ids.id`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, pq.Array(ids), scheduler.Default)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	projected := make(map[int64]map[string]int, len(ids))
+
+	for rows.Next() {
+		var id int64
+		var name string
+		var config *SchedulerConfig
+		var difficulty *float32
+		var current *string
+		var state []byte
+
+		err := rows.Scan(&id, &name, &config, &difficulty, &current, &state)
+		if err != nil {
+			return err
+		}
+
+		algorithm, err := m.scheduler(name, config)
+		if err != nil {
+			return err
+		}
+
+		if current == nil || *current != name || state == nil {
+			state, err = scheduler.Initial(algorithm, difficulty)
+			if err != nil {
+				return err
+			}
+		}
+
+		projected[id], err = scheduler.Project(algorithm, state, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, flashcard := range flashcards {
+		if flashcard.Schedule != nil {
+			flashcard.Schedule.Projected = projected[flashcard.ID]
+		}
+	}
+
+	return nil
+}
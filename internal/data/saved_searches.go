@@ -0,0 +1,277 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/validator"
+)
+
+// SavedSearch is a named flashcard listing query. Query holds the query
+// string of GET /v1/flashcards, so a saved search always reflects the
+// current card bank. Shared searches are visible to every user.
+type SavedSearch struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"-"`
+	Name       string    `json:"name"`
+	Query      string    `json:"query"`
+	Shared     bool      `json:"shared"`
+	Owned      bool      `json:"owned"`
+	Subscribed bool      `json:"subscribed"`
+	Version    int32     `json:"version"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	Links map[string]Link `json:"_links,omitempty"`
+}
+
+func ValidateSavedSearch(v *validator.Validator, s *SavedSearch) {
+	v.Check(s.Name != "", "name", "must be provided")
+	v.Check(len(s.Name) <= 100, "name", "must not be more than 100 bytes long")
+	v.Check(len(s.Query) <= 2000, "query", "must not be more than 2000 bytes long")
+
+	_, err := url.ParseQuery(s.Query)
+	v.Check(err == nil, "query", "must be a valid query string")
+}
+
+// SearchSubscription is a user's request to be told about new cards matching
+// a saved search.
+type SearchSubscription struct {
+	Search         SavedSearch
+	UserID         int64
+	Email          string
+	LastNotifiedAt time.Time
+}
+
+type SavedSearchModel struct {
+	DB *sql.DB
+}
+
+func (m SavedSearchModel) Insert(ctx context.Context, s *SavedSearch) error {
+	query := `
+        INSERT INTO saved_searches (user_id, name, query, shared)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, version, created_at`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	s.Owned = true
+
+	return m.DB.QueryRowContext(ctx, query, s.UserID, s.Name, s.Query, s.Shared).Scan(&s.ID, &s.Version, &s.CreatedAt)
+}
+
+const savedSearchColumns = `
+            s.id, s.user_id, s.name, s.query, s.shared, s.user_id = $1,
+            EXISTS (SELECT 1 FROM saved_search_subscriptions ss WHERE ss.saved_search_id = s.id AND ss.user_id = $1),
+            s.version, s.created_at`
+
+func (s *SavedSearch) scanTargets() []any {
+	return []any{&s.ID, &s.UserID, &s.Name, &s.Query, &s.Shared, &s.Owned, &s.Subscribed, &s.Version, &s.CreatedAt}
+}
+
+// Get returns a saved search the user owns or that has been shared.
+func (m SavedSearchModel) Get(ctx context.Context, id, userID int64) (*SavedSearch, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+        SELECT ` + savedSearchColumns + `
+        FROM saved_searches s
+        WHERE s.id = $2 AND (s.user_id = $1 OR s.shared)`
+
+	var s SavedSearch
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, userID, id).Scan(s.scanTargets()...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &s, nil
+}
+
+// GetAllForUser returns the user's own saved searches followed by those
+// shared by others.
+func (m SavedSearchModel) GetAllForUser(ctx context.Context, userID int64) ([]*SavedSearch, error) {
+	query := `
+        SELECT ` + savedSearchColumns + `
+        FROM saved_searches s
+        WHERE s.user_id = $1 OR s.shared
+        ORDER BY s.user_id <> $1, s.name, s.id`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	searches := []*SavedSearch{}
+
+	for rows.Next() {
+		var s SavedSearch
+
+		err := rows.Scan(s.scanTargets()...)
+		if err != nil {
+			return nil, err
+		}
+
+		searches = append(searches, &s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return searches, nil
+}
+
+func (m SavedSearchModel) Update(ctx context.Context, s *SavedSearch) error {
+	query := `
+        UPDATE saved_searches
+        SET name = $1, query = $2, shared = $3, version = version + 1
+        WHERE id = $4 AND user_id = $5 AND version = $6
+        RETURNING version`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, s.Name, s.Query, s.Shared, s.ID, s.UserID, s.Version).Scan(&s.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m SavedSearchModel) Delete(ctx context.Context, id, userID int64) error {
+	query := `DELETE FROM saved_searches WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Subscribe starts notifications for cards created from now on. Subscribing
+// again is a no-op.
+func (m SavedSearchModel) Subscribe(ctx context.Context, id, userID int64) error {
+	query := `
+        INSERT INTO saved_search_subscriptions (saved_search_id, user_id)
+        VALUES ($1, $2)
+        ON CONFLICT DO NOTHING`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, id, userID)
+	return err
+}
+
+func (m SavedSearchModel) Unsubscribe(ctx context.Context, id, userID int64) error {
+	query := `DELETE FROM saved_search_subscriptions WHERE saved_search_id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetSubscriptions returns every subscription whose search the subscriber
+// can still see, with the subscriber's email address.
+func (m SavedSearchModel) GetSubscriptions(ctx context.Context) ([]*SearchSubscription, error) {
+	query := `
+        SELECT s.id, s.name, s.query, ss.user_id, u.email, ss.last_notified_at
+        FROM saved_search_subscriptions ss
+        INNER JOIN saved_searches s ON s.id = ss.saved_search_id
+        INNER JOIN users u ON u.id = ss.user_id
+        WHERE (s.user_id = ss.user_id OR s.shared) AND u.activated
+        ORDER BY s.id, ss.user_id`
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := []*SearchSubscription{}
+
+	for rows.Next() {
+		var sub SearchSubscription
+
+		err := rows.Scan(&sub.Search.ID, &sub.Search.Name, &sub.Search.Query, &sub.UserID, &sub.Email, &sub.LastNotifiedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		subscriptions = append(subscriptions, &sub)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+func (m SavedSearchModel) MarkNotified(ctx context.Context, id, userID int64, notifiedAt time.Time) error {
+	query := `
+        UPDATE saved_search_subscriptions
+        SET last_notified_at = $3
+        WHERE saved_search_id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, id, userID, notifiedAt)
+	return err
+}
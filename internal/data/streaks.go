@@ -0,0 +1,112 @@
+package data
+
+import (
+	"context"
+	"time"
+)
+
+// DailyGoalUnits are what a daily goal can be counted in: cards reviewed, or
+// minutes spent answering them as timed by the client.
+var DailyGoalUnits = []string{"cards", "minutes"}
+
+// Streak is the user's run of consecutive days meeting their daily goal.
+// Today not being met yet doesn't break the current streak until the day is
+// over.
+type Streak struct {
+	Current     int        `json:"current"`
+	Longest     int        `json:"longest"`
+	LastGoalMet *string    `json:"last_goal_met"`
+	Today       StudyToday `json:"today"`
+}
+
+// StudyToday is the progress towards today's goal.
+type StudyToday struct {
+	Cards   int     `json:"cards"`
+	Minutes float64 `json:"minutes"`
+	Goal    int     `json:"goal"`
+	Unit    string  `json:"unit"`
+	Met     bool    `json:"met"`
+}
+
+// GetStreak works the user's streak out from their review log, with days
+// starting at midnight in loc.
+func (m ReviewModel) GetStreak(ctx context.Context, user *User, now time.Time, loc *time.Location) (*Streak, error) {
+	query := `
+        SELECT to_char(reviewed_at AT TIME ZONE $2, 'YYYY-MM-DD') AS day, count(*), COALESCE(sum(latency_ms), 0)
+        FROM card_reviews
+        WHERE user_id = $1
+        GROUP BY day
+        ORDER BY day`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, user.ID, loc.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	today := now.In(loc).Format(time.DateOnly)
+
+	streak := &Streak{
+		Today: StudyToday{Goal: user.DailyGoal, Unit: user.DailyGoalUnit},
+	}
+
+	var run int
+	var previous time.Time
+
+	for rows.Next() {
+		var day string
+		var cards int
+		var latencyMS int64
+
+		err := rows.Scan(&day, &cards, &latencyMS)
+		if err != nil {
+			return nil, err
+		}
+
+		minutes := float64(latencyMS) / float64(time.Minute/time.Millisecond)
+
+		if day == today {
+			streak.Today.Cards = cards
+			streak.Today.Minutes = minutes
+		}
+
+		met := cards >= user.DailyGoal
+		if user.DailyGoalUnit == "minutes" {
+			met = minutes >= float64(user.DailyGoal)
+		}
+		if !met {
+			continue
+		}
+
+		date, err := time.Parse(time.DateOnly, day)
+		if err != nil {
+			return nil, err
+		}
+
+		if !previous.IsZero() && date.Equal(previous.AddDate(0, 0, 1)) {
+			run++
+		} else {
+			run = 1
+		}
+
+		previous = date
+		streak.Longest = max(streak.Longest, run)
+		streak.LastGoalMet = &day
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	todayDate, _ := time.Parse(time.DateOnly, today)
+	streak.Today.Met = previous.Equal(todayDate)
+
+	if streak.Today.Met || previous.Equal(todayDate.AddDate(0, 0, -1)) {
+		streak.Current = run
+	}
+
+	return streak, nil
+}
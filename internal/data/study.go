@@ -0,0 +1,265 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// CardSchedule places a card in the study queue: "new" for cards the user
+// has never reviewed, "review" for scheduled cards. Projected holds the
+// interval in days each rating would give the card, when worked out.
+type CardSchedule struct {
+	Queue     string         `json:"queue"`
+	DueAt     *time.Time     `json:"due_at"`
+	Interval  int            `json:"interval_days"`
+	Projected map[string]int `json:"projected_intervals,omitempty"`
+	CardState
+}
+
+// StudyCounts counts new and review cards.
+type StudyCounts struct {
+	New    int `json:"new"`
+	Review int `json:"review"`
+}
+
+// GetQueue returns the user's study queue of cards matching the filter: up
+// to reviewLimit review cards, most overdue relative to their interval first,
+// followed by up to newLimit new cards in deck order, or in order of creation
+// outside a deck. Cards the user has suspended, or buried until after now,
+// are left out. With dueOnly, review cards are those due at now; otherwise
+// every scheduled card qualifies, the ones due furthest ahead last. It also
+// counts all the cards that qualify, ignoring the limits.
+func (m FlashcardModel) GetQueue(ctx context.Context, userID int64, filter FlashcardFilter, newLimit, reviewLimit int, now time.Time, dueOnly bool) ([]*Flashcard, StudyCounts, error) {
+	where, args := filter.where(userID)
+
+	join, position := "", "NULL::integer"
+	if filter.DeckID != 0 {
+		join = fmt.Sprintf(`INNER JOIN deck_flashcards df ON df.flashcard_id = f.id AND df.deck_id = $%d`, len(args))
+		position = "df.position"
+	}
+
+	args = append(args, now)
+
+	held := fmt.Sprintf(`(cs.suspended OR cs.buried_until > $%d)`, len(args))
+
+	due := "TRUE"
+	if dueOnly {
+		due = fmt.Sprintf(`(cs.due_at IS NULL OR cs.due_at <= $%d)`, len(args))
+	}
+
+	queue := fmt.Sprintf(`
+        WITH queue AS (
+            SELECT f.id, cs.due_at, COALESCE(cs.interval_days, 0) AS interval_days,
+                COALESCE(cs.state, 'new') AS state, COALESCE(cs.lapses, 0) AS lapses, %s AS position
+            FROM flashcards f
+            LEFT JOIN user_flashcards uf ON f.id = uf.flashcard_id AND uf.user_id = $1
+            LEFT JOIN user_card_state cs ON cs.flashcard_id = f.id AND cs.user_id = $1
+            %s
+            WHERE %s
+            AND %s
+            AND NOT COALESCE(%s, FALSE)
+        )`, position, join, where, due, held)
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var counts StudyCounts
+
+	query := queue + `
+        SELECT count(*) FILTER (WHERE due_at IS NULL), count(*) FILTER (WHERE due_at IS NOT NULL)
+        FROM queue`
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&counts.New, &counts.Review)
+	if err != nil {
+		return nil, StudyCounts{}, err
+	}
+
+	args = append(args, reviewLimit, newLimit)
+
+	query = queue + fmt.Sprintf(`
+        (SELECT id, due_at, interval_days, state, lapses FROM queue
+         WHERE due_at IS NOT NULL
+         ORDER BY EXTRACT(EPOCH FROM $%d - due_at) / GREATEST(interval_days, 1) DESC, due_at, id
+         LIMIT $%d)
+        UNION ALL
+        (SELECT id, NULL, 0, state, lapses FROM queue
+         WHERE due_at IS NULL
+         ORDER BY position, id
+         LIMIT $%d)`, len(args)-2, len(args)-1, len(args))
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, StudyCounts{}, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	schedules := make(map[int64]*CardSchedule)
+
+	for rows.Next() {
+		var id int64
+		schedule := &CardSchedule{Queue: "review"}
+
+		err := rows.Scan(&id, &schedule.DueAt, &schedule.Interval, &schedule.State, &schedule.Lapses)
+		if err != nil {
+			return nil, StudyCounts{}, err
+		}
+
+		if schedule.DueAt == nil {
+			schedule.Queue = "new"
+		}
+
+		ids = append(ids, id)
+		schedules[id] = schedule
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, StudyCounts{}, err
+	}
+
+	flashcards, err := m.GetByIDs(ctx, ids, userID)
+	if err != nil {
+		return nil, StudyCounts{}, err
+	}
+
+	for _, flashcard := range flashcards {
+		flashcard.Schedule = schedules[flashcard.ID]
+	}
+
+	return flashcards, counts, nil
+}
+
+// ForecastDay counts the review cards due on a day in the user's time zone.
+type ForecastDay struct {
+	Date  string `json:"date"`
+	Due   int    `json:"due"`
+	Total int    `json:"running_total"`
+}
+
+// Forecast counts the user's review cards falling due on each of the days
+// from now, with cards already overdue counted on the first. Suspended cards
+// are left out; buried ones count on the day they are due regardless.
+func (m ReviewModel) Forecast(ctx context.Context, userID int64, now time.Time, days int, loc *time.Location) ([]ForecastDay, error) {
+	year, month, day := now.In(loc).Date()
+	start := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	end := start.AddDate(0, 0, days)
+
+	query := `
+        SELECT to_char(GREATEST(due_at, $2) AT TIME ZONE $4, 'YYYY-MM-DD') AS day, count(*)
+        FROM user_card_state
+        WHERE user_id = $1 AND due_at < $3 AND NOT suspended
+        GROUP BY day`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, now, end, loc.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	due := make(map[string]int)
+
+	for rows.Next() {
+		var date string
+		var count int
+
+		err := rows.Scan(&date, &count)
+		if err != nil {
+			return nil, err
+		}
+
+		due[date] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	forecast := make([]ForecastDay, days)
+	total := 0
+
+	for i := range forecast {
+		date := start.AddDate(0, 0, i).Format(time.DateOnly)
+		total += due[date]
+		forecast[i] = ForecastDay{Date: date, Due: due[date], Total: total}
+	}
+
+	return forecast, nil
+}
+
+// CardHold is a user's hold on a card's scheduling: suspended cards are
+// never queued for study, and buried ones not until BuriedUntil.
+type CardHold struct {
+	FlashcardID int64      `json:"flashcard_id"`
+	Suspended   bool       `json:"suspended"`
+	BuriedUntil *time.Time `json:"buried_until"`
+}
+
+// SetSuspended suspends or unsuspends the card for the user. Cards that have
+// never been reviewed can be suspended too; they keep their place among the
+// new cards once unsuspended.
+func (m ReviewModel) SetSuspended(ctx context.Context, userID, flashcardID int64, suspended bool) (*CardHold, error) {
+	query := `
+        INSERT INTO user_card_state (user_id, flashcard_id, suspended)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (user_id, flashcard_id) DO UPDATE
+        SET suspended = EXCLUDED.suspended
+        RETURNING flashcard_id, suspended, buried_until`
+
+	return m.setHold(ctx, query, userID, flashcardID, suspended)
+}
+
+// SetBuriedUntil buries the card for the user until the given time, or
+// unburies it when until is nil.
+func (m ReviewModel) SetBuriedUntil(ctx context.Context, userID, flashcardID int64, until *time.Time) (*CardHold, error) {
+	query := `
+        INSERT INTO user_card_state (user_id, flashcard_id, buried_until)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (user_id, flashcard_id) DO UPDATE
+        SET buried_until = EXCLUDED.buried_until
+        RETURNING flashcard_id, suspended, buried_until`
+
+	return m.setHold(ctx, query, userID, flashcardID, until)
+}
+
+func (m ReviewModel) setHold(ctx context.Context, query string, args ...any) (*CardHold, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var hold CardHold
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&hold.FlashcardID, &hold.Suspended, &hold.BuriedUntil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hold, nil
+}
+
+// CountSince counts the user's reviews since the given time: new are the
+// cards reviewed for the first time, and review the other reviews.
+func (m ReviewModel) CountSince(ctx context.Context, userID int64, since time.Time) (StudyCounts, error) {
+	query := `
+        SELECT
+            count(DISTINCT r.flashcard_id) FILTER (WHERE NOT seen_before),
+            count(*) FILTER (WHERE seen_before)
+        FROM (
+            SELECT r.flashcard_id, EXISTS (
+                SELECT 1 FROM card_reviews p
+                WHERE p.user_id = r.user_id AND p.flashcard_id = r.flashcard_id AND p.reviewed_at < $2
+            ) AS seen_before
+            FROM card_reviews r
+            WHERE r.user_id = $1 AND r.reviewed_at >= $2
+        ) r`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var counts StudyCounts
+
+	err := m.DB.QueryRowContext(ctx, query, userID, since).Scan(&counts.New, &counts.Review)
+	return counts, err
+}
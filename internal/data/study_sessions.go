@@ -0,0 +1,281 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	ErrSessionCompleted = errors.New("study session already completed")
+)
+
+// StudySession groups the answers given in one sitting. Sessions are kept on
+// the server so a client can pick one up again after a restart.
+type StudySession struct {
+	ID          int64            `json:"id"`
+	UserID      int64            `json:"-"`
+	DeckID      *int64           `json:"deck_id"`
+	StartedAt   time.Time        `json:"started_at"`
+	CompletedAt *time.Time       `json:"completed_at"`
+	Answers     []*SessionAnswer `json:"answers,omitempty"`
+}
+
+// SessionAnswer is one attempt at a card. Response is what the user
+// answered, nil when they assessed themselves without submitting one. Graded
+// is set when the server marked the response rather than the user.
+type SessionAnswer struct {
+	ID          int64           `json:"id"`
+	FlashcardID int64           `json:"flashcard_id"`
+	Response    json.RawMessage `json:"response"`
+	Correct     bool            `json:"correct"`
+	Graded      bool            `json:"graded"`
+	LatencyMS   *int            `json:"latency_ms"`
+	AnsweredAt  time.Time       `json:"answered_at"`
+}
+
+type SessionSummary struct {
+	Answered  int      `json:"answered"`
+	Correct   int      `json:"correct"`
+	Incorrect int      `json:"incorrect"`
+	Cards     int      `json:"cards"`
+	Accuracy  *float64 `json:"accuracy"`
+
+	// Nil when no answer was timed
+	AverageLatencyMS *float64 `json:"average_latency_ms"`
+
+	// From the start of the session to its completion, or to now while it
+	// is still open
+	DurationSeconds int64 `json:"duration_seconds"`
+}
+
+type StudySessionModel struct {
+	DB *sql.DB
+}
+
+func (m StudySessionModel) Insert(ctx context.Context, s *StudySession) error {
+	query := `
+        INSERT INTO study_sessions (user_id, deck_id)
+        VALUES ($1, $2)
+        RETURNING id, started_at`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, s.UserID, s.DeckID).Scan(&s.ID, &s.StartedAt)
+}
+
+// Get returns one of the user's sessions with its answers in the order they
+// were given.
+func (m StudySessionModel) Get(ctx context.Context, id, userID int64) (*StudySession, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+        SELECT id, user_id, deck_id, started_at, completed_at
+        FROM study_sessions
+        WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var s StudySession
+
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(&s.ID, &s.UserID, &s.DeckID, &s.StartedAt, &s.CompletedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	query = `
+        SELECT id, flashcard_id, response, correct, graded, latency_ms, answered_at
+        FROM study_session_answers
+        WHERE session_id = $1
+        ORDER BY id`
+
+	rows, err := m.DB.QueryContext(ctx, query, s.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	s.Answers = []*SessionAnswer{}
+
+	for rows.Next() {
+		var a SessionAnswer
+		var response []byte
+
+		err := rows.Scan(&a.ID, &a.FlashcardID, &response, &a.Correct, &a.Graded, &a.LatencyMS, &a.AnsweredAt)
+		if err != nil {
+			return nil, err
+		}
+
+		a.Response = response
+		s.Answers = append(s.Answers, &a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// InsertAnswer adds an answer to one of the user's open sessions.
+func (m StudySessionModel) InsertAnswer(ctx context.Context, sessionID, userID int64, a *SessionAnswer) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = m.checkOpen(ctx, tx, sessionID, userID)
+	if err != nil {
+		return err
+	}
+
+	var response any
+	if a.Response != nil {
+		response = []byte(a.Response)
+	}
+
+	query := `
+        INSERT INTO study_session_answers (session_id, flashcard_id, response, correct, graded, latency_ms)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id, answered_at`
+
+	err = tx.QueryRowContext(ctx, query, sessionID, a.FlashcardID, response, a.Correct, a.Graded, a.LatencyMS).Scan(&a.ID, &a.AnsweredAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Complete closes one of the user's open sessions. No more answers can be
+// added to it afterwards.
+func (m StudySessionModel) Complete(ctx context.Context, s *StudySession) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = m.checkOpen(ctx, tx, s.ID, s.UserID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        UPDATE study_sessions
+        SET completed_at = NOW()
+        WHERE id = $1
+        RETURNING completed_at`
+
+	err = tx.QueryRowContext(ctx, query, s.ID).Scan(&s.CompletedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// checkOpen locks the session row so answers can't be added while it is
+// being completed.
+func (m StudySessionModel) checkOpen(ctx context.Context, tx *sql.Tx, id, userID int64) error {
+	query := `
+        SELECT completed_at IS NOT NULL
+        FROM study_sessions
+        WHERE id = $1 AND user_id = $2
+        FOR UPDATE`
+
+	var completed bool
+
+	err := tx.QueryRowContext(ctx, query, id, userID).Scan(&completed)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	if completed {
+		return ErrSessionCompleted
+	}
+
+	return nil
+}
+
+// Summarize totals up the session's answers.
+func (s *StudySession) Summarize() SessionSummary {
+	var summary SessionSummary
+
+	cards := make(map[int64]bool)
+	latency, timed := 0, 0
+
+	for _, a := range s.Answers {
+		summary.Answered++
+		if a.Correct {
+			summary.Correct++
+		}
+		cards[a.FlashcardID] = true
+
+		if a.LatencyMS != nil {
+			latency += *a.LatencyMS
+			timed++
+		}
+	}
+
+	summary.Incorrect = summary.Answered - summary.Correct
+	summary.Cards = len(cards)
+
+	if summary.Answered > 0 {
+		accuracy := float64(summary.Correct) / float64(summary.Answered)
+		summary.Accuracy = &accuracy
+	}
+
+	if timed > 0 {
+		average := float64(latency) / float64(timed)
+		summary.AverageLatencyMS = &average
+	}
+
+	end := time.Now()
+	if s.CompletedAt != nil {
+		end = *s.CompletedAt
+	}
+	summary.DurationSeconds = int64(end.Sub(s.StartedAt).Seconds())
+
+	return summary
+}
+
+// GradedScore counts the answers the server graded and how many of them were
+// correct. Self-assessed answers are left out, since the user marked them.
+func (s *StudySession) GradedScore() (correct, total int) {
+	for _, a := range s.Answers {
+		if !a.Graded {
+			continue
+		}
+
+		total++
+		if a.Correct {
+			correct++
+		}
+	}
+
+	return correct, total
+}
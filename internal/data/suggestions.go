@@ -0,0 +1,115 @@
+package data
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Suggestions are type-ahead completions for a partial search query.
+type Suggestions struct {
+	Questions  []QuestionSuggestion `json:"questions"`
+	Categories []string             `json:"categories"`
+	Sections   []string             `json:"sections"`
+}
+
+type QuestionSuggestion struct {
+	ID       int64  `json:"id"`
+	Question string `json:"question"`
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Suggest returns up to limit questions, categories and sections that start
+// with q or contain a word similar to it, prefix matches first. The trigram
+// indexes on question and section serve both conditions.
+func (m FlashcardModel) Suggest(ctx context.Context, q string, limit int) (*Suggestions, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	prefix := likeEscaper.Replace(q) + "%"
+
+	suggestions := &Suggestions{
+		Questions:  []QuestionSuggestion{},
+		Categories: []string{},
+		Sections:   []string{},
+	}
+
+	query := `
+        SELECT id, question
+        FROM flashcards
+        WHERE question ILIKE $1 OR $2 <% question
+        ORDER BY question ILIKE $1 DESC, word_similarity($2, question) DESC, id
+        LIMIT $3`
+
+	rows, err := m.DB.QueryContext(ctx, query, prefix, q, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s QuestionSuggestion
+
+		err := rows.Scan(&s.ID, &s.Question)
+		if err != nil {
+			return nil, err
+		}
+
+		suggestions.Questions = append(suggestions.Questions, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	suggestions.Categories, err = m.suggestStrings(ctx, `
+        SELECT name
+        FROM (SELECT DISTINCT unnest(categories) AS name FROM flashcards) c
+        WHERE name ILIKE $1 OR $2 <% name
+        ORDER BY name ILIKE $1 DESC, word_similarity($2, name) DESC, name
+        LIMIT $3`, prefix, q, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions.Sections, err = m.suggestStrings(ctx, `
+        SELECT section
+        FROM flashcards
+        WHERE section ILIKE $1 OR $2 <% section
+        GROUP BY section
+        ORDER BY section ILIKE $1 DESC, word_similarity($2, section) DESC, section
+        LIMIT $3`, prefix, q, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
+
+func (m FlashcardModel) suggestStrings(ctx context.Context, query string, args ...any) ([]string, error) {
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := []string{}
+
+	for rows.Next() {
+		var value string
+
+		err := rows.Scan(&value)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
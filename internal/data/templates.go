@@ -0,0 +1,336 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/validator"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrDuplicateTemplateName = errors.New("duplicate template name")
+)
+
+// placeholderRX matches {{name}} placeholders in template strings.
+var placeholderRX = regexp.MustCompile(`\{\{\s*([a-z_][a-z0-9_]*)\s*\}\}`)
+
+// Template is a reusable starting point for authoring cards of one kind, such
+// as a statutory definition or a time limit. String fields may contain
+// {{name}} placeholders that are filled in when a card is created from it.
+type Template struct {
+	ID          int64           `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Section     *string         `json:"section"`
+	SectionType *string         `json:"section_type"`
+	Categories  []string        `json:"categories"`
+	Type        FlashcardType   `json:"flashcard_type"`
+	Question    string          `json:"question"`
+	Text        string          `json:"text"`
+	Content     json.RawMessage `json:"flashcard_content"`
+	Version     int32           `json:"version"`
+	CreatedAt   time.Time       `json:"created_at"`
+
+	Placeholders []string `json:"placeholders"`
+}
+
+func ValidateTemplate(v *validator.Validator, t *Template) {
+	v.Check(t.Name != "", "name", "must be provided")
+	v.Check(len(t.Name) <= 100, "name", "must not be more than 100 bytes long")
+	v.Check(validator.Unique(t.Categories), "categories", "must not contain duplicate values")
+	ValidateCategoryNames(v, "categories", t.Categories)
+	v.Check(validator.PermittedValue(t.Type, FlashcardTypes...), "flashcard_type", "invalid flashcard type")
+
+	if len(t.Content) > 0 {
+		var skeleton map[string]any
+		v.Check(json.Unmarshal(t.Content, &skeleton) == nil, "flashcard_content", "must be a JSON object")
+	}
+}
+
+// findPlaceholders returns the distinct placeholder names used anywhere in
+// the template, in order of first use.
+func (t *Template) findPlaceholders() {
+	sources := []string{t.Question, t.Text, string(t.Content)}
+	if t.Section != nil {
+		sources = append(sources, *t.Section)
+	}
+
+	t.Placeholders = []string{}
+
+	for _, src := range sources {
+		for _, match := range placeholderRX.FindAllStringSubmatch(src, -1) {
+			if !slices.Contains(t.Placeholders, match[1]) {
+				t.Placeholders = append(t.Placeholders, match[1])
+			}
+		}
+	}
+}
+
+// Fill substitutes values into the template and returns the pre-filled card.
+// Every placeholder must have a value. Top-level keys in content replace the
+// corresponding keys of the template's content skeleton.
+func (t *Template) Fill(v *validator.Validator, values map[string]string, content json.RawMessage) (*Flashcard, error) {
+	for _, name := range t.Placeholders {
+		v.Check(values[name] != "", "values."+name, "must be provided")
+	}
+
+	if !v.Valid() {
+		return nil, nil
+	}
+
+	replace := func(s string) string {
+		return placeholderRX.ReplaceAllStringFunc(s, func(m string) string {
+			return values[placeholderRX.FindStringSubmatch(m)[1]]
+		})
+	}
+
+	flashcard := &Flashcard{
+		SectionType: t.SectionType,
+		Text:        replace(t.Text),
+		Question:    replace(t.Question),
+		Type:        t.Type,
+		Categories:  slices.Clone(t.Categories),
+		CreatedAt:   time.Now(),
+	}
+
+	if t.Section != nil {
+		section := replace(*t.Section)
+		flashcard.Section = &section
+	}
+
+	skeleton := t.Content
+	if len(skeleton) == 0 {
+		skeleton = json.RawMessage("{}")
+	}
+
+	var raw map[string]any
+	err := json.Unmarshal(skeleton, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	fillJSON(raw, replace)
+
+	if len(content) > 0 {
+		var overrides map[string]any
+		err = json.Unmarshal(content, &overrides)
+		if err != nil {
+			return nil, err
+		}
+
+		maps.Copy(raw, overrides)
+	}
+
+	filled, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	flashcard.Content, err = DecodeContent(t.Type, filled)
+	if err != nil {
+		return nil, err
+	}
+
+	return flashcard, nil
+}
+
+func fillJSON(value any, replace func(string) string) any {
+	switch v := value.(type) {
+	case string:
+		return replace(v)
+	case []any:
+		for i := range v {
+			v[i] = fillJSON(v[i], replace)
+		}
+	case map[string]any:
+		for k := range v {
+			v[k] = fillJSON(v[k], replace)
+		}
+	}
+	return value
+}
+
+type TemplateModel struct {
+	DB *sql.DB
+}
+
+const templateColumns = `id, name, description, section, section_type, categories, flashcard_type, question, text, flashcard_content, version, created_at`
+
+func (t *Template) scanTargets() []any {
+	return []any{
+		&t.ID, &t.Name, &t.Description, &t.Section, &t.SectionType, pq.Array(&t.Categories),
+		&t.Type, &t.Question, &t.Text, &t.Content, &t.Version, &t.CreatedAt,
+	}
+}
+
+func (t *Template) content() []byte {
+	if len(t.Content) == 0 {
+		return []byte("{}")
+	}
+	return t.Content
+}
+
+func (m TemplateModel) Insert(ctx context.Context, t *Template) error {
+	t.Categories = NormalizeCategories(t.Categories)
+
+	query := `
+        INSERT INTO flashcard_templates (name, description, section, section_type, categories, flashcard_type, question, text, flashcard_content)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        RETURNING id, version, created_at`
+
+	args := []any{t.Name, t.Description, t.Section, t.SectionType, pq.Array(t.Categories), t.Type, t.Question, t.Text, t.content()}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&t.ID, &t.Version, &t.CreatedAt)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "flashcard_templates_name_key"):
+			return ErrDuplicateTemplateName
+		default:
+			return err
+		}
+	}
+
+	t.findPlaceholders()
+
+	return nil
+}
+
+func (m TemplateModel) Get(ctx context.Context, id int64) (*Template, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `SELECT ` + templateColumns + ` FROM flashcard_templates WHERE id = $1`
+
+	var t Template
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(t.scanTargets()...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	t.findPlaceholders()
+
+	return &t, nil
+}
+
+func (m TemplateModel) GetAll(ctx context.Context, filters Filters) ([]*Template, Metadata, error) {
+	query := fmt.Sprintf(`
+        SELECT count(*) OVER(), %s
+        FROM flashcard_templates
+        ORDER BY %s
+        LIMIT $1 OFFSET $2`, templateColumns, filters.orderBy("id"))
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	templates := []*Template{}
+
+	for rows.Next() {
+		var t Template
+
+		err := rows.Scan(append([]any{&totalRecords}, t.scanTargets()...)...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		t.findPlaceholders()
+
+		templates = append(templates, &t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return templates, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+func (m TemplateModel) Update(ctx context.Context, t *Template) error {
+	t.Categories = NormalizeCategories(t.Categories)
+
+	query := `
+        UPDATE flashcard_templates
+        SET name = $1, description = $2, section = $3, section_type = $4, categories = $5,
+            flashcard_type = $6, question = $7, text = $8, flashcard_content = $9, version = version + 1
+        WHERE id = $10 AND version = $11
+        RETURNING version`
+
+	args := []any{
+		t.Name, t.Description, t.Section, t.SectionType, pq.Array(t.Categories),
+		t.Type, t.Question, t.Text, t.content(), t.ID, t.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&t.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		case strings.Contains(err.Error(), "flashcard_templates_name_key"):
+			return ErrDuplicateTemplateName
+		default:
+			return err
+		}
+	}
+
+	t.findPlaceholders()
+
+	return nil
+}
+
+func (m TemplateModel) Delete(ctx context.Context, id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `DELETE FROM flashcard_templates WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
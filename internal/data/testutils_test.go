@@ -0,0 +1,115 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+)
+
+// fakeDB is a database/sql driver that answers each query with the next of
+// a queue of canned rows and records the arguments it was given, so models
+// can be tested against the values lib/pq sends and receives.
+type fakeDB struct {
+	mu      sync.Mutex
+	results [][][]driver.Value
+	args    [][]driver.Value
+}
+
+// newFakeDB returns a *sql.DB backed by fake, whose queries return results
+// in order.
+func newFakeDB(t *testing.T, results ...[][]driver.Value) (*sql.DB, *fakeDB) {
+	t.Helper()
+
+	fake := &fakeDB{results: results}
+
+	db := sql.OpenDB(fake)
+	t.Cleanup(func() { db.Close() })
+
+	return db, fake
+}
+
+// queue adds the rows the next unanswered query returns.
+func (f *fakeDB) queue(rows ...[]driver.Value) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.results = append(f.results, rows)
+}
+
+func (f *fakeDB) next(args []driver.NamedValue) ([][]driver.Value, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	f.args = append(f.args, values)
+
+	if len(f.results) == 0 {
+		return nil, errors.New("fakedb: unexpected query")
+	}
+
+	rows := f.results[0]
+	f.results = f.results[1:]
+
+	return rows, nil
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{f}, nil }
+func (f *fakeDB) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("fakedb: not supported")
+}
+func (c fakeConn) Close() error              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) { return nil, errors.New("fakedb: not supported") }
+
+func (c fakeConn) QueryContext(_ context.Context, _ string, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := c.db.next(args)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := 0
+	if len(rows) > 0 {
+		columns = len(rows[0])
+	}
+
+	return &fakeRows{columns: make([]string, columns), rows: rows}, nil
+}
+
+func (c fakeConn) ExecContext(_ context.Context, _ string, args []driver.NamedValue) (driver.Result, error) {
+	rows, err := c.db.next(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return driver.RowsAffected(len(rows)), nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+
+	return nil
+}
@@ -5,22 +5,50 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"database/sql"
+	"errors"
+	"slices"
 	"time"
 
 	"flashcards-api.johndennehy101.tech/internal/validator"
+	"github.com/lib/pq"
 )
 
 const (
 	ScopeActivation     = "activation"
 	ScopeAuthentication = "authentication"
+	ScopeMagicLink      = "magic-link"
 )
 
+// TokenScopes are what an authentication token can be limited to. Unlike
+// Scope, which is what the token is for, they narrow what a token may do
+// below the user's own permissions: a flashcards:read token can be embedded
+// in a public study widget without letting it record reviews or edit cards.
+var TokenScopes = []string{"flashcards:read", "flashcards:write", "study:write", "admin:write"}
+
 type Token struct {
 	Plaintext string    `json:"token"`
 	Hash      []byte    `json:"-"`
 	UserID    int64     `json:"-"`
 	Expiry    time.Time `json:"expiry"`
 	Scope     string    `json:"-"`
+	// Scopes limit an authentication token, nil when it has the user's
+	// full access
+	Scopes    Permissions `json:"scopes,omitempty"`
+	UserAgent string      `json:"-"`
+	IP        string      `json:"-"`
+}
+
+// AuthSession is an unexpired authentication token as listed to its user,
+// so they can sign out a device they no longer have.
+type AuthSession struct {
+	ID         int64       `json:"id"`
+	CreatedAt  time.Time   `json:"created_at"`
+	LastUsedAt *time.Time  `json:"last_used_at,omitempty"`
+	Expiry     time.Time   `json:"expiry"`
+	UserAgent  string      `json:"user_agent"`
+	IP         string      `json:"ip"`
+	Scopes     Permissions `json:"scopes,omitempty"`
+	Current    bool        `json:"current"`
 }
 
 func generateToken(userID int64, ttl time.Duration, scope string) *Token {
@@ -42,39 +70,216 @@ func ValidateTokenPlaintext(v *validator.Validator, tokenPlaintext string) {
 	v.Check(len(tokenPlaintext) == 26, "token", "must be 26 bytes long")
 }
 
+func ValidateTokenScopes(v *validator.Validator, scopes []string) {
+	v.Check(len(scopes) > 0, "scopes", "must contain at least one scope")
+	v.Check(validator.Unique(scopes), "scopes", "must not contain duplicate values")
+
+	for _, scope := range scopes {
+		if !slices.Contains(TokenScopes, scope) {
+			v.AddError("scopes", "must only contain flashcards:read, flashcards:write, study:write or admin:write")
+			break
+		}
+	}
+}
+
 type TokenModel struct {
 	DB *sql.DB
 }
 
-func (m TokenModel) New(userID int64, ttl time.Duration, scope string) (*Token, error) {
+func (m TokenModel) New(ctx context.Context, userID int64, ttl time.Duration, scope string) (*Token, error) {
 	token := generateToken(userID, ttl, scope)
 
-	err := m.Insert(token)
+	err := m.Insert(ctx, token)
 	return token, err
 }
 
-func (m TokenModel) Insert(token *Token) error {
+// NewSession creates an authentication token limited to scopes, or with full
+// access when scopes is nil, for the client it is listed under.
+func (m TokenModel) NewSession(ctx context.Context, userID int64, ttl time.Duration, scopes []string, userAgent, ip string) (*Token, error) {
+	token := generateToken(userID, ttl, ScopeAuthentication)
+	token.Scopes = scopes
+	token.UserAgent = userAgent
+	token.IP = ip
+
+	err := m.Insert(ctx, token)
+	return token, err
+}
+
+func (m TokenModel) Insert(ctx context.Context, token *Token) error {
 	query := `
-        INSERT INTO tokens (hash, user_id, expiry, scope) 
-        VALUES ($1, $2, $3, $4)`
+        INSERT INTO tokens (hash, user_id, expiry, scope, scopes, user_agent, ip) 
+        VALUES ($1, $2, $3, $4, $5, $6, $7)`
 
-	args := []any{token.Hash, token.UserID, token.Expiry, token.Scope}
+	args := []any{token.Hash, token.UserID, token.Expiry, token.Scope, pq.Array(token.Scopes), token.UserAgent, token.IP}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	_, err := m.DB.ExecContext(ctx, query, args...)
 	return err
 }
 
-func (m TokenModel) DeleteAllForUser(scope string, userID int64) error {
+// Use deletes the unexpired token for scope, so it can't be used again, and
+// returns the id of the user it was issued to.
+func (m TokenModel) Use(ctx context.Context, scope, tokenPlaintext string) (int64, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `
+        DELETE FROM tokens
+        WHERE hash = $1 AND scope = $2 AND expiry > NOW()
+        RETURNING user_id`
+
+	var userID int64
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, tokenHash[:], scope).Scan(&userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	return userID, nil
+}
+
+func (m TokenModel) DeleteAllForUser(ctx context.Context, scope string, userID int64) error {
 	query := `
         DELETE FROM tokens 
         WHERE scope = $1 AND user_id = $2`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	_, err := m.DB.ExecContext(ctx, query, scope, userID)
 	return err
 }
+
+// DeleteEveryForUser deletes all of the user's tokens, whatever their scope.
+func (m TokenModel) DeleteEveryForUser(ctx context.Context, userID int64) error {
+	query := `
+        DELETE FROM tokens 
+        WHERE user_id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID)
+	return err
+}
+
+// GetSessionsForUser lists the user's unexpired authentication tokens, most
+// recently used first, marking the one whose plaintext is current.
+func (m TokenModel) GetSessionsForUser(ctx context.Context, userID int64, current string) ([]*AuthSession, error) {
+	currentHash := sha256.Sum256([]byte(current))
+
+	query := `
+        SELECT id, created_at, last_used_at, expiry, user_agent, ip, scopes, hash = $3
+        FROM tokens
+        WHERE user_id = $1 AND scope = $2 AND expiry > NOW()
+        ORDER BY COALESCE(last_used_at, created_at) DESC, id DESC`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, ScopeAuthentication, currentHash[:])
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*AuthSession{}
+
+	for rows.Next() {
+		var session AuthSession
+		var scopes []string
+
+		err := rows.Scan(
+			&session.ID,
+			&session.CreatedAt,
+			&session.LastUsedAt,
+			&session.Expiry,
+			&session.UserAgent,
+			&session.IP,
+			pq.Array(&scopes),
+			&session.Current,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		session.Scopes = Permissions(scopes)
+
+		sessions = append(sessions, &session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// DeleteSession revokes one of the user's authentication tokens.
+func (m TokenModel) DeleteSession(ctx context.Context, id, userID int64) error {
+	query := `
+        DELETE FROM tokens
+        WHERE id = $1 AND user_id = $2 AND scope = $3`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID, ScopeAuthentication)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// DeleteOtherSessions revokes all of the user's authentication tokens except
+// the one whose plaintext is current, returning how many went.
+func (m TokenModel) DeleteOtherSessions(ctx context.Context, userID int64, current string) (int64, error) {
+	currentHash := sha256.Sum256([]byte(current))
+
+	query := `
+        DELETE FROM tokens
+        WHERE user_id = $1 AND scope = $2 AND hash != $3`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, ScopeAuthentication, currentHash[:])
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (m TokenModel) DeleteExpired(ctx context.Context) (int64, error) {
+	query := `DELETE FROM tokens WHERE expiry < NOW()`
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
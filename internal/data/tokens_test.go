@@ -0,0 +1,47 @@
+package data
+
+import (
+	"context"
+	"database/sql/driver"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSessionScopesRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	scopes := []string{"flashcards:read"}
+
+	db, fake := newFakeDB(t, nil)
+	tokens := TokenModel{DB: db}
+
+	_, err := tokens.NewSession(ctx, 7, time.Hour, scopes, "curl/8.0", "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored := []byte(fake.args[0][4].(string))
+
+	fake.queue(
+		[]driver.Value{int64(1), now, nil, now.Add(time.Hour), "curl/8.0", "192.0.2.1", stored, true},
+		[]driver.Value{int64(2), now, nil, now.Add(time.Hour), "curl/8.0", "192.0.2.1", nil, false},
+	)
+
+	sessions, err := tokens.GetSessionsForUser(ctx, 7, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sessions) != 2 {
+		t.Fatalf("got %d sessions; want 2", len(sessions))
+	}
+
+	if !slices.Equal(sessions[0].Scopes, Permissions(scopes)) {
+		t.Errorf("scoped session scopes = %v; want %v", sessions[0].Scopes, scopes)
+	}
+
+	if sessions[1].Scopes != nil {
+		t.Errorf("unscoped session scopes = %v; want nil", sessions[1].Scopes)
+	}
+}
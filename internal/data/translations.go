@@ -0,0 +1,104 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/validator"
+	"github.com/lib/pq"
+	"golang.org/x/text/language"
+)
+
+type Translation struct {
+	FlashcardID int64            `json:"flashcard_id"`
+	Language    string           `json:"language"`
+	Question    string           `json:"question"`
+	Type        FlashcardType    `json:"flashcard_type"`
+	Content     FlashcardContent `json:"flashcard_content"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+func ValidateTranslation(v *validator.Validator, t *Translation) {
+	_, err := language.Parse(t.Language)
+	v.Check(t.Language != "", "language", "must be provided")
+	v.Check(t.Language == "" || err == nil, "language", "must be a valid BCP 47 language tag")
+	v.Check(t.Question != "", "question", "must be provided")
+
+	ValidateContent(v, t.Content)
+}
+
+type TranslationModel struct {
+	DB *sql.DB
+}
+
+// Upsert adds the translation, replacing any existing one for the language.
+func (m TranslationModel) Upsert(ctx context.Context, t *Translation) error {
+	contentJSON, err := json.Marshal(t.Content)
+	if err != nil {
+		return fmt.Errorf("failed to marshal translation content: %w", err)
+	}
+
+	query := `
+        INSERT INTO flashcard_translations (flashcard_id, language, question, flashcard_type, flashcard_content)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (flashcard_id, language) DO UPDATE
+        SET question = EXCLUDED.question,
+            flashcard_type = EXCLUDED.flashcard_type,
+            flashcard_content = EXCLUDED.flashcard_content,
+            updated_at = NOW()
+        RETURNING created_at, updated_at`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, t.FlashcardID, t.Language, t.Question, t.Type, contentJSON).Scan(&t.CreatedAt, &t.UpdatedAt)
+}
+
+// GetAllForFlashcards returns the translations of the given flashcards that
+// still match each card's current type.
+func (m TranslationModel) GetAllForFlashcards(ctx context.Context, flashcardIDs []int64) ([]*Translation, error) {
+	query := `
+        SELECT t.flashcard_id, t.language, t.question, t.flashcard_type, t.flashcard_content, t.created_at, t.updated_at
+        FROM flashcard_translations t
+        INNER JOIN flashcards f ON f.id = t.flashcard_id AND f.flashcard_type = t.flashcard_type
+        WHERE t.flashcard_id = ANY($1)
+        ORDER BY t.flashcard_id, t.language`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(flashcardIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	translations := []*Translation{}
+
+	for rows.Next() {
+		var t Translation
+		var contentJSON []byte
+
+		err := rows.Scan(&t.FlashcardID, &t.Language, &t.Question, &t.Type, &contentJSON, &t.CreatedAt, &t.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		t.Content, err = unmarshalContent(t.Type, contentJSON)
+		if err != nil {
+			return nil, err
+		}
+
+		translations = append(translations, &t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return translations, nil
+}
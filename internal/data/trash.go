@@ -0,0 +1,149 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// trashQuery snapshots a card's row into deleted_flashcards before it is
+// deleted. Generated columns are left out, as they can't be inserted on
+// restore.
+const trashQuery = `
+        INSERT INTO deleted_flashcards (flashcard_id, deleted_by, card)
+        SELECT f.id, $2, to_jsonb(f) - 'search_vector' - 'question_normalized'
+        FROM flashcards f
+        WHERE f.id = $1
+        ON CONFLICT (flashcard_id) DO UPDATE
+        SET deleted_by = EXCLUDED.deleted_by, card = EXCLUDED.card, deleted_at = NOW()`
+
+// TrashedFlashcard is a deleted card waiting in a user's trash.
+type TrashedFlashcard struct {
+	ID        int64     `json:"id"`
+	Question  string    `json:"question"`
+	Section   *string   `json:"section"`
+	Type      string    `json:"flashcard_type"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// GetTrash returns the cards the user deleted, most recent first.
+func (m FlashcardModel) GetTrash(ctx context.Context, userID int64, filters Filters) ([]*TrashedFlashcard, Metadata, error) {
+	query := `
+        SELECT count(*) OVER(), flashcard_id, card->>'question', card->>'section', card->>'flashcard_type', deleted_at
+        FROM deleted_flashcards
+        WHERE deleted_by = $1
+        ORDER BY deleted_at DESC, flashcard_id DESC
+        LIMIT $2 OFFSET $3`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	flashcards := []*TrashedFlashcard{}
+
+	for rows.Next() {
+		var flashcard TrashedFlashcard
+
+		err := rows.Scan(&totalRecords, &flashcard.ID, &flashcard.Question, &flashcard.Section, &flashcard.Type, &flashcard.DeletedAt)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		flashcards = append(flashcards, &flashcard)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return flashcards, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// Restore puts cards from the user's trash back under their original ids and
+// returns the ids restored; ids not in the user's trash are skipped. Only
+// the card itself comes back: progress, reviews, attachments, relations and
+// translations were removed with it.
+func (m FlashcardModel) Restore(ctx context.Context, ids []int64, userID int64) ([]int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+        INSERT INTO flashcards (
+            id, section, section_type, source_file, text, question,
+            flashcard_type, flashcard_content, categories, difficulty,
+            hints, metadata, quality_score, measured_difficulty, user_id, version, created_at
+        )
+        SELECT
+            r.id, r.section, r.section_type, r.source_file, r.text, r.question,
+            r.flashcard_type, r.flashcard_content, r.categories, r.difficulty,
+            r.hints, r.metadata, r.quality_score, r.measured_difficulty, r.user_id, r.version, r.created_at
+        FROM deleted_flashcards d
+        CROSS JOIN LATERAL jsonb_populate_record(NULL::flashcards, d.card) r
+        WHERE d.flashcard_id = ANY($1) AND d.deleted_by = $2
+        RETURNING id`
+
+	rows, err := tx.QueryContext(ctx, query, pq.Array(ids), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	restored := []int64{}
+
+	for rows.Next() {
+		var id int64
+
+		err := rows.Scan(&id)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		restored = append(restored, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		rows.Close()
+		return nil, err
+	}
+	rows.Close()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM deleted_flashcards WHERE flashcard_id = ANY($1)`, pq.Array(restored))
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+// PurgeTrash permanently drops trashed cards deleted before cutoff.
+func (m FlashcardModel) PurgeTrash(ctx context.Context, cutoff time.Time) (int64, error) {
+	query := `DELETE FROM deleted_flashcards WHERE deleted_at < $1`
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
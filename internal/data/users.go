@@ -8,7 +8,9 @@ import (
 	"time"
 
 	"flashcards-api.johndennehy101.tech/internal/validator"
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/text/language"
 )
 
 var (
@@ -28,7 +30,23 @@ type User struct {
 	Email     string    `json:"email"`
 	Password  password  `json:"-"`
 	Activated bool      `json:"activated"`
-	Version   int       `json:"-"`
+	Locale    string    `json:"locale"`
+	Timezone  string    `json:"timezone"`
+	// Scheduler is the spaced repetition algorithm for cards whose decks
+	// don't pick one, empty for the default.
+	Scheduler string `json:"scheduler"`
+	// DefaultDeckID is the deck new cards are added to when the request
+	// doesn't name one.
+	DefaultDeckID *int64 `json:"default_deck_id"`
+	// NewPerDay and ReviewsPerDay cap the new and review cards the user is
+	// given to study each day, nil for the server's defaults.
+	NewPerDay     *int `json:"new_cards_per_day"`
+	ReviewsPerDay *int `json:"reviews_per_day"`
+	// DailyGoal is how many cards, or minutes of reviewing, make a day
+	// count towards the user's study streak.
+	DailyGoal     int    `json:"daily_goal"`
+	DailyGoalUnit string `json:"daily_goal_unit"`
+	Version       int    `json:"-"`
 }
 
 type password struct {
@@ -37,18 +55,19 @@ type password struct {
 }
 
 func (p *password) Set(plaintextPassword string) error {
-	hash, err := bcrypt.GenerateFromPassword([]byte(plaintextPassword), 12)
-	if err != nil {
-		return err
-	}
-
 	p.plaintext = &plaintextPassword
-	p.hash = hash
+	p.hash = hashArgon2id(plaintextPassword, PasswordArgon2)
 
 	return nil
 }
 
+// Matches checks the password against the hash, which may be an Argon2id
+// one or a bcrypt one made before Argon2id was adopted.
 func (p *password) Matches(plaintextPassword string) (bool, error) {
+	if p.Scheme() == PasswordSchemeArgon2id {
+		return matchesArgon2id(plaintextPassword, p.hash)
+	}
+
 	err := bcrypt.CompareHashAndPassword(p.hash, []byte(plaintextPassword))
 	if err != nil {
 		switch {
@@ -73,6 +92,35 @@ func ValidatePasswordPlaintext(v *validator.Validator, password string) {
 	v.Check(len(password) <= 72, "password", "must not be more than 72 bytes long")
 }
 
+// ValidateLocaleSettings checks the user's formatting preferences. Both may
+// be empty, meaning the client's Accept-Language and UTC are used instead.
+func ValidateLocaleSettings(v *validator.Validator, user *User) {
+	if user.Locale != "" {
+		_, err := language.Parse(user.Locale)
+		v.Check(err == nil, "locale", "must be a valid BCP 47 language tag")
+	}
+
+	if user.Timezone != "" {
+		_, err := time.LoadLocation(user.Timezone)
+		v.Check(err == nil && user.Timezone != "Local", "timezone", "must be a valid IANA time zone")
+	}
+}
+
+func ValidateStudyLimits(v *validator.Validator, user *User) {
+	if user.NewPerDay != nil {
+		v.Check(*user.NewPerDay >= 0 && *user.NewPerDay <= 1000, "new_cards_per_day", "must be between 0 and 1000")
+	}
+
+	if user.ReviewsPerDay != nil {
+		v.Check(*user.ReviewsPerDay >= 0 && *user.ReviewsPerDay <= 10000, "reviews_per_day", "must be between 0 and 10000")
+	}
+}
+
+func ValidateDailyGoal(v *validator.Validator, user *User) {
+	v.Check(user.DailyGoal >= 1 && user.DailyGoal <= 1000, "daily_goal", "must be between 1 and 1000")
+	v.Check(validator.PermittedValue(user.DailyGoalUnit, DailyGoalUnits...), "daily_goal_unit", "must be cards or minutes")
+}
+
 func ValidateUser(v *validator.Validator, user *User) {
 	v.Check(user.Name != "", "name", "must be provided")
 	v.Check(len(user.Name) <= 500, "name", "must not be more than 500 bytes long")
@@ -81,6 +129,7 @@ func ValidateUser(v *validator.Validator, user *User) {
 
 	if user.Password.plaintext != nil {
 		ValidatePasswordPlaintext(v, *user.Password.plaintext)
+		ValidatePasswordStrength(v, *user.Password.plaintext, user)
 	}
 
 	if user.Password.hash == nil {
@@ -92,18 +141,18 @@ func (u *User) IsAnonymous() bool {
 	return u == AnonymousUser
 }
 
-func (m UserModel) Insert(user *User) error {
+func (m UserModel) Insert(ctx context.Context, user *User) error {
 	query := `
-        INSERT INTO users (name, email, password_hash, activated) 
-        VALUES ($1, $2, $3, $4)
-        RETURNING id, created_at, version`
+        INSERT INTO users (name, email, password_hash, password_scheme, activated) 
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, created_at, daily_goal, daily_goal_unit, version`
 
-	args := []any{user.Name, user.Email, user.Password.hash, user.Activated}
+	args := []any{user.Name, user.Email, user.Password.hash, user.Password.Scheme(), user.Activated}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.CreatedAt, &user.Version)
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.CreatedAt, &user.DailyGoal, &user.DailyGoalUnit, &user.Version)
 	if err != nil {
 		switch {
 		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"`:
@@ -116,15 +165,56 @@ func (m UserModel) Insert(user *User) error {
 	return nil
 }
 
-func (m UserModel) GetByEmail(email string) (*User, error) {
+func (m UserModel) Get(ctx context.Context, id int64) (*User, error) {
 	query := `
-        SELECT id, created_at, name, email, password_hash, activated, version
+        SELECT id, created_at, name, email, password_hash, activated, locale, timezone, scheduler, default_deck_id, new_per_day, reviews_per_day, daily_goal, daily_goal_unit, version
+        FROM users
+        WHERE id = $1`
+
+	var user User
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Password.hash,
+		&user.Activated,
+		&user.Locale,
+		&user.Timezone,
+		&user.Scheduler,
+		&user.DefaultDeckID,
+		&user.NewPerDay,
+		&user.ReviewsPerDay,
+		&user.DailyGoal,
+		&user.DailyGoalUnit,
+		&user.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
+
+func (m UserModel) GetByEmail(ctx context.Context, email string) (*User, error) {
+	query := `
+        SELECT id, created_at, name, email, password_hash, activated, locale, timezone, scheduler, default_deck_id, new_per_day, reviews_per_day, daily_goal, daily_goal_unit, version
         FROM users
         WHERE email = $1`
 
 	var user User
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	err := m.DB.QueryRowContext(ctx, query, email).Scan(
@@ -134,6 +224,14 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 		&user.Email,
 		&user.Password.hash,
 		&user.Activated,
+		&user.Locale,
+		&user.Timezone,
+		&user.Scheduler,
+		&user.DefaultDeckID,
+		&user.NewPerDay,
+		&user.ReviewsPerDay,
+		&user.DailyGoal,
+		&user.DailyGoalUnit,
 		&user.Version,
 	)
 
@@ -149,23 +247,33 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 	return &user, nil
 }
 
-func (m UserModel) Update(user *User) error {
+func (m UserModel) Update(ctx context.Context, user *User) error {
 	query := `
         UPDATE users 
-        SET name = $1, email = $2, password_hash = $3, activated = $4, version = version + 1
-        WHERE id = $5 AND version = $6
+        SET name = $1, email = $2, password_hash = $3, password_scheme = $4, activated = $5, locale = $6, timezone = $7, scheduler = $8,
+            default_deck_id = $9, new_per_day = $10, reviews_per_day = $11, daily_goal = $12, daily_goal_unit = $13, version = version + 1
+        WHERE id = $14 AND version = $15
         RETURNING version`
 
 	args := []any{
 		user.Name,
 		user.Email,
 		user.Password.hash,
+		user.Password.Scheme(),
 		user.Activated,
+		user.Locale,
+		user.Timezone,
+		user.Scheduler,
+		user.DefaultDeckID,
+		user.NewPerDay,
+		user.ReviewsPerDay,
+		user.DailyGoal,
+		user.DailyGoalUnit,
 		user.ID,
 		user.Version,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.Version)
@@ -183,12 +291,44 @@ func (m UserModel) Update(user *User) error {
 	return nil
 }
 
-func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error) {
+// Rehash hashes the user's password again with the current scheme and
+// costs, after a login has proved plaintextPassword is right. It leaves the
+// user's version alone, since nothing the user can see has changed, and
+// does nothing if the password was changed in the meantime.
+func (m UserModel) Rehash(ctx context.Context, user *User, plaintextPassword string) error {
+	oldHash := user.Password.hash
+
+	err := user.Password.Set(plaintextPassword)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        UPDATE users
+        SET password_hash = $1, password_scheme = $2
+        WHERE id = $3 AND password_hash = $4`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err = m.DB.ExecContext(ctx, query, user.Password.hash, user.Password.Scheme(), user.ID, oldHash)
+	return err
+}
+
+// GetForToken returns the user holding the unexpired token for tokenScope,
+// with the scopes the token is limited to.
+func (m UserModel) GetForToken(ctx context.Context, tokenScope, tokenPlaintext string) (*User, Permissions, error) {
 	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
 
-	// Set up the SQL query.
+	// Set up the SQL query. It records the token's last use at most once a
+	// minute, which saves a write on every request a signed-in client makes.
 	query := `
-        SELECT users.id, users.created_at, users.name, users.email, users.password_hash, users.activated, users.version
+        WITH touched AS (
+            UPDATE tokens SET last_used_at = NOW()
+            WHERE hash = $1 AND scope = $2 AND expiry > $3
+            AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+        )
+        SELECT users.id, users.created_at, users.name, users.email, users.password_hash, users.activated, users.locale, users.timezone, users.scheduler, users.default_deck_id, users.new_per_day, users.reviews_per_day, users.daily_goal, users.daily_goal_unit, users.version, tokens.scopes
         FROM users
         INNER JOIN tokens
         ON users.id = tokens.user_id
@@ -200,7 +340,11 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 
 	var user User
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// lib/pq can't scan into a named slice type, so the scopes are read as
+	// a plain []string.
+	var scopes []string
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
@@ -210,17 +354,100 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 		&user.Email,
 		&user.Password.hash,
 		&user.Activated,
+		&user.Locale,
+		&user.Timezone,
+		&user.Scheduler,
+		&user.DefaultDeckID,
+		&user.NewPerDay,
+		&user.ReviewsPerDay,
+		&user.DailyGoal,
+		&user.DailyGoalUnit,
 		&user.Version,
+		pq.Array(&scopes),
 	)
 
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
-			return nil, ErrRecordNotFound
+			return nil, nil, ErrRecordNotFound
 		default:
+			return nil, nil, err
+		}
+	}
+
+	return &user, Permissions(scopes), nil
+}
+
+// Delete removes the user and, through the foreign keys, their tokens, roles,
+// progress, review history, decks and memberships. The cards they wrote are
+// deleted too when deleteCards is set, and otherwise kept without an owner.
+// Their trash is emptied, and other users' trashed copies of their cards
+// forget who wrote them. It returns the ids of the deleted cards.
+func (m UserModel) Delete(ctx context.Context, userID int64, deleteCards bool) ([]int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	deleted := []int64{}
+
+	if deleteCards {
+		rows, err := tx.QueryContext(ctx, `DELETE FROM flashcards WHERE user_id = $1 RETURNING id`, userID)
+		if err != nil {
 			return nil, err
 		}
+
+		for rows.Next() {
+			var id int64
+
+			err := rows.Scan(&id)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+
+			deleted = append(deleted, id)
+		}
+
+		if err = rows.Err(); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		rows.Close()
 	}
 
-	return &user, nil
+	query := `
+        UPDATE deleted_flashcards
+        SET card = jsonb_set(card, '{user_id}', 'null')
+        WHERE card->>'user_id' = $1::text`
+
+	_, err = tx.ExecContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM deleted_flashcards WHERE deleted_by = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return deleted, tx.Commit()
 }
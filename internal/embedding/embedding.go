@@ -0,0 +1,100 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Provider turns texts into embedding vectors. Vectors from different models
+// aren't comparable, so Model identifies the vector space.
+type Provider interface {
+	Embed(texts ...string) ([][]float32, error)
+	Model() string
+}
+
+// OpenAI is a Provider for the OpenAI-compatible /v1/embeddings API, which
+// is also implemented by Ollama, vLLM and other self-hosted servers.
+type OpenAI struct {
+	client *http.Client
+	url    string
+	apiKey string
+	model  string
+}
+
+func NewOpenAI(url, apiKey, model string) *OpenAI {
+	return &OpenAI{
+		client: &http.Client{Timeout: 30 * time.Second},
+		url:    url,
+		apiKey: apiKey,
+		model:  model,
+	}
+}
+
+func (p *OpenAI) Model() string {
+	return p.model
+}
+
+func (p *OpenAI) Embed(texts ...string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]any{
+		"model": p.model,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return nil, fmt.Errorf("embedding: provider returned %d: %s", res.StatusCode, msg)
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding: provider returned %d embeddings for %d inputs", len(result.Data), len(texts))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding: provider returned out of range index %d", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+
+	return vectors, nil
+}
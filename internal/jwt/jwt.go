@@ -0,0 +1,251 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token has expired")
+)
+
+type Header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// Claims is the decoded JWT payload. Registered claims are available through
+// the helper methods; everything else is read with the map directly.
+type Claims map[string]any
+
+func (c Claims) String(key string) string {
+	s, _ := c[key].(string)
+	return s
+}
+
+func (c Claims) Time(key string) time.Time {
+	f, ok := c[key].(float64)
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(int64(f), 0)
+}
+
+// Audience returns the aud claim, which may be a string or a list of strings.
+func (c Claims) Audience() []string {
+	switch aud := c["aud"].(type) {
+	case string:
+		return []string{aud}
+	case []any:
+		var out []string
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func encodeSegment(v any) (string, error) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(js), nil
+}
+
+// SignRS256 encodes and signs the claims with an RSA private key.
+func SignRS256(claims Claims, kid string, key *rsa.PrivateKey) (string, error) {
+	header, err := encodeSegment(Header{Alg: "RS256", Typ: "JWT", Kid: kid})
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := encodeSegment(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := header + "." + payload
+	hash := sha256.Sum256([]byte(signingInput))
+
+	sig, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// SignHS256 encodes the claims and signs them with an HMAC secret.
+func SignHS256(claims Claims, kid string, secret []byte) (string, error) {
+	header, err := encodeSegment(Header{Alg: "HS256", Typ: "JWT", Kid: kid})
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := encodeSegment(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := header + "." + payload
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signingInput))
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Parse splits a compact JWT into its decoded header and claims and returns
+// the signing input and signature for verification.
+func Parse(token string) (Header, Claims, []byte, []byte, error) {
+	var header Header
+	var claims Claims
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return header, nil, nil, nil, ErrInvalidToken
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return header, nil, nil, nil, ErrInvalidToken
+	}
+
+	payloadJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return header, nil, nil, nil, ErrInvalidToken
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return header, nil, nil, nil, ErrInvalidToken
+	}
+
+	if json.Unmarshal(headerJSON, &header) != nil || json.Unmarshal(payloadJSON, &claims) != nil {
+		return header, nil, nil, nil, ErrInvalidToken
+	}
+
+	return header, claims, []byte(parts[0] + "." + parts[1]), sig, nil
+}
+
+// VerifyRS256 checks the signature of a token with the public key returned by
+// keyFunc for the token's kid, and validates the exp claim.
+func VerifyRS256(token string, keyFunc func(kid string) (*rsa.PublicKey, error)) (Claims, error) {
+	header, claims, signingInput, sig, err := Parse(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if header.Alg != "RS256" {
+		return nil, ErrInvalidToken
+	}
+
+	key, err := keyFunc(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256(signingInput)
+
+	err = rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], sig)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if exp := claims.Time("exp"); exp.IsZero() || time.Now().After(exp) {
+		return nil, ErrExpiredToken
+	}
+
+	return claims, nil
+}
+
+// VerifyHS256 checks the signature of a token with the secret returned by
+// keyFunc for the token's kid, and validates the exp claim.
+func VerifyHS256(token string, keyFunc func(kid string) ([]byte, error)) (Claims, error) {
+	header, claims, signingInput, sig, err := Parse(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if header.Alg != "HS256" {
+		return nil, ErrInvalidToken
+	}
+
+	secret, err := keyFunc(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(signingInput)
+
+	if !hmac.Equal(mac.Sum(nil), sig) {
+		return nil, ErrInvalidToken
+	}
+
+	if exp := claims.Time("exp"); exp.IsZero() || time.Now().After(exp) {
+		return nil, ErrExpiredToken
+	}
+
+	return claims, nil
+}
+
+// JWK is the JSON Web Key representation of an RSA public key.
+type JWK struct {
+	Kty string `json:"kty"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func NewJWK(kid string, key *rsa.PublicKey) JWK {
+	return JWK{
+		Kty: "RSA",
+		Alg: "RS256",
+		Use: "sig",
+		Kid: kid,
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func (k JWK) PublicKey() (*rsa.PublicKey, error) {
+	if k.Kty != "RSA" {
+		return nil, ErrInvalidToken
+	}
+
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
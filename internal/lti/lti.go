@@ -0,0 +1,314 @@
+package lti
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"flashcards-api.johndennehy101.tech/internal/jwt"
+)
+
+const (
+	claimMessageType  = "https://purl.imsglobal.org/spec/lti/claim/message_type"
+	claimVersion      = "https://purl.imsglobal.org/spec/lti/claim/version"
+	claimDeploymentID = "https://purl.imsglobal.org/spec/lti/claim/deployment_id"
+	claimContext      = "https://purl.imsglobal.org/spec/lti/claim/context"
+	claimAGS          = "https://purl.imsglobal.org/spec/lti-ags/claim/endpoint"
+
+	scopeScore = "https://purl.imsglobal.org/spec/lti-ags/scope/score"
+)
+
+var (
+	ErrInvalidLaunch = errors.New("invalid LTI launch")
+)
+
+// Platform describes the LMS (Moodle, Canvas, ...) the tool is registered with.
+type Platform struct {
+	Issuer       string
+	ClientID     string
+	DeploymentID string
+	AuthURL      string
+	TokenURL     string
+	JWKSURL      string
+}
+
+// Launch is the validated content of an LTI resource link launch.
+type Launch struct {
+	Subject     string
+	Name        string
+	Email       string
+	ContextID   string
+	LineItemURL string
+}
+
+type Tool struct {
+	platform Platform
+	key      *rsa.PrivateKey
+	kid      string
+	client   *http.Client
+
+	mu        sync.Mutex
+	jwks      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+// New creates a Tool for the platform, signing its own assertions with the
+// PEM-encoded RSA private key at keyFile.
+func New(platform Platform, keyFile string) (*Tool, error) {
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("lti: no PEM data found in %s", keyFile)
+	}
+
+	var key *rsa.PrivateKey
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		var parsed any
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err == nil {
+			var ok bool
+			if key, ok = parsed.(*rsa.PrivateKey); !ok {
+				err = errors.New("lti: private key is not an RSA key")
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tool{
+		platform: platform,
+		key:      key,
+		kid:      "flashcards-lti-1",
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// JWKS returns the tool's public key set, which the platform uses to verify
+// client assertions.
+func (t *Tool) JWKS() jwt.JWKS {
+	return jwt.JWKS{Keys: []jwt.JWK{jwt.NewJWK(t.kid, &t.key.PublicKey)}}
+}
+
+// LoginRedirect builds the OIDC authentication request URL that completes the
+// third-party initiated login.
+func (t *Tool) LoginRedirect(issuer, loginHint, messageHint, redirectURI, state, nonce string) (string, error) {
+	if issuer != t.platform.Issuer {
+		return "", ErrInvalidLaunch
+	}
+
+	qs := url.Values{}
+	qs.Set("scope", "openid")
+	qs.Set("response_type", "id_token")
+	qs.Set("response_mode", "form_post")
+	qs.Set("prompt", "none")
+	qs.Set("client_id", t.platform.ClientID)
+	qs.Set("redirect_uri", redirectURI)
+	qs.Set("login_hint", loginHint)
+	qs.Set("state", state)
+	qs.Set("nonce", nonce)
+	if messageHint != "" {
+		qs.Set("lti_message_hint", messageHint)
+	}
+
+	return t.platform.AuthURL + "?" + qs.Encode(), nil
+}
+
+// ValidateLaunch verifies the platform's id_token and extracts the launch.
+func (t *Tool) ValidateLaunch(idToken, nonce string) (*Launch, error) {
+	claims, err := jwt.VerifyRS256(idToken, t.platformKey)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case claims.String("iss") != t.platform.Issuer,
+		!slices.Contains(claims.Audience(), t.platform.ClientID),
+		claims.String("nonce") != nonce,
+		claims.String(claimVersion) != "1.3.0",
+		claims.String(claimMessageType) != "LtiResourceLinkRequest",
+		t.platform.DeploymentID != "" && claims.String(claimDeploymentID) != t.platform.DeploymentID,
+		claims.String("sub") == "":
+		return nil, ErrInvalidLaunch
+	}
+
+	launch := &Launch{
+		Subject: claims.String("sub"),
+		Name:    claims.String("name"),
+		Email:   claims.String("email"),
+	}
+
+	if ctx, ok := claims[claimContext].(map[string]any); ok {
+		launch.ContextID, _ = ctx["id"].(string)
+	}
+
+	if ags, ok := claims[claimAGS].(map[string]any); ok {
+		launch.LineItemURL, _ = ags["lineitem"].(string)
+	}
+
+	return launch, nil
+}
+
+func (t *Tool) platformKey(kid string) (*rsa.PublicKey, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if key, ok := t.jwks[kid]; ok && time.Since(t.fetchedAt) < time.Hour {
+		return key, nil
+	}
+
+	res, err := t.client.Get(t.platform.JWKSURL)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var set jwt.JWKS
+	err = json.NewDecoder(res.Body).Decode(&set)
+	if err != nil {
+		return nil, err
+	}
+
+	t.jwks = make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		key, err := k.PublicKey()
+		if err == nil {
+			t.jwks[k.Kid] = key
+		}
+	}
+	t.fetchedAt = time.Now()
+
+	key, ok := t.jwks[kid]
+	if !ok {
+		return nil, jwt.ErrInvalidToken
+	}
+
+	return key, nil
+}
+
+// Score is an Assignment and Grade Services score for a single learner.
+type Score struct {
+	UserID       string  `json:"userId"`
+	ScoreGiven   float64 `json:"scoreGiven"`
+	ScoreMaximum float64 `json:"scoreMaximum"`
+	Comment      string  `json:"comment,omitempty"`
+}
+
+// PostScore passes a grade back to the platform's line item.
+func (t *Tool) PostScore(lineItemURL string, score Score) error {
+	accessToken, err := t.accessToken()
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"userId":           score.UserID,
+		"scoreGiven":       score.ScoreGiven,
+		"scoreMaximum":     score.ScoreMaximum,
+		"comment":          score.Comment,
+		"activityProgress": "Completed",
+		"gradingProgress":  "FullyGraded",
+		"timestamp":        time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+
+	scoresURL, err := url.Parse(lineItemURL)
+	if err != nil {
+		return err
+	}
+	scoresURL.Path = strings.TrimSuffix(scoresURL.Path, "/") + "/scores"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, scoresURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/vnd.ims.lis.v1.score+json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	res, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("lti: score pass-back returned %d: %s", res.StatusCode, msg)
+	}
+
+	return nil
+}
+
+// accessToken obtains an AGS access token with the client credentials grant,
+// authenticating with a JWT signed by the tool key.
+func (t *Tool) accessToken() (string, error) {
+	now := time.Now()
+
+	assertion, err := jwt.SignRS256(jwt.Claims{
+		"iss": t.platform.ClientID,
+		"sub": t.platform.ClientID,
+		"aud": t.platform.TokenURL,
+		"iat": now.Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+		"jti": rand.Text(),
+	}, t.kid, t.key)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+	form.Set("client_assertion", assertion)
+	form.Set("scope", scopeScore)
+
+	res, err := t.client.PostForm(t.platform.TokenURL, form)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return "", fmt.Errorf("lti: token endpoint returned %d: %s", res.StatusCode, msg)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&token)
+	if err != nil {
+		return "", err
+	}
+
+	return token.AccessToken, nil
+}
@@ -0,0 +1,206 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidCode     = errors.New("invalid authorization code")
+	ErrUnverifiedEmail = errors.New("email address not verified")
+)
+
+// Identity is the account the user signed in to the provider with. Email is
+// always an address the provider has verified.
+type Identity struct {
+	Subject string
+	Name    string
+	Email   string
+}
+
+// Provider completes the authorization code flow with an identity provider.
+type Provider interface {
+	Exchange(ctx context.Context, code, redirectURI string) (*Identity, error)
+}
+
+type client struct {
+	http         *http.Client
+	clientID     string
+	clientSecret string
+}
+
+func newClient(clientID, clientSecret string) client {
+	return client{
+		http:         &http.Client{Timeout: 10 * time.Second},
+		clientID:     clientID,
+		clientSecret: clientSecret,
+	}
+}
+
+// accessToken exchanges the code at the provider's token endpoint. Providers
+// answer a bad or reused code with a 400 or, like GitHub, with an error
+// field, both reported as ErrInvalidCode.
+func (c client) accessToken(ctx context.Context, tokenURL, code, redirectURI string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURI)
+	form.Set("client_id", c.clientID)
+	form.Set("client_secret", c.clientSecret)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusBadRequest || res.StatusCode == http.StatusUnauthorized:
+		return "", ErrInvalidCode
+	case res.StatusCode >= 300:
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return "", fmt.Errorf("oauth: token endpoint returned %d: %s", res.StatusCode, msg)
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&token)
+	if err != nil {
+		return "", err
+	}
+
+	if token.Error != "" || token.AccessToken == "" {
+		return "", ErrInvalidCode
+	}
+
+	return token.AccessToken, nil
+}
+
+func (c client) getJSON(ctx context.Context, endpoint, accessToken string, dst any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("oauth: %s returned %d: %s", endpoint, res.StatusCode, msg)
+	}
+
+	return json.NewDecoder(res.Body).Decode(dst)
+}
+
+// Google signs users in with their Google account through OpenID Connect.
+type Google struct {
+	client
+}
+
+func NewGoogle(clientID, clientSecret string) *Google {
+	return &Google{newClient(clientID, clientSecret)}
+}
+
+func (p *Google) Exchange(ctx context.Context, code, redirectURI string) (*Identity, error) {
+	accessToken, err := p.accessToken(ctx, "https://oauth2.googleapis.com/token", code, redirectURI)
+	if err != nil {
+		return nil, err
+	}
+
+	var info struct {
+		Subject       string `json:"sub"`
+		Name          string `json:"name"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+	}
+
+	err = p.getJSON(ctx, "https://openidconnect.googleapis.com/v1/userinfo", accessToken, &info)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Email == "" || !info.EmailVerified {
+		return nil, ErrUnverifiedEmail
+	}
+
+	return &Identity{Subject: info.Subject, Name: info.Name, Email: info.Email}, nil
+}
+
+// GitHub signs users in with their GitHub account, using the primary email
+// address once GitHub has verified it.
+type GitHub struct {
+	client
+}
+
+func NewGitHub(clientID, clientSecret string) *GitHub {
+	return &GitHub{newClient(clientID, clientSecret)}
+}
+
+func (p *GitHub) Exchange(ctx context.Context, code, redirectURI string) (*Identity, error) {
+	accessToken, err := p.accessToken(ctx, "https://github.com/login/oauth/access_token", code, redirectURI)
+	if err != nil {
+		return nil, err
+	}
+
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+
+	err = p.getJSON(ctx, "https://api.github.com/user", accessToken, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+
+	err = p.getJSON(ctx, "https://api.github.com/user/emails", accessToken, &emails)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{Subject: strconv.FormatInt(user.ID, 10), Name: user.Name}
+	if identity.Name == "" {
+		identity.Name = user.Login
+	}
+
+	for _, e := range emails {
+		if e.Primary && e.Verified {
+			identity.Email = e.Email
+			return identity, nil
+		}
+	}
+
+	return nil, ErrUnverifiedEmail
+}
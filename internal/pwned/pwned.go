@@ -0,0 +1,165 @@
+package pwned
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Checker reports whether a password has appeared in a known data breach.
+type Checker interface {
+	Breached(ctx context.Context, password string) (bool, error)
+}
+
+func hashPassword(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// API checks passwords against the Have I Been Pwned range API. Only the
+// first five characters of the password's SHA-1 hash are sent; the matching
+// suffixes come back and are compared locally, so neither the password nor
+// its full hash leaves the server.
+type API struct {
+	client *http.Client
+	url    string
+}
+
+func NewAPI(url string) *API {
+	return &API{
+		client: &http.Client{Timeout: 5 * time.Second},
+		url:    strings.TrimSuffix(url, "/"),
+	}
+}
+
+func (c *API) Breached(ctx context.Context, password string) (bool, error) {
+	hash := hashPassword(password)
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+"/range/"+prefix, nil)
+	if err != nil {
+		return false, err
+	}
+
+	// Padding hides how many suffixes share the prefix from anyone watching
+	// the response sizes.
+	req.Header.Set("Add-Padding", "true")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return false, fmt.Errorf("pwned: range API returned %d: %s", res.StatusCode, msg)
+	}
+
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		s, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if ok && s == suffix && count != "0" {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
+
+// File checks passwords against a downloaded copy of the Pwned Passwords
+// list, for servers that can't or shouldn't call out. The file must hold
+// one uppercase SHA-1 hash per line, optionally followed by ":count", in
+// sorted order, as the official download tool writes it. Lookups binary
+// search the file rather than loading it.
+type File struct {
+	f    *os.File
+	size int64
+}
+
+func NewFile(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &File{f: f, size: info.Size()}, nil
+}
+
+func (c *File) Breached(ctx context.Context, password string) (bool, error) {
+	hash := hashPassword(password)
+
+	// Find the first line starting at or after each offset, narrowing the
+	// range until it holds the line that would come before the hash.
+	lo, hi := int64(0), c.size
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+
+		line, err := c.lineAfter(mid)
+		if err != nil {
+			return false, err
+		}
+
+		if line == "" || line > hash {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+
+	for _, offset := range []int64{lo, hi} {
+		line, err := c.lineAfter(offset)
+		if err != nil {
+			return false, err
+		}
+
+		if line == hash {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// lineAfter returns the hash on the first line that starts at or after
+// offset, or "" at the end of the file. Offset 0 is the start of the first
+// line.
+func (c *File) lineAfter(offset int64) (string, error) {
+	start := offset
+	if offset > 0 {
+		start = offset - 1
+	}
+
+	r := bufio.NewReader(io.NewSectionReader(c.f, start, c.size-start))
+
+	if offset > 0 {
+		_, err := r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				return "", nil
+			}
+			return "", err
+		}
+	}
+
+	line, err := r.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+
+	hash, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+	return strings.ToUpper(hash), nil
+}
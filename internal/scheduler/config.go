@@ -0,0 +1,46 @@
+package scheduler
+
+import "math"
+
+// Config tunes an algorithm's intervals, for decks that need a different
+// curve from the algorithm's own, such as exam-cram decks. Zero values keep
+// the algorithm's behaviour.
+type Config struct {
+	// GraduatingInterval is the interval in days after a card's first
+	// successful review.
+	GraduatingInterval int `json:"graduating_interval,omitzero"`
+
+	// EasyBonus multiplies the interval after a perfect recall.
+	EasyBonus float64 `json:"easy_bonus,omitzero"`
+
+	// MaxInterval caps every interval, in days.
+	MaxInterval int `json:"maximum_interval,omitzero"`
+
+	// LapseInterval is the share of its previous interval a forgotten card
+	// keeps, instead of starting again from a day.
+	LapseInterval float64 `json:"lapse_interval,omitzero"`
+}
+
+// adjust applies the config to the interval an algorithm worked out for a
+// review with the given grade. previous is the card's interval going into the
+// review, and first is set for cards never recalled before.
+func (c Config) adjust(interval, previous, grade int, first bool) int {
+	switch {
+	case grade < PassingGrade:
+		if c.LapseInterval > 0 && previous > 0 {
+			interval = int(math.Round(float64(previous) * c.LapseInterval))
+		}
+	case first && c.GraduatingInterval > 0:
+		interval = c.GraduatingInterval
+	}
+
+	if grade == MaxGrade && c.EasyBonus > 0 {
+		interval = int(math.Round(float64(interval) * c.EasyBonus))
+	}
+
+	if c.MaxInterval > 0 {
+		interval = min(interval, c.MaxInterval)
+	}
+
+	return max(interval, 1)
+}
@@ -0,0 +1,127 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+// FSRSWeights are the default FSRS-4.5 model parameters, fitted on a large
+// set of Anki review logs.
+var FSRSWeights = [17]float64{
+	0.4872, 1.4003, 3.7145, 13.8206, 5.1618, 1.2298, 0.8975, 0.031,
+	1.6474, 0.1367, 1.0461, 2.1072, 0.0793, 0.3246, 1.587, 0.2272, 2.8755,
+}
+
+const (
+	fsrsDecay  = -0.5
+	fsrsFactor = 19.0 / 81.0
+)
+
+// FSRS is the Free Spaced Repetition Scheduler. It models each card's memory
+// stability (the days until recall probability drops to 90%) and difficulty,
+// and schedules the next review for when recall probability reaches
+// Retention.
+//
+// FSRS rates reviews Again, Hard, Good or Easy. Grades below PassingGrade are
+// Again, and 3, 4 and 5 are Hard, Good and Easy.
+type FSRS struct {
+	Weights     [17]float64
+	Retention   float64
+	MaxInterval int
+	Config      Config
+}
+
+type FSRSState struct {
+	Stability  float64   `json:"stability"`
+	Difficulty float64   `json:"difficulty"`
+	Reps       int       `json:"reps"`
+	Lapses     int       `json:"lapses"`
+	LastReview time.Time `json:"last_review"`
+}
+
+func NewFSRS() FSRS {
+	return FSRS{Weights: FSRSWeights, Retention: 0.9, MaxInterval: 36500}
+}
+
+func (f FSRS) Review(state json.RawMessage, grade int, now time.Time) (Result, error) {
+	var s FSRSState
+
+	if state != nil {
+		err := json.Unmarshal(state, &s)
+		if err != nil {
+			return Result{}, err
+		}
+	}
+
+	rating := 1
+	if grade >= PassingGrade {
+		rating = grade - 1
+	}
+
+	w := f.Weights
+
+	// The state doesn't keep the interval, so the previous one is taken to
+	// be the time since the last review.
+	previous, first := 0, s.Reps == 0
+	if !first {
+		previous = int(math.Round(now.Sub(s.LastReview).Hours() / 24))
+	}
+
+	if s.Reps == 0 {
+		s.Stability = w[rating-1]
+
+		// A seeded difficulty stands for a card rated Good and moves
+		// with the rating from there.
+		if s.Difficulty > 0 {
+			s.Difficulty = clampDifficulty(s.Difficulty + f.initialDifficulty(rating) - f.initialDifficulty(3))
+		} else {
+			s.Difficulty = f.initialDifficulty(rating)
+		}
+	} else {
+		elapsed := max(now.Sub(s.LastReview).Hours()/24, 0)
+		r := math.Pow(1+fsrsFactor*elapsed/s.Stability, fsrsDecay)
+
+		if rating == 1 {
+			s.Stability = w[11] * math.Pow(s.Difficulty, -w[12]) * (math.Pow(s.Stability+1, w[13]) - 1) * math.Exp((1-r)*w[14])
+			s.Lapses++
+		} else {
+			bonus := 1.0
+			switch rating {
+			case 2:
+				bonus = w[15]
+			case 4:
+				bonus = w[16]
+			}
+
+			s.Stability *= 1 + math.Exp(w[8])*(11-s.Difficulty)*math.Pow(s.Stability, -w[9])*(math.Exp((1-r)*w[10])-1)*bonus
+		}
+
+		// Difficulty moves with the rating and reverts towards the
+		// difficulty of a card first rated Good.
+		d := s.Difficulty - w[6]*float64(rating-3)
+		s.Difficulty = clampDifficulty(w[7]*f.initialDifficulty(3) + (1-w[7])*d)
+	}
+
+	s.Reps++
+	s.LastReview = now
+
+	interval := int(math.Round(s.Stability / fsrsFactor * (math.Pow(f.Retention, 1/fsrsDecay) - 1)))
+	interval = f.Config.adjust(min(max(interval, 1), f.MaxInterval), previous, grade, first)
+
+	return result(s, interval, now)
+}
+
+// Seed starts the card with a difficulty from 1, for cards no one fails, to
+// 10 for cards everyone does.
+func (f FSRS) Seed(difficulty float64) (json.RawMessage, error) {
+	return json.Marshal(FSRSState{Difficulty: 1 + 9*difficulty})
+}
+
+func (f FSRS) initialDifficulty(rating int) float64 {
+	return clampDifficulty(f.Weights[4] - float64(rating-3)*f.Weights[5])
+}
+
+func clampDifficulty(d float64) float64 {
+	return min(max(d, 1), 10)
+}
@@ -0,0 +1,54 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DefaultLeitnerIntervals are the days between reviews of cards in each box,
+// doubling from one box to the next.
+var DefaultLeitnerIntervals = []int{1, 2, 4, 8, 16}
+
+// Leitner is the Leitner box system. Cards start in the first box and move up
+// a box each time they are recalled, to the last box at most, and back to the
+// first when they are not. Each box has a fixed review interval.
+type Leitner struct {
+	Intervals []int // days, one per box
+	Config    Config
+}
+
+type LeitnerState struct {
+	Box int `json:"box"`
+}
+
+func NewLeitner(intervals []int) Leitner {
+	return Leitner{Intervals: intervals}
+}
+
+func (l Leitner) Review(state json.RawMessage, grade int, now time.Time) (Result, error) {
+	s := LeitnerState{Box: 1}
+
+	if state != nil {
+		err := json.Unmarshal(state, &s)
+		if err != nil {
+			return Result{}, err
+		}
+	}
+
+	box := min(max(s.Box, 1), len(l.Intervals))
+	previous, first := 0, box == 1
+	if state != nil {
+		previous = l.Intervals[box-1]
+	}
+
+	if grade >= PassingGrade {
+		s.Box++
+	} else {
+		s.Box = 1
+	}
+
+	// The box count may have been lowered since the card was last reviewed.
+	s.Box = min(max(s.Box, 1), len(l.Intervals))
+
+	return result(s, l.Config.adjust(l.Intervals[s.Box-1], previous, grade, first), now)
+}
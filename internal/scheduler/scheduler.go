@@ -0,0 +1,126 @@
+// Package scheduler works out when a card should next be reviewed from how
+// well the learner recalled it. Each algorithm keeps its own per-card state,
+// which callers store as opaque JSON so algorithms can be mixed freely.
+package scheduler
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	MinGrade = 0
+	MaxGrade = 5
+
+	// PassingGrade is the lowest grade that counts as the card being
+	// recalled.
+	PassingGrade = 3
+
+	// Default is the algorithm used when neither the deck nor the user
+	// picks one.
+	Default = "sm2"
+)
+
+// Algorithms lists the names accepted by Set.Get.
+var Algorithms = []string{"sm2", "fsrs", "leitner"}
+
+// Ratings are the four self-assessment buttons offered to learners, from
+// forgotten to effortless recall.
+var Ratings = []string{"again", "hard", "good", "easy"}
+
+var ratingGrades = map[string]int{"again": 1, "hard": 3, "good": 4, "easy": 5}
+
+// RatingGrade returns the grade a rating stands for. Again fails the card;
+// hard, good and easy are passing grades of increasing ease, which each
+// algorithm turns into its own adjustment.
+func RatingGrade(rating string) (int, bool) {
+	grade, ok := ratingGrades[rating]
+	return grade, ok
+}
+
+// Project returns the interval in days each rating would give a card with the
+// given state if it were reviewed at now.
+func Project(s Scheduler, state json.RawMessage, now time.Time) (map[string]int, error) {
+	intervals := make(map[string]int, len(Ratings))
+
+	for _, rating := range Ratings {
+		res, err := s.Review(state, ratingGrades[rating], now)
+		if err != nil {
+			return nil, err
+		}
+
+		intervals[rating] = res.Interval
+	}
+
+	return intervals, nil
+}
+
+// Result is a card's schedule after a review.
+type Result struct {
+	State    json.RawMessage
+	Interval int // days
+	Due      time.Time
+}
+
+// Scheduler is a spaced repetition algorithm. Review is given the card's
+// state from its previous Result, or nil for a card it hasn't scheduled
+// before, and a grade from 0 (blackout) to 5 (perfect recall).
+type Scheduler interface {
+	Review(state json.RawMessage, grade int, now time.Time) (Result, error)
+}
+
+// Seeder is implemented by algorithms that can start a new card off according
+// to how hard learners find it.
+type Seeder interface {
+	// Seed returns the state of a new card failed by the given share of
+	// reviews, from 0 to 1.
+	Seed(difficulty float64) (json.RawMessage, error)
+}
+
+// Initial returns the state a new card starts with under s: seeded from its
+// measured difficulty when that is known and s is a Seeder, nil otherwise.
+func Initial(s Scheduler, difficulty *float32) (json.RawMessage, error) {
+	seeder, ok := s.(Seeder)
+	if !ok || difficulty == nil {
+		return nil, nil
+	}
+
+	return seeder.Seed(min(max(float64(*difficulty), 0), 1))
+}
+
+// Set holds the algorithms with their server-wide settings.
+type Set struct {
+	Leitner Leitner
+}
+
+func NewSet() Set {
+	return Set{Leitner: NewLeitner(DefaultLeitnerIntervals)}
+}
+
+// Get returns the algorithm with the given name, or the default one for an
+// empty name, tuned with cfg.
+func (s Set) Get(name string, cfg Config) (Scheduler, bool) {
+	switch name {
+	case "", "sm2":
+		return SM2{Config: cfg}, true
+	case "fsrs":
+		f := NewFSRS()
+		f.Config = cfg
+		return f, true
+	case "leitner":
+		l := s.Leitner
+		l.Config = cfg
+		return l, true
+	default:
+		return nil, false
+	}
+}
+
+func result(state any, interval int, now time.Time) (Result, error) {
+	js, err := json.Marshal(state)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{State: js, Interval: interval, Due: now.AddDate(0, 0, interval)}, nil
+}
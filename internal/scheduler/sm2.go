@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+const (
+	DefaultEase = 2.5
+	MinEase     = 1.3
+)
+
+// SM2 is the SuperMemo 2 algorithm: each card has an ease factor that grows
+// with easy recalls and shrinks with hard ones, and the interval is
+// multiplied by it after every successful review.
+type SM2 struct {
+	Config Config
+}
+
+type SM2State struct {
+	Ease        float64 `json:"ease_factor"`
+	Interval    int     `json:"interval_days"`
+	Repetitions int     `json:"repetitions"`
+}
+
+// Seed starts the card with an ease scaled from DefaultEase, for cards no one
+// fails, down to MinEase for cards everyone does, so hard cards come back
+// sooner from their third review on.
+func (m SM2) Seed(difficulty float64) (json.RawMessage, error) {
+	return json.Marshal(SM2State{Ease: DefaultEase - (DefaultEase-MinEase)*difficulty})
+}
+
+func (m SM2) Review(state json.RawMessage, grade int, now time.Time) (Result, error) {
+	s := SM2State{Ease: DefaultEase}
+
+	if state != nil {
+		err := json.Unmarshal(state, &s)
+		if err != nil {
+			return Result{}, err
+		}
+	}
+
+	previous, first := s.Interval, s.Repetitions == 0
+
+	if grade >= PassingGrade {
+		switch s.Repetitions {
+		case 0:
+			s.Interval = 1
+		case 1:
+			s.Interval = 6
+		default:
+			s.Interval = int(math.Round(float64(s.Interval) * s.Ease))
+		}
+		s.Repetitions++
+	} else {
+		s.Repetitions = 0
+		s.Interval = 1
+	}
+
+	s.Interval = m.Config.adjust(s.Interval, previous, grade, first)
+
+	q := float64(MaxGrade - grade)
+	s.Ease = max(s.Ease+0.1-q*(0.08+q*0.02), MinEase)
+
+	return result(s, s.Interval, now)
+}
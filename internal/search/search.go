@@ -0,0 +1,138 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Document is the flattened representation of a flashcard that is mirrored
+// into the external engine. Only the id is read back from search hits; the
+// full card is always hydrated from PostgreSQL.
+type Document struct {
+	ID            int64    `json:"id"`
+	Question      string   `json:"question"`
+	Text          string   `json:"text"`
+	Answer        string   `json:"answer,omitempty"`
+	Justification string   `json:"justification,omitempty"`
+	Section       string   `json:"section,omitempty"`
+	SourceFile    string   `json:"source_file,omitempty"`
+	Type          string   `json:"flashcard_type"`
+	Categories    []string `json:"categories"`
+}
+
+type Engine interface {
+	Index(docs ...Document) error
+	Delete(id int64) error
+	Search(query string, limit, offset int) ([]int64, int, error)
+}
+
+// Meilisearch is an Engine backed by the Meilisearch REST API.
+type Meilisearch struct {
+	client *http.Client
+	host   string
+	apiKey string
+	index  string
+}
+
+func NewMeilisearch(host, apiKey, index string) *Meilisearch {
+	return &Meilisearch{
+		client: &http.Client{Timeout: 5 * time.Second},
+		host:   host,
+		apiKey: apiKey,
+		index:  index,
+	}
+}
+
+func (m *Meilisearch) Index(docs ...Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	path := fmt.Sprintf("/indexes/%s/documents?primaryKey=id", url.PathEscape(m.index))
+
+	return m.do(http.MethodPost, path, docs, nil)
+}
+
+func (m *Meilisearch) Delete(id int64) error {
+	path := fmt.Sprintf("/indexes/%s/documents/%d", url.PathEscape(m.index), id)
+
+	return m.do(http.MethodDelete, path, nil, nil)
+}
+
+func (m *Meilisearch) Search(query string, limit, offset int) ([]int64, int, error) {
+	req := map[string]any{
+		"q":                    query,
+		"limit":                limit,
+		"offset":               offset,
+		"attributesToRetrieve": []string{"id"},
+	}
+
+	var resp struct {
+		Hits []struct {
+			ID int64 `json:"id"`
+		} `json:"hits"`
+		EstimatedTotalHits int `json:"estimatedTotalHits"`
+	}
+
+	path := fmt.Sprintf("/indexes/%s/search", url.PathEscape(m.index))
+
+	err := m.do(http.MethodPost, path, req, &resp)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]int64, len(resp.Hits))
+	for i, hit := range resp.Hits {
+		ids[i] = hit.ID
+	}
+
+	return ids, resp.EstimatedTotalHits, nil
+}
+
+func (m *Meilisearch) do(method, path string, body, dst any) error {
+	var reader io.Reader
+
+	if body != nil {
+		js, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(js)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, m.host+path, reader)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if m.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+m.apiKey)
+	}
+
+	res, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("meilisearch: %s %s returned %d: %s", method, path, res.StatusCode, msg)
+	}
+
+	if dst == nil {
+		return nil
+	}
+
+	return json.NewDecoder(res.Body).Decode(dst)
+}
@@ -0,0 +1,146 @@
+// Package slowquery wraps a database/sql connector so that statements taking
+// longer than a threshold are logged and counted.
+package slowquery
+
+import (
+	"context"
+	"database/sql/driver"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Wrap returns a connector whose connections time every query and exec, and
+// report those slower than threshold. Parameter values are never logged, only
+// their types, since they may hold emails, password hashes or tokens.
+func Wrap(c driver.Connector, threshold time.Duration, logger *slog.Logger, counter *expvar.Int) driver.Connector {
+	return &connector{Connector: c, threshold: threshold, logger: logger, counter: counter}
+}
+
+type connector struct {
+	driver.Connector
+	threshold time.Duration
+	logger    *slog.Logger
+	counter   *expvar.Int
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &watchedConn{Conn: conn, connector: c}, nil
+}
+
+func (c *connector) observe(ctx context.Context, query string, args []driver.NamedValue, start time.Time) {
+	duration := time.Since(start)
+	if duration < c.threshold {
+		return
+	}
+
+	c.counter.Add(1)
+
+	c.logger.WarnContext(ctx, "slow query",
+		"query", Normalize(query),
+		"duration", duration.String(),
+		"params", redact(args),
+	)
+}
+
+type watchedConn struct {
+	driver.Conn
+	connector *connector
+}
+
+func (wc *watchedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := wc.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	defer wc.connector.observe(ctx, query, args, time.Now())
+
+	return queryer.QueryContext(ctx, query, args)
+}
+
+func (wc *watchedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := wc.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	defer wc.connector.observe(ctx, query, args, time.Now())
+
+	return execer.ExecContext(ctx, query, args)
+}
+
+func (wc *watchedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := wc.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return wc.Conn.Prepare(query)
+}
+
+func (wc *watchedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := wc.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	//lint:ignore SA1019 drivers without BeginTx only offer Begin
+	return wc.Conn.Begin()
+}
+
+func (wc *watchedConn) Ping(ctx context.Context) error {
+	if pinger, ok := wc.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (wc *watchedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := wc.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (wc *watchedConn) IsValid() bool {
+	if validator, ok := wc.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+var (
+	whitespaceRX = regexp.MustCompile(`\s+`)
+	stringRX     = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numberRX     = regexp.MustCompile(`\$?\b\d+(?:\.\d+)?\b`)
+)
+
+// Normalize collapses whitespace and replaces inline literals with ? so that
+// the same statement always logs identically.
+func Normalize(query string) string {
+	query = stringRX.ReplaceAllString(query, "?")
+	query = numberRX.ReplaceAllStringFunc(query, func(n string) string {
+		// Leave $1-style placeholders alone.
+		if strings.HasPrefix(n, "$") {
+			return n
+		}
+		return "?"
+	})
+	query = whitespaceRX.ReplaceAllString(query, " ")
+	return strings.TrimSpace(query)
+}
+
+func redact(args []driver.NamedValue) string {
+	parts := make([]string, len(args))
+
+	for i, arg := range args {
+		parts[i] = fmt.Sprintf("$%d=%T", arg.Ordinal, arg.Value)
+	}
+
+	return strings.Join(parts, " ")
+}
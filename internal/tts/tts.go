@@ -0,0 +1,79 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Provider turns text into speech audio.
+type Provider interface {
+	Synthesize(text string) (audio []byte, contentType string, err error)
+}
+
+// OpenAI is a Provider for the OpenAI-compatible /v1/audio/speech API, which
+// is also implemented by several self-hosted TTS servers.
+type OpenAI struct {
+	client *http.Client
+	url    string
+	apiKey string
+	model  string
+	voice  string
+}
+
+func NewOpenAI(url, apiKey, model, voice string) *OpenAI {
+	return &OpenAI{
+		client: &http.Client{Timeout: 60 * time.Second},
+		url:    url,
+		apiKey: apiKey,
+		model:  model,
+		voice:  voice,
+	}
+}
+
+func (p *OpenAI) Synthesize(text string) ([]byte, string, error) {
+	body, err := json.Marshal(map[string]string{
+		"model":           p.model,
+		"voice":           p.voice,
+		"input":           text,
+		"response_format": "mp3",
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, "", err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return nil, "", fmt.Errorf("tts: provider returned %d: %s", res.StatusCode, msg)
+	}
+
+	audio, err := io.ReadAll(io.LimitReader(res.Body, 10<<20))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return audio, "audio/mpeg", nil
+}
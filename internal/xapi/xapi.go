@@ -0,0 +1,130 @@
+package xapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const version = "1.0.3"
+
+var (
+	VerbExperienced = Verb{ID: "http://adlnet.gov/expapi/verbs/experienced", Display: map[string]string{"en-US": "experienced"}}
+	VerbAnswered    = Verb{ID: "http://adlnet.gov/expapi/verbs/answered", Display: map[string]string{"en-US": "answered"}}
+	VerbPassed      = Verb{ID: "http://adlnet.gov/expapi/verbs/passed", Display: map[string]string{"en-US": "passed"}}
+	VerbFailed      = Verb{ID: "http://adlnet.gov/expapi/verbs/failed", Display: map[string]string{"en-US": "failed"}}
+	VerbCompleted   = Verb{ID: "http://adlnet.gov/expapi/verbs/completed", Display: map[string]string{"en-US": "completed"}}
+)
+
+type Actor struct {
+	ObjectType string `json:"objectType"`
+	Name       string `json:"name,omitempty"`
+	Mbox       string `json:"mbox"`
+}
+
+type Verb struct {
+	ID      string            `json:"id"`
+	Display map[string]string `json:"display"`
+}
+
+type Definition struct {
+	Name        map[string]string `json:"name,omitempty"`
+	Description map[string]string `json:"description,omitempty"`
+	Type        string            `json:"type,omitempty"`
+}
+
+type Activity struct {
+	ObjectType string      `json:"objectType"`
+	ID         string      `json:"id"`
+	Definition *Definition `json:"definition,omitempty"`
+}
+
+type Score struct {
+	Scaled float64 `json:"scaled"`
+	Raw    float64 `json:"raw,omitempty"`
+	Max    float64 `json:"max,omitempty"`
+}
+
+type Result struct {
+	Success    *bool  `json:"success,omitempty"`
+	Completion *bool  `json:"completion,omitempty"`
+	Response   string `json:"response,omitempty"`
+	Score      *Score `json:"score,omitempty"`
+}
+
+type Statement struct {
+	Actor     Actor     `json:"actor"`
+	Verb      Verb      `json:"verb"`
+	Object    Activity  `json:"object"`
+	Result    *Result   `json:"result,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func NewAgent(name, email string) Actor {
+	return Actor{ObjectType: "Agent", Name: name, Mbox: "mailto:" + email}
+}
+
+// Client posts statements to a Learning Record Store.
+type Client struct {
+	client   *http.Client
+	endpoint string
+	username string
+	password string
+}
+
+func New(endpoint, username, password string) *Client {
+	return &Client{
+		client:   &http.Client{Timeout: 10 * time.Second},
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		username: username,
+		password: password,
+	}
+}
+
+func (c *Client) Send(statements ...Statement) error {
+	body, err := json.Marshal(statements)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/statements", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Experience-API-Version", version)
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	for i := 1; i <= 3; i++ {
+		var res *http.Response
+
+		res, err = c.client.Do(req)
+		if err == nil {
+			msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+			res.Body.Close()
+
+			if res.StatusCode < 300 {
+				return nil
+			}
+			err = fmt.Errorf("xapi: LRS returned %d: %s", res.StatusCode, msg)
+		}
+
+		if i != 3 {
+			time.Sleep(500 * time.Millisecond)
+			req.Body, _ = req.GetBody()
+		}
+	}
+
+	return err
+}
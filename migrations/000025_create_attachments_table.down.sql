@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS attachments_flashcard_id_idx;
+DROP TABLE IF EXISTS attachments;
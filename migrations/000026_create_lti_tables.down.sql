@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS lti_launches;
+DROP TABLE IF EXISTS lti_users;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS lti_users (
+    issuer text NOT NULL,
+    subject text NOT NULL,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (issuer, subject)
+);
+
+CREATE TABLE IF NOT EXISTS lti_launches (
+    user_id bigint PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    issuer text NOT NULL,
+    subject text NOT NULL,
+    context_id text NOT NULL DEFAULT '',
+    lineitem_url text NOT NULL DEFAULT '',
+    launched_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
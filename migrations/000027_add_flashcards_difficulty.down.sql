@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS flashcards_difficulty_idx;
+ALTER TABLE flashcards DROP CONSTRAINT IF EXISTS flashcards_difficulty_check;
+ALTER TABLE flashcards DROP COLUMN IF EXISTS difficulty;
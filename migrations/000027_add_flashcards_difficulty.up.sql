@@ -0,0 +1,5 @@
+ALTER TABLE flashcards ADD COLUMN IF NOT EXISTS difficulty smallint;
+
+ALTER TABLE flashcards ADD CONSTRAINT flashcards_difficulty_check CHECK (difficulty BETWEEN 1 AND 5);
+
+CREATE INDEX IF NOT EXISTS flashcards_difficulty_idx ON flashcards (difficulty);
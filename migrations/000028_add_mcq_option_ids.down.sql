@@ -0,0 +1,14 @@
+UPDATE flashcards
+SET flashcard_content = (flashcard_content - 'options' - 'correct_option_id') || jsonb_build_object(
+    'options', (
+        SELECT jsonb_agg(t.value->'text' ORDER BY t.ord)
+        FROM jsonb_array_elements(flashcard_content->'options') WITH ORDINALITY AS t(value, ord)
+    ),
+    'correct_index', (
+        SELECT t.ord - 1
+        FROM jsonb_array_elements(flashcard_content->'options') WITH ORDINALITY AS t(value, ord)
+        WHERE t.value->>'id' = flashcard_content->>'correct_option_id'
+    )
+)
+WHERE flashcard_type = 'mcq'
+AND jsonb_typeof(flashcard_content->'options'->0) = 'object';
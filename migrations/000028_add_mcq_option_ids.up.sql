@@ -0,0 +1,10 @@
+UPDATE flashcards
+SET flashcard_content = (flashcard_content - 'options' - 'correct_index') || jsonb_build_object(
+    'options', (
+        SELECT jsonb_agg(jsonb_build_object('id', 'o' || t.ord, 'text', t.value) ORDER BY t.ord)
+        FROM jsonb_array_elements_text(flashcard_content->'options') WITH ORDINALITY AS t(value, ord)
+    ),
+    'correct_option_id', 'o' || ((flashcard_content->>'correct_index')::int + 1)
+)
+WHERE flashcard_type = 'mcq'
+AND jsonb_typeof(flashcard_content->'options'->0) = 'string';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS hook_subscriptions_user_event_idx;
+DROP TABLE IF EXISTS hook_subscriptions;
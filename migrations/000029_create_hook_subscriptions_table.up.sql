@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS hook_subscriptions (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event text NOT NULL,
+    target_url text NOT NULL,
+    secret text NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS hook_subscriptions_user_event_idx ON hook_subscriptions (user_id, event);
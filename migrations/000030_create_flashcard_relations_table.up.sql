@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS flashcard_relations (
+    flashcard_id bigint NOT NULL REFERENCES flashcards(id) ON DELETE CASCADE,
+    related_id bigint NOT NULL REFERENCES flashcards(id) ON DELETE CASCADE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (flashcard_id, related_id),
+    -- Relations are symmetric and stored once, lowest id first.
+    CONSTRAINT flashcard_relations_order_check CHECK (flashcard_id < related_id)
+);
+
+CREATE INDEX IF NOT EXISTS flashcard_relations_related_id_idx ON flashcard_relations (related_id);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS flashcard_prerequisites (
+    flashcard_id bigint NOT NULL REFERENCES flashcards(id) ON DELETE CASCADE,
+    prerequisite_id bigint NOT NULL REFERENCES flashcards(id) ON DELETE CASCADE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (flashcard_id, prerequisite_id),
+    CONSTRAINT flashcard_prerequisites_self_check CHECK (flashcard_id <> prerequisite_id)
+);
+
+CREATE INDEX IF NOT EXISTS flashcard_prerequisites_prerequisite_id_idx ON flashcard_prerequisites (prerequisite_id);
@@ -0,0 +1 @@
+ALTER TABLE flashcards ADD COLUMN IF NOT EXISTS hints text[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS flashcard_translations (
+    flashcard_id bigint NOT NULL REFERENCES flashcards(id) ON DELETE CASCADE,
+    language text NOT NULL,
+    question text NOT NULL,
+    -- The card type the content was written for; translations are ignored
+    -- once the card is changed to a different type.
+    flashcard_type text NOT NULL,
+    flashcard_content jsonb NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (flashcard_id, language)
+);
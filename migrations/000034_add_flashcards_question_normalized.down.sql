@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS flashcards_question_normalized_idx;
+ALTER TABLE flashcards DROP COLUMN IF EXISTS question_normalized;
+DROP FUNCTION IF EXISTS normalize_question(text);
@@ -0,0 +1,8 @@
+CREATE OR REPLACE FUNCTION normalize_question(q text) RETURNS text
+LANGUAGE sql IMMUTABLE PARALLEL SAFE
+AS $$ SELECT btrim(regexp_replace(lower(q), '[^[:alnum:]]+', ' ', 'g')) $$;
+
+ALTER TABLE flashcards
+    ADD COLUMN IF NOT EXISTS question_normalized text GENERATED ALWAYS AS (normalize_question(question)) STORED;
+
+CREATE INDEX IF NOT EXISTS flashcards_question_normalized_idx ON flashcards (question_normalized);
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS flashcard_templates (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL UNIQUE,
+    description text NOT NULL DEFAULT '',
+    section text,
+    section_type text,
+    categories text[] NOT NULL DEFAULT '{}',
+    flashcard_type text NOT NULL,
+    question text NOT NULL DEFAULT '',
+    text text NOT NULL DEFAULT '',
+    flashcard_content jsonb NOT NULL DEFAULT '{}',
+    version integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO flashcard_templates (name, description, section, section_type, categories, flashcard_type, question, text, flashcard_content) VALUES
+('Statutory definition', 'Definition of a term in an Act', 'Section {{section}}', 'section', '{"statute"}', 'qa',
+ 'How does the {{act}} define "{{term}}"?', '', '{"answer": "", "justification": "Section {{section}} of the {{act}}."}'),
+('Time limit', 'Period within which a step must be taken', '{{rule}}', 'rule', '{"time limits"}', 'numeric',
+ 'Within how many days must {{step}}?', '', '{"answer": 0, "unit": "days", "tolerance": 0, "justification": "{{rule}}."}'),
+('Leading case', 'Principle established by a leading case', NULL, NULL, '{"case law"}', 'qa',
+ 'What principle was established in {{case}}?', '', '{"answer": "", "justification": "{{case}}."}');
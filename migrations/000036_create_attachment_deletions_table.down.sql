@@ -0,0 +1,7 @@
+DELETE FROM permissions WHERE code = 'admin:write';
+
+DROP TRIGGER IF EXISTS attachments_queue_deletion ON attachments;
+
+DROP FUNCTION IF EXISTS queue_attachment_deletion();
+
+DROP TABLE IF EXISTS attachment_deletions;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS attachment_deletions (
+    storage_key text PRIMARY KEY,
+    deleted_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+-- Attachment rows also go when their flashcard is deleted (ON DELETE CASCADE),
+-- which leaves the blob behind in storage. Queue every removed key so the
+-- janitor can delete the blob.
+CREATE OR REPLACE FUNCTION queue_attachment_deletion() RETURNS trigger
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    INSERT INTO attachment_deletions (storage_key) VALUES (OLD.storage_key) ON CONFLICT DO NOTHING;
+    RETURN OLD;
+END;
+$$;
+
+CREATE TRIGGER attachments_queue_deletion
+    AFTER DELETE ON attachments
+    FOR EACH ROW EXECUTE FUNCTION queue_attachment_deletion();
+
+INSERT INTO permissions (code) VALUES ('admin:write');
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS flashcard_revisions (
+    flashcard_id bigint NOT NULL REFERENCES flashcards(id) ON DELETE CASCADE,
+    version integer NOT NULL,
+    question text NOT NULL,
+    flashcard_type text NOT NULL,
+    flashcard_content jsonb NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (flashcard_id, version)
+);
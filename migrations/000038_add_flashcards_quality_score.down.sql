@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS flashcards_quality_score_idx;
+
+ALTER TABLE flashcards DROP COLUMN IF EXISTS quality_score;
+
+DROP TABLE IF EXISTS flashcard_reports;
+
+DROP TABLE IF EXISTS flashcard_reviews;
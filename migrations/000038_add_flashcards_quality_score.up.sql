@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS flashcard_reviews (
+    id bigserial PRIMARY KEY,
+    flashcard_id bigint NOT NULL REFERENCES flashcards(id) ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    correct boolean NOT NULL,
+    latency_ms integer CHECK (latency_ms >= 0),
+    reviewed_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS flashcard_reviews_flashcard_id_idx ON flashcard_reviews (flashcard_id, reviewed_at);
+
+CREATE TABLE IF NOT EXISTS flashcard_reports (
+    id bigserial PRIMARY KEY,
+    flashcard_id bigint NOT NULL REFERENCES flashcards(id) ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason text NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    resolved_at timestamp(0) with time zone
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS flashcard_reports_open_idx ON flashcard_reports (flashcard_id, user_id) WHERE resolved_at IS NULL;
+
+ALTER TABLE flashcards ADD COLUMN IF NOT EXISTS quality_score real;
+
+CREATE INDEX IF NOT EXISTS flashcards_quality_score_idx ON flashcards (quality_score);
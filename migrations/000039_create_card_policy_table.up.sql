@@ -0,0 +1,11 @@
+-- Server-wide defaults and required fields for new flashcards. There is
+-- exactly one row.
+CREATE TABLE IF NOT EXISTS card_policy (
+    id boolean PRIMARY KEY DEFAULT true CHECK (id),
+    default_categories text[] NOT NULL DEFAULT '{}',
+    required_fields text[] NOT NULL DEFAULT '{}',
+    version integer NOT NULL DEFAULT 1,
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO card_policy DEFAULT VALUES ON CONFLICT DO NOTHING;
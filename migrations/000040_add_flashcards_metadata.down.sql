@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS flashcards_metadata_idx;
+
+ALTER TABLE flashcards DROP COLUMN IF EXISTS metadata;
+
+DROP TABLE IF EXISTS metadata_fields;
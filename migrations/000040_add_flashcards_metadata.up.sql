@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS metadata_fields (
+    name text PRIMARY KEY,
+    field_type text NOT NULL,
+    options text[] NOT NULL DEFAULT '{}',
+    required boolean NOT NULL DEFAULT false,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE flashcards ADD COLUMN IF NOT EXISTS metadata jsonb NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS flashcards_metadata_idx ON flashcards USING GIN (metadata jsonb_path_ops);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS flashcards_search_vector_idx;
+
+ALTER TABLE flashcards DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,9 @@
+ALTER TABLE flashcards ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', question), 'A') ||
+    setweight(to_tsvector('english', COALESCE(flashcard_content->>'answer', '')), 'B') ||
+    setweight(jsonb_to_tsvector('english', COALESCE(flashcard_content->'options', '[]'), '["string"]'), 'B') ||
+    setweight(to_tsvector('english', COALESCE(flashcard_content->>'justification', '')), 'C') ||
+    setweight(to_tsvector('english', text), 'D')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS flashcards_search_vector_idx ON flashcards USING GIN (search_vector);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name text NOT NULL,
+    query text NOT NULL DEFAULT '',
+    shared boolean NOT NULL DEFAULT false,
+    version integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS saved_searches_user_id_idx ON saved_searches (user_id);
+
+CREATE TABLE IF NOT EXISTS saved_search_subscriptions (
+    saved_search_id bigint NOT NULL REFERENCES saved_searches(id) ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    last_notified_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (saved_search_id, user_id)
+);
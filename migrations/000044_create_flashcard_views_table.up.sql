@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS flashcard_views (
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    flashcard_id bigint NOT NULL REFERENCES flashcards(id) ON DELETE CASCADE,
+    seen_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, flashcard_id)
+);
+
+CREATE INDEX IF NOT EXISTS flashcard_views_user_seen_at_idx ON flashcard_views (user_id, seen_at DESC);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS flashcard_embeddings;
+
+DROP EXTENSION IF EXISTS vector;
@@ -0,0 +1,21 @@
+-- Semantic search is opt-in: the table is only created where the pgvector
+-- extension is available. The column is left without a fixed dimension so
+-- any embedding model can be used, which rules out an ANN index; nearest
+-- neighbours are found with a scan over the configured model's rows.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = 'vector') THEN
+        CREATE EXTENSION IF NOT EXISTS vector;
+
+        CREATE TABLE IF NOT EXISTS flashcard_embeddings (
+            flashcard_id bigint NOT NULL REFERENCES flashcards(id) ON DELETE CASCADE,
+            model text NOT NULL,
+            embedding vector NOT NULL,
+            created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+            PRIMARY KEY (flashcard_id, model)
+        );
+
+        CREATE INDEX IF NOT EXISTS flashcard_embeddings_model_idx ON flashcard_embeddings (model);
+    END IF;
+END
+$$;
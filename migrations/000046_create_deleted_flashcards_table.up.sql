@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS deleted_flashcards (
+    flashcard_id bigint PRIMARY KEY,
+    deleted_by bigint REFERENCES users(id) ON DELETE SET NULL,
+    card jsonb NOT NULL,
+    deleted_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS deleted_flashcards_deleted_by_idx ON deleted_flashcards (deleted_by, deleted_at DESC);
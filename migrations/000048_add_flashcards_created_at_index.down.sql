@@ -0,0 +1 @@
+DROP INDEX IF EXISTS flashcards_created_at_id_idx;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS flashcards_created_at_id_idx ON flashcards (created_at, id);
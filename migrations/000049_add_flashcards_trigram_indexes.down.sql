@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS flashcards_section_trgm_idx;
+DROP INDEX IF EXISTS flashcards_question_trgm_idx;
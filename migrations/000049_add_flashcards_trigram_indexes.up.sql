@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS flashcards_question_trgm_idx ON flashcards USING GIN (question gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS flashcards_section_trgm_idx ON flashcards USING GIN (section gin_trgm_ops);
@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS flashcards_sync_categories ON flashcards;
+
+DROP FUNCTION IF EXISTS sync_flashcard_categories();
+
+DROP TABLE IF EXISTS flashcard_categories;
+
+DROP TABLE IF EXISTS categories;
@@ -0,0 +1,50 @@
+CREATE TABLE IF NOT EXISTS categories (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL UNIQUE,
+    description text NOT NULL DEFAULT '',
+    version integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS flashcard_categories (
+    flashcard_id bigint NOT NULL REFERENCES flashcards(id) ON DELETE CASCADE,
+    category_id bigint NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    PRIMARY KEY (flashcard_id, category_id)
+);
+
+CREATE INDEX IF NOT EXISTS flashcard_categories_category_id_idx ON flashcard_categories (category_id);
+
+-- flashcards.categories stays the column the listing filters on. Keep the
+-- categories table and the join rows in step with it on every write, creating
+-- a category the first time a card uses its name.
+CREATE OR REPLACE FUNCTION sync_flashcard_categories() RETURNS trigger
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    INSERT INTO categories (name)
+    SELECT DISTINCT unnest(NEW.categories)
+    ON CONFLICT (name) DO NOTHING;
+
+    DELETE FROM flashcard_categories fc
+    USING categories c
+    WHERE fc.flashcard_id = NEW.id AND c.id = fc.category_id AND NOT c.name = ANY(NEW.categories);
+
+    INSERT INTO flashcard_categories (flashcard_id, category_id)
+    SELECT NEW.id, c.id FROM categories c WHERE c.name = ANY(NEW.categories)
+    ON CONFLICT DO NOTHING;
+
+    RETURN NULL;
+END;
+$$;
+
+CREATE TRIGGER flashcards_sync_categories
+    AFTER INSERT OR UPDATE OF categories ON flashcards
+    FOR EACH ROW EXECUTE FUNCTION sync_flashcard_categories();
+
+INSERT INTO categories (name)
+SELECT DISTINCT unnest(categories) FROM flashcards
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO flashcard_categories (flashcard_id, category_id)
+SELECT f.id, c.id FROM flashcards f JOIN categories c ON c.name = ANY(f.categories)
+ON CONFLICT DO NOTHING;
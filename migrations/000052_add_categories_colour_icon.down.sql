@@ -0,0 +1,3 @@
+ALTER TABLE categories DROP COLUMN IF EXISTS icon;
+
+ALTER TABLE categories DROP COLUMN IF EXISTS colour;
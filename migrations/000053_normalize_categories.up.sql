@@ -0,0 +1,48 @@
+CREATE OR REPLACE FUNCTION normalize_category(name text) RETURNS text
+LANGUAGE sql IMMUTABLE
+AS $$ SELECT lower(regexp_replace(regexp_replace(name, '^\s+|\s+$', '', 'g'), '\s+', ' ', 'g')) $$;
+
+CREATE OR REPLACE FUNCTION normalize_categories(names text[]) RETURNS text[]
+LANGUAGE sql IMMUTABLE
+AS $$
+    SELECT ARRAY(
+        SELECT normalize_category(c)
+        FROM unnest(names) WITH ORDINALITY AS t(c, ord)
+        WHERE normalize_category(c) <> ''
+        GROUP BY normalize_category(c)
+        ORDER BY min(ord)
+    )
+$$;
+
+-- Keep the oldest category of each group that normalizes to the same name.
+-- The join rows of the others are rebuilt at the end.
+DELETE FROM categories c
+USING categories keep
+WHERE normalize_category(c.name) = normalize_category(keep.name)
+AND keep.id < c.id;
+
+DELETE FROM categories WHERE normalize_category(name) = '';
+
+UPDATE categories SET name = normalize_category(name)
+WHERE name <> normalize_category(name);
+
+UPDATE flashcards SET categories = normalize_categories(categories)
+WHERE categories <> normalize_categories(categories);
+
+UPDATE flashcard_templates SET categories = normalize_categories(categories)
+WHERE categories <> normalize_categories(categories);
+
+UPDATE deleted_flashcards
+SET card = jsonb_set(card, '{categories}', to_jsonb(normalize_categories(ARRAY(SELECT jsonb_array_elements_text(card->'categories')))))
+WHERE jsonb_typeof(card->'categories') = 'array';
+
+INSERT INTO flashcard_categories (flashcard_id, category_id)
+SELECT f.id, c.id FROM flashcards f JOIN categories c ON c.name = ANY(f.categories)
+ON CONFLICT DO NOTHING;
+
+DROP FUNCTION normalize_categories(text[]);
+
+DROP FUNCTION normalize_category(text);
+
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS slug text
+    GENERATED ALWAYS AS (btrim(regexp_replace(name, '[^[:alnum:]]+', '-', 'g'), '-')) STORED;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS deck_flashcards;
+
+DROP TABLE IF EXISTS decks;
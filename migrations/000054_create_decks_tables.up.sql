@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS decks (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name text NOT NULL,
+    description text NOT NULL DEFAULT '',
+    version integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    CONSTRAINT decks_user_id_name_key UNIQUE (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS deck_flashcards (
+    deck_id bigint NOT NULL REFERENCES decks(id) ON DELETE CASCADE,
+    flashcard_id bigint NOT NULL REFERENCES flashcards(id) ON DELETE CASCADE,
+    added_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (deck_id, flashcard_id)
+);
+
+CREATE INDEX IF NOT EXISTS deck_flashcards_flashcard_id_idx ON deck_flashcards (flashcard_id);
@@ -0,0 +1,3 @@
+ALTER TABLE decks DROP COLUMN IF EXISTS share_token_hash;
+
+ALTER TABLE decks DROP COLUMN IF EXISTS visibility;
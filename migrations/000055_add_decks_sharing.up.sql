@@ -0,0 +1,4 @@
+ALTER TABLE decks ADD COLUMN IF NOT EXISTS visibility text NOT NULL DEFAULT 'private'
+    CHECK (visibility IN ('private', 'public'));
+
+ALTER TABLE decks ADD COLUMN IF NOT EXISTS share_token_hash bytea UNIQUE;
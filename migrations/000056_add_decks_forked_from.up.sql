@@ -0,0 +1 @@
+ALTER TABLE decks ADD COLUMN IF NOT EXISTS forked_from bigint REFERENCES decks(id) ON DELETE SET NULL;
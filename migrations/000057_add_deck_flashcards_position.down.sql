@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS deck_flashcards_deck_id_position_idx;
+
+ALTER TABLE deck_flashcards DROP COLUMN IF EXISTS position;
@@ -0,0 +1,13 @@
+ALTER TABLE deck_flashcards ADD COLUMN IF NOT EXISTS position integer;
+
+UPDATE deck_flashcards df
+SET position = ranked.position
+FROM (
+    SELECT deck_id, flashcard_id, row_number() OVER (PARTITION BY deck_id ORDER BY added_at, flashcard_id) AS position
+    FROM deck_flashcards
+) ranked
+WHERE df.deck_id = ranked.deck_id AND df.flashcard_id = ranked.flashcard_id;
+
+ALTER TABLE deck_flashcards ALTER COLUMN position SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS deck_flashcards_deck_id_position_idx ON deck_flashcards (deck_id, position);
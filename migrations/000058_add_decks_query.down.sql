@@ -0,0 +1 @@
+ALTER TABLE decks DROP COLUMN IF EXISTS query;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS deck_invitations;
+
+DROP TABLE IF EXISTS deck_members;
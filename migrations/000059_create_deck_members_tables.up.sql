@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS deck_members (
+    deck_id bigint NOT NULL REFERENCES decks(id) ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role text NOT NULL CHECK (role IN ('editor', 'viewer')),
+    added_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (deck_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS deck_members_user_id_idx ON deck_members (user_id);
+
+CREATE TABLE IF NOT EXISTS deck_invitations (
+    id bigserial PRIMARY KEY,
+    deck_id bigint NOT NULL REFERENCES decks(id) ON DELETE CASCADE,
+    email citext NOT NULL,
+    role text NOT NULL CHECK (role IN ('editor', 'viewer')),
+    token_hash bytea NOT NULL UNIQUE,
+    expires_at timestamp(0) with time zone NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    CONSTRAINT deck_invitations_deck_id_email_key UNIQUE (deck_id, email)
+);
@@ -0,0 +1 @@
+ALTER TABLE decks ADD COLUMN IF NOT EXISTS archived_at timestamp(0) with time zone;
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS default_deck_id bigint REFERENCES decks(id) ON DELETE SET NULL;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS card_reviews;
+
+DROP TABLE IF EXISTS card_scheduling;
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS card_scheduling (
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    flashcard_id bigint NOT NULL REFERENCES flashcards(id) ON DELETE CASCADE,
+    ease_factor real NOT NULL,
+    interval_days integer NOT NULL,
+    repetitions integer NOT NULL,
+    due_at timestamp(0) with time zone NOT NULL,
+    last_reviewed_at timestamp(0) with time zone NOT NULL,
+    PRIMARY KEY (user_id, flashcard_id)
+);
+
+CREATE INDEX IF NOT EXISTS card_scheduling_due_idx ON card_scheduling (user_id, due_at);
+
+CREATE TABLE IF NOT EXISTS card_reviews (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    flashcard_id bigint NOT NULL REFERENCES flashcards(id) ON DELETE CASCADE,
+    grade smallint NOT NULL CHECK (grade BETWEEN 0 AND 5),
+    ease_factor real NOT NULL,
+    interval_days integer NOT NULL,
+    due_at timestamp(0) with time zone NOT NULL,
+    reviewed_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS card_reviews_user_id_idx ON card_reviews (user_id, reviewed_at);
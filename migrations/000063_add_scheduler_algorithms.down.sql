@@ -0,0 +1,23 @@
+ALTER TABLE decks DROP COLUMN IF EXISTS scheduler;
+ALTER TABLE users DROP COLUMN IF EXISTS scheduler;
+
+ALTER TABLE card_reviews ADD COLUMN IF NOT EXISTS ease_factor real;
+
+UPDATE card_reviews
+SET ease_factor = COALESCE((state->>'ease_factor')::real, 2.5);
+
+ALTER TABLE card_reviews ALTER COLUMN ease_factor SET NOT NULL;
+ALTER TABLE card_reviews DROP COLUMN IF EXISTS state;
+ALTER TABLE card_reviews DROP COLUMN IF EXISTS algorithm;
+
+ALTER TABLE card_scheduling ADD COLUMN IF NOT EXISTS ease_factor real;
+ALTER TABLE card_scheduling ADD COLUMN IF NOT EXISTS repetitions integer;
+
+UPDATE card_scheduling
+SET ease_factor = COALESCE((state->>'ease_factor')::real, 2.5),
+    repetitions = COALESCE((state->>'repetitions')::integer, (state->>'reps')::integer, 0);
+
+ALTER TABLE card_scheduling ALTER COLUMN ease_factor SET NOT NULL;
+ALTER TABLE card_scheduling ALTER COLUMN repetitions SET NOT NULL;
+ALTER TABLE card_scheduling DROP COLUMN IF EXISTS state;
+ALTER TABLE card_scheduling DROP COLUMN IF EXISTS algorithm;
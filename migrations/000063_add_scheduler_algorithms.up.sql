@@ -0,0 +1,21 @@
+ALTER TABLE card_scheduling ADD COLUMN IF NOT EXISTS algorithm text NOT NULL DEFAULT 'sm2';
+ALTER TABLE card_scheduling ADD COLUMN IF NOT EXISTS state jsonb;
+
+UPDATE card_scheduling
+SET state = jsonb_build_object('ease_factor', ease_factor, 'interval_days', interval_days, 'repetitions', repetitions);
+
+ALTER TABLE card_scheduling ALTER COLUMN state SET NOT NULL;
+ALTER TABLE card_scheduling DROP COLUMN IF EXISTS ease_factor;
+ALTER TABLE card_scheduling DROP COLUMN IF EXISTS repetitions;
+
+ALTER TABLE card_reviews ADD COLUMN IF NOT EXISTS algorithm text NOT NULL DEFAULT 'sm2';
+ALTER TABLE card_reviews ADD COLUMN IF NOT EXISTS state jsonb;
+
+UPDATE card_reviews
+SET state = jsonb_build_object('ease_factor', ease_factor, 'interval_days', interval_days);
+
+ALTER TABLE card_reviews ALTER COLUMN state SET NOT NULL;
+ALTER TABLE card_reviews DROP COLUMN IF EXISTS ease_factor;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS scheduler text NOT NULL DEFAULT '';
+ALTER TABLE decks ADD COLUMN IF NOT EXISTS scheduler text;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS study_session_answers;
+
+DROP TABLE IF EXISTS study_sessions;
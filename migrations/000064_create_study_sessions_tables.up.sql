@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS study_sessions (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    deck_id bigint REFERENCES decks(id) ON DELETE SET NULL,
+    started_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    completed_at timestamp(0) with time zone
+);
+
+CREATE INDEX IF NOT EXISTS study_sessions_user_id_idx ON study_sessions (user_id, started_at);
+
+CREATE TABLE IF NOT EXISTS study_session_answers (
+    id bigserial PRIMARY KEY,
+    session_id bigint NOT NULL REFERENCES study_sessions(id) ON DELETE CASCADE,
+    flashcard_id bigint NOT NULL REFERENCES flashcards(id) ON DELETE CASCADE,
+    response jsonb,
+    correct boolean NOT NULL,
+    latency_ms integer CHECK (latency_ms >= 0),
+    answered_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS study_session_answers_session_id_idx ON study_session_answers (session_id, id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS exam_questions;
+
+DROP TABLE IF EXISTS exams;
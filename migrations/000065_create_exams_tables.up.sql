@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS exams (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    deadline timestamp(0) with time zone NOT NULL,
+    submitted_at timestamp(0) with time zone,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS exams_user_id_idx ON exams (user_id, created_at);
+
+CREATE TABLE IF NOT EXISTS exam_questions (
+    exam_id bigint NOT NULL REFERENCES exams(id) ON DELETE CASCADE,
+    position integer NOT NULL,
+    flashcard_id bigint NOT NULL REFERENCES flashcards(id) ON DELETE CASCADE,
+    category text NOT NULL DEFAULT '',
+    answer jsonb,
+    correct boolean,
+    PRIMARY KEY (exam_id, position),
+    UNIQUE (exam_id, flashcard_id)
+);
@@ -0,0 +1,9 @@
+DELETE FROM card_scheduling WHERE state IS NULL;
+
+ALTER TABLE card_scheduling ALTER COLUMN last_reviewed_at SET NOT NULL;
+ALTER TABLE card_scheduling ALTER COLUMN due_at SET NOT NULL;
+ALTER TABLE card_scheduling ALTER COLUMN interval_days SET NOT NULL;
+ALTER TABLE card_scheduling ALTER COLUMN state SET NOT NULL;
+
+ALTER TABLE card_scheduling DROP COLUMN IF EXISTS buried_until;
+ALTER TABLE card_scheduling DROP COLUMN IF EXISTS suspended;
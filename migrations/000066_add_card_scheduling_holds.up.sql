@@ -0,0 +1,7 @@
+ALTER TABLE card_scheduling ADD COLUMN IF NOT EXISTS suspended boolean NOT NULL DEFAULT false;
+ALTER TABLE card_scheduling ADD COLUMN IF NOT EXISTS buried_until timestamp(0) with time zone;
+
+ALTER TABLE card_scheduling ALTER COLUMN state DROP NOT NULL;
+ALTER TABLE card_scheduling ALTER COLUMN interval_days DROP NOT NULL;
+ALTER TABLE card_scheduling ALTER COLUMN due_at DROP NOT NULL;
+ALTER TABLE card_scheduling ALTER COLUMN last_reviewed_at DROP NOT NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS reviews_per_day;
+ALTER TABLE users DROP COLUMN IF EXISTS new_per_day;
@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS new_per_day integer;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS reviews_per_day integer;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS card_reviews_flashcard_id_idx;
+
+ALTER TABLE card_reviews DROP COLUMN IF EXISTS latency_ms;
+ALTER TABLE card_reviews DROP COLUMN IF EXISTS previous_interval_days;
@@ -0,0 +1,4 @@
+ALTER TABLE card_reviews ADD COLUMN IF NOT EXISTS previous_interval_days integer;
+ALTER TABLE card_reviews ADD COLUMN IF NOT EXISTS latency_ms integer;
+
+CREATE INDEX IF NOT EXISTS card_reviews_flashcard_id_idx ON card_reviews (user_id, flashcard_id, reviewed_at);
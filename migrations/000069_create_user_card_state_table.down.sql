@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS user_card_state_state_due_idx;
+
+ALTER TABLE user_card_state DROP COLUMN IF EXISTS lapses;
+ALTER TABLE user_card_state DROP COLUMN IF EXISTS ease;
+ALTER TABLE user_card_state DROP COLUMN IF EXISTS state;
+
+ALTER INDEX user_card_state_due_idx RENAME TO card_scheduling_due_idx;
+ALTER TABLE user_card_state RENAME COLUMN scheduler_state TO state;
+ALTER TABLE user_card_state RENAME TO card_scheduling;
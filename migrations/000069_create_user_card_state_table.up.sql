@@ -0,0 +1,23 @@
+ALTER TABLE card_scheduling RENAME TO user_card_state;
+ALTER TABLE user_card_state RENAME COLUMN state TO scheduler_state;
+ALTER INDEX card_scheduling_due_idx RENAME TO user_card_state_due_idx;
+
+ALTER TABLE user_card_state ADD COLUMN IF NOT EXISTS state text NOT NULL DEFAULT 'new'
+    CHECK (state IN ('new', 'learning', 'review', 'relearning'));
+ALTER TABLE user_card_state ADD COLUMN IF NOT EXISTS ease real;
+ALTER TABLE user_card_state ADD COLUMN IF NOT EXISTS lapses integer NOT NULL DEFAULT 0;
+
+UPDATE user_card_state s
+SET state = CASE WHEN s.interval_days > 1 THEN 'review' ELSE 'learning' END,
+    ease = (s.scheduler_state->>'ease_factor')::real,
+    lapses = (
+        SELECT count(*) FROM card_reviews r
+        WHERE r.user_id = s.user_id AND r.flashcard_id = s.flashcard_id AND r.grade < 3
+        AND EXISTS (
+            SELECT 1 FROM card_reviews p
+            WHERE p.user_id = r.user_id AND p.flashcard_id = r.flashcard_id AND p.reviewed_at < r.reviewed_at AND p.grade >= 3
+        )
+    )
+WHERE s.scheduler_state IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS user_card_state_state_due_idx ON user_card_state (user_id, state, due_at);
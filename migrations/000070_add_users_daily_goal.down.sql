@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS daily_goal_unit;
+ALTER TABLE users DROP COLUMN IF EXISTS daily_goal;
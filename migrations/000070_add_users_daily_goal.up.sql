@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS daily_goal integer NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS daily_goal_unit text NOT NULL DEFAULT 'cards'
+    CHECK (daily_goal_unit IN ('cards', 'minutes'));
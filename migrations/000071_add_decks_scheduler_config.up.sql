@@ -0,0 +1 @@
+ALTER TABLE decks ADD COLUMN IF NOT EXISTS scheduler_config jsonb;
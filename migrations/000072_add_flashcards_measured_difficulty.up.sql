@@ -0,0 +1 @@
+ALTER TABLE flashcards ADD COLUMN IF NOT EXISTS measured_difficulty real;
@@ -0,0 +1,3 @@
+ALTER TABLE exam_questions DROP COLUMN IF EXISTS answer_ms;
+
+ALTER TABLE exams DROP COLUMN IF EXISTS question_time_limit_ms;
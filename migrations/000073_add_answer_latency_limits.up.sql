@@ -0,0 +1,3 @@
+ALTER TABLE exams ADD COLUMN IF NOT EXISTS question_time_limit_ms integer;
+
+ALTER TABLE exam_questions ADD COLUMN IF NOT EXISTS answer_ms integer CHECK (answer_ms >= 0);
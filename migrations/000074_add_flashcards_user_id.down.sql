@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS flashcards_user_id_idx;
+
+ALTER TABLE flashcards DROP COLUMN IF EXISTS user_id;
@@ -0,0 +1,3 @@
+ALTER TABLE flashcards ADD COLUMN IF NOT EXISTS user_id bigint REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS flashcards_user_id_idx ON flashcards (user_id);
@@ -0,0 +1,9 @@
+INSERT INTO users_permissions (user_id, permission_id)
+SELECT DISTINCT ur.user_id, rp.permission_id
+FROM users_roles ur
+INNER JOIN roles_permissions rp ON rp.role_id = ur.role_id
+ON CONFLICT DO NOTHING;
+
+DROP TABLE IF EXISTS users_roles;
+DROP TABLE IF EXISTS roles_permissions;
+DROP TABLE IF EXISTS roles;
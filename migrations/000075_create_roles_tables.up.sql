@@ -0,0 +1,49 @@
+CREATE TABLE IF NOT EXISTS roles (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS roles_permissions (
+    role_id bigint NOT NULL REFERENCES roles ON DELETE CASCADE,
+    permission_id bigint NOT NULL REFERENCES permissions ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE IF NOT EXISTS users_roles (
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    role_id bigint NOT NULL REFERENCES roles ON DELETE CASCADE,
+    granted_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, role_id)
+);
+
+INSERT INTO roles (name)
+VALUES ('admin'), ('editor'), ('reader');
+
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+INNER JOIN permissions p ON
+    (r.name = 'admin' AND p.code IN ('flashcards:read', 'flashcards:write', 'admin:write')) OR
+    (r.name = 'editor' AND p.code IN ('flashcards:read', 'flashcards:write')) OR
+    (r.name = 'reader' AND p.code = 'flashcards:read');
+
+-- Give every user the broadest role their direct grants add up to, then drop
+-- the grants the role covers so that revoking it takes them away.
+INSERT INTO users_roles (user_id, role_id)
+SELECT up.user_id, r.id
+FROM (
+    SELECT up.user_id,
+        CASE
+            WHEN bool_or(p.code = 'admin:write') THEN 'admin'
+            WHEN bool_or(p.code = 'flashcards:write') THEN 'editor'
+            ELSE 'reader'
+        END AS role
+    FROM users_permissions up
+    INNER JOIN permissions p ON p.id = up.permission_id
+    GROUP BY up.user_id
+) up
+INNER JOIN roles r ON r.name = up.role;
+
+DELETE FROM users_permissions up
+USING users_roles ur, roles_permissions rp
+WHERE ur.user_id = up.user_id AND rp.role_id = ur.role_id AND rp.permission_id = up.permission_id;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS email_changes (
+    user_id bigint PRIMARY KEY REFERENCES users ON DELETE CASCADE,
+    email citext NOT NULL,
+    token_hash bytea NOT NULL UNIQUE,
+    expires_at timestamp(0) with time zone NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS data_exports_queue_deletion ON data_exports;
+DROP FUNCTION IF EXISTS queue_data_export_deletion();
+DROP TABLE IF EXISTS data_exports;
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS data_exports (
+    user_id bigint PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    status text NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'ready', 'failed')),
+    storage_key text,
+    size_bytes bigint,
+    error text,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    completed_at timestamp(0) with time zone,
+    expires_at timestamp(0) with time zone
+);
+
+CREATE INDEX IF NOT EXISTS data_exports_expires_at_idx ON data_exports (expires_at);
+
+-- Archives are replaced by the next export, expire, and go with the account.
+-- Queue the old blob with the attachment ones so the janitor deletes it.
+CREATE OR REPLACE FUNCTION queue_data_export_deletion() RETURNS trigger
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    IF OLD.storage_key IS NOT NULL AND (TG_OP = 'DELETE' OR NEW.storage_key IS DISTINCT FROM OLD.storage_key) THEN
+        INSERT INTO attachment_deletions (storage_key) VALUES (OLD.storage_key) ON CONFLICT DO NOTHING;
+    END IF;
+    RETURN OLD;
+END;
+$$;
+
+CREATE TRIGGER data_exports_queue_deletion
+    AFTER DELETE OR UPDATE OF storage_key ON data_exports
+    FOR EACH ROW EXECUTE FUNCTION queue_data_export_deletion();
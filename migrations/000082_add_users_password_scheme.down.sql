@@ -0,0 +1,13 @@
+-- The code before this migration only verifies bcrypt, so users whose
+-- password has since been rehashed with Argon2id couldn't log in after a
+-- rollback. Refuse to roll back while any such hash exists; those users have
+-- to reset their password first.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM users WHERE password_scheme = 'argon2id') THEN
+        RAISE EXCEPTION 'users have Argon2id password hashes that bcrypt-only code cannot verify';
+    END IF;
+END
+$$;
+
+ALTER TABLE users DROP COLUMN IF EXISTS password_scheme;
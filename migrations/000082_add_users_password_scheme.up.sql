@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_scheme text NOT NULL DEFAULT 'bcrypt'
+    CHECK (password_scheme IN ('bcrypt', 'argon2id'));
@@ -0,0 +1 @@
+ALTER TABLE study_session_answers DROP COLUMN IF EXISTS graded;
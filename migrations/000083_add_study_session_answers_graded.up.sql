@@ -0,0 +1 @@
+ALTER TABLE study_session_answers ADD COLUMN IF NOT EXISTS graded boolean NOT NULL DEFAULT false;
@@ -0,0 +1,13 @@
+ALTER TABLE exams ADD COLUMN IF NOT EXISTS question_time_limit_ms integer;
+
+UPDATE exams e
+SET question_time_limit_ms = q.time_limit_ms
+FROM (
+    SELECT exam_id, max(time_limit_ms) AS time_limit_ms
+    FROM exam_questions
+    GROUP BY exam_id
+) q
+WHERE q.exam_id = e.id;
+
+ALTER TABLE exam_questions DROP COLUMN IF EXISTS served_at;
+ALTER TABLE exam_questions DROP COLUMN IF EXISTS time_limit_ms;
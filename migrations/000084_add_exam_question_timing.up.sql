@@ -0,0 +1,9 @@
+ALTER TABLE exam_questions ADD COLUMN IF NOT EXISTS time_limit_ms integer CHECK (time_limit_ms > 0);
+ALTER TABLE exam_questions ADD COLUMN IF NOT EXISTS served_at timestamp with time zone;
+
+UPDATE exam_questions eq
+SET time_limit_ms = e.question_time_limit_ms
+FROM exams e
+WHERE e.id = eq.exam_id AND e.question_time_limit_ms IS NOT NULL;
+
+ALTER TABLE exams DROP COLUMN IF EXISTS question_time_limit_ms;